@@ -0,0 +1,33 @@
+// Command puppet-dap is a Debug Adapter Protocol server for Puppet manifests and plans.
+//
+// Usage:
+//
+//	puppet-dap [-listen <address>]
+//
+// The server communicates over stdin and stdout unless an address such as localhost:4711 is given,
+// in which case it accepts clients on that TCP address, one at a time.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lyraproj/puppet-evaluator/dap"
+)
+
+func main() {
+	listen := flag.String(`listen`, ``, `TCP address to listen on instead of using stdin and stdout`)
+	flag.Parse()
+
+	var err error
+	if *listen != `` {
+		err = dap.ListenAndServe(*listen)
+	} else {
+		err = dap.NewServer(os.Stdin, os.Stdout).Serve()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
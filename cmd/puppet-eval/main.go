@@ -0,0 +1,389 @@
+// Command puppet-eval evaluates a Puppet manifest or expression and prints the result.
+//
+// Usage:
+//
+//	puppet-eval [flags] [file]
+//	puppet-eval [flags] -e <expression>
+//	puppet-eval [flags] -i
+//
+// The manifest is read from stdin when neither a file nor an expression is given. The -i flag
+// starts an interactive session that reads expressions from stdin and the -debug flag pauses the
+// evaluation at the first expression and reads debugger commands from stdin. The -profile and
+// -profile-folded flags write the timings of the evaluation to files and the -coverprofile and
+// -lcov flags write the coverage of the evaluated code to files. The -exports flag appoints the
+// file where exported resources are stored and from where they are imported. The -facts flag
+// appoints a JSON or YAML file with the facts that are assigned to global variables and the
+// -host-facts flag computes those facts from the local host instead. The exit code
+// reflects the highest severity of the issues that were reported during the evaluation:
+//
+//	0  no issues or only ignored issues
+//	1  an error was reported
+//	2  the command line was invalid
+//	3  warnings or deprecations were reported
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/coverage"
+	"github.com/lyraproj/puppet-evaluator/debugger"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/exports"
+	"github.com/lyraproj/puppet-evaluator/facts"
+	"github.com/lyraproj/puppet-evaluator/pcore"
+	"github.com/lyraproj/puppet-evaluator/profiler"
+	"github.com/lyraproj/puppet-evaluator/repl"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+const (
+	exitOk      = 0
+	exitError   = 1
+	exitUsage   = 2
+	exitWarning = 3
+)
+
+type (
+	options struct {
+		expression  string
+		modulePath  string
+		environment string
+		strict      string
+		tasks       bool
+		workflow    bool
+		format      string
+		renderAs    string
+		interactive bool
+		debug       bool
+		profile     string
+		folded      string
+		coverage    string
+		lcov        string
+		exports     string
+		facts       string
+		hostFacts   bool
+	}
+
+	// severityLogger delegates to another logger and keeps track of the highest severity
+	// that has been logged
+	severityLogger struct {
+		eval.Logger
+		severity issue.Severity
+	}
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run evaluates the manifest appointed by the given arguments and returns the exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	opts := &options{}
+	flags := flag.NewFlagSet(`puppet-eval`, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.expression, `e`, ``, `evaluate the given expression instead of a file`)
+	flags.StringVar(&opts.modulePath, `modulepath`, ``, `directory containing the modules of the environment`)
+	flags.StringVar(&opts.environment, `environment`, `production`, `name of the environment`)
+	flags.StringVar(&opts.strict, `strict`, `warning`, `strictness of the validation of the manifest: off, warning, or error`)
+	flags.BoolVar(&opts.tasks, `tasks`, false, `enable tasks and plans`)
+	flags.BoolVar(&opts.workflow, `workflow`, false, `enable workflow constructs`)
+	flags.StringVar(&opts.format, `format`, ``, `format used when rendering the result as text, e.g. %p, %#p, or %s. The result is rendered like notice renders it when no format is given`)
+	flags.StringVar(&opts.renderAs, `render-as`, `text`, `how to render the result: text, json, or rich_data`)
+	flags.BoolVar(&opts.interactive, `i`, false, `start an interactive session`)
+	flags.BoolVar(&opts.debug, `debug`, false, `debug the evaluation using commands read from stdin`)
+	flags.StringVar(&opts.profile, `profile`, ``, `write a profiling report of the evaluation to the given file`)
+	flags.StringVar(&opts.folded, `profile-folded`, ``, `write the profiled stacks of the evaluation in folded format to the given file`)
+	flags.StringVar(&opts.coverage, `coverprofile`, ``, `write a coverage profile of the evaluated code to the given file`)
+	flags.StringVar(&opts.lcov, `lcov`, ``, `write the coverage of the evaluated code in LCOV format to the given file`)
+	flags.StringVar(&opts.exports, `exports`, ``, `store exported resources in, and import them from, the given file`)
+	flags.StringVar(&opts.facts, `facts`, ``, `read the facts from the given JSON or YAML file`)
+	flags.BoolVar(&opts.hostFacts, `host-facts`, false, `compute the facts from the local host`)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, `Usage: puppet-eval [flags] [file]`)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	usageError := func(format string, args ...interface{}) int {
+		fmt.Fprintf(stderr, format, args...)
+		fmt.Fprintln(stderr)
+		flags.Usage()
+		return exitUsage
+	}
+	switch opts.strict {
+	case `off`, `warning`, `error`:
+	default:
+		return usageError(`invalid value for -strict: '%s'`, opts.strict)
+	}
+	switch opts.renderAs {
+	case `text`, `json`, `rich_data`:
+	default:
+		return usageError(`invalid value for -render-as: '%s'`, opts.renderAs)
+	}
+	if opts.facts != `` && opts.hostFacts {
+		return usageError(`-facts cannot be given together with -host-facts`)
+	}
+
+	if opts.interactive {
+		if opts.expression != `` || flags.NArg() > 0 {
+			return usageError(`neither a file nor -e can be given together with -i`)
+		}
+		pcore.InitializePuppet()
+		eval.Puppet.Reset()
+		eval.Puppet.SetLogger(eval.NewStdLogger())
+		if err := opts.applySettings(); err != nil {
+			return usageError(`invalid value for -facts: %s`, err)
+		}
+		repl.New(stdin, stdout, func() {
+			if err := opts.applySettings(); err != nil {
+				fmt.Fprintln(stderr, err)
+			}
+		}).Run()
+		return exitOk
+	}
+
+	filename := `<stdin>`
+	var source []byte
+	var err error
+	switch {
+	case opts.expression != ``:
+		if flags.NArg() > 0 {
+			return usageError(`a file cannot be given together with -e`)
+		}
+		filename = `<expression>`
+		source = []byte(opts.expression)
+	case flags.NArg() == 1:
+		filename = flags.Arg(0)
+		source, err = ioutil.ReadFile(filename)
+	case flags.NArg() == 0:
+		if opts.debug {
+			return usageError(`a file or -e must be given together with -debug`)
+		}
+		source, err = ioutil.ReadAll(stdin)
+	default:
+		return usageError(`only one file can be evaluated`)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	pcore.InitializePuppet()
+	eval.Puppet.Reset()
+	if err := opts.applySettings(); err != nil {
+		return usageError(`invalid value for -facts: %s`, err)
+	}
+	logger := &severityLogger{Logger: eval.NewStdLogger(), severity: issue.SEVERITY_IGNORE}
+	eval.Puppet.SetLogger(logger)
+
+	var prof *profiler.Profiler
+	if opts.profile != `` || opts.folded != `` {
+		prof = profiler.New()
+	}
+
+	var cov *coverage.Coverage
+	if opts.coverage != `` || opts.lcov != `` {
+		cov = coverage.New()
+	}
+
+	err = eval.Puppet.Try(func(c eval.Context) error {
+		if cov != nil {
+			cov.Attach(c)
+		}
+		if opts.exports != `` {
+			exports.New(opts.exports).Attach(c)
+		}
+		expr, err := parse(c, filename, string(source))
+		if err != nil {
+			return err
+		}
+		if cov != nil {
+			cov.Parsed(expr)
+		}
+		c.AddDefinitions(expr)
+		if prof != nil {
+			prof.Attach(c)
+		}
+		if opts.debug {
+			c.ResolveDefinitions()
+			d := debugger.New(debugger.NewConsole(stdin, stdout))
+			d.Pause()
+			d.BreakOnError(true)
+			d.Attach(c)
+		}
+		result, ri := eval.TopEvaluate(c, expr)
+		if ri != nil {
+			return ri
+		}
+		return opts.render(c, result, stdout)
+	})
+	if prof != nil {
+		if perr := opts.writeProfile(prof); perr != nil && err == nil {
+			err = perr
+		}
+	}
+	if cov != nil {
+		if cerr := opts.writeCoverage(cov); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		severity := issue.SEVERITY_ERROR
+		if ri, ok := err.(issue.Reported); ok {
+			severity = ri.Severity()
+		}
+		logger.severity = maxSeverity(logger.severity, severity)
+		fmt.Fprintln(stderr, err.Error())
+	}
+	return exitCode(logger.severity)
+}
+
+// applySettings transfers the options to the settings of the runtime. An error is returned when
+// the facts file cannot be loaded.
+func (o *options) applySettings() error {
+	p := eval.Puppet
+	if o.modulePath != `` {
+		p.Set(`module_path`, types.WrapString(o.modulePath))
+	}
+	p.Set(`environment`, types.WrapString(o.environment))
+	p.Set(`strict`, types.WrapString(o.strict))
+	p.Set(`tasks`, types.WrapBoolean(o.tasks))
+	p.Set(`workflow`, types.WrapBoolean(o.workflow))
+	switch {
+	case o.facts != ``:
+		fp := facts.NewFileProvider(o.facts)
+		p.SetFactsProvider(nil)
+		if err := p.Try(func(c eval.Context) error { return fp.Load(c) }); err != nil {
+			return err
+		}
+		p.SetFactsProvider(fp)
+	case o.hostFacts:
+		p.SetFactsProvider(facts.NewHostProvider())
+	default:
+		p.SetFactsProvider(nil)
+	}
+	return nil
+}
+
+// parse parses the given source and validates it with the parser of the runtime, which honors the
+// strict setting. The library validation used by ParseAndValidate is always strict. The issues found
+// by the validation are logged and an error is returned if one of them is an error.
+func parse(c eval.Context, filename, source string) (parser.Expression, error) {
+	expr, result := eval.Puppet.NewParser().Parse(filename, source)
+	if result == nil {
+		return expr, nil
+	}
+	if expr == nil {
+		// The source could not be parsed
+		return nil, result.Issues()[0]
+	}
+	for _, i := range result.Issues() {
+		c.Logger().LogIssue(i)
+	}
+	if result.Error() {
+		return nil, c.Fail(fmt.Sprintf(`Error validating %s`, filename))
+	}
+	return expr, nil
+}
+
+// writeProfile writes the report and the folded stacks of the given profiler to the files given
+// by the options
+func (o *options) writeProfile(prof *profiler.Profiler) error {
+	if err := writeFile(o.profile, prof.WriteReport); err != nil {
+		return err
+	}
+	return writeFile(o.folded, prof.WriteFolded)
+}
+
+// writeCoverage writes the coverage profile and the LCOV tracefile of the given coverage to the
+// files given by the options
+func (o *options) writeCoverage(cov *coverage.Coverage) error {
+	if err := writeFile(o.coverage, cov.WriteProfile); err != nil {
+		return err
+	}
+	return writeFile(o.lcov, cov.WriteLCOV)
+}
+
+// writeFile creates the given file and lets the given writer write to it. Nothing is written
+// when the file name is empty.
+func writeFile(file string, writer func(io.Writer) error) error {
+	if file == `` {
+		return nil
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err = writer(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// render writes the given value to the given writer in the form determined by the options
+func (o *options) render(c eval.Context, value eval.Value, out io.Writer) error {
+	switch o.renderAs {
+	case `json`, `rich_data`:
+		buf := bytes.NewBufferString(``)
+		options := types.SingletonHash2(`rich_data`, types.WrapBoolean(o.renderAs == `rich_data`))
+		serialization.NewSerializer(c, options).Convert(value, serialization.NewJsonStreamer(buf))
+		buf.WriteByte('\n')
+		_, err := buf.WriteTo(out)
+		return err
+	default:
+		if o.format == `` {
+			_, err := fmt.Fprintln(out, eval.ToString(value))
+			return err
+		}
+		fc, err := eval.NewFormatContext3(value, types.WrapString(o.format))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, eval.ToString2(value, fc))
+		return err
+	}
+}
+
+func (l *severityLogger) Log(level eval.LogLevel, args ...eval.Value) {
+	l.severity = maxSeverity(l.severity, level.Severity())
+	l.Logger.Log(level, args...)
+}
+
+func (l *severityLogger) Logf(level eval.LogLevel, format string, args ...interface{}) {
+	l.severity = maxSeverity(l.severity, level.Severity())
+	l.Logger.Logf(level, format, args...)
+}
+
+func (l *severityLogger) LogIssue(i issue.Reported) {
+	l.severity = maxSeverity(l.severity, i.Severity())
+	l.Logger.LogIssue(i)
+}
+
+func maxSeverity(a, b issue.Severity) issue.Severity {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func exitCode(severity issue.Severity) int {
+	switch severity {
+	case issue.SEVERITY_ERROR:
+		return exitError
+	case issue.SEVERITY_WARNING, issue.SEVERITY_DEPRECATION:
+		return exitWarning
+	default:
+		return exitOk
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+func Example_run() {
+	code := run([]string{`-e`, `$x = { a => [1, 2], b => 'c' } notice('evaluating') $x`}, nil, os.Stdout, os.Stdout)
+	fmt.Println(code)
+	// Output:
+	// notice: evaluating
+	// {'a' => [1, 2], 'b' => 'c'}
+	// 0
+}
+
+func Example_run_nested() {
+	code := run([]string{`-e`, `{ a => { b => [1, { c => 2 }] } }`}, nil, os.Stdout, os.Stdout)
+	fmt.Println(code)
+	// Output:
+	// {'a' => {'b' => [1, {'c' => 2}]}}
+	// 0
+}
+
+func Example_run_json() {
+	code := run([]string{`-render-as`, `json`}, strings.NewReader(`{ a => [1, 2], b => 'c' }`), os.Stdout, os.Stdout)
+	fmt.Println(code)
+	// Output:
+	// {"a":[1,2],"b":"c"}
+	// 0
+}
+
+func Example_run_strict() {
+	for _, strict := range []string{`off`, `warning`, `error`} {
+		fmt.Println(strict, run([]string{`-strict`, strict, `-e`, `$x = { a => 1, a => 2 }`}, nil, ioutil.Discard, ioutil.Discard))
+	}
+	// Output:
+	// off 0
+	// warning 3
+	// error 1
+}
+
+func Example_run_facts() {
+	f, err := ioutil.TempFile(``, `facts*.json`)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"os": {"family": "Debian"}, "hostname": "web01"}`)
+	f.Close()
+
+	code := run([]string{`-facts`, f.Name(), `-e`, `[$hostname, $facts['os']['family']]`}, nil, os.Stdout, os.Stdout)
+	fmt.Println(code)
+	// Output:
+	// ['web01', 'Debian']
+	// 0
+}
+
+func Example_run_factsNotFound() {
+	stderr := bytes.NewBufferString(``)
+	code := run([]string{`-facts`, `/no/such/facts.json`, `-e`, `1`}, nil, os.Stdout, stderr)
+	fmt.Println(strings.TrimSpace(strings.SplitN(stderr.String(), "\n", 2)[0]))
+	fmt.Println(code)
+	// Output:
+	// invalid value for -facts: File '/no/such/facts.json' does not exist
+	// 2
+}
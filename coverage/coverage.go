@@ -0,0 +1,339 @@
+// Package coverage implements an eval.Coverage that records which statements and branches of the
+// evaluated Puppet code that are exercised, and reports the result in the coverprofile format
+// used by Go or in the LCOV format.
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// Coverage is an eval.Coverage that counts how many times each statement and each branch of
+	// a conditional expression is evaluated. Code that is parsed repeatedly, e.g. by different
+	// loaders, is counted as one.
+	Coverage struct {
+		lock       sync.Mutex
+		files      map[string]*file
+		statements map[parser.Expression]*statement
+		branches   map[parser.Expression]*branchPoint
+	}
+
+	// FileCoverage summarizes the coverage of one file
+	FileCoverage struct {
+		File            string
+		Lines           int
+		LinesCovered    int
+		Branches        int
+		BranchesCovered int
+	}
+
+	file struct {
+		name       string
+		statements map[int]*statement
+		branches   map[int]*branchPoint
+	}
+
+	// statement is an expression that is evaluated on its own, such as an expression in a block
+	// or the body of a function
+	statement struct {
+		line      int
+		column    int
+		endColumn int
+		count     int
+	}
+
+	// branchPoint is a conditional expression. It holds one count per branch
+	branchPoint struct {
+		line      int
+		evaluated bool
+		counts    []int
+	}
+
+	conditional interface {
+		Then() parser.Expression
+		Else() parser.Expression
+	}
+)
+
+// New creates a new Coverage
+func New() *Coverage {
+	return &Coverage{
+		files:      make(map[string]*file),
+		statements: make(map[parser.Expression]*statement),
+		branches:   make(map[parser.Expression]*branchPoint)}
+}
+
+// Attach attaches the coverage to the given context. Only code that is parsed after the coverage
+// has been attached is covered.
+func (c *Coverage) Attach(ctx eval.Context) {
+	ctx.Set(eval.CoverageKey, c)
+}
+
+// Detach detaches the coverage from the given context
+func (c *Coverage) Detach(ctx eval.Context) {
+	ctx.Delete(eval.CoverageKey)
+}
+
+func (c *Coverage) Parsed(program parser.Expression) {
+	name := program.File()
+	if name == `` {
+		// Type expressions and other snippets that are parsed on the fly are not covered
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	f, ok := c.files[name]
+	if !ok {
+		f = &file{name: name, statements: make(map[int]*statement), branches: make(map[int]*branchPoint)}
+		c.files[name] = f
+	}
+
+	visit := func(_ []parser.Expression, e parser.Expression) {
+		switch e := e.(type) {
+		case *parser.Program:
+			c.addBody(f, e.Body())
+		case *parser.BlockExpression:
+			for _, s := range e.Statements() {
+				c.addStatement(f, s)
+			}
+		case parser.NamedDefinition:
+			c.addBody(f, e.Body())
+		case *parser.LambdaExpression:
+			c.addBody(f, e.Body())
+		case *parser.IfExpression, *parser.UnlessExpression:
+			ce := e.(conditional)
+			c.addBody(f, ce.Then())
+			c.addBody(f, ce.Else())
+			c.addBranchPoint(f, e, 2)
+		case *parser.CaseExpression:
+			options := e.Options()
+			n := len(options) + 1
+			for _, o := range options {
+				co := o.(*parser.CaseOption)
+				c.addBody(f, co.Then())
+				for _, v := range co.Values() {
+					if _, ok := v.(*parser.LiteralDefault); ok {
+						n = len(options)
+					}
+				}
+			}
+			c.addBranchPoint(f, e, n)
+		case *parser.SelectorExpression:
+			selectors := e.Selectors()
+			n := len(selectors) + 1
+			for _, s := range selectors {
+				if _, ok := s.(*parser.SelectorEntry).Matching().(*parser.LiteralDefault); ok {
+					n = len(selectors)
+				}
+			}
+			c.addBranchPoint(f, e, n)
+		}
+	}
+	visit(nil, program)
+	program.AllContents(nil, visit)
+}
+
+// addBody adds the given body unless it is a block, in which case its statements are added when
+// the block itself is visited
+func (c *Coverage) addBody(f *file, body parser.Expression) {
+	if _, ok := body.(*parser.BlockExpression); !ok {
+		c.addStatement(f, body)
+	}
+}
+
+func (c *Coverage) addStatement(f *file, expr parser.Expression) {
+	if expr == nil || expr.IsNop() {
+		return
+	}
+	if _, ok := expr.(parser.Definition); ok {
+		// Definitions are never evaluated. Their bodies are covered separately
+		return
+	}
+	offset := expr.ByteOffset()
+	s, ok := f.statements[offset]
+	if !ok {
+		// The length of an expression is not exact enough to tell where it ends so a statement is
+		// considered to end where its first line ends
+		locator := expr.Locator()
+		line := locator.String()[offset:]
+		if nl := strings.IndexByte(line, '\n'); nl >= 0 {
+			line = line[:nl]
+		}
+		end := offset + len(strings.TrimRightFunc(line, unicode.IsSpace))
+		s = &statement{line: expr.Line(), column: expr.Pos(), endColumn: locator.PosOnLine(end)}
+		f.statements[offset] = s
+	}
+	c.statements[expr] = s
+}
+
+func (c *Coverage) addBranchPoint(f *file, expr parser.Expression, n int) {
+	offset := expr.ByteOffset()
+	b, ok := f.branches[offset]
+	if !ok {
+		b = &branchPoint{line: expr.Line(), counts: make([]int, n)}
+		f.branches[offset] = b
+	}
+	c.branches[expr] = b
+}
+
+func (c *Coverage) Covered(expr parser.Expression) {
+	c.lock.Lock()
+	if s, ok := c.statements[expr]; ok {
+		s.count++
+	}
+	c.lock.Unlock()
+}
+
+func (c *Coverage) Branch(expr parser.Expression, branch int) {
+	c.lock.Lock()
+	if b, ok := c.branches[expr]; ok {
+		b.evaluated = true
+		if branch < len(b.counts) {
+			b.counts[branch]++
+		}
+	}
+	c.lock.Unlock()
+}
+
+// Files returns a summary of the coverage of each file, sorted by file name
+func (c *Coverage) Files() []*FileCoverage {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	result := make([]*FileCoverage, 0, len(c.files))
+	for _, f := range c.sortedFiles() {
+		fc := &FileCoverage{File: f.name}
+		for _, count := range f.lines() {
+			fc.Lines++
+			if count > 0 {
+				fc.LinesCovered++
+			}
+		}
+		for _, b := range f.sortedBranches() {
+			for _, count := range b.counts {
+				fc.Branches++
+				if count > 0 {
+					fc.BranchesCovered++
+				}
+			}
+		}
+		result = append(result, fc)
+	}
+	return result
+}
+
+// WriteProfile writes the statement coverage to the given writer using the coverprofile format
+// of the Go cover tool
+func (c *Coverage) WriteProfile(w io.Writer) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, `mode: count`)
+	for _, f := range c.sortedFiles() {
+		for _, s := range f.sortedStatements() {
+			fmt.Fprintf(bw, "%s:%d.%d,%d.%d 1 %d\n", f.name, s.line, s.column, s.line, s.endColumn, s.count)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteLCOV writes the line and branch coverage to the given writer using the LCOV tracefile
+// format
+func (c *Coverage) WriteLCOV(w io.Writer) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	bw := bufio.NewWriter(w)
+	for _, f := range c.sortedFiles() {
+		fmt.Fprintln(bw, `TN:`)
+		fmt.Fprintf(bw, "SF:%s\n", f.name)
+
+		branches, branchesHit := 0, 0
+		for i, b := range f.sortedBranches() {
+			for j, count := range b.counts {
+				taken := `-`
+				if b.evaluated {
+					taken = fmt.Sprintf(`%d`, count)
+				}
+				fmt.Fprintf(bw, "BRDA:%d,%d,%d,%s\n", b.line, i, j, taken)
+				branches++
+				if count > 0 {
+					branchesHit++
+				}
+			}
+		}
+		fmt.Fprintf(bw, "BRF:%d\nBRH:%d\n", branches, branchesHit)
+
+		lines := f.lines()
+		numbers := make([]int, 0, len(lines))
+		for line := range lines {
+			numbers = append(numbers, line)
+		}
+		sort.Ints(numbers)
+		linesHit := 0
+		for _, line := range numbers {
+			fmt.Fprintf(bw, "DA:%d,%d\n", line, lines[line])
+			if lines[line] > 0 {
+				linesHit++
+			}
+		}
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\n", len(numbers), linesHit)
+		fmt.Fprintln(bw, `end_of_record`)
+	}
+	return bw.Flush()
+}
+
+func (c *Coverage) sortedFiles() []*file {
+	files := make([]*file, 0, len(c.files))
+	for _, f := range c.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files
+}
+
+// lines returns the execution count of each line that starts a statement. The count of a line is
+// the highest count of the statements that start on it.
+func (f *file) lines() map[int]int {
+	lines := make(map[int]int, len(f.statements))
+	for _, s := range f.statements {
+		if count, ok := lines[s.line]; !ok || s.count > count {
+			lines[s.line] = s.count
+		}
+	}
+	return lines
+}
+
+func (f *file) sortedStatements() []*statement {
+	offsets := make([]int, 0, len(f.statements))
+	for offset := range f.statements {
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+	statements := make([]*statement, len(offsets))
+	for i, offset := range offsets {
+		statements[i] = f.statements[offset]
+	}
+	return statements
+}
+
+func (f *file) sortedBranches() []*branchPoint {
+	offsets := make([]int, 0, len(f.branches))
+	for offset := range f.branches {
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+	branches := make([]*branchPoint, len(offsets))
+	for i, offset := range offsets {
+		branches[i] = f.branches[offset]
+	}
+	return branches
+}
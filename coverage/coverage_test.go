@@ -0,0 +1,86 @@
+package coverage_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lyraproj/puppet-evaluator/coverage"
+	"github.com/lyraproj/puppet-evaluator/eval"
+
+	// Ensure that pcore is initialized
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+const manifest = `function classify(Integer $x) {
+  if $x > 10 {
+    'big'
+  } else {
+    'small'
+  }
+}
+$r = [1, 2].map |$v| {
+  case $v {
+    1: { classify($v) }
+    2, 3: { 'two or three' }
+  }
+}
+$s = $r[0] ? { 'small' => true, default => false }
+`
+
+func evaluate(cv *coverage.Coverage) {
+	eval.Puppet.Do(func(c eval.Context) {
+		cv.Attach(c)
+		expr := c.ParseAndValidate(`test.pp`, manifest, false)
+		c.AddDefinitions(expr)
+		eval.TopEvaluate(c, expr)
+	})
+}
+
+func ExampleCoverage_WriteProfile() {
+	cv := coverage.New()
+	evaluate(cv)
+	cv.WriteProfile(os.Stdout)
+	// Output:
+	// mode: count
+	// test.pp:2.3,2.15 1 1
+	// test.pp:3.5,3.10 1 0
+	// test.pp:5.5,5.12 1 1
+	// test.pp:8.1,8.23 1 1
+	// test.pp:9.3,9.12 1 2
+	// test.pp:10.10,10.24 1 1
+	// test.pp:11.13,11.29 1 1
+	// test.pp:14.1,14.51 1 1
+}
+
+func ExampleCoverage_WriteLCOV() {
+	cv := coverage.New()
+	evaluate(cv)
+	cv.WriteLCOV(os.Stdout)
+	for _, f := range cv.Files() {
+		fmt.Printf("%s: %d/%d lines, %d/%d branches\n", f.File, f.LinesCovered, f.Lines, f.BranchesCovered, f.Branches)
+	}
+	// Output:
+	// TN:
+	// SF:test.pp
+	// BRDA:2,0,0,0
+	// BRDA:2,0,1,1
+	// BRDA:9,1,0,1
+	// BRDA:9,1,1,1
+	// BRDA:9,1,2,0
+	// BRDA:14,2,0,1
+	// BRDA:14,2,1,0
+	// BRF:7
+	// BRH:4
+	// DA:2,1
+	// DA:3,0
+	// DA:5,1
+	// DA:8,1
+	// DA:9,2
+	// DA:10,1
+	// DA:11,1
+	// DA:14,1
+	// LF:8
+	// LH:7
+	// end_of_record
+	// test.pp: 7/8 lines, 4/7 branches
+}
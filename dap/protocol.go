@@ -0,0 +1,176 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+type (
+	// request is a message sent from the client to the adapter
+	request struct {
+		Seq       int             `json:"seq"`
+		Type      string          `json:"type"`
+		Command   string          `json:"command"`
+		Arguments json.RawMessage `json:"arguments,omitempty"`
+	}
+
+	// response is the answer that the adapter sends for each request
+	response struct {
+		Seq        int         `json:"seq"`
+		Type       string      `json:"type"`
+		RequestSeq int         `json:"request_seq"`
+		Success    bool        `json:"success"`
+		Command    string      `json:"command"`
+		Message    string      `json:"message,omitempty"`
+		Body       interface{} `json:"body,omitempty"`
+	}
+
+	// event is a message that the adapter sends on its own initiative
+	event struct {
+		Seq   int         `json:"seq"`
+		Type  string      `json:"type"`
+		Event string      `json:"event"`
+		Body  interface{} `json:"body,omitempty"`
+	}
+
+	capabilities struct {
+		SupportsConfigurationDoneRequest bool                        `json:"supportsConfigurationDoneRequest"`
+		SupportsEvaluateForHovers        bool                        `json:"supportsEvaluateForHovers"`
+		SupportsTerminateRequest         bool                        `json:"supportsTerminateRequest"`
+		ExceptionBreakpointFilters       []exceptionBreakpointFilter `json:"exceptionBreakpointFilters"`
+	}
+
+	exceptionBreakpointFilter struct {
+		Filter  string `json:"filter"`
+		Label   string `json:"label"`
+		Default bool   `json:"default"`
+	}
+
+	launchArguments struct {
+		Program     string          `json:"program"`
+		Plan        string          `json:"plan"`
+		Arguments   json.RawMessage `json:"arguments"`
+		ModulePath  string          `json:"modulePath"`
+		StopOnEntry bool            `json:"stopOnEntry"`
+		NoDebug     bool            `json:"noDebug"`
+	}
+
+	source struct {
+		Name string `json:"name,omitempty"`
+		Path string `json:"path,omitempty"`
+	}
+
+	sourceBreakpoint struct {
+		Line int `json:"line"`
+	}
+
+	setBreakpointsArguments struct {
+		Source      source             `json:"source"`
+		Breakpoints []sourceBreakpoint `json:"breakpoints"`
+	}
+
+	breakpoint struct {
+		Verified bool `json:"verified"`
+		Line     int  `json:"line"`
+	}
+
+	setExceptionBreakpointsArguments struct {
+		Filters []string `json:"filters"`
+	}
+
+	thread struct {
+		Id   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	stackFrame struct {
+		Id     int     `json:"id"`
+		Name   string  `json:"name"`
+		Source *source `json:"source,omitempty"`
+		Line   int     `json:"line"`
+		Column int     `json:"column"`
+	}
+
+	scopesArguments struct {
+		FrameId int `json:"frameId"`
+	}
+
+	scope struct {
+		Name               string `json:"name"`
+		VariablesReference int    `json:"variablesReference"`
+		Expensive          bool   `json:"expensive"`
+	}
+
+	variablesArguments struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+
+	variable struct {
+		Name               string `json:"name"`
+		Value              string `json:"value"`
+		Type               string `json:"type,omitempty"`
+		VariablesReference int    `json:"variablesReference"`
+	}
+
+	evaluateArguments struct {
+		Expression string `json:"expression"`
+		FrameId    int    `json:"frameId"`
+		Context    string `json:"context"`
+	}
+
+	stoppedEvent struct {
+		Reason            string `json:"reason"`
+		Description       string `json:"description,omitempty"`
+		Text              string `json:"text,omitempty"`
+		ThreadId          int    `json:"threadId"`
+		AllThreadsStopped bool   `json:"allThreadsStopped"`
+	}
+
+	outputEvent struct {
+		Category string `json:"category"`
+		Output   string `json:"output"`
+	}
+
+	exitedEvent struct {
+		ExitCode int `json:"exitCode"`
+	}
+)
+
+// readRequest reads one message framed by a Content-Length header from the given reader
+func readRequest(in *bufio.Reader) (*request, error) {
+	header, err := textproto.NewReader(in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get(`Content-Length`)))
+	if err != nil || length <= 0 {
+		return nil, fmt.Errorf(`invalid Content-Length header '%s'`, header.Get(`Content-Length`))
+	}
+	content := make([]byte, length)
+	if _, err = io.ReadFull(in, content); err != nil {
+		return nil, err
+	}
+	req := &request{}
+	if err = json.Unmarshal(content, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// writeMessage writes the given message as JSON preceded by a Content-Length header
+func writeMessage(out io.Writer, msg interface{}) error {
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(out, "Content-Length: %d\r\n\r\n", len(content)); err != nil {
+		return err
+	}
+	_, err = out.Write(content)
+	return err
+}
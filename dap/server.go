@@ -0,0 +1,604 @@
+// Package dap implements a Debug Adapter Protocol server that launches manifests and plans and
+// lets a client such as VS Code control their evaluation by means of a debugger.Debugger.
+package dap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/debugger"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/pcore"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// threadId is the id of the one and only thread that the server reports
+const threadId = 1
+
+type (
+	// Server serves one client that communicates using the Debug Adapter Protocol
+	Server struct {
+		in  *bufio.Reader
+		out io.Writer
+
+		writeLock sync.Mutex
+		seq       int
+
+		// lock guards the fields below
+		lock         sync.Mutex
+		debugger     *debugger.Debugger
+		launch       *launchArguments
+		configured   bool
+		started      bool
+		disconnected bool
+		stop         *debugger.Stop
+		stopReason   string
+		references   []eval.Value
+		scopes       map[int]bool
+
+		// commands are received by the evaluation while it is stopped
+		commands chan command
+	}
+
+	// command is either a function that must run on the goroutine of the stopped evaluation, or
+	// the action that resumes it
+	command struct {
+		action debugger.Action
+		fn     func()
+		done   chan struct{}
+	}
+
+	// outputLogger sends everything that is logged to the client as output events
+	outputLogger struct {
+		server *Server
+	}
+)
+
+// NewServer creates a server that reads requests from the given reader and writes responses and
+// events to the given writer
+func NewServer(in io.Reader, out io.Writer) *Server {
+	s := &Server{in: bufio.NewReader(in), out: out, stopReason: `step`, commands: make(chan command)}
+	s.debugger = debugger.New(s.stopped)
+	return s
+}
+
+// ListenAndServe listens on the given TCP address and serves the clients that connect to it, one
+// at a time
+func ListenAndServe(address string) error {
+	listener, err := net.Listen(`tcp`, address)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		err = NewServer(conn, conn).Serve()
+		conn.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Serve reads and handles requests until the client disconnects or the input is exhausted
+func (s *Server) Serve() error {
+	for {
+		req, err := readRequest(s.in)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if !s.handle(req) {
+			return nil
+		}
+	}
+}
+
+// handle responds to the given request and returns false when the client has disconnected
+func (s *Server) handle(req *request) bool {
+	var body interface{}
+	var err error
+	switch req.Command {
+	case `initialize`:
+		body = &capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsEvaluateForHovers:        true,
+			SupportsTerminateRequest:         true,
+			ExceptionBreakpointFilters:       []exceptionBreakpointFilter{{Filter: `error`, Label: `Errors`, Default: true}}}
+	case `launch`:
+		args := &launchArguments{}
+		if err = unmarshal(req, args); err == nil {
+			if args.Program == `` && args.Plan == `` {
+				err = fmt.Errorf(`launch requires a program or a plan`)
+			} else {
+				s.lock.Lock()
+				s.launch = args
+				s.lock.Unlock()
+			}
+		}
+	case `setBreakpoints`:
+		args := &setBreakpointsArguments{}
+		if err = unmarshal(req, args); err == nil {
+			body = s.setBreakpoints(args)
+		}
+	case `setExceptionBreakpoints`:
+		args := &setExceptionBreakpointsArguments{}
+		if err = unmarshal(req, args); err == nil {
+			breakOnError := false
+			for _, f := range args.Filters {
+				breakOnError = breakOnError || f == `error`
+			}
+			s.debugger.BreakOnError(breakOnError)
+		}
+	case `configurationDone`:
+		s.lock.Lock()
+		s.configured = true
+		s.lock.Unlock()
+	case `threads`:
+		body = map[string]interface{}{`threads`: []thread{{threadId, `main`}}}
+	case `stackTrace`:
+		body, err = s.stackTrace()
+	case `scopes`:
+		args := &scopesArguments{}
+		if err = unmarshal(req, args); err == nil {
+			body, err = s.scopesOf(args.FrameId)
+		}
+	case `variables`:
+		args := &variablesArguments{}
+		if err = unmarshal(req, args); err == nil {
+			body, err = s.variables(args.VariablesReference)
+		}
+	case `evaluate`:
+		args := &evaluateArguments{}
+		if err = unmarshal(req, args); err == nil {
+			body, err = s.evaluate(args.Expression)
+		}
+	case `continue`:
+		body = map[string]interface{}{`allThreadsContinued`: true}
+		err = s.resume(debugger.Continue, `step`)
+	case `next`:
+		err = s.resume(debugger.StepOver, `step`)
+	case `stepIn`:
+		err = s.resume(debugger.StepInto, `step`)
+	case `stepOut`:
+		err = s.resume(debugger.StepOut, `step`)
+	case `pause`:
+		s.lock.Lock()
+		s.stopReason = `pause`
+		s.lock.Unlock()
+		s.debugger.Pause()
+	case `terminate`:
+		// A running evaluation is aborted at its next expression and a stopped one right away
+		s.debugger.BreakOnError(false)
+		s.debugger.Terminate()
+		s.resume(debugger.Terminate, `step`)
+	case `disconnect`:
+		s.lock.Lock()
+		s.disconnected = true
+		s.lock.Unlock()
+		s.debugger.BreakOnError(false)
+		s.resume(debugger.Continue, `step`)
+	default:
+		err = fmt.Errorf(`unsupported command '%s'`, req.Command)
+	}
+
+	resp := &response{Type: `response`, RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+		resp.Body = nil
+	}
+	s.send(resp)
+
+	switch req.Command {
+	case `initialize`:
+		s.sendEvent(`initialized`, nil)
+	case `launch`, `configurationDone`:
+		s.start()
+	case `disconnect`:
+		return false
+	}
+	return true
+}
+
+// setBreakpoints replaces the breakpoints of a source file
+func (s *Server) setBreakpoints(args *setBreakpointsArguments) interface{} {
+	file := absPath(args.Source.Path)
+	s.debugger.ClearBreakpoints(file)
+	bps := make([]breakpoint, len(args.Breakpoints))
+	for i, sb := range args.Breakpoints {
+		s.debugger.SetBreakpoint(file, sb.Line)
+		bps[i] = breakpoint{Verified: true, Line: sb.Line}
+	}
+	return map[string]interface{}{`breakpoints`: bps}
+}
+
+// start starts the evaluation once the client has sent both the launch arguments and the end of
+// its configuration
+func (s *Server) start() {
+	s.lock.Lock()
+	args := s.launch
+	ready := args != nil && s.configured && !s.started
+	if ready {
+		s.started = true
+		if args.StopOnEntry && !args.NoDebug {
+			s.stopReason = `entry`
+			s.debugger.Pause()
+		}
+	}
+	s.lock.Unlock()
+	if ready {
+		go s.run(args)
+	}
+}
+
+// run evaluates the program and plan given in the launch arguments. It is called on a goroutine
+// of its own and reports the outcome using output, exited, and terminated events.
+func (s *Server) run(args *launchArguments) {
+	pcore.InitializePuppet()
+	p := eval.Puppet
+	p.Reset()
+	if args.ModulePath != `` {
+		p.Set(`module_path`, types.WrapString(absPath(args.ModulePath)))
+	}
+	p.Set(`tasks`, types.WrapBoolean(args.Plan != ``))
+	p.SetLogger(&outputLogger{s})
+
+	err := p.Try(func(c eval.Context) error {
+		var result eval.Value = eval.UNDEF
+		if args.Program != `` {
+			file := absPath(args.Program)
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			expr := c.ParseAndValidate(file, string(content), false)
+			c.AddDefinitions(expr)
+			c.ResolveDefinitions()
+			if !(args.NoDebug || args.Plan != ``) {
+				// When a plan is given, the program only serves to define it
+				s.debugger.Attach(c)
+			}
+			var ri issue.Reported
+			if result, ri = eval.TopEvaluate(c, expr); ri != nil {
+				return ri
+			}
+		}
+
+		if args.Plan != `` {
+			plan, ok := eval.Load(c, eval.NewTypedName(eval.NsPlan, args.Plan))
+			if !ok {
+				panic(eval.Error(eval.EVAL_UNKNOWN_PLAN, issue.H{`name`: args.Plan}))
+			}
+			planArgs := eval.EMPTY_MAP
+			if len(args.Arguments) > 0 {
+				collector := serialization.NewCollector()
+				serialization.JsonToData(`arguments`, bytes.NewReader(args.Arguments), collector)
+				planArgs = eval.AssertInstance(`arguments`, types.DefaultHashType(), collector.Value()).(eval.OrderedMap)
+			}
+			if !args.NoDebug {
+				s.debugger.Attach(c)
+			}
+			result = plan.(eval.CallNamed).CallNamed(c, nil, planArgs)
+		}
+		s.output(`stdout`, eval.ToString2(result, eval.PRETTY)+"\n")
+		return nil
+	})
+
+	exitCode := 0
+	if err != nil {
+		exitCode = 1
+		s.output(`stderr`, err.Error()+"\n")
+	}
+	s.sendEvent(`exited`, &exitedEvent{exitCode})
+	s.sendEvent(`terminated`, nil)
+}
+
+// stopped is the handler of the debugger. It is called on the goroutine of the evaluation and
+// runs the commands that it receives until one of them resumes the evaluation.
+func (s *Server) stopped(stop *debugger.Stop) debugger.Action {
+	s.lock.Lock()
+	if s.disconnected {
+		s.lock.Unlock()
+		return debugger.Continue
+	}
+	s.stop = stop
+	s.references = nil
+	s.scopes = nil
+	body := &stoppedEvent{Reason: s.stopReason, ThreadId: threadId, AllThreadsStopped: true}
+	s.lock.Unlock()
+
+	switch stop.Reason() {
+	case debugger.Breakpoint:
+		body.Reason = `breakpoint`
+	case debugger.Error:
+		body.Reason = `exception`
+		body.Description = `Error`
+		body.Text = stop.Issue().Error()
+	}
+	s.sendEvent(`stopped`, body)
+
+	for cmd := range s.commands {
+		if cmd.fn == nil {
+			return cmd.action
+		}
+		cmd.fn()
+		close(cmd.done)
+	}
+	return debugger.Continue
+}
+
+// resume resumes a stopped evaluation using the given action. The reason is reported when the
+// evaluation stops after completing a step.
+func (s *Server) resume(action debugger.Action, reason string) error {
+	s.lock.Lock()
+	stop := s.stop
+	s.stop = nil
+	s.stopReason = reason
+	s.lock.Unlock()
+	if stop == nil {
+		return fmt.Errorf(`the evaluation is not stopped`)
+	}
+	s.commands <- command{action: action}
+	return nil
+}
+
+// inStop calls the given function on the goroutine of the stopped evaluation and waits for it
+// to return
+func (s *Server) inStop(f func(stop *debugger.Stop)) error {
+	s.lock.Lock()
+	stop := s.stop
+	s.lock.Unlock()
+	if stop == nil {
+		return fmt.Errorf(`the evaluation is not stopped`)
+	}
+	done := make(chan struct{})
+	s.commands <- command{fn: func() { f(stop) }, done: done}
+	<-done
+	return nil
+}
+
+func (s *Server) stackTrace() (interface{}, error) {
+	var frames []stackFrame
+	err := s.inStop(func(stop *debugger.Stop) {
+		stack := stop.Stack()
+		frames = make([]stackFrame, len(stack))
+		for i, l := range stack {
+			// A frame is named after the call that it belongs to, i.e. the next location on the stack
+			name := `main`
+			if i+1 < len(stack) {
+				name = callName(stack[i+1])
+			}
+			frames[i] = stackFrame{Id: i, Name: name, Line: l.Line(), Column: l.Pos()}
+			if f := l.File(); f != `` {
+				frames[i].Source = &source{Name: filepath.Base(f), Path: f}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{`stackFrames`: frames, `totalFrames`: len(frames)}, nil
+}
+
+// scopesOf returns the scopes of the given frame. Only the innermost frame has variables since
+// the scopes of the callers are not retained by the evaluator.
+func (s *Server) scopesOf(frameId int) (interface{}, error) {
+	scopes := []scope{}
+	err := s.inStop(func(stop *debugger.Stop) {
+		if frameId != 0 {
+			return
+		}
+		locals := make([]*types.HashEntry, 0, 8)
+		globals := make([]*types.HashEntry, 0, 8)
+		stop.Variables().EachPair(func(k, v eval.Value) {
+			e := types.WrapHashEntry(k, v)
+			if stop.Scope().State(k.String()) == eval.Global {
+				globals = append(globals, e)
+			} else {
+				locals = append(locals, e)
+			}
+		})
+		scopes = append(scopes,
+			scope{Name: `Locals`, VariablesReference: s.scopeReference(types.WrapHash(locals))},
+			scope{Name: `Globals`, VariablesReference: s.scopeReference(types.WrapHash(globals))})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{`scopes`: scopes}, nil
+}
+
+// variables returns the elements of the value appointed by the given reference
+func (s *Server) variables(ref int) (interface{}, error) {
+	vars := []variable{}
+	err := s.inStop(func(stop *debugger.Stop) {
+		s.lock.Lock()
+		if ref <= 0 || ref > len(s.references) {
+			s.lock.Unlock()
+			return
+		}
+		value := s.references[ref-1]
+		isScope := s.scopes[ref]
+		s.lock.Unlock()
+
+		add := func(name string, v eval.Value) {
+			vars = append(vars, variable{Name: name, Value: display(v), Type: v.PType().Name(), VariablesReference: s.reference(v)})
+		}
+		switch value := value.(type) {
+		case eval.OrderedMap:
+			value.EachPair(func(k, v eval.Value) {
+				if isScope {
+					add(`$`+k.String(), v)
+				} else {
+					add(display(k), v)
+				}
+			})
+		case eval.List:
+			value.EachWithIndex(func(v eval.Value, i int) { add(fmt.Sprintf(`[%d]`, i), v) })
+		case eval.PuppetObject:
+			value.InitHash().EachPair(func(k, v eval.Value) { add(k.String(), v) })
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{`variables`: vars}, nil
+}
+
+func (s *Server) evaluate(expression string) (interface{}, error) {
+	var result eval.Value
+	var err error
+	if ie := s.inStop(func(stop *debugger.Stop) { result, err = stop.Evaluate(expression) }); ie != nil {
+		return nil, ie
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{`result`: display(result), `type`: result.PType().Name(), `variablesReference`: s.reference(result)}, nil
+}
+
+// reference returns a variables reference for the given value when it has elements that the
+// client can expand, and zero otherwise
+func (s *Server) reference(v eval.Value) int {
+	switch v := v.(type) {
+	case eval.List:
+		if v.Len() == 0 {
+			return 0
+		}
+	case eval.PuppetObject:
+	default:
+		return 0
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.references = append(s.references, v)
+	return len(s.references)
+}
+
+// scopeReference returns a variables reference for the variables of a scope
+func (s *Server) scopeReference(vars eval.OrderedMap) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.references = append(s.references, vars)
+	if s.scopes == nil {
+		s.scopes = make(map[int]bool)
+	}
+	s.scopes[len(s.references)] = true
+	return len(s.references)
+}
+
+func (s *Server) output(category, text string) {
+	s.sendEvent(`output`, &outputEvent{category, text})
+}
+
+func (s *Server) sendEvent(name string, body interface{}) {
+	s.send(&event{Type: `event`, Event: name, Body: body})
+}
+
+// send assigns a sequence number to the given message and writes it. Nothing is written once
+// the client has disconnected.
+func (s *Server) send(msg interface{}) {
+	s.lock.Lock()
+	disconnected := s.disconnected
+	s.lock.Unlock()
+	if disconnected {
+		if r, ok := msg.(*response); !ok || r.Command != `disconnect` {
+			return
+		}
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	s.seq++
+	switch msg := msg.(type) {
+	case *response:
+		msg.Seq = s.seq
+	case *event:
+		msg.Seq = s.seq
+	}
+	// A client that is gone cannot be told that writing failed
+	_ = writeMessage(s.out, msg)
+}
+
+func (l *outputLogger) Log(level eval.LogLevel, args ...eval.Value) {
+	b := bytes.NewBufferString(``)
+	fmt.Fprintf(b, `%s: `, level)
+	for _, arg := range args {
+		eval.ToString3(arg, b)
+	}
+	b.WriteByte('\n')
+	l.server.output(category(level), b.String())
+}
+
+func (l *outputLogger) Logf(level eval.LogLevel, format string, args ...interface{}) {
+	l.server.output(category(level), fmt.Sprintf(`%s: `, level)+fmt.Sprintf(format, args...)+"\n")
+}
+
+func (l *outputLogger) LogIssue(i issue.Reported) {
+	l.server.output(`stderr`, i.String()+"\n")
+}
+
+// category returns the output category for the given level
+func category(level eval.LogLevel) string {
+	switch level {
+	case eval.DEBUG, eval.INFO, eval.NOTICE:
+		return `stdout`
+	default:
+		return `stderr`
+	}
+}
+
+// callName returns the name of the function or method that is called at the given location
+func callName(l issue.Location) string {
+	if call, ok := l.(parser.CallExpression); ok {
+		f := call.Functor()
+		if na, ok := f.(*parser.NamedAccessExpression); ok {
+			f = na.Rhs()
+		}
+		if n, ok := f.(parser.NameExpression); ok {
+			return n.Name()
+		}
+	}
+	if lb, ok := l.(issue.Labeled); ok {
+		return lb.Label()
+	}
+	return `main`
+}
+
+// display returns the value formatted on a single line using the %p format
+func display(v eval.Value) string {
+	fc, err := eval.NewFormatContext3(v, types.WrapString(`%p`))
+	if err != nil {
+		return v.String()
+	}
+	return eval.ToString2(v, fc)
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+func unmarshal(req *request, args interface{}) error {
+	if len(req.Arguments) == 0 {
+		return nil
+	}
+	return json.Unmarshal(req.Arguments, args)
+}
@@ -0,0 +1,248 @@
+package dap_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/dap"
+
+	// Ensure that pcore is initialized
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+const manifest = `function twice(Integer $x) {
+  $y = $x * 2
+  $y
+}
+$a = [1, 2]
+$b = $a.map |$v| {
+  twice($v)
+}
+$b
+`
+
+// client drives a server through a pair of pipes
+type client struct {
+	seq int
+	in  io.Writer
+	out *bufio.Reader
+}
+
+type message struct {
+	Type    string                 `json:"type"`
+	Command string                 `json:"command"`
+	Event   string                 `json:"event"`
+	Success bool                   `json:"success"`
+	Body    map[string]interface{} `json:"body"`
+}
+
+func (c *client) request(command string, args interface{}) map[string]interface{} {
+	c.send(command, args)
+	return c.expect(`response`, command)
+}
+
+// send sends a request without waiting for the response
+func (c *client) send(command string, args interface{}) {
+	c.seq++
+	content, _ := json.Marshal(map[string]interface{}{`seq`: c.seq, `type`: `request`, `command`: command, `arguments`: args})
+	fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(content), content)
+}
+
+// next reads the next message and returns it together with its content
+func (c *client) next() (*message, []byte) {
+	header, err := textproto.NewReader(c.out).ReadMIMEHeader()
+	if err != nil {
+		panic(err)
+	}
+	length, _ := strconv.Atoi(header.Get(`Content-Length`))
+	content := make([]byte, length)
+	io.ReadFull(c.out, content)
+	msg := &message{}
+	json.Unmarshal(content, msg)
+	return msg, content
+}
+
+// expect reads messages until a response or event with the given name is found
+func (c *client) expect(kind, name string) map[string]interface{} {
+	for {
+		msg, content := c.next()
+		if msg.Type == kind && (msg.Command == name || msg.Event == name) {
+			if kind == `response` && !msg.Success {
+				panic(string(content))
+			}
+			return msg.Body
+		}
+	}
+}
+
+func ExampleServer() {
+	dir, _ := ioutil.TempDir(``, `dap`)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, `test.pp`)
+	ioutil.WriteFile(file, []byte(manifest), 0644)
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	go dap.NewServer(inReader, outWriter).Serve()
+	c := &client{in: inWriter, out: bufio.NewReader(outReader)}
+
+	c.request(`initialize`, map[string]interface{}{`adapterID`: `puppet`})
+	c.expect(`event`, `initialized`)
+	c.request(`launch`, map[string]interface{}{`program`: file})
+	c.request(`setBreakpoints`, map[string]interface{}{
+		`source`:      map[string]interface{}{`path`: file},
+		`breakpoints`: []interface{}{map[string]interface{}{`line`: 3}}})
+	c.request(`configurationDone`, nil)
+
+	stopped := c.expect(`event`, `stopped`)
+	fmt.Println(`stopped:`, stopped[`reason`])
+
+	frames := c.request(`stackTrace`, map[string]interface{}{`threadId`: 1})[`stackFrames`].([]interface{})
+	for _, f := range frames {
+		frame := f.(map[string]interface{})
+		fmt.Printf("frame: %s %s:%v\n", frame[`name`], frame[`source`].(map[string]interface{})[`name`], frame[`line`])
+	}
+
+	printVariables := func(ref interface{}) {
+		vars := c.request(`variables`, map[string]interface{}{`variablesReference`: ref})[`variables`].([]interface{})
+		for _, v := range vars {
+			variable := v.(map[string]interface{})
+			fmt.Printf("  %s = %s\n", variable[`name`], variable[`value`])
+			if ref := variable[`variablesReference`].(float64); ref > 0 {
+				vars := c.request(`variables`, map[string]interface{}{`variablesReference`: ref})[`variables`].([]interface{})
+				for _, v := range vars {
+					element := v.(map[string]interface{})
+					fmt.Printf("    %s = %s\n", element[`name`], element[`value`])
+				}
+			}
+		}
+	}
+	scopes := c.request(`scopes`, map[string]interface{}{`frameId`: 0})[`scopes`].([]interface{})
+	for _, s := range scopes {
+		scope := s.(map[string]interface{})
+		fmt.Printf("%s:\n", scope[`name`])
+		printVariables(scope[`variablesReference`])
+	}
+
+	result := c.request(`evaluate`, map[string]interface{}{`expression`: `$y + 10`, `frameId`: 0})
+	fmt.Println(`evaluate:`, result[`result`])
+
+	c.request(`continue`, map[string]interface{}{`threadId`: 1})
+	fmt.Println(`stopped:`, c.expect(`event`, `stopped`)[`reason`])
+	c.request(`setBreakpoints`, map[string]interface{}{`source`: map[string]interface{}{`path`: file}, `breakpoints`: []interface{}{}})
+	c.request(`continue`, map[string]interface{}{`threadId`: 1})
+	fmt.Print(`output: `, c.expect(`event`, `output`)[`output`])
+	fmt.Println(`exit code:`, c.expect(`event`, `exited`)[`exitCode`])
+	c.expect(`event`, `terminated`)
+	c.request(`disconnect`, nil)
+
+	// Output:
+	// stopped: breakpoint
+	// frame: twice test.pp:3
+	// frame: map test.pp:7
+	// frame: main test.pp:6
+	// Locals:
+	//   $v = 1
+	//   $x = 1
+	//   $y = 2
+	// Globals:
+	//   $a = [1, 2]
+	//     [0] = 1
+	//     [1] = 2
+	// evaluate: 12
+	// stopped: breakpoint
+	// output: [2, 4]
+	// exit code: 0
+}
+
+func ExampleServer_plan() {
+	dir, _ := ioutil.TempDir(``, `dap`)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, `plan.pp`)
+	ioutil.WriteFile(file, []byte("plan triple(Integer $n) {\n  $n * 3\n}\n"), 0644)
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	go dap.NewServer(inReader, outWriter).Serve()
+	c := &client{in: inWriter, out: bufio.NewReader(outReader)}
+
+	c.request(`initialize`, nil)
+	c.expect(`event`, `initialized`)
+	c.request(`launch`, map[string]interface{}{
+		`program`: file, `plan`: `triple`, `arguments`: map[string]interface{}{`n`: 4}, `stopOnEntry`: true})
+	c.request(`configurationDone`, nil)
+	fmt.Println(`stopped:`, c.expect(`event`, `stopped`)[`reason`])
+	fmt.Println(`evaluate:`, c.request(`evaluate`, map[string]interface{}{`expression`: `$n`})[`result`])
+	c.request(`continue`, nil)
+	fmt.Print(`output: `, c.expect(`event`, `output`)[`output`])
+	c.expect(`event`, `terminated`)
+	c.request(`disconnect`, nil)
+
+	// Output:
+	// stopped: entry
+	// evaluate: 4
+	// output: 12
+}
+
+func ExampleServer_terminate() {
+	dir, _ := ioutil.TempDir(``, `dap`)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, `test.pp`)
+	ioutil.WriteFile(file, []byte("notice('one')\nnotice('two')\nnotice('three')\n"), 0644)
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	go dap.NewServer(inReader, outWriter).Serve()
+	c := &client{in: inWriter, out: bufio.NewReader(outReader)}
+
+	c.request(`initialize`, nil)
+	c.expect(`event`, `initialized`)
+	c.request(`launch`, map[string]interface{}{`program`: file})
+	c.request(`setBreakpoints`, map[string]interface{}{
+		`source`:      map[string]interface{}{`path`: file},
+		`breakpoints`: []interface{}{map[string]interface{}{`line`: 2}}})
+	c.request(`configurationDone`, nil)
+	fmt.Println(`stopped:`, c.expect(`event`, `stopped`)[`reason`])
+
+	// Print the messages that follow the terminate request. The disconnect request is sent once the
+	// evaluation has terminated, so any output that follows would be printed before its response.
+	c.send(`terminate`, nil)
+	terminated, responded, disconnecting := false, false, false
+	for {
+		msg, _ := c.next()
+		switch {
+		case msg.Event == `output`:
+			fmt.Printf("output: %s", strings.Replace(msg.Body[`output`].(string), file, filepath.Base(file), 1))
+		case msg.Event == `exited`:
+			fmt.Println(`exit code:`, msg.Body[`exitCode`])
+		case msg.Type == `event`:
+			fmt.Println(`event:`, msg.Event)
+			terminated = terminated || msg.Event == `terminated`
+		case msg.Command == `disconnect`:
+			fmt.Println(`disconnected`)
+			return
+		default:
+			// The response to terminate may arrive before or after the events
+			responded = true
+		}
+		if terminated && responded && !disconnecting {
+			c.send(`disconnect`, nil)
+			disconnecting = true
+		}
+	}
+
+	// Output:
+	// stopped: breakpoint
+	// output: The evaluation was terminated by the debugger (file: test.pp, line: 2, column: 1)
+	// exit code: 1
+	// event: terminated
+	// disconnected
+}
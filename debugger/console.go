@@ -0,0 +1,105 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+const consoleHelp = `Commands:
+  c, continue          continue until the next breakpoint
+  s, step              step into calls
+  n, next              step over calls
+  o, out               step out of the current call
+  bt, where            show the call stack
+  v, vars              show the variables of the current scope
+  p, print <expr>      evaluate an expression in the current scope
+  b, break <file:line> set a breakpoint
+  d, delete <file:line> remove a breakpoint
+  h, help              show this text`
+
+// NewConsole returns a Handler that reads commands line by line from the given reader and writes
+// its output to the given writer. Evaluation continues when the reader is exhausted.
+func NewConsole(in io.Reader, out io.Writer) Handler {
+	scanner := bufio.NewScanner(in)
+	return func(stop *Stop) Action {
+		loc := stop.Location()
+		fmt.Fprintf(out, "Stopped at %s (%s)\n", locationString(loc), stop.Reason())
+		if ri := stop.Issue(); ri != nil {
+			fmt.Fprintln(out, ri.Error())
+		}
+		for {
+			fmt.Fprint(out, `(debug) `)
+			if !scanner.Scan() {
+				fmt.Fprintln(out)
+				return Continue
+			}
+			line := strings.TrimSpace(scanner.Text())
+			cmd := line
+			arg := ``
+			if i := strings.IndexAny(line, " \t"); i > 0 {
+				cmd = line[:i]
+				arg = strings.TrimSpace(line[i+1:])
+			}
+			switch cmd {
+			case `c`, `continue`:
+				return Continue
+			case `s`, `step`:
+				return StepInto
+			case `n`, `next`:
+				return StepOver
+			case `o`, `out`:
+				return StepOut
+			case `bt`, `where`:
+				for i, l := range stop.Stack() {
+					fmt.Fprintf(out, "#%d %s\n", i, locationString(l))
+				}
+			case `v`, `vars`:
+				stop.Variables().EachPair(func(k, v eval.Value) {
+					fmt.Fprintf(out, "$%s = %s\n", k, eval.ToString2(v, eval.PRETTY))
+				})
+			case `p`, `print`:
+				if v, err := stop.Evaluate(arg); err != nil {
+					fmt.Fprintln(out, err.Error())
+				} else {
+					fmt.Fprintln(out, eval.ToString2(v, eval.PRETTY))
+				}
+			case `b`, `break`, `d`, `delete`:
+				file, line, ok := parseFileLine(arg)
+				if !ok {
+					fmt.Fprintf(out, "Expected <file>:<line>, got '%s'\n", arg)
+				} else if cmd == `b` || cmd == `break` {
+					stop.Debugger().SetBreakpoint(file, line)
+				} else {
+					stop.Debugger().ClearBreakpoint(file, line)
+				}
+			case `h`, `help`:
+				fmt.Fprintln(out, consoleHelp)
+			case ``:
+			default:
+				fmt.Fprintf(out, "Unknown command '%s'. Use help to list the available commands\n", cmd)
+			}
+		}
+	}
+}
+
+func locationString(l issue.Location) string {
+	return fmt.Sprintf(`%s:%d:%d`, l.File(), l.Line(), l.Pos())
+}
+
+func parseFileLine(s string) (string, int, bool) {
+	i := strings.LastIndexByte(s, ':')
+	if i <= 0 {
+		return ``, 0, false
+	}
+	line, err := strconv.Atoi(s[i+1:])
+	if err != nil || line <= 0 {
+		return ``, 0, false
+	}
+	return s[:i], line, true
+}
@@ -0,0 +1,325 @@
+package debugger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// Action tells the debugger how to continue after a stop
+	Action int
+
+	// Reason tells why the debugger stopped
+	Reason int
+
+	// A Handler is called by the debugger when evaluation stops. Evaluation remains paused until the
+	// handler returns the action that determines how it continues.
+	Handler func(stop *Stop) Action
+
+	// Debugger is an eval.Debugger that pauses the evaluation when a breakpoint is reached, when a
+	// step is completed, or when an issue is raised, and hands control over to a Handler.
+	Debugger struct {
+		lock         sync.Mutex
+		handler      Handler
+		breakpoints  map[string]map[int]bool
+		breakOnError bool
+		action       Action
+		stopDepth    int
+		paused       bool
+		lastIssue    issue.Reported
+		position     position
+	}
+
+	// A Stop describes the state of a paused evaluation
+	Stop struct {
+		debugger   *Debugger
+		context    eval.Context
+		reason     Reason
+		expression parser.Expression
+		issue      issue.Reported
+	}
+
+	position struct {
+		file  string
+		line  int
+		depth int
+	}
+)
+
+const (
+	// Continue runs until a breakpoint is reached or an issue is raised
+	Continue = Action(iota)
+
+	// StepInto stops at the next expression that starts a new line or is evaluated in another call
+	StepInto
+
+	// StepOver stops at the next expression that starts a new line without descending into calls
+	StepOver
+
+	// StepOut stops at the next expression that is evaluated after the current call has returned
+	StepOut
+
+	// Terminate aborts the evaluation with an EVAL_DEBUGGER_TERMINATED error
+	Terminate
+)
+
+const (
+	// Breakpoint means that a breakpoint was reached
+	Breakpoint = Reason(iota)
+
+	// Step means that a step was completed or that the debugger was paused
+	Step
+
+	// Error means that an issue was raised
+	Error
+)
+
+// New creates a new Debugger that calls the given handler each time evaluation stops. The debugger
+// will not stop until breakpoints are set, breaking on errors is enabled, or Pause is called.
+func New(handler Handler) *Debugger {
+	return &Debugger{handler: handler, breakpoints: make(map[string]map[int]bool)}
+}
+
+// Attach attaches the debugger to the given context
+func (d *Debugger) Attach(c eval.Context) {
+	c.Set(eval.DebuggerKey, d)
+}
+
+// Detach detaches the debugger from the given context
+func (d *Debugger) Detach(c eval.Context) {
+	c.Delete(eval.DebuggerKey)
+}
+
+// BreakOnError controls whether or not the debugger stops when an issue is raised
+func (d *Debugger) BreakOnError(flag bool) {
+	d.lock.Lock()
+	d.breakOnError = flag
+	d.lock.Unlock()
+}
+
+// Pause makes the debugger stop at the next expression that starts a new line
+func (d *Debugger) Pause() {
+	d.lock.Lock()
+	d.action = StepInto
+	d.lock.Unlock()
+}
+
+// Terminate makes the debugger abort the evaluation at the next expression
+func (d *Debugger) Terminate() {
+	d.lock.Lock()
+	d.action = Terminate
+	d.lock.Unlock()
+}
+
+// SetBreakpoint sets a breakpoint at the given line of the given file
+func (d *Debugger) SetBreakpoint(file string, line int) {
+	d.lock.Lock()
+	lines, ok := d.breakpoints[file]
+	if !ok {
+		lines = make(map[int]bool)
+		d.breakpoints[file] = lines
+	}
+	lines[line] = true
+	d.lock.Unlock()
+}
+
+// ClearBreakpoint removes the breakpoint at the given line of the given file
+func (d *Debugger) ClearBreakpoint(file string, line int) {
+	d.lock.Lock()
+	delete(d.breakpoints[file], line)
+	d.lock.Unlock()
+}
+
+// ClearBreakpoints removes all breakpoints of the given file
+func (d *Debugger) ClearBreakpoints(file string) {
+	d.lock.Lock()
+	delete(d.breakpoints, file)
+	d.lock.Unlock()
+}
+
+func (d *Debugger) Before(c eval.Context, expr parser.Expression) {
+	if d.paused {
+		// Expressions evaluated on behalf of the handler are not subject to debugging
+		return
+	}
+	if _, ok := expr.(*parser.Program); ok {
+		// The program is a container. Stops are made at its expressions
+		return
+	}
+	d.lock.Lock()
+	terminate := d.action == Terminate
+	d.lock.Unlock()
+	if terminate {
+		panic(eval.Error2(expr, eval.EVAL_DEBUGGER_TERMINATED, issue.NO_ARGS))
+	}
+
+	pos := position{expr.File(), expr.Line(), callDepth(c)}
+	if pos == d.position {
+		return
+	}
+	d.position = pos
+
+	d.lock.Lock()
+	stop := false
+	switch d.action {
+	case StepInto:
+		stop = true
+	case StepOver:
+		stop = pos.depth <= d.stopDepth
+	case StepOut:
+		stop = pos.depth < d.stopDepth
+	}
+	reason := Step
+	if !stop && d.breakpoints[pos.file][pos.line] {
+		stop = true
+		reason = Breakpoint
+	}
+	d.lock.Unlock()
+
+	if stop {
+		d.stop(c, reason, expr, nil)
+	}
+}
+
+func (d *Debugger) Raised(c eval.Context, expr parser.Expression, reported issue.Reported) {
+	if d.paused || reported == d.lastIssue {
+		return
+	}
+	// The issue is reported once for each expression that it propagates through. Only the
+	// innermost expression is of interest.
+	d.lastIssue = reported
+
+	d.lock.Lock()
+	stop := d.breakOnError && d.action != Terminate
+	d.lock.Unlock()
+
+	if stop {
+		d.stop(c, Error, expr, reported)
+	}
+}
+
+func (d *Debugger) stop(c eval.Context, reason Reason, expr parser.Expression, reported issue.Reported) {
+	d.paused = true
+	action := d.handler(&Stop{debugger: d, context: c, reason: reason, expression: expr, issue: reported})
+	d.paused = false
+
+	d.lock.Lock()
+	d.action = action
+	d.stopDepth = callDepth(c)
+	d.lock.Unlock()
+
+	if action == Terminate {
+		panic(eval.Error2(expr, eval.EVAL_DEBUGGER_TERMINATED, issue.NO_ARGS))
+	}
+}
+
+// callDepth returns the number of calls that are in progress
+func callDepth(c eval.Context) int {
+	depth := 0
+	for _, l := range c.Stack() {
+		if _, ok := l.(*parser.Program); !ok {
+			depth++
+		}
+	}
+	return depth
+}
+
+func (a Action) String() string {
+	switch a {
+	case StepInto:
+		return `step into`
+	case StepOver:
+		return `step over`
+	case StepOut:
+		return `step out`
+	case Terminate:
+		return `terminate`
+	default:
+		return `continue`
+	}
+}
+
+func (r Reason) String() string {
+	switch r {
+	case Breakpoint:
+		return `breakpoint`
+	case Error:
+		return `error`
+	default:
+		return `step`
+	}
+}
+
+// Debugger returns the debugger that stopped
+func (s *Stop) Debugger() *Debugger {
+	return s.debugger
+}
+
+// Reason returns the reason for the stop
+func (s *Stop) Reason() Reason {
+	return s.reason
+}
+
+// Location returns the location of the expression that is about to be evaluated or, when the
+// reason is Error, the location of the expression that raised the issue
+func (s *Stop) Location() issue.Location {
+	return s.expression
+}
+
+// Issue returns the raised issue when the reason is Error, and nil otherwise
+func (s *Stop) Issue() issue.Reported {
+	return s.issue
+}
+
+// Stack returns the locations of the calls that are in progress, innermost first. The first
+// element is always the location of the stop.
+func (s *Stop) Stack() []issue.Location {
+	cs := s.context.Stack()
+	stack := make([]issue.Location, 0, len(cs)+1)
+	stack = append(stack, s.expression)
+	for i := len(cs) - 1; i >= 0; i-- {
+		if _, ok := cs[i].(*parser.Program); !ok {
+			stack = append(stack, cs[i])
+		}
+	}
+	return stack
+}
+
+// Scope returns the current scope of the paused evaluation
+func (s *Stop) Scope() eval.Scope {
+	return s.context.Scope()
+}
+
+// Variables returns a hash of all variables that are visible in the current scope, sorted by name
+func (s *Stop) Variables() eval.OrderedMap {
+	entries := make([]*types.HashEntry, 0, 16)
+	if vi, ok := s.context.Scope().(eval.VariableIterator); ok {
+		vi.Each(func(name string, value eval.Value) {
+			entries = append(entries, types.WrapHashEntry2(name, value))
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key().String() < entries[j].Key().String() })
+	return types.WrapHash(entries)
+}
+
+// Evaluate parses and evaluates the given source in the current scope. The evaluation is never
+// subject to debugging.
+func (s *Stop) Evaluate(source string) (result eval.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf(`%v`, r)
+			}
+		}
+	}()
+	c := s.context
+	return eval.Evaluate(c, c.ParseAndValidate(`<debugger>`, source, false)), nil
+}
@@ -0,0 +1,69 @@
+package debugger_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/debugger"
+	"github.com/lyraproj/puppet-evaluator/eval"
+
+	// Ensure that pcore is initialized
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+const manifest = `function twice(Integer $x) {
+  $y = $x * 2
+  $y
+}
+$a = [1, 2]
+$b = $a.map |$v| {
+  twice($v)
+}
+$c = twice('x')
+`
+
+func evaluate(c eval.Context, d *debugger.Debugger) {
+	expr := c.ParseAndValidate(`test.pp`, manifest, false)
+	c.AddDefinitions(expr)
+	c.ResolveDefinitions()
+	d.Attach(c)
+	_, err := eval.TopEvaluate(c, expr)
+	fmt.Println(err)
+}
+
+func ExampleDebugger() {
+	actions := []debugger.Action{debugger.StepInto, debugger.StepOut, debugger.Continue}
+	d := debugger.New(func(stop *debugger.Stop) debugger.Action {
+		fmt.Printf("%s at line %d: %s\n", stop.Reason(), stop.Location().Line(), eval.ToString(stop.Variables()))
+		action := actions[0]
+		actions = actions[1:]
+		return action
+	})
+	d.SetBreakpoint(`test.pp`, 7)
+	eval.Puppet.Do(func(c eval.Context) { evaluate(c, d) })
+	// Output:
+	// breakpoint at line 7: {'a' => [1, 2], 'v' => 1}
+	// step at line 2: {'a' => [1, 2], 'v' => 1, 'x' => 1}
+	// step at line 7: {'a' => [1, 2], 'v' => 2}
+	// Error when evaluating a Function Call: Expected argument 0 to be Integer, got String (file: test.pp, line: 9, column: 12)
+}
+
+func ExampleNewConsole() {
+	commands := strings.Join([]string{`n`, `n`, `p $a.map |$v| { $v + 1 }`, `c`, `bt`, `vars`, `c`}, "\n")
+	d := debugger.New(debugger.NewConsole(strings.NewReader(commands), os.Stdout))
+	d.Pause()
+	d.BreakOnError(true)
+	eval.Puppet.Do(func(c eval.Context) { evaluate(c, d) })
+	// Output:
+	// Stopped at test.pp:1:10 (step)
+	// (debug) Stopped at test.pp:5:1 (step)
+	// (debug) Stopped at test.pp:6:1 (step)
+	// (debug) [2, 3]
+	// (debug) Stopped at test.pp:9:6 (error)
+	// Error when evaluating a Function Call: Expected argument 0 to be Integer, got String (file: test.pp, line: 9, column: 12)
+	// (debug) #0 test.pp:9:6
+	// (debug) $a = [1, 2]
+	// $b = [2, 4]
+	// (debug) Error when evaluating a Function Call: Expected argument 0 to be Integer, got String (file: test.pp, line: 9, column: 12)
+}
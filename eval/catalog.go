@@ -0,0 +1,175 @@
+package eval
+
+import (
+	"github.com/lyraproj/issue/issue"
+)
+
+const CatalogKey = `puppet.catalog`
+
+const ContainerKey = `puppet.container`
+
+type (
+	// A ResourceReference is a reference to a resource of a specific type with a specific
+	// title. It is implemented by the Resource[<type name>, <title>] type, more commonly
+	// written as <Type name>['<title>']. The reference is valid even when no such resource
+	// has been declared yet.
+	ResourceReference interface {
+		Type
+
+		// TypeName returns the capitalized name of the resource type, e.g. "File"
+		TypeName() string
+
+		// Title returns the resource title
+		Title() string
+	}
+
+	// A Resource is the value that is produced and stored in the Catalog when a resource
+	// expression is evaluated.
+	Resource interface {
+		PuppetObject
+
+		// TypeName returns the capitalized name of the resource type, e.g. "File"
+		TypeName() string
+
+		// Title returns the resource title
+		Title() string
+
+		// Reference returns a reference to this resource
+		Reference() ResourceReference
+
+		// Parameters returns the parameters of this resource
+		Parameters() OrderedMap
+
+		// Origin returns the location of the expression that declared this resource
+		Origin() issue.Location
+
+		// Container returns a reference to the class or defined type resource that contains
+		// this resource, or nil if the resource is not contained
+		Container() ResourceReference
+	}
+
+	// An Edge is a relationship between two resources in the catalog. The edge is created
+	// by one of the relationship operators ->, ~>, <-, or <~.
+	Edge interface {
+		Value
+
+		// Source returns the resource that must be applied first
+		Source() ResourceReference
+
+		// Target returns the resource that must be applied after the source
+		Target() ResourceReference
+
+		// Subscribe returns true if the target should be notified when the source changes
+		Subscribe() bool
+	}
+
+	// A Collector selects resources of a specific type from the catalog. It is created when a
+	// collector expression such as File <| owner == 'root' |> is evaluated.
+	Collector interface {
+		// TypeName returns the capitalized name of the resource type that is collected
+		TypeName() string
+
+		// Exported returns true if only exported resources are collected
+		Exported() bool
+
+		// Matches returns true if the given resource, which is known to be of the collected type,
+		// matches the query of the collector
+		Matches(resource Resource) bool
+
+		// Overrides returns the parameters that will override the parameters of each collected
+		// resource. The returned map is empty when there are no overrides.
+		Overrides() OrderedMap
+	}
+
+	// A Catalog contains all resources and edges produced by an evaluation.
+	Catalog interface {
+		Value
+
+		// AddResource adds the given resource to the catalog. It will panic with an issue.Reported
+		// if a resource with the same reference has already been added.
+		AddResource(resource Resource)
+
+		// AddVirtualResource adds the given resource to the catalog without realizing it. The
+		// resource is realized when it is matched by a collector or appointed by Realize. It will
+		// panic with an issue.Reported if a resource with the same reference has already been added.
+		AddVirtualResource(resource Resource, exported bool)
+
+		// ImportResource adds the given resource, which was exported by another evaluation, to the
+		// catalog without realizing it. The resource is collected by exported resource collectors but
+		// is not exported by this catalog. It will panic with an issue.Reported if a resource with the
+		// same reference has already been added.
+		ImportResource(resource Resource)
+
+		// AddEdge adds an edge between the source and the target
+		AddEdge(source, target ResourceReference, subscribe bool)
+
+		// Collect realizes all virtual resources that match the given collector and applies the
+		// overrides of the collector to all matching resources. The collector is retained so that
+		// matching resources that are added later are collected too. References to the resources
+		// that matched are returned.
+		Collect(collector Collector) []ResourceReference
+
+		// Contain makes the container the container of the contained resource. It will panic with an
+		// issue.Reported if the contained resource is not found in the catalog.
+		Contain(container, contained ResourceReference)
+
+		// Edges returns all edges in the order they were added
+		Edges() []Edge
+
+		// ExportedResources returns all exported resources, realized or not, in the order they
+		// were added
+		ExportedResources() []Resource
+
+		// Override replaces the parameters of the resource, realized or virtual, that is appointed
+		// by the given reference with the parameters returned by the given function when called with
+		// that resource. A resource that hasn't been added yet is overridden when it is added.
+		Override(ref ResourceReference, override func(resource Resource) OrderedMap)
+
+		// Realize realizes the virtual resource appointed by the given reference. A resource that
+		// hasn't been added yet is realized when it is added.
+		Realize(ref ResourceReference)
+
+		// Resource returns the realized resource with the given type name and title together with
+		// a bool that indicates if the resource was found
+		Resource(typeName, title string) (Resource, bool)
+
+		// Resources returns all realized resources in the order they were added
+		Resources() []Resource
+
+		// Validate ensures that all edges in the catalog refers to resources that exist in
+		// the catalog, that all resources appointed by Realize have been realized, and that all
+		// resources appointed by Override have been added. It will panic with an issue.Reported if
+		// that is not the case.
+		Validate()
+
+		// VirtualResources returns all virtual resources that have not been realized in the
+		// order they were added
+		VirtualResources() []Resource
+	}
+)
+
+// NewCatalog creates a new empty Catalog
+var NewCatalog func() Catalog
+
+// NewResource creates a new Resource. The parameters map and the container may be nil.
+var NewResource func(typeName, title string, parameters OrderedMap, container ResourceReference, origin issue.Location) Resource
+
+// CatalogOf returns the catalog that is stored in the given context. A new Catalog
+// is created and stored in the context when no catalog is found.
+func CatalogOf(c Context) Catalog {
+	if cv, ok := c.Get(CatalogKey); ok {
+		return cv.(Catalog)
+	}
+	cat := NewCatalog()
+	c.Set(CatalogKey, cat)
+	return cat
+}
+
+// ContainerOf returns a reference to the class or defined type resource that is currently being
+// evaluated, or nil if no such resource is being evaluated.
+func ContainerOf(c Context) ResourceReference {
+	if cv, ok := c.Get(ContainerKey); ok {
+		return cv.(ResourceReference)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package eval
+
+import (
+	"github.com/lyraproj/issue/issue"
+)
+
+type (
+	// A ResourceDefinition is a class or a defined type declared in the Puppet language
+	ResourceDefinition interface {
+		Value
+		issue.Named
+
+		// Parameters returns the parameters of the definition
+		Parameters() []Parameter
+	}
+
+	// A Class is a named collection of resources. A class is declared at most once
+	// in a catalog and is represented by the resource Class['<name>']
+	Class interface {
+		ResourceDefinition
+
+		// ParentName returns the name of the class that this class inherits or an
+		// empty string if the class doesn't inherit another class
+		ParentName() string
+	}
+
+	// A DefinedType is a resource type whose body is evaluated once for each resource
+	// of the type that is declared in a catalog
+	DefinedType interface {
+		ResourceDefinition
+	}
+
+	// A Node is a node definition. The body of the node is evaluated when the node is
+	// the best match for the name of the node that a catalog is compiled for.
+	Node interface {
+		Value
+
+		// HostMatches returns the strings and regular expressions that the node matches
+		HostMatches() []Value
+
+		// IsDefault returns true if this is the default node
+		IsDefault() bool
+
+		// MatchName returns true if one of the host matches of the node is a string that
+		// is equal to the given name
+		MatchName(name string) bool
+
+		// MatchRegexp returns true if one of the host matches of the node is a regular
+		// expression that matches the given name
+		MatchRegexp(name string) bool
+	}
+)
+
+// DeclareClass declares the class with the given name using the given parameters and
+// returns a reference to the Class resource. The class is evaluated the first time it is
+// declared. Subsequent declarations will panic with an issue.Reported unless the parameters
+// argument is nil. A nil parameters argument results in an include-like declaration.
+var DeclareClass func(c Context, name string, parameters OrderedMap) ResourceReference
+
+// FindNode returns the node definition that is the best match for the given node name
+// together with a bool that indicates if a match was found. An exact match of the name is
+// tried first, then the name is stripped of its last dot separated segment, one at a time,
+// and each shorter name is tried. Regular expressions are then tried in the order they were
+// defined. Finally, the default node is tried.
+var FindNode func(c Context, nodeName string) (Node, bool)
+
+// EvaluateNode evaluates the body of the node definition that is the best match for
+// the given node name. Nothing is evaluated when no node definitions exist. An error is
+// raised when node definitions exist but none of them match the given name.
+var EvaluateNode func(c Context, nodeName string)
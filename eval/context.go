@@ -150,6 +150,11 @@ func Evaluate(c Context, expr parser.Expression) Value {
 	return c.GetEvaluator().Eval(expr)
 }
 
+// EvaluateEpp parses the given template using the EPP mode of the parser and evaluates the result in a
+// fresh local scope where the given arguments are bound to the parameters of the template. The rendered
+// text is returned.
+var EvaluateEpp func(c Context, filename, template string, arguments OrderedMap) string
+
 var CurrentContext func() Context
 
 var StackTop func() issue.Location
@@ -0,0 +1,32 @@
+package eval
+
+import (
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+const CoverageKey = `puppet.coverage`
+
+// A Coverage is notified by the evaluator when it is stored in the evaluation context using the
+// CoverageKey.
+type Coverage interface {
+	// Parsed is called when source has been parsed and validated, before any part of it is
+	// evaluated. It enables the coverage to know about code that is never evaluated.
+	Parsed(program parser.Expression)
+
+	// Covered is called before the given expression is evaluated
+	Covered(expr parser.Expression)
+
+	// Branch is called when a conditional expression selects a branch. For if and unless, the then
+	// branch is 0 and the else branch is 1. For case and selector expressions, the branch is the
+	// index of the selected option, or the number of options when no option was selected.
+	Branch(expr parser.Expression, branch int)
+}
+
+// CoverageOf returns the coverage that is stored in the given context, or nil if no such
+// coverage exists
+func CoverageOf(c Context) Coverage {
+	if cv, ok := c.Get(CoverageKey); ok {
+		return cv.(Coverage)
+	}
+	return nil
+}
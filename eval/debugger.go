@@ -0,0 +1,28 @@
+package eval
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+const DebuggerKey = `puppet.debugger`
+
+// A Debugger is notified by the evaluator when it is stored in the evaluation context using the
+// DebuggerKey. Both methods are called synchronously, so evaluation is paused until they return.
+type Debugger interface {
+	// Before is called before the given expression is evaluated
+	Before(c Context, expr parser.Expression)
+
+	// Raised is called when the evaluation of the given expression raises the given issue. The issue
+	// is raised again when this method returns.
+	Raised(c Context, expr parser.Expression, reported issue.Reported)
+}
+
+// DebuggerOf returns the debugger that is stored in the given context, or nil if no such
+// debugger exists
+func DebuggerOf(c Context) Debugger {
+	if dv, ok := c.Get(DebuggerKey); ok {
+		return dv.(Debugger)
+	}
+	return nil
+}
@@ -145,3 +145,8 @@ func GuardedReverseIndexFrom(a []Equality, b Equality, startPos int, g Guard) in
 var PuppetEquals func(a, b Value) bool
 
 var PuppetMatch func(c Context, a, b Value) bool
+
+// PuppetCompare compares a and b using the rules of the magnitude comparison operators and returns
+// -1, 0, or 1. Strings are compared case insensitively unless caseSensitive is true. A panic is
+// raised when the values are not comparable.
+var PuppetCompare func(a, b Value, caseSensitive bool) int
@@ -2,11 +2,16 @@ package eval_test
 
 import (
 	"fmt"
-	"github.com/lyraproj/puppet-evaluator/eval"
-	"github.com/lyraproj/semver/semver"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/semver/semver"
+
 	// Initialize pcore
 	_ "github.com/lyraproj/puppet-evaluator/pcore"
 )
@@ -87,6 +92,487 @@ func ExamplePcore_parseTypeError() {
 	// Output: expected one of ',' or ']', got 'EOF' (line: 1, column: 9)
 }
 
+func ExamplePcore_rubyRegexpEngine() {
+	eval.Puppet.Set(`regexp_engine`, types.WrapString(`ruby`))
+	defer eval.Puppet.Set(`regexp_engine`, types.WrapString(`go`))
+
+	eval.Puppet.Do(func(ctx eval.Context) {
+		v, err := eval.TopEvaluate(ctx, ctx.ParseAndValidate(`site.pp`, `
+      $price = if 'total: $42' =~ /(?<=\$)(\d+)/ { $1 }
+      $kind = 'a-b' ? { /(?<!-)b/ => 'plain', default => 'dashed' }
+      [$price, $kind, 'aXbXXc'.split(/X++/), 'Hello hello'.match(/(?i)(?<w>h\w+) \k<w>/), 'one' =~ Pattern[/\A\p{Alpha}+\z/]]`, false))
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Println(v)
+	})
+	// Output: ['42', 'dashed', ['a', 'b', 'c'], ['Hello hello', 'Hello'], true]
+}
+
+// evaluateEach evaluates the given manifest and prints the resulting value, or each element of the
+// value when it is an array. The error is printed instead when the evaluation fails.
+func evaluateEach(ctx eval.Context, manifest string) {
+	v, err := eval.TopEvaluate(ctx, ctx.ParseAndValidate(`site.pp`, manifest, false))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if a, ok := v.(*types.ArrayValue); ok {
+		a.Each(func(e eval.Value) { fmt.Println(e) })
+	} else {
+		fmt.Println(v)
+	}
+}
+
+func ExampleTopEvaluate_reverseEachIsLazy() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		n := int64(0)
+		ctx.Scope().Set(`source`, types.WrapIterator(types.NewIterator(types.DefaultIntegerType(), func() (eval.Value, bool) {
+			if n == 3 {
+				return nil, false
+			}
+			n++
+			fmt.Println(`produced`, n)
+			return types.WrapInteger(n), true
+		})))
+		evaluateEach(ctx, `
+      $reversed = $source.reverse_each
+      notice('not consumed yet')
+      [$reversed.map |$x| { $x }]`)
+	})
+	// Output:
+	// notice: not consumed yet
+	// produced 1
+	// produced 2
+	// produced 3
+	// [3, 2, 1]
+}
+
+func ExampleTopEvaluate_iterators() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		evaluateEach(ctx, `
+      $odd = [1, 2, 3, 4, 5, 6, 7].step(2).map |$x| { $x * 10 }
+      $rev = [1, 2, 3].reverse_each.filter |$x| { $x != 2 }
+      $tree = { a => [1, 2], b => { c => 3 } }
+      $leaves = $tree.tree_each({ include_containers => false }).map |$e| { $e }
+      $breadth = $tree.tree_each({ order => breadth_first, include_root => false }).map |$e| { $e[0] }
+      $containers = $tree.tree_each({ include_values => false }).map |$e| { $e[0] }
+      $hashes = $tree.tree_each({ container_type => Hash, include_containers => false }).map |$e| { $e }
+      [$odd, $rev, [1, 2, 3, 4, 5].slice(2), [1, 2, 1, 3].unique, { a => 1, b => 2, c => 1 }.unique, $leaves, $breadth, $containers, $hashes]`)
+	})
+	// Output:
+	// [10, 30, 50, 70]
+	// [3, 1]
+	// [[1, 2], [3, 4], [5]]
+	// [1, 2, 3]
+	// {['a', 'c'] => 1, ['b'] => 2}
+	// [[['a', 0], 1], [['a', 1], 2], [['b', 'c'], 3]]
+	// [['a'], ['b'], ['a', 0], ['a', 1], ['b', 'c']]
+	// [[], ['a'], ['b']]
+	// [[['a'], [1, 2]], [['b', 'c'], 3]]
+}
+
+func ExampleTopEvaluate_strings() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		evaluateEach(ctx, `
+      [
+        regsubst(['web-01', 'db-02'], '(\w+)-(\d+)', '\2.\1'),
+        scanf('eth0: 1500 up', '%[a-z]%d: %d %s'),
+        ['  a  ', "b\n"].strip.upcase.join(','),
+        [camelcase('big_bad_wolf'), snakecase('BigBadWolf'), capitalize('wOLF')],
+        [length('wölf'), index('wolf', 'l'), 'wolf'.starts_with(['x', 'wo'])],
+        [versioncmp('1.2.9', '1.2.10'), versioncmp('1.2', '1.2.0', true), versioncmp('10', '1', true), versioncmp('1.10', '1.1', true)]
+      ]`)
+	})
+	// Output:
+	// ['01.web', '02.db']
+	// ['eth', 0, 1500, 'up']
+	// A,B
+	// ['BigBadWolf', 'big_bad_wolf', 'Wolf']
+	// [4, 2, true]
+	// [-1, 0, 1, 1]
+}
+
+func ExampleTopEvaluate_stringConverters() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		evaluateEach(ctx, `
+      [
+        [chomp("a\n"), chomp("b\r\n"), chomp('c'), chop('ab'), chop("c\r\n"), chop('')],
+        [lstrip('  a  '), rstrip('  a  '), strip("\t a \n"), downcase('AbC'), upcase('aBc')],
+        [chomp(["x\n", 'y']), chop(['ab', 'cd']), downcase(['A', 'B']), lstrip([' a', ' b'])],
+        downcase({ 'A' => 'B', 'C' => 'D' }),
+        rstrip({ 'a ' => 'b ' }),
+        [upcase(1), downcase(2.5), capitalize(['aB', 'cD']), camelcase({ 'a_b' => 'c_d' })]
+      ]`)
+	})
+	// Output:
+	// ['a', 'b', 'c', 'a', 'c', '']
+	// ['a  ', '  a', 'a', 'abc', 'ABC']
+	// [['x', 'y'], ['a', 'c'], ['a', 'b'], ['a', 'b']]
+	// {'a' => 'b', 'c' => 'd'}
+	// {'a' => 'b'}
+	// [1, 2.50000, ['Ab', 'Cd'], {'AB' => 'CD'}]
+}
+
+func ExampleTopEvaluate_stringQueries() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		evaluateEach(ctx, `
+      [
+        [size('wölf'), size([1, 2, 3]), size({ a => 1 }), length(Binary('AQID')), length('')],
+        ['wolf'.ends_with('lf'), 'wolf'.ends_with(['x', 'y']), 'wolf'.starts_with('w'), 'wolf'.starts_with('o')],
+        [index('wolf', 'x'), index(['a', 'b', 'c'], 'c'), index({ a => 1, b => 2 }, 2), index([1, 5, 9]) |$x| { $x > 4 }],
+        [join([1, 2, 3]), join(['a', 'b'], '-'), join([])]
+      ]`)
+	})
+	// Output:
+	// [4, 3, 1, 3, 0]
+	// [true, false, true, false]
+	// [undef, 2, 'b', 1]
+	// ['123', 'a-b', '']
+}
+
+func ExampleTopEvaluate_scanf() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		evaluateEach(ctx, `
+      [
+        scanf('12 abc', '%d %d'),
+        scanf('abc', '%d'),
+        scanf('x=1', 'y=%d'),
+        scanf('1,2', '%d;%d'),
+        scanf('12345', '%2d%3d'),
+        scanf('ff 0x1f 17 101', '%x %x %o %b'),
+        scanf('0x10 010 -7', '%i %i %i'),
+        scanf('3.5e2 -1.25', '%f %f'),
+        scanf('abc def', '%c%*c%s'),
+        scanf('key: value', '%[^:]: %s'),
+        scanf('100%', '%d%%'),
+        scanf('-123', '%1d'),
+        scanf('-123', '%2d'),
+        scanf('0x1f', '%1x'),
+        scanf('42', '%d') |$r| { $r[0] * 2 }
+      ]`)
+	})
+	// Output:
+	// [12]
+	// []
+	// []
+	// [1]
+	// [12, 345]
+	// [255, 31, 15, 5]
+	// [16, 8, -7]
+	// [350.000, -1.2500]
+	// ['a', 'c']
+	// ['key', 'value']
+	// [100]
+	// []
+	// [-1]
+	// [0]
+	// 84
+}
+
+func ExampleTopEvaluate_regsubst() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		evaluateEach(ctx, `
+      [
+        [regsubst('aaa', 'a', 'b'), regsubst('aaa', 'a', 'b', 'G'), regsubst('AbA', 'a', 'x', 'IG')],
+        [regsubst("a\nb", 'a.b', 'x'), regsubst("a\nb", 'a.b', 'x', 'M')],
+        [regsubst('web-01', /(\w+)-(\d+)/, '\2:\1'), regsubst(['ab', 'ba'], 'a', 'x')],
+        regsubst(Sensitive('secret'), 'e', '3', 'G').unwrap
+      ]`)
+		evaluateEach(ctx, `regsubst('a', 'a', 'b', 'E')`)
+	})
+	// Output:
+	// ['baa', 'bbb', 'xbx']
+	// ["a\nb", 'x']
+	// ['01:web', ['xb', 'bx']]
+	// s3cr3t
+	// Cannot compile regular expression 'a': the E flag requires the ruby regexp engine (file: site.pp, line: 1, column: 1)
+}
+
+func ExampleTopEvaluate_collections() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		evaluateEach(ctx, `
+      $hosts = { web1 => 'web', db1 => 'db', web2 => 'web' }
+      [
+        $hosts.group_by |$name, $role| { $role },
+        [5, 3, 8, 1].step(1).partition |$x| { $x > 4 },
+        merge({ a => 1, b => 2 }, { b => 10 }) |$key, $old, $new| { $old + $new },
+        zip([1, 2], ['a', 'b'], [true]),
+        [sort(['b', 'c', 'a']), sort([1, 3, 2]) |$x, $y| { compare($y, $x) }, flatten([1, [2, [3]]], 1)],
+        [min(4, 2, 8), max([4, 2, 8]), sum([1, 2, 3].reverse_each), compare('a', 'B'), empty([])],
+        [[1].step(1).empty, [].reverse_each.empty, [1, 2].reverse_each.empty]
+      ]`)
+		evaluateEach(ctx, `[1, 'a'].sort`)
+	})
+	// Output:
+	// {'web' => [['web1', 'web'], ['web2', 'web']], 'db' => [['db1', 'db']]}
+	// [[5, 8], [3, 1]]
+	// {'a' => 1, 'b' => 12}
+	// [[1, 'a', true], [2, 'b', undef]]
+	// [['a', 'b', 'c'], [3, 2, 1], [1, 2, [3]]]
+	// [2, 8, 6, -1, true]
+	// [false, true, false]
+	// Operator '<' is not applicable to a String when right side is an Integer (file: site.pp, line: 1, column: 1)
+}
+
+func ExampleTopEvaluate_serialization() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		evaluateEach(ctx, `
+      $config = @(END)
+        server { host = example.com, port = 8080 }
+        url = "http://"${server.host}
+        END
+      [
+        to_json({ b => 1, a => [1.5, true, undef] }),
+        to_json(Timestamp('2019-01-01T00:00:00 UTC'), { rich_data => true }),
+        to_yaml({ b => 1, a => ['x', { z => 2, y => 3 }] }),
+        parse_json('{"b": 1, "a": [1.5, null]}'),
+        parse_hocon($config)
+      ]`)
+		evaluateEach(ctx, `to_json({ a => [SemVer('1.0.0')] })`)
+		evaluateEach(ctx, `to_json({ a => { 1 => 2 } })`)
+		evaluateEach(ctx, `to_json({ [1, 'b'] => 2 })`)
+	})
+	// Output:
+	// {"b":1,"a":[1.5,true,null]}
+	// {"__ptype":"Timestamp","__pvalue":"2019-01-01T00:00:00.000000000 UTC"}
+	// b: 1
+	// a:
+	//   - x
+	//   - z: 2
+	//     "y": 3
+	//
+	// {'b' => 1, 'a' => [1.50000, undef]}
+	// {'server' => {'host' => 'example.com', 'port' => 8080}, 'url' => 'http://example.com'}
+	// to_json/'a'/0 contains a SemVer value which is not Data. Enable rich_data to convert it (file: site.pp, line: 1, column: 1)
+	// to_json/'a'/1 is an Integer hash key which is not a String. Enable rich_data to convert it (file: site.pp, line: 1, column: 1)
+	// to_json/[1, 'b'] is an Array hash key which is not a String. Enable rich_data to convert it (file: site.pp, line: 1, column: 1)
+}
+
+func ExampleTopEvaluate_files() {
+	dir, err := ioutil.TempDir(``, `modules`)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	files := filepath.Join(dir, `mymod`, `files`)
+	os.MkdirAll(files, 0755)
+	ioutil.WriteFile(filepath.Join(files, `motd.txt`), []byte(`Welcome`), 0644)
+	templates := filepath.Join(dir, `mymod`, `templates`)
+	os.MkdirAll(templates, 0755)
+	ioutil.WriteFile(filepath.Join(templates, `motd.epp`), []byte(`Welcome <%= 1 + 1 %> times.`), 0644)
+	ioutil.WriteFile(filepath.Join(templates, `motd.erb`), []byte(`Welcome <%= @n %> times.`), 0644)
+
+	eval.Puppet.Reset()
+	defer eval.Puppet.Reset()
+	eval.Puppet.Set(`module_path`, types.WrapString(dir))
+	eval.Puppet.Do(func(ctx eval.Context) {
+		evaluateEach(ctx, `
+      [
+        find_file('mymod/missing.txt', ['mymod/motd.txt']) =~ /mymod\/files\/motd\.txt$/,
+        find_file('mymod/missing.txt'),
+        file('mymod/missing.txt', 'mymod/motd.txt'),
+        binary_file('mymod/motd.txt')
+      ]`)
+		evaluateEach(ctx, `file('mymod/missing.txt', 'nomod/motd.txt')`)
+
+		// Files that were not found are looked up again
+		ioutil.WriteFile(filepath.Join(files, `missing.txt`), []byte(`Added`), 0644)
+		evaluateEach(ctx, `file('mymod/missing.txt')`)
+
+		evaluateEach(ctx, `template('mymod/motd.epp', 'mymod/motd.epp')`)
+		evaluateEach(ctx, `template('mymod/motd.erb')`)
+		evaluateEach(ctx, `template('mymod/missing.epp')`)
+	})
+	// Output:
+	// true
+	// undef
+	// Welcome
+	// V2VsY29tZQ==
+	// Could not find any files from mymod/missing.txt, nomod/motd.txt. Files are referenced as <module name>/<file> or by absolute path (file: site.pp, line: 1, column: 1)
+	// Added
+	// Welcome 2 times.Welcome 2 times.
+	// The template mymod/motd.erb is not an EPP template. ERB templates are not supported, use the epp function with an EPP template instead (file: site.pp, line: 1, column: 1)
+	// File 'mymod/missing.epp' does not exist (file: site.pp, line: 1, column: 1)
+}
+
+func ExampleCatalogOf() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		_, err := eval.TopEvaluate(ctx, ctx.ParseAndValidate(`site.pp`, `
+      file { '/tmp/x': ensure => present }
+      -> file { ['/tmp/y', '/tmp/z']: ensure => absent }
+      ~> Notify['done']
+      notify { 'done': }`, false))
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		cat := eval.CatalogOf(ctx)
+		cat.Validate()
+		for _, r := range cat.Resources() {
+			fmt.Println(r.Reference(), r.Parameters())
+		}
+		for _, e := range cat.Edges() {
+			fmt.Println(e.Source(), e.Target(), e.Subscribe())
+		}
+	})
+	// Output:
+	// File['/tmp/x'] {'ensure' => 'present'}
+	// File['/tmp/y'] {'ensure' => 'absent'}
+	// File['/tmp/z'] {'ensure' => 'absent'}
+	// Notify['done'] {}
+	// File['/tmp/x'] File['/tmp/y'] false
+	// File['/tmp/x'] File['/tmp/z'] false
+	// File['/tmp/y'] Notify['done'] true
+	// File['/tmp/z'] Notify['done'] true
+}
+
+func ExampleCatalogOf_virtual() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		_, err := eval.TopEvaluate(ctx, ctx.ParseAndValidate(`site.pp`, `
+      @user { 'alice': groups => ['admin', 'dev'] }
+      @user { 'bob': groups => 'dev' }
+      @user { 'carol': groups => 'ops' }
+      @user { 'dave': groups => 'ops' }
+      User <| groups == 'admin' or title == 'carol' |> { shell => '/bin/zsh' }
+      realize(User['dave'])`, false))
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		cat := eval.CatalogOf(ctx)
+		cat.Validate()
+		for _, r := range cat.Resources() {
+			fmt.Println(r.Reference(), r.Parameters())
+		}
+		for _, r := range cat.VirtualResources() {
+			fmt.Println(`virtual`, r.Reference())
+		}
+	})
+	// Output:
+	// User['alice'] {'groups' => ['admin', 'dev'], 'shell' => '/bin/zsh'}
+	// User['carol'] {'groups' => 'ops', 'shell' => '/bin/zsh'}
+	// User['dave'] {'groups' => 'ops'}
+	// virtual User['bob']
+}
+
+func ExampleCatalogOf_defaultsAndOverrides() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		expr := ctx.ParseAndValidate(`site.pp`, `
+      File { mode => '0644', owner => 'root' }
+      class base {
+        file { '/etc/motd': content => 'hi', group => 'wheel' }
+      }
+      class special inherits base {
+        File['/etc/motd'] { content => 'special', group +> 'admin', owner => undef }
+      }
+      class app {
+        File { owner => 'app' }
+        file { '/opt/app': }
+        include app::logs
+      }
+      class app::logs { file { '/opt/app/logs': } }
+      include special
+      include app
+      file { '/tmp/x': }`, false)
+		ctx.AddDefinitions(expr)
+		if _, err := eval.TopEvaluate(ctx, expr); err != nil {
+			fmt.Println(err)
+			return
+		}
+		for _, r := range eval.CatalogOf(ctx).Resources() {
+			if r.TypeName() == `File` {
+				fmt.Println(r.Reference(), r.Parameters())
+			}
+		}
+	})
+	// Output:
+	// File['/etc/motd'] {'mode' => '0644', 'content' => 'special', 'group' => ['wheel', 'admin']}
+	// File['/opt/app'] {'mode' => '0644', 'owner' => 'app'}
+	// File['/opt/app/logs'] {'mode' => '0644', 'owner' => 'app'}
+	// File['/tmp/x'] {'mode' => '0644', 'owner' => 'root'}
+}
+
+func ExampleCatalogOf_overrideBeforeDeclaration() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		_, err := eval.TopEvaluate(ctx, ctx.ParseAndValidate(`site.pp`, `
+      File['/tmp/x'] { mode => '0600', owner +> 'root' }
+      file { '/tmp/x': mode => '0644' }
+      Notify['never'] { message => 'hello' }`, false))
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		cat := eval.CatalogOf(ctx)
+		for _, r := range cat.Resources() {
+			fmt.Println(r.Reference(), r.Parameters())
+		}
+		defer func() {
+			fmt.Println(recover())
+		}()
+		cat.Validate()
+	})
+	// Output:
+	// File['/tmp/x'] {'mode' => '0600', 'owner' => 'root'}
+	// Reference to unresolved resource Notify['never']
+}
+
+func ExampleDeclareClass() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		expr := ctx.ParseAndValidate(`site.pp`, `
+      class base($owner = 'root') { $dir = '/opt' }
+      class app(String $version, $path = "${dir}/app-${version}") inherits base {
+        file { $path: owner => $owner }
+        mydir { 'logs': path => "${path}/logs" }
+      }
+      define mydir($path) { file { $path: ensure => directory } }
+      node /^web\d+$/ { class { 'app': version => '1.2' } }
+      node default { }`, false)
+		ctx.AddDefinitions(expr)
+		if _, err := eval.TopEvaluate(ctx, expr); err != nil {
+			fmt.Println(err)
+			return
+		}
+		eval.EvaluateNode(ctx, `web01`)
+		for _, r := range eval.CatalogOf(ctx).Resources() {
+			fmt.Println(r.Reference(), r.Parameters(), r.Container())
+		}
+	})
+	// Output:
+	// Class['Base'] {} <nil>
+	// Class['App'] {'version' => '1.2'} <nil>
+	// File['/opt/app-1.2'] {'owner' => 'root'} Class['App']
+	// Mydir['logs'] {'path' => '/opt/app-1.2/logs'} Class['App']
+	// File['/opt/app-1.2/logs'] {'ensure' => 'directory'} Mydir['logs']
+}
+
+func ExampleEvaluateEpp() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		template := `<%- | String $name, Array[String] $items, $greeting = "Hello ${name}" | -%>
+<%= $greeting %>, your items are:
+<% $items.each |$item| { -%>
+  * <%= $item %>
+<% } -%>
+`
+		fmt.Print(eval.EvaluateEpp(ctx, `items.epp`, template, eval.Wrap(ctx, map[string]interface{}{
+			`name`:  `Bob`,
+			`items`: []string{`apple`, `banana`}}).(eval.OrderedMap)))
+	})
+	// Output:
+	// Hello Bob, your items are:
+	//   * apple
+	//   * banana
+}
+
+func ExampleEvaluateEpp_unknownParameter() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		evaluateEach(ctx, `inline_epp('<%- | String $name | -%>Hello <%= $name %>', { name => 'Bob', tag => 'greeting' })`)
+	})
+	// Output: EPP template 'inline_epp' has no parameter named 'tag' (file: inline_epp, line: 1, column: 1)
+}
+
 func ExampleObjectType_fromReflectedValue() {
 	type TestStruct struct {
 		Message   string
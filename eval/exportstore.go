@@ -0,0 +1,25 @@
+package eval
+
+const ExportStoreKey = `puppet.exportStore`
+
+// An ExportStore persists exported resources so that they can be imported by other evaluations.
+// Resources are exported to the store that is stored in the evaluation context using the
+// ExportStoreKey when they are declared, and imported from it when an exported resource collector
+// is evaluated.
+type ExportStore interface {
+	// Export stores the given resource. A resource with the same type and title that was exported
+	// earlier is replaced.
+	Export(c Context, resource Resource)
+
+	// Import returns all stored resources of the given type
+	Import(c Context, typeName string) []Resource
+}
+
+// ExportStoreOf returns the export store that is stored in the given context, or nil if no such
+// store exists
+func ExportStoreOf(c Context) ExportStore {
+	if sv, ok := c.Get(ExportStoreKey); ok {
+		return sv.(ExportStore)
+	}
+	return nil
+}
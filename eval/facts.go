@@ -0,0 +1,10 @@
+package eval
+
+// A FactsProvider provides the facts of the node that is the target of an evaluation. The Pcore
+// runtime consults its FactsProvider when it creates a root context and assigns the facts to the
+// global variable $facts and each fact that has a valid variable name to a top-scope variable of
+// that name. Facts are global variables and can therefore not be reassigned by the evaluation.
+type FactsProvider interface {
+	// Facts returns a Hash that maps fact names to fact values
+	Facts(c Context) OrderedMap
+}
@@ -5,6 +5,7 @@ import (
 )
 
 const (
+	EVAL_ALIAS_NOT_ENTIRE_STRING                   = `EVAL_ALIAS_NOT_ENTIRE_STRING`
 	EVAL_ARGUMENTS_ERROR                           = `EVAL_ARGUMENTS_ERROR`
 	EVAL_ATTEMPT_TO_REDEFINE                       = `EVAL_ATTEMPT_TO_REDEFINE`
 	EVAL_ATTEMPT_TO_SET_UNSETTABLE                 = `EVAL_ATTEMPT_TO_SET_UNSETTABLE`
@@ -17,25 +18,38 @@ const (
 	EVAL_CONSTANT_REQUIRES_VALUE                   = `EVAL_CONSTANT_REQUIRES_VALUE`
 	EVAL_CONSTANT_WITH_FINAL                       = `EVAL_CONSTANT_WITH_FINAL`
 	EVAL_CTOR_NOT_FOUND                            = `EVAL_CTOR_NOT_FOUND`
+	EVAL_DEBUGGER_TERMINATED                       = `EVAL_DEBUGGER_TERMINATED`
+	EVAL_DUPLICATE_ATTRIBUTE                       = `EVAL_DUPLICATE_ATTRIBUTE`
 	EVAL_DUPLICATE_KEY                             = `EVAL_DUPLICATE_KEY`
+	EVAL_DUPLICATE_RESOURCE                        = `EVAL_DUPLICATE_RESOURCE`
 	EVAL_EMPTY_TYPE_PARAMETER_LIST                 = `EVAL_EMPTY_TYPE_PARAMETER_LIST`
 	EVAL_EQUALITY_ATTRIBUTE_NOT_FOUND              = `EVAL_EQUALITY_ATTRIBUTE_NOT_FOUND`
 	EVAL_EQUALITY_NOT_ATTRIBUTE                    = `EVAL_EQUALITY_NOT_ATTRIBUTE`
 	EVAL_EQUALITY_ON_CONSTANT                      = `EVAL_EQUALITY_ON_CONSTANT`
 	EVAL_EQUALITY_REDEFINED                        = `EVAL_EQUALITY_REDEFINED`
+	EVAL_ERB_NOT_SUPPORTED                         = `EVAL_ERB_NOT_SUPPORTED`
 	EVAL_FAILURE                                   = `EVAL_FAILURE`
+	EVAL_FILES_NOT_FOUND                           = `EVAL_FILES_NOT_FOUND`
 	EVAL_FILE_NOT_FOUND                            = `EVAL_FILE_NOT_FOUND`
 	EVAL_FILE_READ_DENIED                          = `EVAL_FILE_READ_DENIED`
 	EVAL_GO_FUNCTION_ERROR                         = `EVAL_GO_FUNCTION_ERROR`
+	EVAL_GO_GENERATION_UNSUPPORTED                 = `EVAL_GO_GENERATION_UNSUPPORTED`
 	EVAL_GO_RUNTIME_TYPE_WITHOUT_GO_TYPE           = `EVAL_GO_RUNTIME_TYPE_WITHOUT_GO_TYPE`
 	EVAL_ILLEGAL_ARGUMENT                          = `EVAL_ILLEGAL_ARGUMENT`
 	EVAL_ILLEGAL_ARGUMENT_COUNT                    = `EVAL_ILLEGAL_ARGUMENT_COUNT`
 	EVAL_ILLEGAL_ARGUMENT_TYPE                     = `EVAL_ILLEGAL_ARGUMENT_TYPE`
 	EVAL_ILLEGAL_ASSIGNMENT                        = `EVAL_ILLEGAL_ASSIGNMENT`
+	EVAL_ILLEGAL_ATTRIBUTES_SPLAT                  = `EVAL_ILLEGAL_ATTRIBUTES_SPLAT`
 	EVAL_ILLEGAL_BREAK                             = `EVAL_ILLEGAL_BREAK`
 	EVAL_ILLEGAL_KIND_VALUE_COMBINATION            = `EVAL_ILLEGAL_KIND_VALUE_COMBINATION`
 	EVAL_ILLEGAL_NEXT                              = `EVAL_ILLEGAL_NEXT`
+	EVAL_ILLEGAL_NODE_INHERITANCE                  = `EVAL_ILLEGAL_NODE_INHERITANCE`
 	EVAL_ILLEGAL_OBJECT_INHERITANCE                = `EVAL_ILLEGAL_OBJECT_INHERITANCE`
+	EVAL_ILLEGAL_OVERRIDE_OPERAND                  = `EVAL_ILLEGAL_OVERRIDE_OPERAND`
+	EVAL_ILLEGAL_QUERY_EXPRESSION                  = `EVAL_ILLEGAL_QUERY_EXPRESSION`
+	EVAL_ILLEGAL_RELATIONSHIP_OPERAND              = `EVAL_ILLEGAL_RELATIONSHIP_OPERAND`
+	EVAL_ILLEGAL_RESOURCE_TITLE                    = `EVAL_ILLEGAL_RESOURCE_TITLE`
+	EVAL_ILLEGAL_RESOURCE_TYPE                     = `EVAL_ILLEGAL_RESOURCE_TYPE`
 	EVAL_ILLEGAL_RETURN                            = `EVAL_ILLEGAL_RETURN`
 	EVAL_ILLEGAL_MULTI_ASSIGNMENT_SIZE             = `EVAL_ILLEGAL_MULTI_ASSIGNMENT_SIZE`
 	EVAL_ILLEGAL_WHEN_STATIC_EXPRESSION            = `EVAL_ILLEGAL_WHEN_STATIC_EXPRESSION`
@@ -56,13 +70,20 @@ const (
 	EVAL_INVALID_VERSION                           = `EVAL_INVALID_VERSION`
 	EVAL_INVALID_VERSION_RANGE                     = `EVAL_INVALID_VERSION_RANGE`
 	EVAL_IS_DIRECTORY                              = `EVAL_IS_DIRECTORY`
+	EVAL_JSON_READ_ERROR                           = `EVAL_JSON_READ_ERROR`
+	EVAL_JSON_SCHEMA_UNREPRESENTABLE_TYPE          = `EVAL_JSON_SCHEMA_UNREPRESENTABLE_TYPE`
+	EVAL_JSON_SCHEMA_UNSUPPORTED                   = `EVAL_JSON_SCHEMA_UNSUPPORTED`
+	EVAL_LOOKUP_NOT_FOUND                          = `EVAL_LOOKUP_NOT_FOUND`
+	EVAL_LOOKUP_RECURSION                          = `EVAL_LOOKUP_RECURSION`
 	EVAL_MATCH_NOT_REGEXP                          = `EVAL_MATCH_NOT_REGEXP`
 	EVAL_MATCH_NOT_STRING                          = `EVAL_MATCH_NOT_STRING`
 	EVAL_MEMBER_NAME_CONFLICT                      = `EVAL_MEMBER_NAME_CONFLICT`
 	EVAL_MISSING_MULTI_ASSIGNMENT_KEY              = `EVAL_MISSING_MULTI_ASSIGNMENT_KEY`
 	EVAL_MISSING_REGEXP_IN_TYPE                    = `EVAL_MISSING_REGEXP_IN_TYPE`
 	EVAL_MISSING_REQUIRED_ATTRIBUTE                = `EVAL_MISSING_REQUIRED_ATTRIBUTE`
+	EVAL_MISSING_REQUIRED_PARAMETER                = `EVAL_MISSING_REQUIRED_PARAMETER`
 	EVAL_MISSING_TYPE_PARAMETER                    = `EVAL_MISSING_TYPE_PARAMETER`
+	EVAL_NOT_VIRTUALIZABLE                         = `EVAL_NOT_VIRTUALIZABLE`
 	EVAL_NO_ATTRIBUTE_READER                       = `EVAL_NO_ATTRIBUTE_READER`
 	EVAL_NO_CURRENT_CONTEXT                        = `EVAL_NO_CURRENT_CONTEXT`
 	EVAL_NO_DEFINITION                             = `EVAL_NO_DEFINITION`
@@ -74,6 +95,7 @@ const (
 	EVAL_NOT_PARAMETERIZED_TYPE                    = `EVAL_NOT_PARAMETERIZED_TYPE`
 	EVAL_NOT_SEMVER                                = `EVAL_NOT_SEMVER`
 	EVAL_NOT_SUPPORTED_BY_GO_TIME_LAYOUT           = `EVAL_NOT_SUPPORTED_BY_GO_TIME_LAYOUT`
+	EVAL_NO_MATCHING_NODE                          = `EVAL_NO_MATCHING_NODE`
 	EVAL_OBJECT_INHERITS_SELF                      = `EVAL_OBJECT_INHERITS_SELF`
 	EVAL_OPERATOR_NOT_APPLICABLE                   = `EVAL_OPERATOR_NOT_APPLICABLE`
 	EVAL_OPERATOR_NOT_APPLICABLE_WHEN              = `EVAL_OPERATOR_NOT_APPLICABLE_WHEN`
@@ -82,12 +104,16 @@ const (
 	EVAL_OVERRIDDEN_NOT_FOUND                      = `EVAL_OVERRIDDEN_NOT_FOUND`
 	EVAL_OVERRIDE_OF_FINAL                         = `EVAL_OVERRIDE_OF_FINAL`
 	EVAL_OVERRIDE_IS_MISSING                       = `EVAL_OVERRIDE_IS_MISSING`
+	EVAL_PARAMETER_ALREADY_SET                     = `EVAL_PARAMETER_ALREADY_SET`
 	EVAL_PARSE_ERROR                               = `EVAL_PARSE_ERROR`
+	EVAL_RENDER_OUTSIDE_EPP                        = `EVAL_RENDER_OUTSIDE_EPP`
 	EVAL_SERIALIZATION_ATTRIBUTE_NOT_FOUND         = `EVAL_SERIALIZATION_ATTRIBUTE_NOT_FOUND`
 	EVAL_SERIALIZATION_NOT_ATTRIBUTE               = `EVAL_SERIALIZATION_NOT_ATTRIBUTE`
 	EVAL_SERIALIZATION_BAD_KIND                    = `EVAL_SERIALIZATION_BAD_KIND`
 	EVAL_SERIALIZATION_DEFAULT_CONVERTED_TO_STRING = `EVAL_SERIALIZATION_DEFAULT_CONVERTED_TO_STRING`
 	EVAL_SERIALIZATION_ENDLESS_RECURSION           = `EVAL_SERIALIZATION_ENDLESS_RECURSION`
+	EVAL_SERIALIZATION_NOT_DATA                    = `EVAL_SERIALIZATION_NOT_DATA`
+	EVAL_SERIALIZATION_NOT_DATA_KEY                = `EVAL_SERIALIZATION_NOT_DATA_KEY`
 	EVAL_SERIALIZATION_REQUIRED_AFTER_OPTIONAL     = `EVAL_SERIALIZATION_REQUIRED_AFTER_OPTIONAL`
 	EVAL_SERIALIZATION_UNKNOWN_CONVERTED_TO_STRING = `EVAL_SERIALIZATION_UNKNOWN_CONVERTED_TO_STRING`
 	EVAL_TASK_BAD_JSON                             = `EVAL_TASK_BAD_JSON`
@@ -113,13 +139,17 @@ const (
 	EVAL_UNABLE_TO_READ_FILE                       = `EVAL_UNABLE_TO_READ_FILE`
 	EVAL_UNHANDLED_PCORE_VERSION                   = `EVAL_UNHANDLED_PCORE_VERSION`
 	EVAL_UNHANDLED_EXPRESSION                      = `EVAL_UNHANDLED_EXPRESSION`
+	EVAL_UNKNOWN_CLASS                             = `EVAL_UNKNOWN_CLASS`
 	EVAL_UNKNOWN_FUNCTION                          = `EVAL_UNKNOWN_FUNCTION`
+	EVAL_UNKNOWN_INTERPOLATION_METHOD              = `EVAL_UNKNOWN_INTERPOLATION_METHOD`
+	EVAL_UNKNOWN_PARAMETER                         = `EVAL_UNKNOWN_PARAMETER`
 	EVAL_UNKNOWN_PLAN                              = `EVAL_UNKNOWN_PLAN`
 	EVAL_UNKNOWN_TASK                              = `EVAL_UNKNOWN_TASK`
 	EVAL_UNKNOWN_VARIABLE                          = `EVAL_UNKNOWN_VARIABLE`
 	EVAL_UNREFLECTABLE_RETURN                      = `EVAL_UNREFLECTABLE_RETURN`
 	EVAL_UNREFLECTABLE_TYPE                        = `EVAL_UNREFLECTABLE_TYPE`
 	EVAL_UNREFLECTABLE_VALUE                       = `EVAL_UNREFLECTABLE_VALUE`
+	EVAL_UNRESOLVED_RESOURCE_REFERENCE             = `EVAL_UNRESOLVED_RESOURCE_REFERENCE`
 	EVAL_UNRESOLVED_TYPE                           = `EVAL_UNRESOLVED_TYPE`
 	EVAL_UNRESOLVED_TYPE_OF                        = `EVAL_UNRESOLVED_TYPE_OF`
 	EVAL_UNSUPPORTED_STRING_FORMAT                 = `EVAL_UNSUPPORTED_STRING_FORMAT`
@@ -127,6 +157,8 @@ const (
 )
 
 func init() {
+	issue.Hard(EVAL_ALIAS_NOT_ENTIRE_STRING, `'alias' interpolation is only permitted if the expression is equal to the entire string`)
+
 	issue.Hard2(EVAL_ARGUMENTS_ERROR, `Error when evaluating %{expression}: %{message}`, issue.HF{`expression`: issue.AnOrA})
 
 	issue.Hard(EVAL_ATTEMPT_TO_REDEFINE, `attempt to redefine %{name}`)
@@ -152,8 +184,14 @@ func init() {
 	// TRANSLATOR 'final => false' is puppet syntax and should not be translated
 	issue.Hard(EVAL_CONSTANT_WITH_FINAL, `%{label} of kind 'constant' cannot be combined with final => false`)
 
+	issue.Hard(EVAL_DEBUGGER_TERMINATED, `The evaluation was terminated by the debugger`)
+
+	issue.Hard(EVAL_DUPLICATE_ATTRIBUTE, `The attribute '%{attribute}' has already been set`)
+
 	issue.Hard(EVAL_DUPLICATE_KEY, `The key '%{key}' is declared more than once`)
 
+	issue.Hard(EVAL_DUPLICATE_RESOURCE, `Duplicate declaration: %{ref} is already declared %{origin}`)
+
 	issue.Hard(EVAL_EMPTY_TYPE_PARAMETER_LIST, `The %{label}-Type cannot be parameterized using an empty parameter list`)
 
 	issue.Hard(EVAL_EQUALITY_ATTRIBUTE_NOT_FOUND, `%{label} equality is referencing non existent attribute '%{attribute}'`)
@@ -164,14 +202,20 @@ func init() {
 
 	issue.Hard(EVAL_EQUALITY_REDEFINED, `%{label} equality is referencing %{attribute} which is included in equality of %{including_parent}`)
 
+	issue.Hard(EVAL_ERB_NOT_SUPPORTED, `The template %{path} is not an EPP template. ERB templates are not supported, use the epp function with an EPP template instead`)
+
 	issue.Hard(EVAL_FAILURE, `%{message}`)
 
+	issue.Hard(EVAL_FILES_NOT_FOUND, `Could not find any files from %{paths}. Files are referenced as <module name>/<file> or by absolute path`)
+
 	issue.Hard(EVAL_FILE_NOT_FOUND, `File '%{path}' does not exist`)
 
 	issue.Hard(EVAL_FILE_READ_DENIED, `Insufficient permissions to read '%{path}'`)
 
 	issue.Hard(EVAL_GO_FUNCTION_ERROR, `Go function %{name} returned error '%{error}'`)
 
+	issue.Hard(EVAL_GO_GENERATION_UNSUPPORTED, `Unable to generate Go source for %{type}: %{detail}`)
+
 	issue.Hard(EVAL_GO_RUNTIME_TYPE_WITHOUT_GO_TYPE, `Attempt to create a Runtime['go', '%{name}'] without providing a Go type`)
 
 	issue.Hard2(EVAL_ILLEGAL_ARGUMENT,
@@ -188,8 +232,22 @@ func init() {
 	issue.Hard2(EVAL_ILLEGAL_ASSIGNMENT, `Illegal attempt to assign to %{value}. Not an assignable reference`,
 		issue.HF{`value`: issue.AnOrA})
 
+	issue.Hard2(EVAL_ILLEGAL_ATTRIBUTES_SPLAT, `Attributes splat expects a Hash, got %{actual}`, issue.HF{`actual`: issue.AnOrA})
+
 	issue.Hard(EVAL_ILLEGAL_BREAK, `break() from context where this is illegal`)
 
+	issue.Hard(EVAL_ILLEGAL_NODE_INHERITANCE, `Node inheritance is not supported`)
+
+	issue.Hard(EVAL_ILLEGAL_OVERRIDE_OPERAND, `Illegal resource override operand, can not override %{actual}. A resource reference with a title is required`)
+
+	issue.Hard2(EVAL_ILLEGAL_QUERY_EXPRESSION, `%{expression} is not supported in a collector query. Only ==, !=, 'and', and 'or' are supported`, issue.HF{`expression`: issue.UcAnOrA})
+
+	issue.Hard(EVAL_ILLEGAL_RELATIONSHIP_OPERAND, `Illegal relationship operand, can not form a relationship with %{actual}. A resource reference with a title is required`)
+
+	issue.Hard2(EVAL_ILLEGAL_RESOURCE_TITLE, `Illegal resource title type, expected String, got %{actual}`, issue.HF{`actual`: issue.AnOrA})
+
+	issue.Hard(EVAL_ILLEGAL_RESOURCE_TYPE, `Illegal resource type name %{actual}`)
+
 	issue.Hard2(EVAL_ILLEGAL_WHEN_STATIC_EXPRESSION, `%{expression} is illegal within a type declaration`, issue.HF{`expression`: issue.UcAnOrA})
 
 	issue.Hard(EVAL_ILLEGAL_KIND_VALUE_COMBINATION, `%{label} of kind '%{kind}' cannot be combined with an attribute value`)
@@ -236,6 +294,16 @@ func init() {
 
 	issue.Hard(EVAL_INVALID_URI, `Cannot parse an URI from string '%{str}': '%{detail}'`)
 
+	issue.Hard(EVAL_JSON_READ_ERROR, `Unable to read JSON at %{path}: %{detail}`)
+
+	issue.Hard(EVAL_JSON_SCHEMA_UNREPRESENTABLE_TYPE, `%{type} at %{path} cannot be represented in JSON Schema`)
+
+	issue.Hard(EVAL_JSON_SCHEMA_UNSUPPORTED, `JSON Schema at %{path} cannot be represented as a Puppet type: %{detail}`)
+
+	issue.Hard(EVAL_LOOKUP_NOT_FOUND, `Function lookup() did not find a value for the name %{name}`)
+
+	issue.Hard(EVAL_LOOKUP_RECURSION, `Recursive lookup detected in [%{name_stack}]`)
+
 	issue.Hard(EVAL_MATCH_NOT_REGEXP, `Can not convert right match operand to a regular expression. Caused by '%{detail}'`)
 
 	issue.Hard2(EVAL_MATCH_NOT_STRING, `"Left match operand must result in a String value. Got %{left}`, issue.HF{`left`: issue.AnOrA})
@@ -248,8 +316,14 @@ func init() {
 
 	issue.Hard(EVAL_MISSING_REQUIRED_ATTRIBUTE, `%{label} requires a value but none was provided`)
 
+	issue.Hard(EVAL_MISSING_REQUIRED_PARAMETER, `%{label} expects a value for parameter '%{name}'`)
+
 	issue.Hard(EVAL_MISSING_TYPE_PARAMETER, `'%{name}' is not a known type parameter for %{label}-Type`)
 
+	issue.Hard(EVAL_NOT_VIRTUALIZABLE, `%{type} resources cannot be declared virtual or exported`)
+
+	issue.Hard(EVAL_NO_MATCHING_NODE, `Could not find node statement with name 'default' or '%{name}'`)
+
 	issue.Hard(EVAL_OBJECT_INHERITS_SELF, `The Object type '%{label}' inherits from itself`)
 
 	issue.Hard(EVAL_NO_ATTRIBUTE_READER, `No attribute reader is implemented for %{label}`)
@@ -291,8 +365,12 @@ func init() {
 
 	issue.Hard(EVAL_OVERRIDE_OF_FINAL, `%{member} attempts to override final %{label}`)
 
+	issue.Hard(EVAL_PARAMETER_ALREADY_SET, `Parameter '%{name}' is already set on %{ref}; cannot redefine`)
+
 	issue.Hard(EVAL_PARSE_ERROR, `Unable to parse %{language}. Detail: %{detail}`)
 
+	issue.Hard(EVAL_RENDER_OUTSIDE_EPP, `Rendering of text is only permitted in an EPP template`)
+
 	issue.Hard(EVAL_SERIALIZATION_ATTRIBUTE_NOT_FOUND, `%{label} serialization is referencing non existent attribute '%{attribute}'`)
 
 	issue.Hard(EVAL_SERIALIZATION_NOT_ATTRIBUTE, `{label} serialization is referencing %{attribute}. Only attribute references are allowed`)
@@ -303,6 +381,10 @@ func init() {
 
 	issue.Hard(EVAL_SERIALIZATION_ENDLESS_RECURSION, `Endless recursion detected when attempting to serialize value of class %{type_name}'`)
 
+	issue.Hard2(EVAL_SERIALIZATION_NOT_DATA, `%{path} contains %{klass} value which is not Data. Enable rich_data to convert it`, issue.HF{`klass`: issue.AnOrA})
+
+	issue.Hard2(EVAL_SERIALIZATION_NOT_DATA_KEY, `%{path} is %{klass} hash key which is not a String. Enable rich_data to convert it`, issue.HF{`klass`: issue.AnOrA})
+
 	issue.Hard2(EVAL_SERIALIZATION_UNKNOWN_CONVERTED_TO_STRING, `%{path} contains %{klass} value. It will be converted to the String '%{value}'`, issue.HF{`klass`: issue.AnOrA})
 
 	issue.Hard(EVAL_SERIALIZATION_REQUIRED_AFTER_OPTIONAL, `%{label} serialization is referencing required %{required} after optional %{optional}. Optional attributes must be last`)
@@ -353,8 +435,14 @@ func init() {
 
 	issue.Hard(EVAL_UNHANDLED_PCORE_VERSION, `The pcore version for TypeSet '%{name}' is not understood by this runtime. Expected range %{expected_range}, got %{pcore_version}`)
 
+	issue.Hard(EVAL_UNKNOWN_CLASS, `Could not find class '%{name}'`)
+
 	issue.Hard(EVAL_UNKNOWN_FUNCTION, `Unknown function: '%{name}'`)
 
+	issue.Hard(EVAL_UNKNOWN_INTERPOLATION_METHOD, `Unknown interpolation method '%{name}'`)
+
+	issue.Hard(EVAL_UNKNOWN_PARAMETER, `%{label} has no parameter named '%{name}'`)
+
 	issue.Hard(EVAL_UNKNOWN_PLAN, `Unknown plan: '%{name}'`)
 
 	issue.Hard(EVAL_UNKNOWN_TASK, `Task not found: '%{name}'`)
@@ -367,6 +455,8 @@ func init() {
 
 	issue.Hard(EVAL_UNREFLECTABLE_VALUE, `Unable to create a reflect.Value from value of type '%{type}'`)
 
+	issue.Hard(EVAL_UNRESOLVED_RESOURCE_REFERENCE, `Reference to unresolved resource %{ref}`)
+
 	issue.Hard(EVAL_UNRESOLVED_TYPE, `Reference to unresolved type '%{typeString}'`)
 
 	issue.Hard(EVAL_UNRESOLVED_TYPE_OF, `Unable to resolve attribute '%{navigation}' of type '%{type}'`)
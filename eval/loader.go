@@ -48,6 +48,18 @@ type (
 		Loader
 
 		ModuleName() string
+
+		// Path returns the root directory of the module
+		Path() string
+	}
+
+	// ModuleFileLoader is implemented by module loaders that resolve the files of the module
+	ModuleFileLoader interface {
+		ModuleLoader
+
+		// FilePath returns the path of the given slash separated file in the given subdirectory of
+		// the module, together with a boolean indicating if the file exists or not
+		FilePath(subDir, file string) (string, bool)
 	}
 
 	DependencyLoader interface {
@@ -64,6 +76,7 @@ type (
 )
 
 const (
+	MANIFEST_PATH         = PathType(`manifest`)
 	PUPPET_DATA_TYPE_PATH = PathType(`puppetDataType`)
 	PUPPET_FUNCTION_PATH  = PathType(`puppetFunction`)
 	PLAN_PATH             = PathType(`plan`)
@@ -0,0 +1,8 @@
+package eval
+
+// Lookup looks up the given key using the hierarchical data binding that is configured by the
+// 'hiera_config' setting and by the hiera.yaml files found in the root of each module. The options
+// may contain a 'merge' entry that determines how values found at several levels of the hierarchy
+// are merged. The function returns the found value together with a boolean indicating if a value was
+// found or not.
+var Lookup func(c Context, key string, options OrderedMap) (Value, bool)
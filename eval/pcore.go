@@ -45,6 +45,10 @@ type (
 		// SetLogger changes the logger
 		SetLogger(Logger)
 
+		// SetFactsProvider changes the provider of the facts that are assigned to global
+		// variables when a root context is created. A nil provider means no facts.
+		SetFactsProvider(FactsProvider)
+
 		// Do executes a given function with an initialized Context instance.
 		//
 		// The Context will be parented by the Go context returned by context.Background()
@@ -0,0 +1,42 @@
+package eval
+
+import (
+	"github.com/lyraproj/issue/issue"
+)
+
+const ProfilerKey = `puppet.profiler`
+
+// ProfileKind tells what kind of unit that is being profiled
+type ProfileKind string
+
+const (
+	// ProfileGoFunction is a function implemented in Go
+	ProfileGoFunction = ProfileKind(`go function`)
+
+	// ProfileFunction is a function or plan implemented in Puppet
+	ProfileFunction = ProfileKind(`function`)
+
+	// ProfileLambda is a lambda implemented in Puppet
+	ProfileLambda = ProfileKind(`lambda`)
+
+	// ProfileExpression is an expression at the top level of a program
+	ProfileExpression = ProfileKind(`expression`)
+)
+
+// A Profiler is notified by the evaluator when it is stored in the evaluation context using the
+// ProfilerKey.
+type Profiler interface {
+	// Enter is called when the evaluation of a unit starts. The location is where the unit is
+	// defined, or nil when it has no source. The returned function is called when the evaluation
+	// ends, regardless of how it ends.
+	Enter(kind ProfileKind, name string, location issue.Location) func()
+}
+
+// ProfilerOf returns the profiler that is stored in the given context, or nil if no such
+// profiler exists
+func ProfilerOf(c Context) Profiler {
+	if pv, ok := c.Get(ProfilerKey); ok {
+		return pv.(Profiler)
+	}
+	return nil
+}
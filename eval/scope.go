@@ -49,4 +49,13 @@ type (
 		// State returns NotFound, Global, or Local
 		State(name string) VariableState
 	}
+
+	// A VariableIterator is a Scope that can iterate over its variables. All scopes created by
+	// this module implement it.
+	VariableIterator interface {
+		// Each calls the given function once for each variable that is visible from this scope, i.e.
+		// variables hidden by other variables with the same name are not included. The order of the
+		// variables is undefined.
+		Each(func(name string, value Value))
+	}
 )
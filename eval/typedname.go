@@ -15,6 +15,12 @@ const  NsType        = Namespace(`type`)
 // NsFunction denotes a callable function
 const  NsFunction    = Namespace(`function`)
 
+// NsClass denotes a host class that is defined using the Puppet language
+const NsClass = Namespace(`class`)
+
+// NsDefinedType denotes a resource type that is defined using the 'define' keyword in the Puppet language
+const NsDefinedType = Namespace(`defined_type`)
+
 // NsInterface denotes an entity that must have an "interface" property that appoints
 // an object type which in turn contains a declaration of the methods that the interface
 // implements.
@@ -5,7 +5,8 @@ import (
 	"fmt"
 	"io"
 	"reflect"
-	"regexp"
+
+	"github.com/lyraproj/puppet-evaluator/regex"
 )
 
 type (
@@ -207,7 +208,7 @@ type (
 
 	StringValue interface {
 		List
-		Split(pattern *regexp.Regexp) List
+		Split(pattern regex.Regexp) List
 		ToLower() StringValue
 		ToUpper() StringValue
 		EqualsIgnoreCase(Value) bool
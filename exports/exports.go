@@ -0,0 +1,110 @@
+// Package exports implements an eval.ExportStore that keeps exported resources in a local JSON
+// file so that resources exported by one evaluation can be imported by another.
+package exports
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// Store is an eval.ExportStore that persists the exported resources as rich data in a JSON file.
+// The file is read and written each time a resource is exported or imported.
+type Store struct {
+	lock sync.Mutex
+	path string
+}
+
+// New creates a new Store that uses the file at the given path. The file and its directory are
+// created when the first resource is exported.
+func New(path string) *Store {
+	return &Store{path: path}
+}
+
+// Attach attaches the store to the given context
+func (s *Store) Attach(c eval.Context) {
+	c.Set(eval.ExportStoreKey, s)
+}
+
+// Detach detaches the store from the given context
+func (s *Store) Detach(c eval.Context) {
+	c.Delete(eval.ExportStoreKey)
+}
+
+func (s *Store) Export(c eval.Context, resource eval.Resource) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	resource = eval.NewResource(resource.TypeName(), resource.Title(), resource.Parameters(), nil, nil)
+	resources := s.read(c)
+	found := false
+	for i, r := range resources {
+		if sameResource(r, resource) {
+			resources[i] = resource
+			found = true
+			break
+		}
+	}
+	if !found {
+		resources = append(resources, resource)
+	}
+	s.write(c, resources)
+}
+
+func (s *Store) Import(c eval.Context, typeName string) []eval.Resource {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	resources := make([]eval.Resource, 0)
+	for _, r := range s.read(c) {
+		if strings.EqualFold(r.TypeName(), typeName) {
+			resources = append(resources, r)
+		}
+	}
+	return resources
+}
+
+// read returns the resources that are stored in the file, or an empty slice when the file does
+// not exist
+func (s *Store) read(c eval.Context) []eval.Resource {
+	bf, ok := types.BinaryFromFile2(c, s.path)
+	if !ok {
+		return []eval.Resource{}
+	}
+	d := serialization.NewDeserializer(c, eval.EMPTY_MAP)
+	serialization.JsonToData(s.path, bytes.NewReader(bf.Bytes()), d)
+	list := d.Value().(eval.List)
+	resources := make([]eval.Resource, list.Len())
+	list.EachWithIndex(func(v eval.Value, i int) { resources[i] = v.(eval.Resource) })
+	return resources
+}
+
+func (s *Store) write(c eval.Context, resources []eval.Resource) {
+	vs := make([]eval.Value, len(resources))
+	for i, r := range resources {
+		vs[i] = r
+	}
+	buf := bytes.NewBufferString(``)
+	serialization.NewSerializer(c, types.SingletonHash2(`rich_data`, types.BooleanTrue)).Convert(types.WrapValues(vs), serialization.NewJsonStreamer(buf))
+	buf.WriteByte('\n')
+
+	err := os.MkdirAll(filepath.Dir(s.path), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(s.path, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: err.Error()}))
+	}
+}
+
+func sameResource(a, b eval.Resource) bool {
+	return strings.EqualFold(a.TypeName(), b.TypeName()) && a.Title() == b.Title()
+}
@@ -0,0 +1,54 @@
+package exports_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/exports"
+
+	// Ensure that pcore is initialized
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func ExampleStore() {
+	dir, err := ioutil.TempDir(``, `exports`)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	store := exports.New(filepath.Join(dir, `exports.json`))
+
+	evaluate := func(source string) {
+		eval.Puppet.Do(func(c eval.Context) {
+			store.Attach(c)
+			if _, err := eval.TopEvaluate(c, c.ParseAndValidate(`site.pp`, source, false)); err != nil {
+				fmt.Println(err)
+				return
+			}
+			cat := eval.CatalogOf(c)
+			for _, r := range cat.Resources() {
+				fmt.Println(r.Reference(), r.Parameters())
+			}
+			for _, r := range cat.ExportedResources() {
+				fmt.Println(`exported`, r.Reference())
+			}
+		})
+	}
+
+	// The web nodes export their host entries
+	evaluate(`@@host { 'web01': ip => '10.0.0.1', tag => 'web' }`)
+	evaluate(`@@host { 'web02': ip => '10.0.0.2', tag => 'web' }`)
+
+	// The load balancer imports them without exporting them again
+	evaluate(`Host <<| tag == 'web' |>>`)
+
+	// Output:
+	// exported Host['web01']
+	// exported Host['web02']
+	// Host['web01'] {'ip' => '10.0.0.1', 'tag' => 'web'}
+	// Host['web02'] {'ip' => '10.0.0.2', 'tag' => 'web'}
+}
@@ -0,0 +1,63 @@
+// Package facts contains eval.FactsProvider implementations that read the facts from a file or
+// compute them from the local host.
+package facts
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-evaluator/yaml"
+)
+
+// FileProvider is an eval.FactsProvider that reads the facts from a JSON or YAML file. The file is
+// read once, when the facts are first requested or when Load is called.
+type FileProvider struct {
+	lock  sync.Mutex
+	path  string
+	facts eval.OrderedMap
+}
+
+// NewFileProvider creates a provider that reads the facts from the file at the given path. The file
+// must contain a hash. It is parsed as JSON when its extension is .json and as YAML otherwise.
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+func (p *FileProvider) Facts(c eval.Context) eval.OrderedMap {
+	if err := p.Load(c); err != nil {
+		panic(err)
+	}
+	return p.facts
+}
+
+// Load reads the facts from the file unless they have been read already. An error is returned when
+// the file cannot be read or when it does not contain a hash.
+func (p *FileProvider) Load(c eval.Context) (err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.facts != nil {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				panic(r)
+			}
+		}
+	}()
+	data := types.BinaryFromFile(c, p.path).Bytes()
+	var v eval.Value
+	if strings.EqualFold(filepath.Ext(p.path), `.json`) {
+		v = serialization.UnmarshalJson(data)
+	} else {
+		v = yaml.Unmarshal(c, data)
+	}
+	p.facts = eval.AssertInstance(p.path, types.DefaultHashType(), v).(eval.OrderedMap)
+	return nil
+}
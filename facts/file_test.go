@@ -0,0 +1,47 @@
+package facts_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/facts"
+)
+
+func ExampleFileProvider() {
+	dir, err := ioutil.TempDir(``, `facts`)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, `facts.yaml`)
+	err = ioutil.WriteFile(path, []byte("kernel: Linux\nos:\n  family: RedHat\n  release:\n    major: '7'\n"), 0644)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	eval.Puppet.SetFactsProvider(facts.NewFileProvider(path))
+	defer eval.Puppet.SetFactsProvider(nil)
+	eval.Puppet.Do(func(c eval.Context) {
+		expr := c.ParseAndValidate(`site.pp`, `
+      class web {
+        $release = $facts['os']['release']['major']
+        $message = "${::kernel} ${os['family']} ${release}"
+      }
+      include web
+      $web::message`, false)
+		c.AddDefinitions(expr)
+		v, err := eval.TopEvaluate(c, expr)
+		fmt.Println(v, err)
+
+		_, err = eval.TopEvaluate(c, c.ParseAndValidate(`site.pp`, `$facts = {}`, false))
+		fmt.Println(err)
+	})
+	// Output:
+	// Linux RedHat 7 <nil>
+	// Cannot reassign variable '$facts' (file: site.pp, line: 1, column: 1)
+}
@@ -0,0 +1,491 @@
+package facts
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// HostProvider is an eval.FactsProvider that computes the facts of the local Linux host from the
+// /proc file system, /etc/os-release, and the network interfaces of the host. The facts are named
+// and structured like the core facts produced by Facter. Facts that cannot be determined, e.g.
+// because the host isn't running Linux, are omitted. The facts are computed once, when they are
+// first requested.
+type HostProvider struct {
+	lock       sync.Mutex
+	root       string
+	interfaces func() []netInterface
+	facts      eval.OrderedMap
+}
+
+// netInterface describes a network interface and its addresses
+type netInterface struct {
+	name  string
+	mac   string
+	mtu   int
+	addrs []*net.IPNet
+}
+
+// NewHostProvider creates a provider that computes the facts of the local host
+func NewHostProvider() *HostProvider {
+	return newHostProvider(`/`, hostInterfaces)
+}
+
+func newHostProvider(root string, interfaces func() []netInterface) *HostProvider {
+	return &HostProvider{root: root, interfaces: interfaces}
+}
+
+func (p *HostProvider) Facts(c eval.Context) eval.OrderedMap {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.facts == nil {
+		hw := p.hardware()
+		f := builder{}
+		f.add(`kernel`, str(p.read(`proc/sys/kernel/ostype`)))
+		release := p.read(`proc/sys/kernel/osrelease`)
+		f.add(`kernelrelease`, str(release))
+		if release != `` {
+			version := strings.SplitN(release, `-`, 2)[0]
+			f.add(`kernelversion`, str(version))
+			f.add(`kernelmajversion`, str(strings.Join(splitN(version, `.`, 2), `.`)))
+		}
+		f.add(`os`, p.os(hw))
+		f.add(`networking`, p.networking())
+		f.add(`memory`, p.memory())
+		f.add(`processors`, p.processors(hw))
+		p.facts = f.hash()
+	}
+	return p.facts
+}
+
+// builder collects the entries of a Hash. Entries with a nil value are omitted.
+type builder []*types.HashEntry
+
+func (b *builder) add(key string, value eval.Value) {
+	if value != nil {
+		*b = append(*b, types.WrapHashEntry2(key, value))
+	}
+}
+
+// hash returns a Hash with the entries of the builder sorted by key
+func (b builder) hash() *types.HashValue {
+	sort.Slice(b, func(i, j int) bool { return b[i].Key().String() < b[j].Key().String() })
+	return types.WrapHash(b)
+}
+
+// value returns the hash of the builder, or nil if the builder is empty
+func (b builder) value() eval.Value {
+	if len(b) == 0 {
+		return nil
+	}
+	return b.hash()
+}
+
+func str(s string) eval.Value {
+	if s == `` {
+		return nil
+	}
+	return types.WrapString(s)
+}
+
+// splitN returns at most n leading elements of s split by sep
+func splitN(s, sep string, n int) []string {
+	parts := strings.Split(s, sep)
+	if len(parts) > n {
+		parts = parts[:n]
+	}
+	return parts
+}
+
+// read returns the trimmed contents of the given file below the root, or an empty string if the
+// file cannot be read
+func (p *HostProvider) read(path string) string {
+	bs, err := ioutil.ReadFile(filepath.Join(p.root, path))
+	if err != nil {
+		return ``
+	}
+	return string(bytes.TrimSpace(bs))
+}
+
+// lines calls the given function with each line of the given file below the root
+func (p *HostProvider) lines(path string, f func(line string)) {
+	bs, err := ioutil.ReadFile(filepath.Join(p.root, path))
+	if err != nil {
+		return
+	}
+	s := bufio.NewScanner(bytes.NewReader(bs))
+	for s.Scan() {
+		f(s.Text())
+	}
+}
+
+// hardware returns the machine hardware name, e.g. x86_64
+func (p *HostProvider) hardware() string {
+	if hw := p.read(`proc/sys/kernel/arch`); hw != `` {
+		return hw
+	}
+	switch runtime.GOARCH {
+	case `amd64`:
+		return `x86_64`
+	case `386`:
+		return `i686`
+	case `arm64`:
+		return `aarch64`
+	case `arm`:
+		return `armv7l`
+	default:
+		return runtime.GOARCH
+	}
+}
+
+var osNames = map[string]string{
+	`almalinux`: `AlmaLinux`,
+	`alpine`:    `Alpine`,
+	`amzn`:      `Amazon`,
+	`arch`:      `Archlinux`,
+	`centos`:    `CentOS`,
+	`debian`:    `Debian`,
+	`fedora`:    `Fedora`,
+	`gentoo`:    `Gentoo`,
+	`linuxmint`: `LinuxMint`,
+	`ol`:        `OracleLinux`,
+	`opensuse`:  `OpenSuSE`,
+	`rhel`:      `RedHat`,
+	`rocky`:     `Rocky`,
+	`sles`:      `SLES`,
+	`ubuntu`:    `Ubuntu`,
+}
+
+var osFamilies = map[string]string{
+	`almalinux`: `RedHat`,
+	`amzn`:      `RedHat`,
+	`arch`:      `Archlinux`,
+	`centos`:    `RedHat`,
+	`debian`:    `Debian`,
+	`fedora`:    `RedHat`,
+	`gentoo`:    `Gentoo`,
+	`linuxmint`: `Debian`,
+	`ol`:        `RedHat`,
+	`opensuse`:  `Suse`,
+	`rhel`:      `RedHat`,
+	`rocky`:     `RedHat`,
+	`sles`:      `Suse`,
+	`suse`:      `Suse`,
+	`ubuntu`:    `Debian`,
+}
+
+// osRelease returns the variables of the os-release file
+func (p *HostProvider) osRelease() map[string]string {
+	vars := make(map[string]string)
+	path := `etc/os-release`
+	if p.read(path) == `` {
+		path = `usr/lib/os-release`
+	}
+	p.lines(path, func(line string) {
+		if eq := strings.IndexByte(line, '='); eq > 0 && !strings.HasPrefix(line, `#`) {
+			v := strings.TrimSpace(line[eq+1:])
+			if uq, err := strconv.Unquote(v); err == nil {
+				v = uq
+			} else {
+				v = strings.Trim(v, `'"`)
+			}
+			vars[strings.TrimSpace(line[:eq])] = v
+		}
+	})
+	return vars
+}
+
+func (p *HostProvider) os(hw string) eval.Value {
+	vars := p.osRelease()
+	id := vars[`ID`]
+	if strings.HasPrefix(id, `opensuse`) {
+		id = `opensuse`
+	}
+	name, ok := osNames[id]
+	if fs := strings.Fields(vars[`NAME`]); !ok && len(fs) > 0 {
+		name = fs[0]
+	}
+	family, ok := osFamilies[id]
+	if !ok {
+		for _, like := range strings.Fields(vars[`ID_LIKE`]) {
+			if family, ok = osFamilies[like]; ok {
+				break
+			}
+		}
+		if !ok {
+			family = name
+		}
+	}
+
+	arch := hw
+	if family == `Debian` {
+		// Debian based distributions use the architecture names of dpkg
+		switch hw {
+		case `x86_64`:
+			arch = `amd64`
+		case `aarch64`:
+			arch = `arm64`
+		}
+	}
+
+	release := version(vars[`VERSION_ID`])
+	o := builder{}
+	o.add(`architecture`, str(arch))
+	o.add(`family`, str(family))
+	o.add(`hardware`, str(hw))
+	o.add(`name`, str(name))
+	o.add(`release`, release)
+	if id != `` {
+		d := builder{}
+		d.add(`codename`, str(vars[`VERSION_CODENAME`]))
+		d.add(`description`, str(vars[`PRETTY_NAME`]))
+		d.add(`id`, str(name))
+		d.add(`release`, release)
+		o.add(`distro`, d.value())
+	}
+	return o.value()
+}
+
+// version returns a hash with the full, major, and minor parts of the given version
+func version(full string) eval.Value {
+	if full == `` {
+		return nil
+	}
+	parts := strings.SplitN(full, `.`, 3)
+	v := builder{}
+	v.add(`full`, str(full))
+	v.add(`major`, str(parts[0]))
+	if len(parts) > 1 {
+		v.add(`minor`, str(parts[1]))
+	}
+	return v.hash()
+}
+
+func (p *HostProvider) networking() eval.Value {
+	hostname := p.read(`proc/sys/kernel/hostname`)
+	domain := p.read(`proc/sys/kernel/domainname`)
+	if domain == `(none)` {
+		domain = ``
+	}
+	if dot := strings.IndexByte(hostname, '.'); dot > 0 {
+		if domain == `` {
+			domain = hostname[dot+1:]
+		}
+		hostname = hostname[:dot]
+	}
+	if domain == `` {
+		p.lines(`etc/resolv.conf`, func(line string) {
+			fs := strings.Fields(line)
+			if domain == `` && len(fs) > 1 && (fs[0] == `domain` || fs[0] == `search`) {
+				domain = fs[1]
+			}
+		})
+	}
+
+	n := builder{}
+	n.add(`hostname`, str(hostname))
+	n.add(`domain`, str(domain))
+	if hostname != `` && domain != `` {
+		n.add(`fqdn`, str(hostname+`.`+domain))
+	} else {
+		n.add(`fqdn`, str(hostname))
+	}
+
+	primary := p.primaryInterface()
+	ifs := builder{}
+	var primaryFacts *builder
+	for _, ni := range p.interfaces() {
+		i := interfaceFacts(ni)
+		ifs.add(ni.name, i.value())
+		if ni.name == primary || primary == `` && primaryFacts == nil && ni.name != `lo` {
+			primary = ni.name
+			primaryFacts = &i
+		}
+	}
+	n.add(`interfaces`, ifs.value())
+	n.add(`primary`, str(primary))
+	if primaryFacts != nil {
+		for _, e := range *primaryFacts {
+			n.add(e.Key().String(), e.Value())
+		}
+	}
+	return n.value()
+}
+
+// primaryInterface returns the name of the interface of the default route
+func (p *HostProvider) primaryInterface() string {
+	primary := ``
+	p.lines(`proc/net/route`, func(line string) {
+		fs := strings.Fields(line)
+		if primary == `` && len(fs) > 1 && fs[1] == `00000000` {
+			primary = fs[0]
+		}
+	})
+	return primary
+}
+
+func interfaceFacts(ni netInterface) builder {
+	i := builder{}
+	i.add(`mac`, str(ni.mac))
+	if ni.mtu > 0 {
+		i.add(`mtu`, types.WrapInteger(int64(ni.mtu)))
+	}
+	for _, a := range ni.addrs {
+		suffix := `6`
+		if a.IP.To4() != nil {
+			suffix = ``
+		}
+		if _, found := findEntry(i, `ip`+suffix); !found {
+			i.add(`ip`+suffix, str(a.IP.String()))
+			i.add(`netmask`+suffix, str(net.IP(a.Mask).String()))
+			i.add(`network`+suffix, str(a.IP.Mask(a.Mask).String()))
+		}
+	}
+	return i
+}
+
+func findEntry(b builder, key string) (eval.Value, bool) {
+	for _, e := range b {
+		if e.Key().String() == key {
+			return e.Value(), true
+		}
+	}
+	return nil, false
+}
+
+// hostInterfaces returns the network interfaces of the local host
+func hostInterfaces() []netInterface {
+	ifs, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	nis := make([]netInterface, 0, len(ifs))
+	for _, ifc := range ifs {
+		ni := netInterface{name: ifc.Name, mac: ifc.HardwareAddr.String(), mtu: ifc.MTU}
+		if addrs, err := ifc.Addrs(); err == nil {
+			for _, a := range addrs {
+				if ipn, ok := a.(*net.IPNet); ok {
+					ni.addrs = append(ni.addrs, ipn)
+				}
+			}
+		}
+		nis = append(nis, ni)
+	}
+	return nis
+}
+
+func (p *HostProvider) memory() eval.Value {
+	info := make(map[string]int64)
+	p.lines(`proc/meminfo`, func(line string) {
+		fs := strings.Fields(line)
+		if len(fs) >= 2 {
+			if n, err := strconv.ParseInt(fs[1], 10, 64); err == nil {
+				if len(fs) > 2 && fs[2] == `kB` {
+					n *= 1024
+				}
+				info[strings.TrimSuffix(fs[0], `:`)] = n
+			}
+		}
+	})
+
+	m := builder{}
+	if total, ok := info[`MemTotal`]; ok {
+		available, ok := info[`MemAvailable`]
+		if !ok {
+			available = info[`MemFree`] + info[`Buffers`] + info[`Cached`]
+		}
+		m.add(`system`, memoryFacts(total, available))
+	}
+	if total := info[`SwapTotal`]; total > 0 {
+		m.add(`swap`, memoryFacts(total, info[`SwapFree`]))
+	}
+	return m.value()
+}
+
+func memoryFacts(total, available int64) eval.Value {
+	used := total - available
+	m := builder{}
+	m.add(`available`, str(humanBytes(available)))
+	m.add(`available_bytes`, types.WrapInteger(available))
+	if total > 0 {
+		m.add(`capacity`, str(fmt.Sprintf(`%.2f%%`, float64(used)*100/float64(total))))
+	}
+	m.add(`total`, str(humanBytes(total)))
+	m.add(`total_bytes`, types.WrapInteger(total))
+	m.add(`used`, str(humanBytes(used)))
+	m.add(`used_bytes`, types.WrapInteger(used))
+	return m.hash()
+}
+
+// humanBytes formats the given number of bytes using binary units, e.g. 1.50 GiB
+func humanBytes(n int64) string {
+	units := []string{`KiB`, `MiB`, `GiB`, `TiB`, `PiB`, `EiB`}
+	if n < 1024 {
+		return fmt.Sprintf(`%d bytes`, n)
+	}
+	f := float64(n) / 1024
+	u := 0
+	for f >= 1024 && u < len(units)-1 {
+		f /= 1024
+		u++
+	}
+	return fmt.Sprintf(`%.2f %s`, f, units[u])
+}
+
+func (p *HostProvider) processors(hw string) eval.Value {
+	count := 0
+	models := make([]eval.Value, 0)
+	physical := make(map[string]bool)
+	speed := ``
+	p.lines(`proc/cpuinfo`, func(line string) {
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 {
+			return
+		}
+		key := strings.TrimSpace(line[:colon])
+		value := strings.TrimSpace(line[colon+1:])
+		switch key {
+		case `processor`:
+			if _, err := strconv.Atoi(value); err == nil {
+				count++
+			}
+		case `model name`, `cpu model`:
+			models = append(models, types.WrapString(value))
+		case `physical id`:
+			physical[value] = true
+		case `cpu MHz`:
+			if mhz, err := strconv.ParseFloat(value, 64); err == nil && speed == `` {
+				if mhz >= 1000 {
+					speed = fmt.Sprintf(`%.2f GHz`, mhz/1000)
+				} else {
+					speed = fmt.Sprintf(`%.2f MHz`, mhz)
+				}
+			}
+		}
+	})
+	if count == 0 {
+		return nil
+	}
+	physicalCount := len(physical)
+	if physicalCount == 0 {
+		physicalCount = 1
+	}
+	ps := builder{}
+	ps.add(`count`, types.WrapInteger(int64(count)))
+	ps.add(`isa`, str(hw))
+	ps.add(`models`, types.WrapValues(models))
+	ps.add(`physicalcount`, types.WrapInteger(int64(physicalCount)))
+	ps.add(`speed`, str(speed))
+	return ps.hash()
+}
@@ -0,0 +1,36 @@
+package facts
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+
+	// Ensure that pcore is initialized
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func ExampleHostProvider() {
+	interfaces := func() []netInterface {
+		ip, network, _ := net.ParseCIDR(`192.168.2.10/24`)
+		network.IP = ip
+		return []netInterface{
+			{name: `lo`, mtu: 65536, addrs: []*net.IPNet{{IP: net.IPv4(127, 0, 0, 1), Mask: net.CIDRMask(8, 32)}}},
+			{name: `eth0`, mac: `52:54:00:12:34:56`, mtu: 1500, addrs: []*net.IPNet{network}},
+		}
+	}
+	eval.Puppet.Do(func(c eval.Context) {
+		newHostProvider(`testdata/host`, interfaces).Facts(c).EachPair(func(k, v eval.Value) {
+			fmt.Println(k, `=>`, v)
+		})
+	})
+	// Output:
+	// kernel => Linux
+	// kernelmajversion => 4.15
+	// kernelrelease => 4.15.0-45-generic
+	// kernelversion => 4.15.0
+	// memory => {'swap' => {'available' => '2.00 GiB', 'available_bytes' => 2147479552, 'capacity' => '0.00%', 'total' => '2.00 GiB', 'total_bytes' => 2147479552, 'used' => '0 bytes', 'used_bytes' => 0}, 'system' => {'available' => '3.89 GiB', 'available_bytes' => 4181938176, 'capacity' => '50.00%', 'total' => '7.79 GiB', 'total_bytes' => 8363876352, 'used' => '3.89 GiB', 'used_bytes' => 4181938176}}
+	// networking => {'domain' => 'example.com', 'fqdn' => 'web01.example.com', 'hostname' => 'web01', 'interfaces' => {'eth0' => {'ip' => '192.168.2.10', 'mac' => '52:54:00:12:34:56', 'mtu' => 1500, 'netmask' => '255.255.255.0', 'network' => '192.168.2.0'}, 'lo' => {'ip' => '127.0.0.1', 'mtu' => 65536, 'netmask' => '255.0.0.0', 'network' => '127.0.0.0'}}, 'ip' => '192.168.2.10', 'mac' => '52:54:00:12:34:56', 'mtu' => 1500, 'netmask' => '255.255.255.0', 'network' => '192.168.2.0', 'primary' => 'eth0'}
+	// os => {'architecture' => 'amd64', 'distro' => {'codename' => 'bionic', 'description' => 'Ubuntu 18.04.2 LTS', 'id' => 'Ubuntu', 'release' => {'full' => '18.04', 'major' => '18', 'minor' => '04'}}, 'family' => 'Debian', 'hardware' => 'x86_64', 'name' => 'Ubuntu', 'release' => {'full' => '18.04', 'major' => '18', 'minor' => '04'}}
+	// processors => {'count' => 2, 'isa' => 'x86_64', 'models' => ['Intel(R) Core(TM) i7-8550U CPU @ 1.80GHz', 'Intel(R) Core(TM) i7-8550U CPU @ 1.80GHz'], 'physicalcount' => 1, 'speed' => '1.99 GHz'}
+}
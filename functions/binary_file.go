@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/puppet-evaluator/eval"
 	"github.com/lyraproj/puppet-evaluator/types"
 )
@@ -10,7 +11,12 @@ func init() {
 		func(d eval.Dispatch) {
 			d.Param(`String`)
 			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
-				return types.BinaryFromFile(c, args[0].String())
+				name := args[0].String()
+				path, ok := findFile([]string{name})
+				if !ok {
+					panic(eval.Error(eval.EVAL_FILES_NOT_FOUND, issue.H{`paths`: name}))
+				}
+				return types.BinaryFromFile(c, path)
 			})
 		})
 }
@@ -0,0 +1,21 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+// camelcase capitalizes each underscore separated segment of the given string and removes the
+// underscores, e.g. 'hello_big_world' becomes 'HelloBigWorld'
+func camelcase(s string) string {
+	segments := strings.Split(s, `_`)
+	for i, segment := range segments {
+		segments[i] = capitalize(segment)
+	}
+	return strings.Join(segments, ``)
+}
+
+func init() {
+	eval.NewGoFunction(`camelcase`, stringConverter(camelcase)...)
+}
@@ -0,0 +1,22 @@
+package functions
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+// capitalize converts the first character of the given string to upper case and the rest to lower
+// case
+func capitalize(s string) string {
+	if s == `` {
+		return s
+	}
+	r, w := utf8.DecodeRuneInString(s)
+	return strings.ToUpper(string(r)) + strings.ToLower(s[w:])
+}
+
+func init() {
+	eval.NewGoFunction(`capitalize`, stringConverter(capitalize)...)
+}
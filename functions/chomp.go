@@ -0,0 +1,22 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+// chomp removes one trailing line break, i.e. "\r\n", "\n", or "\r", from the given string
+func chomp(s string) string {
+	switch {
+	case strings.HasSuffix(s, "\r\n"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(s, "\n"), strings.HasSuffix(s, "\r"):
+		return s[:len(s)-1]
+	}
+	return s
+}
+
+func init() {
+	eval.NewGoFunction(`chomp`, stringConverter(chomp)...)
+}
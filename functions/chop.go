@@ -0,0 +1,22 @@
+package functions
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+// chop removes the last character from the given string. A trailing "\r\n" is removed as one
+// character.
+func chop(s string) string {
+	if strings.HasSuffix(s, "\r\n") {
+		return s[:len(s)-2]
+	}
+	_, w := utf8.DecodeLastRuneInString(s)
+	return s[:len(s)-w]
+}
+
+func init() {
+	eval.NewGoFunction(`chop`, stringConverter(chop)...)
+}
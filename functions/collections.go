@@ -0,0 +1,46 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// eachEntry calls the consumer with each element of the iterable and its index. When the iterable is
+// hash style, the consumer is instead called with the key and the value, and the element is the
+// [key, value] pair.
+func eachEntry(iter eval.IterableValue, consumer func(index, value, element eval.Value)) {
+	if iter.IsHashStyle() {
+		iter.Iterator().Each(func(v eval.Value) {
+			e := v.(eval.List)
+			consumer(e.At(0), e.At(1), v)
+		})
+		return
+	}
+	index := int64(-1)
+	iter.Iterator().Each(func(v eval.Value) {
+		index++
+		consumer(types.WrapInteger(index), v, v)
+	})
+}
+
+// entryPredicate returns a function that calls the block with the element, or with the index and
+// the value when the block takes two parameters, and returns the truthiness of the result
+func entryPredicate(c eval.Context, block eval.Lambda, twoParams bool) func(index, value, element eval.Value) bool {
+	if twoParams {
+		return func(index, value, element eval.Value) bool { return eval.IsTruthy(block.Call(c, nil, index, value)) }
+	}
+	return func(index, value, element eval.Value) bool { return eval.IsTruthy(block.Call(c, nil, element)) }
+}
+
+// valueComparator returns a comparator that uses the result of the block, which must be an Integer
+// that is less than zero when a is less than b. When the block is nil, the values are compared using
+// the rules of the comparison operators.
+func valueComparator(c eval.Context, block eval.Lambda, caseSensitive bool) func(a, b eval.Value) int {
+	if block == nil {
+		return func(a, b eval.Value) int { return eval.PuppetCompare(a, b, caseSensitive) }
+	}
+	return func(a, b eval.Value) int {
+		result := eval.AssertInstance(`comparator block return`, types.DefaultIntegerType(), block.Call(c, nil, a, b))
+		return int(result.(eval.IntegerValue).Int())
+	}
+}
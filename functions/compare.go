@@ -0,0 +1,28 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`compare`,
+		func(d eval.Dispatch) {
+			d.Param(`String`)
+			d.Param(`String`)
+			d.OptionalParam(`Boolean`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				ignoreCase := len(args) < 3 || args[2].(eval.BooleanValue).Bool()
+				return types.WrapInteger(int64(eval.PuppetCompare(args[0], args[1], !ignoreCase)))
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Any`)
+			d.Param(`Any`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return types.WrapInteger(int64(eval.PuppetCompare(args[0], args[1], true)))
+			})
+		},
+	)
+}
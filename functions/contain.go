@@ -0,0 +1,21 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+func init() {
+	eval.NewGoFunction(`contain`,
+		func(d eval.Dispatch) {
+			d.RepeatedParam(classNamesParam)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				container := eval.ContainerOf(c)
+				return declareClasses(c, `contain`, args, func(ref eval.ResourceReference) {
+					if container != nil {
+						eval.CatalogOf(c).Contain(container, ref)
+					}
+				})
+			})
+		},
+	)
+}
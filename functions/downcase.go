@@ -0,0 +1,11 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+func init() {
+	eval.NewGoFunction(`downcase`, stringConverter(strings.ToLower)...)
+}
@@ -0,0 +1,46 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`empty`,
+		func(d eval.Dispatch) {
+			d.Param(`Variant[Collection, String]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return types.WrapBoolean(args[0].(eval.SizedValue).IsEmpty())
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Binary`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return types.WrapBoolean(len(args[0].(*types.BinaryValue).Bytes()) == 0)
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				_, ok := args[0].(eval.IterableValue).Iterator().Next()
+				return types.WrapBoolean(!ok)
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Undef`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return types.BooleanTrue
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Numeric`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return types.BooleanFalse
+			})
+		},
+	)
+}
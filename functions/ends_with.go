@@ -0,0 +1,21 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`ends_with`,
+		func(d eval.Dispatch) {
+			d.Param(`String`)
+			d.Param(`Variant[String, Array[String]]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				s := args[0].String()
+				return types.WrapBoolean(anyAffix(args[1], func(suffix string) bool { return strings.HasSuffix(s, suffix) }))
+			})
+		},
+	)
+}
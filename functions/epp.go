@@ -0,0 +1,27 @@
+package functions
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`epp`,
+		func(d eval.Dispatch) {
+			d.Param(`String`)
+			d.OptionalParam(`Hash[Pattern[/^\w+$/], Any]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				name := args[0].String()
+				path, ok := moduleFilePath(`templates`, name)
+				if !ok {
+					panic(eval.Error(eval.EVAL_FILE_NOT_FOUND, issue.H{`path`: name}))
+				}
+				arguments := eval.EMPTY_MAP
+				if len(args) > 1 {
+					arguments = args[1].(eval.OrderedMap)
+				}
+				return types.WrapString(eval.EvaluateEpp(c, path, string(types.BinaryFromFile(c, path).Bytes()), arguments))
+			})
+		})
+}
@@ -0,0 +1,24 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`file`,
+		func(d eval.Dispatch) {
+			d.RequiredRepeatedParam(`Variant[String, Array[String]]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				names := fileNames(args)
+				path, ok := findFile(names)
+				if !ok {
+					panic(eval.Error(eval.EVAL_FILES_NOT_FOUND, issue.H{`paths`: strings.Join(names, `, `)}))
+				}
+				return types.WrapString(string(types.BinaryFromFile(c, path).Bytes()))
+			})
+		})
+}
@@ -0,0 +1,56 @@
+package functions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+// moduleFilePath resolves a reference on the form '<module name>/<file>' into the path of the file in the
+// given subdirectory of the module. Absolute paths are used verbatim. The resolved path is returned together
+// with a boolean indicating if the file exists or not.
+func moduleFilePath(subDir, name string) (string, bool) {
+	if filepath.IsAbs(name) {
+		_, err := os.Stat(name)
+		return name, err == nil
+	}
+	if parts := strings.SplitN(name, `/`, 2); len(parts) == 2 {
+		l := eval.Puppet.Loader(parts[0])
+		switch l.(type) {
+		case eval.ModuleFileLoader:
+			return l.(eval.ModuleFileLoader).FilePath(subDir, parts[1])
+		case eval.ModuleLoader:
+			path := filepath.Join(l.(eval.ModuleLoader).Path(), subDir, filepath.FromSlash(parts[1]))
+			_, err := os.Stat(path)
+			return path, err == nil
+		}
+	}
+	return ``, false
+}
+
+// findFile returns the path of the first of the given module file references or absolute paths
+// that appoints an existing file in the files directory of a module
+func findFile(names []string) (string, bool) {
+	for _, name := range names {
+		if path, ok := moduleFilePath(`files`, name); ok {
+			return path, true
+		}
+	}
+	return ``, false
+}
+
+// fileNames returns the strings of the given arguments. Arrays are flattened
+func fileNames(args []eval.Value) []string {
+	names := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg.(type) {
+		case eval.StringValue:
+			names = append(names, arg.String())
+		case eval.List:
+			names = append(names, fileNames(arg.(eval.List).AppendTo(nil))...)
+		}
+	}
+	return names
+}
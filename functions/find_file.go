@@ -0,0 +1,19 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`find_file`,
+		func(d eval.Dispatch) {
+			d.RequiredRepeatedParam(`Variant[String, Array[String]]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				if path, ok := findFile(fileNames(args)); ok {
+					return types.WrapString(path)
+				}
+				return eval.UNDEF
+			})
+		})
+}
@@ -2,8 +2,21 @@ package functions
 
 import (
 	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
 )
 
+// flattenDepth flattens nested arrays, but no deeper than the given depth
+func flattenDepth(elements eval.List, depth int64, receiver []eval.Value) []eval.Value {
+	elements.Each(func(e eval.Value) {
+		if av, ok := e.(*types.ArrayValue); ok && depth > 0 {
+			receiver = flattenDepth(av, depth-1, receiver)
+		} else {
+			receiver = append(receiver, e)
+		}
+	})
+	return receiver
+}
+
 func init() {
 	eval.NewGoFunction(`flatten`,
 		func(d eval.Dispatch) {
@@ -18,5 +31,14 @@ func init() {
 				}
 			})
 		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Param(`Integer[0]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				elements := args[0].(eval.IterableValue).Iterator().AsArray()
+				return types.WrapValues(flattenDepth(elements, args[1].(eval.IntegerValue).Int(), make([]eval.Value, 0, elements.Len())))
+			})
+		},
 	)
 }
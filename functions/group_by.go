@@ -0,0 +1,50 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// groupBy returns a hash that maps each result of the block to the elements that produced it
+func groupBy(c eval.Context, iter eval.IterableValue, block eval.Lambda, twoParams bool) eval.Value {
+	keys := make([]eval.Value, 0)
+	groups := make(map[eval.HashKey][]eval.Value)
+	eachEntry(iter, func(index, value, element eval.Value) {
+		var key eval.Value
+		if twoParams {
+			key = block.Call(c, nil, index, value)
+		} else {
+			key = block.Call(c, nil, element)
+		}
+		hk := eval.ToKey(key)
+		if _, ok := groups[hk]; !ok {
+			keys = append(keys, key)
+		}
+		groups[hk] = append(groups[hk], element)
+	})
+	entries := make([]*types.HashEntry, len(keys))
+	for i, key := range keys {
+		entries[i] = types.WrapHashEntry(key, types.WrapValues(groups[eval.ToKey(key)]))
+	}
+	return types.WrapHash(entries)
+}
+
+func init() {
+	eval.NewGoFunction(`group_by`,
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return groupBy(c, args[0].(eval.IterableValue), block, false)
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Block(`Callable[2,2]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return groupBy(c, args[0].(eval.IterableValue), block, true)
+			})
+		},
+	)
+}
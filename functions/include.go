@@ -0,0 +1,47 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// classNamesParam is the type of the parameters accepted by the include, contain, and require functions
+const classNamesParam = `Variant[String[1], Type[Resource], Array[Variant[String[1], Type[Resource]]]]`
+
+// declareClasses performs an include-like declaration of each class that is appointed by the given
+// arguments and returns an array of references to the declared classes. The doer is called with a
+// reference to each declared class.
+func declareClasses(c eval.Context, name string, args []eval.Value, doer func(ref eval.ResourceReference)) eval.Value {
+	refs := make([]eval.Value, 0, len(args))
+	for i, arg := range types.WrapValues(args).Flatten().AppendTo(nil) {
+		cn := ``
+		switch arg.(type) {
+		case eval.StringValue:
+			cn = arg.String()
+		case eval.ResourceReference:
+			if rr := arg.(eval.ResourceReference); rr.TypeName() == `Class` && rr.Title() != `` {
+				cn = rr.Title()
+			}
+		}
+		if cn == `` {
+			panic(types.NewIllegalArgumentType2(name, i, `Variant[String[1], Type[Class]]`, arg))
+		}
+		ref := eval.DeclareClass(c, cn, nil)
+		if doer != nil {
+			doer(ref)
+		}
+		refs = append(refs, ref)
+	}
+	return types.WrapValues(refs)
+}
+
+func init() {
+	eval.NewGoFunction(`include`,
+		func(d eval.Dispatch) {
+			d.RepeatedParam(classNamesParam)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return declareClasses(c, `include`, args, nil)
+			})
+		},
+	)
+}
@@ -0,0 +1,82 @@
+package functions
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// indexOf returns the index of the first element of the iterable for which the predicate returns
+// true, or undef if no such element exists. The index is the key when the iterable is a Hash.
+func indexOf(iter eval.IterableValue, predicate func(index, value eval.Value) bool) eval.Value {
+	result := eval.UNDEF
+	if iter.IsHashStyle() {
+		iter.Iterator().Find(func(v eval.Value) bool {
+			e := v.(eval.List)
+			if predicate(e.At(0), e.At(1)) {
+				result = e.At(0)
+				return true
+			}
+			return false
+		})
+		return result
+	}
+	index := int64(-1)
+	iter.Iterator().Find(func(v eval.Value) bool {
+		index++
+		if predicate(types.WrapInteger(index), v) {
+			result = types.WrapInteger(index)
+			return true
+		}
+		return false
+	})
+	return result
+}
+
+func init() {
+	eval.NewGoFunction(`index`,
+		func(d eval.Dispatch) {
+			d.Param(`String`)
+			d.Param(`String`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				s := args[0].String()
+				if i := strings.Index(s, args[1].String()); i >= 0 {
+					return types.WrapInteger(int64(utf8.RuneCountInString(s[:i])))
+				}
+				return eval.UNDEF
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Param(`Any`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return indexOf(args[0].(eval.IterableValue), func(index, value eval.Value) bool {
+					return eval.Equals(value, args[1])
+				})
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return indexOf(args[0].(eval.IterableValue), func(index, value eval.Value) bool {
+					return eval.IsTruthy(block.Call(c, nil, value))
+				})
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Block(`Callable[2,2]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return indexOf(args[0].(eval.IterableValue), func(index, value eval.Value) bool {
+					return eval.IsTruthy(block.Call(c, nil, index, value))
+				})
+			})
+		},
+	)
+}
@@ -0,0 +1,21 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`inline_epp`,
+		func(d eval.Dispatch) {
+			d.Param(`String`)
+			d.OptionalParam(`Hash[Pattern[/^\w+$/], Any]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				arguments := eval.EMPTY_MAP
+				if len(args) > 1 {
+					arguments = args[1].(eval.OrderedMap)
+				}
+				return types.WrapString(eval.EvaluateEpp(c, `inline_epp`, args[0].String(), arguments))
+			})
+		})
+}
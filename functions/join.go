@@ -0,0 +1,31 @@
+package functions
+
+import (
+	"bytes"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`join`,
+		func(d eval.Dispatch) {
+			d.Param(`Array`)
+			d.OptionalParam(`String`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				delimiter := ``
+				if len(args) > 1 {
+					delimiter = args[1].String()
+				}
+				b := bytes.NewBufferString(``)
+				args[0].(eval.List).Flatten().EachWithIndex(func(v eval.Value, i int) {
+					if i > 0 {
+						b.WriteString(delimiter)
+					}
+					b.WriteString(v.String())
+				})
+				return types.WrapString(b.String())
+			})
+		},
+	)
+}
@@ -0,0 +1,22 @@
+package functions
+
+import (
+	"bytes"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`json_data`,
+		func(d eval.Dispatch) {
+			d.Param(`Hash[String[1], Any]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				path := args[0].(eval.OrderedMap).Get5(`path`, eval.EMPTY_STRING).String()
+				collector := serialization.NewCollector()
+				serialization.JsonToData(path, bytes.NewReader(types.BinaryFromFile(c, path).Bytes()), collector)
+				return eval.AssertInstance(path, types.DefaultHashType(), collector.Value())
+			})
+		})
+}
@@ -0,0 +1,35 @@
+package functions
+
+import (
+	"unicode/utf8"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	// size is an alias for length
+	for _, name := range []string{`length`, `size`} {
+		eval.NewGoFunction(name,
+			func(d eval.Dispatch) {
+				d.Param(`String`)
+				d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+					return types.WrapInteger(int64(utf8.RuneCountInString(args[0].String())))
+				})
+			},
+
+			func(d eval.Dispatch) {
+				d.Param(`Binary`)
+				d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+					return types.WrapInteger(int64(len(args[0].(*types.BinaryValue).Bytes())))
+				})
+			},
+
+			func(d eval.Dispatch) {
+				d.Param(`Collection`)
+				d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+					return types.WrapInteger(int64(args[0].(eval.SizedValue).Len()))
+				})
+			})
+	}
+}
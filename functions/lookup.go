@@ -0,0 +1,134 @@
+package functions
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/lookup"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction2(`lookup`,
+		func(l eval.LocalTypes) {
+			l.Type(`NameType`, `Variant[String, Array[String]]`)
+			l.Type(`ValueType`, `Type`)
+			l.Type(`MergeType`, lookup.MergeType)
+			l.Type(`OptionsWithName`, `Struct[
+        name => NameType,
+        Optional[value_type] => ValueType,
+        Optional[default_value] => Any,
+        Optional[override] => Hash[String, Any],
+        Optional[default_values_hash] => Hash[String, Any],
+        Optional[merge] => MergeType]`)
+			l.Type(`OptionsWithoutName`, `Struct[
+        Optional[value_type] => ValueType,
+        Optional[default_value] => Any,
+        Optional[override] => Hash[String, Any],
+        Optional[default_values_hash] => Hash[String, Any],
+        Optional[merge] => MergeType]`)
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`NameType`)
+			d.OptionalParam(`Optional[ValueType]`)
+			d.OptionalParam(`Optional[MergeType]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return lookupWithOptions(c, args[0], positionalOptions(args), nil)
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`NameType`)
+			d.Param(`Optional[ValueType]`)
+			d.Param(`Optional[MergeType]`)
+			d.Param(`Any`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				options := positionalOptions(args).Merge(types.SingletonHash2(`default_value`, args[3]))
+				return lookupWithOptions(c, args[0], options, nil)
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`NameType`)
+			d.OptionalParam(`Optional[ValueType]`)
+			d.OptionalParam(`Optional[MergeType]`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return lookupWithOptions(c, args[0], positionalOptions(args), block)
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`OptionsWithName`)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				options := args[0].(eval.OrderedMap)
+				return lookupWithOptions(c, options.Get5(`name`, eval.UNDEF), options, block)
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`NameType`)
+			d.Param(`OptionsWithoutName`)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return lookupWithOptions(c, args[0], args[1].(eval.OrderedMap), block)
+			})
+		},
+	)
+}
+
+// positionalOptions creates an options hash from the optional value type and merge arguments
+func positionalOptions(args []eval.Value) eval.OrderedMap {
+	entries := make([]*types.HashEntry, 0, 2)
+	if len(args) > 1 {
+		entries = append(entries, types.WrapHashEntry2(`value_type`, args[1]))
+		if len(args) > 2 {
+			entries = append(entries, types.WrapHashEntry2(`merge`, args[2]))
+		}
+	}
+	return types.WrapHash(entries)
+}
+
+func lookupWithOptions(c eval.Context, names eval.Value, options eval.OrderedMap, block eval.Lambda) eval.Value {
+	var valueType eval.Type = types.DefaultAnyType()
+	if vt, ok := options.Get4(`value_type`); ok && vt != eval.UNDEF {
+		valueType = vt.(eval.Type)
+	}
+	lookupOptions := eval.EMPTY_MAP
+	if mv, ok := options.Get4(`merge`); ok && mv != eval.UNDEF {
+		lookupOptions = types.SingletonHash2(`merge`, mv)
+	}
+
+	var keys []string
+	if nl, ok := names.(*types.ArrayValue); ok {
+		keys = make([]string, nl.Len())
+		nl.EachWithIndex(func(n eval.Value, i int) { keys[i] = n.String() })
+	} else {
+		keys = []string{names.String()}
+	}
+
+	override := options.Get5(`override`, eval.EMPTY_MAP).(eval.OrderedMap)
+	for _, key := range keys {
+		if v, ok := override.Get4(key); ok {
+			return eval.AssertInstance(`lookup() found value`, valueType, v)
+		}
+		if v, ok := lookup.Lookup(c, key, lookupOptions); ok {
+			return eval.AssertInstance(`lookup() found value`, valueType, v)
+		}
+	}
+
+	defaults := options.Get5(`default_values_hash`, eval.EMPTY_MAP).(eval.OrderedMap)
+	for _, key := range keys {
+		if v, ok := defaults.Get4(key); ok {
+			return eval.AssertInstance(`lookup() default value`, valueType, v)
+		}
+	}
+	if block != nil {
+		return eval.AssertInstance(`lookup() default value`, valueType, block.Call(c, nil, names))
+	}
+	if dv, ok := options.Get4(`default_value`); ok {
+		return eval.AssertInstance(`lookup() default value`, valueType, dv)
+	}
+	panic(eval.Error(eval.EVAL_LOOKUP_NOT_FOUND, issue.H{`name`: eval.ToString(names)}))
+}
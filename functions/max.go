@@ -0,0 +1,17 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+func init() {
+	eval.NewGoFunction(`max`,
+		func(d eval.Dispatch) {
+			d.RequiredRepeatedParam(`Any`)
+			d.OptionalBlock(`Callable[2,2]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return extreme(c, args, block, 1)
+			})
+		},
+	)
+}
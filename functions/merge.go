@@ -0,0 +1,48 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// merge merges the given hashes from left to right. When a key is present in more than one hash,
+// the block, if given, is called with the key, the current value, and the new value and its result
+// becomes the value. Otherwise the new value wins. Undef and empty string arguments are ignored.
+func merge(c eval.Context, hashes []eval.Value, block eval.Lambda) eval.Value {
+	keys := make([]eval.Value, 0)
+	values := make(map[eval.HashKey]eval.Value)
+	for _, h := range hashes {
+		hash, ok := h.(eval.OrderedMap)
+		if !ok {
+			continue
+		}
+		hash.EachPair(func(k, v eval.Value) {
+			hk := eval.ToKey(k)
+			if old, found := values[hk]; found {
+				if block != nil {
+					v = block.Call(c, nil, k, old, v)
+				}
+			} else {
+				keys = append(keys, k)
+			}
+			values[hk] = v
+		})
+	}
+	entries := make([]*types.HashEntry, len(keys))
+	for i, key := range keys {
+		entries[i] = types.WrapHashEntry(key, values[eval.ToKey(key)])
+	}
+	return types.WrapHash(entries)
+}
+
+func init() {
+	eval.NewGoFunction(`merge`,
+		func(d eval.Dispatch) {
+			d.RequiredRepeatedParam(`Variant[Hash, Undef, String[0,0]]`)
+			d.OptionalBlock(`Callable[3,3]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return merge(c, args, block)
+			})
+		},
+	)
+}
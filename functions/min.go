@@ -0,0 +1,40 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+// extreme returns the value that the comparator orders first when sign is -1, or last when sign is
+// 1. A single Iterable argument that is not a String is replaced by its elements.
+func extreme(c eval.Context, args []eval.Value, block eval.Lambda, sign int) eval.Value {
+	if len(args) == 1 {
+		if _, ok := args[0].(eval.StringValue); !ok {
+			if iter, ok := args[0].(eval.IterableValue); ok {
+				args = iter.Iterator().AsArray().AppendTo(nil)
+			}
+		}
+	}
+	if len(args) == 0 {
+		return eval.UNDEF
+	}
+	cmp := valueComparator(c, block, false)
+	result := args[0]
+	for _, v := range args[1:] {
+		if cmp(v, result)*sign > 0 {
+			result = v
+		}
+	}
+	return result
+}
+
+func init() {
+	eval.NewGoFunction(`min`,
+		func(d eval.Dispatch) {
+			d.RequiredRepeatedParam(`Any`)
+			d.OptionalBlock(`Callable[2,2]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return extreme(c, args, block, -1)
+			})
+		},
+	)
+}
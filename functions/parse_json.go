@@ -0,0 +1,24 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`parse_json`,
+		func(d eval.Dispatch) {
+			d.Param(`String`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return serialization.UnmarshalJson([]byte(args[0].String()))
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Binary`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return serialization.UnmarshalJson(args[0].(*types.BinaryValue).Bytes())
+			})
+		})
+}
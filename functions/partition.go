@@ -0,0 +1,41 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// partition returns a tuple with an array of the elements for which the predicate is true and an
+// array of the remaining elements
+func partition(iter eval.IterableValue, predicate func(index, value, element eval.Value) bool) eval.Value {
+	accepted := make([]eval.Value, 0)
+	rejected := make([]eval.Value, 0)
+	eachEntry(iter, func(index, value, element eval.Value) {
+		if predicate(index, value, element) {
+			accepted = append(accepted, element)
+		} else {
+			rejected = append(rejected, element)
+		}
+	})
+	return types.WrapValues([]eval.Value{types.WrapValues(accepted), types.WrapValues(rejected)})
+}
+
+func init() {
+	eval.NewGoFunction(`partition`,
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return partition(args[0].(eval.IterableValue), entryPredicate(c, block, false))
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Block(`Callable[2,2]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return partition(args[0].(eval.IterableValue), entryPredicate(c, block, true))
+			})
+		},
+	)
+}
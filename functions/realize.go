@@ -0,0 +1,25 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`realize`,
+		func(d eval.Dispatch) {
+			d.RepeatedParam(`Variant[Type[Resource], Array[Type[Resource]]]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				cat := eval.CatalogOf(c)
+				for i, arg := range types.WrapValues(args).Flatten().AppendTo(nil) {
+					ref := arg.(eval.ResourceReference)
+					if ref.TypeName() == `` || ref.Title() == `` {
+						panic(types.NewIllegalArgumentType2(`realize`, i, `Type[Resource] with a type name and a title`, arg))
+					}
+					cat.Realize(ref)
+				}
+				return eval.UNDEF
+			})
+		},
+	)
+}
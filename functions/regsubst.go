@@ -0,0 +1,163 @@
+package functions
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/regex"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// compileWithFlags compiles the pattern using the selected engine and the options denoted by the
+// regsubst flags E (extended), I (ignore case), and M (multiline, i.e. dot matches newline)
+func compileWithFlags(pattern, flags string) regex.Regexp {
+	engine := types.RegexpEngine()
+	opts := ``
+	for _, f := range flags {
+		switch f {
+		case 'E':
+			if engine != regex.RubyEngine {
+				panic(eval.Error(eval.EVAL_INVALID_REGEXP, issue.H{`pattern`: pattern, `detail`: `the E flag requires the ruby regexp engine`}))
+			}
+			opts += `x`
+		case 'I':
+			opts += `i`
+		case 'M':
+			if engine == regex.RubyEngine {
+				opts += `m`
+			} else {
+				opts += `s`
+			}
+		}
+	}
+	if opts != `` {
+		pattern = `(?` + opts + `)` + pattern
+	}
+	rx, err := types.CompileRegexp(pattern)
+	if err != nil {
+		panic(eval.Error(eval.EVAL_INVALID_REGEXP, issue.H{`pattern`: pattern, `detail`: err.Error()}))
+	}
+	return rx
+}
+
+// expandReplacement writes the replacement to the buffer after expanding the references \0 through
+// \9, \&, \`, \', \k<name>, and \\ using the given match
+func expandReplacement(b *bytes.Buffer, rx regex.Regexp, replacement, s string, m []int) {
+	group := func(i int) {
+		if 2*i+1 < len(m) && m[2*i] >= 0 {
+			b.WriteString(s[m[2*i]:m[2*i+1]])
+		}
+	}
+	for i := 0; i < len(replacement); i++ {
+		c := replacement[i]
+		if c != '\\' || i+1 == len(replacement) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		c = replacement[i]
+		switch {
+		case '0' <= c && c <= '9':
+			group(int(c - '0'))
+		case c == '&':
+			group(0)
+		case c == '`':
+			b.WriteString(s[:m[0]])
+		case c == '\'':
+			b.WriteString(s[m[1]:])
+		case c == '\\':
+			b.WriteByte('\\')
+		case c == 'k' && i+1 < len(replacement) && replacement[i+1] == '<' && strings.IndexByte(replacement[i+1:], '>') > 0:
+			end := i + 1 + strings.IndexByte(replacement[i+1:], '>')
+			name := replacement[i+2 : end]
+			for gi, n := range rx.SubexpNames() {
+				if n == name && gi > 0 {
+					group(gi)
+					break
+				}
+			}
+			i = end
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		}
+	}
+}
+
+// regsubst replaces the first match of the pattern in the target, or all matches when global is
+// true
+func regsubst(target string, rx regex.Regexp, replacement string, global bool) string {
+	n := 1
+	if global {
+		n = -1
+	}
+	matches := rx.FindAllStringSubmatchIndex(target, n)
+	if len(matches) == 0 {
+		return target
+	}
+	b := bytes.NewBufferString(``)
+	pos := 0
+	for _, m := range matches {
+		b.WriteString(target[pos:m[0]])
+		expandReplacement(b, rx, replacement, target, m)
+		pos = m[1]
+	}
+	b.WriteString(target[pos:])
+	return b.String()
+}
+
+// regsubstTarget applies the substitution to a String or to each String in an Array. A Sensitive
+// target yields a Sensitive result.
+func regsubstTarget(target eval.Value, rx regex.Regexp, replacement string, global bool) eval.Value {
+	switch target.(type) {
+	case *types.SensitiveValue:
+		return types.WrapSensitive(regsubstTarget(target.(*types.SensitiveValue).Unwrap(), rx, replacement, global))
+	case eval.StringValue:
+		return types.WrapString(regsubst(target.String(), rx, replacement, global))
+	default:
+		return target.(eval.List).Map(func(e eval.Value) eval.Value { return regsubstTarget(e, rx, replacement, global) })
+	}
+}
+
+func optionalFlags(args []eval.Value, idx int) string {
+	if len(args) > idx && args[idx] != eval.UNDEF {
+		return args[idx].String()
+	}
+	return ``
+}
+
+func init() {
+	eval.NewGoFunction(`regsubst`,
+		func(d eval.Dispatch) {
+			d.Param(`Variant[Array[Variant[String, Sensitive[String]]], Sensitive[Array[Variant[String, Sensitive[String]]]], Variant[String, Sensitive[String]]]`)
+			d.Param(`String`)
+			d.Param(`String`)
+			d.OptionalParam(`Optional[Pattern[/^[GEIM]*$/]]`)
+			d.OptionalParam(`Enum['N', 'E', 'S', 'U']`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				// The encoding argument is accepted for compatibility. All strings are UTF-8.
+				flags := optionalFlags(args, 3)
+				rx := compileWithFlags(args[1].String(), flags)
+				return regsubstTarget(args[0], rx, args[2].String(), strings.ContainsRune(flags, 'G'))
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Variant[Array[Variant[String, Sensitive[String]]], Sensitive[Array[Variant[String, Sensitive[String]]]], Variant[String, Sensitive[String]]]`)
+			d.Param(`Variant[Regexp, Type[Regexp]]`)
+			d.Param(`String`)
+			d.OptionalParam(`Optional[Pattern[/^G?$/]]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				var rx regex.Regexp
+				if rt, ok := args[1].(*types.RegexpType); ok {
+					rx = rt.Matcher()
+				} else {
+					rx = args[1].(*types.RegexpValue).Matcher()
+				}
+				return regsubstTarget(args[0], rx, args[2].String(), optionalFlags(args, 3) == `G`)
+			})
+		},
+	)
+}
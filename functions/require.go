@@ -0,0 +1,21 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+func init() {
+	eval.NewGoFunction(`require`,
+		func(d eval.Dispatch) {
+			d.RepeatedParam(classNamesParam)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				container := eval.ContainerOf(c)
+				return declareClasses(c, `require`, args, func(ref eval.ResourceReference) {
+					if container != nil {
+						eval.CatalogOf(c).AddEdge(ref, container, false)
+					}
+				})
+			})
+		},
+	)
+}
@@ -0,0 +1,46 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// reverseIterator returns an iterator that produces the elements of the given iterable in reverse
+// order. The elements of an Array are not copied. The elements of other iterables are produced
+// when the first element is requested.
+func reverseIterator(iter eval.IterableValue) eval.Iterator {
+	base := iter.Iterator()
+	var elements eval.List
+	idx := 0
+	return types.NewIterator(base.ElementType(), func() (eval.Value, bool) {
+		if elements == nil {
+			elements = base.AsArray()
+			idx = elements.Len()
+		}
+		if idx == 0 {
+			return nil, false
+		}
+		idx--
+		return elements.At(idx), true
+	})
+}
+
+func init() {
+	eval.NewGoFunction(`reverse_each`,
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return types.WrapIterator(reverseIterator(args[0].(eval.IterableValue)))
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				reverseIterator(args[0].(eval.IterableValue)).Each(func(v eval.Value) { block.Call(c, nil, v) })
+				return args[0]
+			})
+		},
+	)
+}
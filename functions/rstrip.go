@@ -0,0 +1,13 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+func init() {
+	eval.NewGoFunction(`rstrip`, stringConverter(func(s string) string {
+		return strings.TrimRightFunc(s, isStripSpace)
+	})...)
+}
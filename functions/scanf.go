@@ -0,0 +1,284 @@
+package functions
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+type scanner struct {
+	input string
+	pos   int
+}
+
+func (s *scanner) skipSpace() {
+	s.pos += len(s.input[s.pos:]) - len(strings.TrimLeftFunc(s.input[s.pos:], unicode.IsSpace))
+}
+
+// take returns the longest prefix of the remaining input, at most width runes long unless width is
+// zero, whose runes are accepted by the given function. The function is called with the index of
+// the rune in the prefix.
+func (s *scanner) take(width int, accept func(i int, r rune) bool) string {
+	start := s.pos
+	for i := 0; (width == 0 || i < width) && s.pos < len(s.input); i++ {
+		r, w := utf8.DecodeRuneInString(s.input[s.pos:])
+		if !accept(i, r) {
+			break
+		}
+		s.pos += w
+	}
+	return s.input[start:s.pos]
+}
+
+// takeInteger returns a run of digits that are valid in the given base, optionally preceded by a sign
+// and the given prefix. The sign and the prefix count towards the width. Nothing is returned when the
+// sign uses up the width, and the prefix is only taken when it leaves room for a digit.
+func (s *scanner) takeInteger(width int, base int, prefix string) string {
+	start := s.pos
+	if s.pos < len(s.input) && (s.input[s.pos] == '+' || s.input[s.pos] == '-') {
+		if width == 1 {
+			return ``
+		}
+		s.pos++
+		if width > 0 {
+			width--
+		}
+	}
+	if prefix != `` && (width == 0 || width > len(prefix)) && len(s.input)-s.pos > len(prefix) && strings.EqualFold(s.input[s.pos:s.pos+len(prefix)], prefix) {
+		s.pos += len(prefix)
+		if width > 0 {
+			width -= len(prefix)
+		}
+	}
+	if s.take(width, func(_ int, r rune) bool { return digitValue(r) < base }) == `` {
+		s.pos = start
+	}
+	return s.input[start:s.pos]
+}
+
+// takeFloat returns a decimal floating point number with an optional sign, fraction, and exponent
+func (s *scanner) takeFloat(width int) string {
+	start := s.pos
+	state := 0
+	s.take(width, func(i int, r rune) bool {
+		switch {
+		case (r == '+' || r == '-') && (i == 0 || state == 3):
+			state++
+		case r >= '0' && r <= '9':
+			if state == 0 || state == 3 {
+				state++
+			}
+		case r == '.' && state < 2:
+			state = 2
+		case (r == 'e' || r == 'E') && state < 3:
+			state = 3
+		default:
+			return false
+		}
+		return true
+	})
+	if _, err := strconv.ParseFloat(s.input[start:s.pos], 64); err != nil {
+		s.pos = start
+	}
+	return s.input[start:s.pos]
+}
+
+func digitValue(r rune) int {
+	switch {
+	case '0' <= r && r <= '9':
+		return int(r - '0')
+	case 'a' <= r && r <= 'f':
+		return int(r-'a') + 10
+	case 'A' <= r && r <= 'F':
+		return int(r-'A') + 10
+	}
+	return 36
+}
+
+// parseInteger parses a string produced by takeInteger. The base is determined by the prefix when
+// base is zero
+func parseInteger(str string, base int) (eval.Value, bool) {
+	sign := ``
+	if str[0] == '+' || str[0] == '-' {
+		sign, str = str[:1], str[1:]
+	}
+	if base == 0 {
+		lc := strings.ToLower(str)
+		switch {
+		case strings.HasPrefix(lc, `0x`):
+			base, str = 16, str[2:]
+		case strings.HasPrefix(lc, `0b`):
+			base, str = 2, str[2:]
+		case strings.HasPrefix(lc, `0`) && len(str) > 1:
+			base, str = 8, str[1:]
+		default:
+			base = 10
+		}
+	} else if base == 16 && strings.HasPrefix(strings.ToLower(str), `0x`) || base == 2 && strings.HasPrefix(strings.ToLower(str), `0b`) {
+		str = str[2:]
+	}
+	i, err := strconv.ParseInt(sign+str, base, 64)
+	if err != nil {
+		return nil, false
+	}
+	return types.WrapInteger(i), true
+}
+
+// charSetTest returns a test for the runes that are accepted by a scan set such as [a-z_] or [^,]
+func charSetTest(set string) func(int, rune) bool {
+	negate := strings.HasPrefix(set, `^`)
+	if negate {
+		set = set[1:]
+	}
+	runes := []rune(set)
+	return func(_ int, r rune) bool {
+		for i := 0; i < len(runes); i++ {
+			if i+2 < len(runes) && runes[i+1] == '-' {
+				if runes[i] <= r && r <= runes[i+2] {
+					return !negate
+				}
+				i += 2
+			} else if runes[i] == r {
+				return !negate
+			}
+		}
+		return negate
+	}
+}
+
+// scanf scans the data according to the format in the same way as Ruby's String#scanf and returns
+// the converted values. The scan stops at the first directive that cannot be satisfied.
+func scanf(data, format string) []eval.Value {
+	result := make([]eval.Value, 0)
+	s := &scanner{input: data}
+	for i := 0; i < len(format); {
+		c := format[i]
+		if unicode.IsSpace(rune(c)) {
+			for i < len(format) && unicode.IsSpace(rune(format[i])) {
+				i++
+			}
+			s.skipSpace()
+			continue
+		}
+		if c != '%' || i+1 < len(format) && format[i+1] == '%' {
+			if c == '%' {
+				i++
+				s.skipSpace()
+			}
+			if s.pos >= len(data) || data[s.pos] != c {
+				break
+			}
+			s.pos++
+			i++
+			continue
+		}
+
+		i++
+		suppress := i < len(format) && format[i] == '*'
+		if suppress {
+			i++
+		}
+		width := 0
+		for ; i < len(format) && format[i] >= '0' && format[i] <= '9'; i++ {
+			width = width*10 + int(format[i]-'0')
+		}
+		if i >= len(format) {
+			break
+		}
+		conv := format[i]
+		i++
+
+		var set string
+		if conv == '[' {
+			end := i
+			if end < len(format) && format[end] == '^' {
+				end++
+			}
+			if end < len(format) && format[end] == ']' {
+				end++
+			}
+			end += strings.IndexByte(format[end:], ']')
+			if end < i {
+				break
+			}
+			set = format[i:end]
+			i = end + 1
+		}
+		if conv != 'c' && conv != '[' {
+			s.skipSpace()
+		}
+
+		var value eval.Value
+		ok := false
+		switch conv {
+		case 'd', 'u':
+			if str := s.takeInteger(width, 10, ``); str != `` {
+				value, ok = parseInteger(str, 10)
+			}
+		case 'i':
+			if str := s.takeInteger(width, 16, `0x`); str != `` {
+				value, ok = parseInteger(str, 0)
+			}
+		case 'x', 'X':
+			if str := s.takeInteger(width, 16, `0x`); str != `` {
+				value, ok = parseInteger(str, 16)
+			}
+		case 'o':
+			if str := s.takeInteger(width, 8, ``); str != `` {
+				value, ok = parseInteger(str, 8)
+			}
+		case 'b':
+			if str := s.takeInteger(width, 2, `0b`); str != `` {
+				value, ok = parseInteger(str, 2)
+			}
+		case 'f', 'e', 'E', 'g', 'G', 'a', 'A':
+			if str := s.takeFloat(width); str != `` {
+				f, _ := strconv.ParseFloat(str, 64)
+				value, ok = types.WrapFloat(f), true
+			}
+		case 's':
+			if str := s.take(width, func(_ int, r rune) bool { return !unicode.IsSpace(r) }); str != `` {
+				value, ok = types.WrapString(str), true
+			}
+		case 'c':
+			if width == 0 {
+				width = 1
+			}
+			if str := s.take(width, func(int, rune) bool { return true }); str != `` {
+				value, ok = types.WrapString(str), true
+			}
+		case '[':
+			if str := s.take(width, charSetTest(set)); str != `` {
+				value, ok = types.WrapString(str), true
+			}
+		}
+		if !ok {
+			break
+		}
+		if !suppress {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func init() {
+	eval.NewGoFunction(`scanf`,
+		func(d eval.Dispatch) {
+			d.Param(`String`)
+			d.Param(`String`)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				result := types.WrapValues(scanf(args[0].String(), args[1].String()))
+				if block != nil {
+					return block.Call(c, nil, result)
+				}
+				return result
+			})
+		},
+	)
+}
@@ -0,0 +1,66 @@
+package functions
+
+import (
+	"bytes"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// serializationOptionsType is the type of the options accepted by the functions that emit
+// JSON or YAML
+const serializationOptionsType = `Struct[{
+	Optional[rich_data] => Boolean,
+	Optional[symbol_as_string] => Boolean
+}]`
+
+// serializerOptions returns the options of a strict Serializer for the function with the given
+// name. Values that are not Data are rejected unless rich_data is enabled in the given options,
+// and no local references are produced since the result is intended for external consumers.
+func serializerOptions(name string, options eval.OrderedMap) eval.OrderedMap {
+	var richData, symbolAsString eval.Value = types.BooleanFalse, types.BooleanFalse
+	if options != nil {
+		richData = options.Get5(`rich_data`, richData)
+		symbolAsString = options.Get5(`symbol_as_string`, symbolAsString)
+	}
+	return types.WrapHash([]*types.HashEntry{
+		types.WrapHashEntry2(`rich_data`, richData),
+		types.WrapHashEntry2(`symbol_as_string`, symbolAsString),
+		types.WrapHashEntry2(`message_prefix`, types.WrapString(name)),
+		types.WrapHashEntry2(`local_reference`, types.BooleanFalse),
+		types.WrapHashEntry2(`strict`, types.BooleanTrue),
+	})
+}
+
+// optionsArg returns the options hash found at the given index of args, or nil when it is
+// absent or undef.
+func optionsArg(args []eval.Value, index int) eval.OrderedMap {
+	if len(args) > index {
+		if options, ok := args[index].(eval.OrderedMap); ok {
+			return options
+		}
+	}
+	return nil
+}
+
+// toJson serializes the given value into compact JSON
+func toJson(c eval.Context, name string, value eval.Value, options eval.OrderedMap) []byte {
+	b := bytes.NewBufferString(``)
+	serialization.NewSerializer(c, serializerOptions(name, options)).Convert(value, serialization.NewJsonStreamer(b))
+	return b.Bytes()
+}
+
+// skipUndef returns the given value with all undef elements of arrays and undef values of hashes
+// recursively removed.
+func skipUndef(v eval.Value) eval.Value {
+	isUndef := func(e eval.Value) bool { return e == eval.UNDEF }
+	switch v.(type) {
+	case *types.HashValue:
+		return v.(*types.HashValue).RejectPairs(func(k, e eval.Value) bool { return isUndef(e) }).MapValues(skipUndef)
+	case *types.ArrayValue:
+		return v.(*types.ArrayValue).Reject(isUndef).Map(skipUndef)
+	default:
+		return v
+	}
+}
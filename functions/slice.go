@@ -0,0 +1,59 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// sliceEach calls the consumer with each consecutive slice of n elements. The last slice is shorter
+// when the number of elements is not a multiple of n.
+func sliceEach(iter eval.IterableValue, n int64, consumer eval.SliceConsumer) {
+	iter.Iterator().AsArray().EachSlice(int(n), consumer)
+}
+
+func init() {
+	eval.NewGoFunction(`slice`,
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Param(`Integer[1]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				slices := make([]eval.Value, 0)
+				sliceEach(args[0].(eval.IterableValue), args[1].(eval.IntegerValue).Int(), func(slice eval.List) {
+					slices = append(slices, slice)
+				})
+				return types.WrapValues(slices)
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Param(`Integer[1]`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				sliceEach(args[0].(eval.IterableValue), args[1].(eval.IntegerValue).Int(), func(slice eval.List) {
+					block.Call(c, nil, slice)
+				})
+				return args[0]
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Param(`Integer[1]`)
+			d.Block(`Callable[2,default]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				// The elements of each slice are passed as separate arguments. The last slice is padded
+				// with undef
+				n := int(args[1].(eval.IntegerValue).Int())
+				sliceEach(args[0].(eval.IterableValue), int64(n), func(slice eval.List) {
+					blockArgs := slice.AppendTo(make([]eval.Value, 0, n))
+					for len(blockArgs) < n {
+						blockArgs = append(blockArgs, eval.UNDEF)
+					}
+					block.Call(c, nil, blockArgs...)
+				})
+				return args[0]
+			})
+		},
+	)
+}
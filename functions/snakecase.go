@@ -0,0 +1,37 @@
+package functions
+
+import (
+	"bytes"
+	"unicode"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+// snakecase converts the given string to lower case and separates its words with underscores, e.g.
+// 'HelloBigWorld' becomes 'hello_big_world' and 'HTTPServer' becomes 'http_server'. Hyphens and
+// spaces are replaced by underscores.
+func snakecase(s string) string {
+	runes := []rune(s)
+	b := bytes.NewBufferString(``)
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == ' ':
+			b.WriteByte('_')
+		case unicode.IsUpper(r):
+			if i > 0 {
+				prev := runes[i-1]
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+					b.WriteByte('_')
+				}
+			}
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func init() {
+	eval.NewGoFunction(`snakecase`, stringConverter(snakecase)...)
+}
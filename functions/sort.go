@@ -0,0 +1,37 @@
+package functions
+
+import (
+	"bytes"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func sortValues(c eval.Context, values eval.List, block eval.Lambda) eval.List {
+	cmp := valueComparator(c, block, true)
+	return types.WrapValues(values.AppendTo(nil)).Sort(func(a, b eval.Value) bool { return cmp(a, b) < 0 })
+}
+
+func init() {
+	eval.NewGoFunction(`sort`,
+		func(d eval.Dispatch) {
+			d.Param(`String`)
+			d.OptionalBlock(`Callable[2,2]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				b := bytes.NewBufferString(``)
+				sortValues(c, args[0].(eval.StringValue).Iterator().AsArray(), block).Each(func(v eval.Value) {
+					b.WriteString(v.String())
+				})
+				return types.WrapString(b.String())
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.OptionalBlock(`Callable[2,2]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return sortValues(c, args[0].(eval.IterableValue).Iterator().AsArray(), block)
+			})
+		},
+	)
+}
@@ -11,8 +11,7 @@ func init() {
 			d.Param(`String`)
 			d.Param(`String`)
 			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
-				types.WrapRegexp(args[1].String()).Regexp()
-				return args[0].(eval.StringValue).Split(types.WrapRegexp(args[1].String()).Regexp())
+				return args[0].(eval.StringValue).Split(types.WrapRegexp(args[1].String()).Matcher())
 			})
 		},
 
@@ -20,7 +19,7 @@ func init() {
 			d.Param(`String`)
 			d.Param(`Regexp`)
 			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
-				return args[0].(eval.StringValue).Split(args[1].(*types.RegexpValue).Regexp())
+				return args[0].(eval.StringValue).Split(args[1].(*types.RegexpValue).Matcher())
 			})
 		},
 
@@ -28,7 +27,7 @@ func init() {
 			d.Param(`String`)
 			d.Param(`Type[Regexp]`)
 			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
-				return args[0].(eval.StringValue).Split(args[1].(*types.RegexpType).Regexp())
+				return args[0].(eval.StringValue).Split(args[1].(*types.RegexpType).Matcher())
 			})
 		},
 	)
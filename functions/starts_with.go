@@ -0,0 +1,30 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// anyAffix returns true if the test returns true for at least one of the given affixes. The affixes
+// are either a String or an Array of strings.
+func anyAffix(affixes eval.Value, test func(affix string) bool) bool {
+	if _, ok := affixes.(eval.StringValue); ok {
+		return test(affixes.String())
+	}
+	return affixes.(eval.List).Any(func(v eval.Value) bool { return test(v.String()) })
+}
+
+func init() {
+	eval.NewGoFunction(`starts_with`,
+		func(d eval.Dispatch) {
+			d.Param(`String`)
+			d.Param(`Variant[String, Array[String]]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				s := args[0].String()
+				return types.WrapBoolean(anyAffix(args[1], func(prefix string) bool { return strings.HasPrefix(s, prefix) }))
+			})
+		},
+	)
+}
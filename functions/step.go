@@ -0,0 +1,47 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// stepIterator returns an iterator that lazily produces the first element of the given iterable
+// and then every step'th element after that
+func stepIterator(iter eval.IterableValue, step int64) eval.Iterator {
+	base := iter.Iterator()
+	first := true
+	return types.NewIterator(base.ElementType(), func() (eval.Value, bool) {
+		if first {
+			first = false
+		} else {
+			for i := int64(1); i < step; i++ {
+				if _, ok := base.Next(); !ok {
+					return nil, false
+				}
+			}
+		}
+		return base.Next()
+	})
+}
+
+func init() {
+	eval.NewGoFunction(`step`,
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Param(`Integer[1]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return types.WrapIterator(stepIterator(args[0].(eval.IterableValue), args[1].(eval.IntegerValue).Int()))
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Param(`Integer[1]`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				stepIterator(args[0].(eval.IterableValue), args[1].(eval.IntegerValue).Int()).Each(func(v eval.Value) { block.Call(c, nil, v) })
+				return args[0]
+			})
+		},
+	)
+}
@@ -0,0 +1,43 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// mapStrings applies the conversion to the given value when it is a String, and recursively to the
+// elements of an Array and the keys and values of a Hash. Other values are returned unchanged.
+func mapStrings(v eval.Value, convert func(string) string) eval.Value {
+	switch v.(type) {
+	case eval.StringValue:
+		return types.WrapString(convert(v.String()))
+	case eval.OrderedMap:
+		return v.(eval.OrderedMap).MapEntries(func(e eval.MapEntry) eval.MapEntry {
+			return types.WrapHashEntry(mapStrings(e.Key(), convert), mapStrings(e.Value(), convert))
+		})
+	case eval.List:
+		return v.(eval.List).Map(func(e eval.Value) eval.Value { return mapStrings(e, convert) })
+	default:
+		return v
+	}
+}
+
+// stringConverter returns the dispatchers of a function that applies the conversion to a String, or
+// to all strings in an Array or Hash. A Numeric argument is returned unchanged.
+func stringConverter(convert func(string) string) []eval.DispatchCreator {
+	return []eval.DispatchCreator{
+		func(d eval.Dispatch) {
+			d.Param(`Numeric`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return args[0]
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Variant[String, Array, Hash]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return mapStrings(args[0], convert)
+			})
+		},
+	}
+}
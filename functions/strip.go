@@ -0,0 +1,22 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+// isStripSpace returns true for the characters that Ruby's strip removes
+func isStripSpace(r rune) bool {
+	switch r {
+	case 0, ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+func init() {
+	eval.NewGoFunction(`strip`, stringConverter(func(s string) string {
+		return strings.TrimFunc(s, isStripSpace)
+	})...)
+}
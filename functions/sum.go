@@ -0,0 +1,41 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// sum returns the sum of the numbers produced by the iterable. The sum is an Integer unless at
+// least one of the numbers is a Float.
+func sum(iter eval.IterableValue) eval.Value {
+	isum := int64(0)
+	fsum := float64(0)
+	isFloat := false
+	iter.Iterator().Each(func(v eval.Value) {
+		eval.AssertInstance(`sum element`, types.DefaultNumericType(), v)
+		if iv, ok := v.(eval.IntegerValue); ok && !isFloat {
+			isum += iv.Int()
+			return
+		}
+		if !isFloat {
+			isFloat = true
+			fsum = float64(isum)
+		}
+		fsum += v.(eval.NumericValue).Float()
+	})
+	if isFloat {
+		return types.WrapFloat(fsum)
+	}
+	return types.WrapInteger(isum)
+}
+
+func init() {
+	eval.NewGoFunction(`sum`,
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return sum(args[0].(eval.IterableValue))
+			})
+		},
+	)
+}
@@ -0,0 +1,34 @@
+package functions
+
+import (
+	"bytes"
+	"path/filepath"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`template`,
+		func(d eval.Dispatch) {
+			d.RequiredRepeatedParam(`String`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				// The results of all templates are concatenated. Only EPP templates can be evaluated so
+				// the ERB templates of Puppet are rejected.
+				b := bytes.NewBufferString(``)
+				for _, arg := range args {
+					name := arg.String()
+					path, ok := moduleFilePath(`templates`, name)
+					if !ok {
+						panic(eval.Error(eval.EVAL_FILE_NOT_FOUND, issue.H{`path`: name}))
+					}
+					if filepath.Ext(path) != `.epp` {
+						panic(eval.Error(eval.EVAL_ERB_NOT_SUPPORTED, issue.H{`path`: name}))
+					}
+					b.WriteString(eval.EvaluateEpp(c, path, string(types.BinaryFromFile(c, path).Bytes()), eval.EMPTY_MAP))
+				}
+				return types.WrapString(b.String())
+			})
+		})
+}
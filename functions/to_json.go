@@ -0,0 +1,17 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`to_json`,
+		func(d eval.Dispatch) {
+			d.Param(`Any`)
+			d.OptionalParam(serializationOptionsType)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return types.WrapString(string(toJson(c, `to_json`, args[0], optionsArg(args, 1))))
+			})
+		})
+}
@@ -0,0 +1,44 @@
+package functions
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+const prettyJsonOptionsType = `Struct[{
+	Optional[rich_data] => Boolean,
+	Optional[symbol_as_string] => Boolean,
+	Optional[indent] => String
+}]`
+
+func init() {
+	eval.NewGoFunction(`to_json_pretty`,
+		func(d eval.Dispatch) {
+			d.Param(`Any`)
+			d.OptionalParam(`Optional[Boolean]`)
+			d.OptionalParam(prettyJsonOptionsType)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				value := args[0]
+				if len(args) > 1 {
+					if skip, ok := args[1].(eval.BooleanValue); ok && skip.Bool() {
+						value = skipUndef(value)
+					}
+				}
+				options := optionsArg(args, 2)
+				indent := `  `
+				if options != nil {
+					indent = options.Get5(`indent`, types.WrapString(indent)).String()
+				}
+				b := bytes.NewBufferString(``)
+				if err := json.Indent(b, toJson(c, `to_json_pretty`, value, options), ``, indent); err != nil {
+					panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: err.Error()}))
+				}
+				b.WriteByte('\n')
+				return types.WrapString(b.String())
+			})
+		})
+}
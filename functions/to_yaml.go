@@ -0,0 +1,18 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-evaluator/yaml"
+)
+
+func init() {
+	eval.NewGoFunction(`to_yaml`,
+		func(d eval.Dispatch) {
+			d.Param(`Any`)
+			d.OptionalParam(serializationOptionsType)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return types.WrapString(string(yaml.Marshal(c, args[0], serializerOptions(`to_yaml`, optionsArg(args, 1)))))
+			})
+		})
+}
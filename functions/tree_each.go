@@ -0,0 +1,155 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+type (
+	treeOptions struct {
+		breadthFirst      bool
+		includeContainers bool
+		includeValues     bool
+		includeRoot       bool
+		containerType     eval.Type
+	}
+
+	treeNode struct {
+		path  []eval.Value
+		value eval.Value
+	}
+)
+
+const treeOptionsType = `Struct[{
+	Optional[order] => Enum[depth_first, breadth_first],
+	Optional[include_containers] => Boolean,
+	Optional[include_values] => Boolean,
+	Optional[include_root] => Boolean,
+	Optional[container_type] => Type
+}]`
+
+func treeOptionsArg(args []eval.Value) eval.OrderedMap {
+	if len(args) > 1 {
+		return args[1].(eval.OrderedMap)
+	}
+	return nil
+}
+
+func newTreeOptions(options eval.OrderedMap) *treeOptions {
+	opts := &treeOptions{includeContainers: true, includeValues: true, includeRoot: true}
+	if options == nil {
+		return opts
+	}
+	opts.breadthFirst = options.Get5(`order`, types.WrapString(`depth_first`)).String() == `breadth_first`
+	opts.includeContainers = options.Get5(`include_containers`, types.BooleanTrue).(eval.BooleanValue).Bool()
+	opts.includeValues = options.Get5(`include_values`, types.BooleanTrue).(eval.BooleanValue).Bool()
+	opts.includeRoot = options.Get5(`include_root`, types.BooleanTrue).(eval.BooleanValue).Bool()
+	if ct, ok := options.Get4(`container_type`); ok {
+		opts.containerType = ct.(eval.Type)
+	}
+	return opts
+}
+
+// children returns the children of the given node, or nil when the node is not a container
+func (o *treeOptions) children(n *treeNode) []*treeNode {
+	if o.containerType != nil && !eval.IsInstance(o.containerType, n.value) {
+		return nil
+	}
+	var children []*treeNode
+	childPath := func(key eval.Value) []eval.Value {
+		path := make([]eval.Value, len(n.path), len(n.path)+1)
+		copy(path, n.path)
+		return append(path, key)
+	}
+	switch n.value.(type) {
+	case eval.StringValue, eval.Type:
+		// Strings are not containers even though they are lists, and types are not containers even
+		// when they are objects
+	case eval.OrderedMap:
+		children = make([]*treeNode, 0, n.value.(eval.OrderedMap).Len())
+		n.value.(eval.OrderedMap).EachPair(func(k, v eval.Value) {
+			children = append(children, &treeNode{childPath(k), v})
+		})
+	case eval.List:
+		children = make([]*treeNode, 0, n.value.(eval.List).Len())
+		n.value.(eval.List).EachWithIndex(func(v eval.Value, i int) {
+			children = append(children, &treeNode{childPath(types.WrapInteger(int64(i))), v})
+		})
+	case eval.PuppetObject:
+		children = make([]*treeNode, 0)
+		n.value.(eval.PuppetObject).InitHash().EachPair(func(k, v eval.Value) {
+			children = append(children, &treeNode{childPath(k), v})
+		})
+	}
+	return children
+}
+
+// treeIterator returns an iterator that lazily produces a [path, value] tuple for each node of the
+// given tree that is selected by the options
+func treeIterator(tree eval.Value, opts *treeOptions) eval.Iterator {
+	if eval.IsInstance(types.DefaultIteratorType(), tree) {
+		tree = tree.(eval.IteratorValue).AsArray()
+	}
+	pending := []*treeNode{{[]eval.Value{}, tree}}
+	return types.NewIterator(types.DefaultArrayType(), func() (eval.Value, bool) {
+		for len(pending) > 0 {
+			var n *treeNode
+			if opts.breadthFirst {
+				n, pending = pending[0], pending[1:]
+			} else {
+				n, pending = pending[len(pending)-1], pending[:len(pending)-1]
+			}
+			children := opts.children(n)
+			if opts.breadthFirst {
+				pending = append(pending, children...)
+			} else {
+				// Push in reverse so that the first child is produced first
+				for i := len(children) - 1; i >= 0; i-- {
+					pending = append(pending, children[i])
+				}
+			}
+			if len(n.path) == 0 && !opts.includeRoot {
+				continue
+			}
+			if children == nil && opts.includeValues || children != nil && opts.includeContainers {
+				return types.WrapValues([]eval.Value{types.WrapValues(n.path), n.value}), true
+			}
+		}
+		return nil, false
+	})
+}
+
+func init() {
+	eval.NewGoFunction(`tree_each`,
+		func(d eval.Dispatch) {
+			d.Param(`Variant[Iterator, Array, Hash, Object]`)
+			d.OptionalParam(treeOptionsType)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return types.WrapIterator(treeIterator(args[0], newTreeOptions(treeOptionsArg(args))))
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Variant[Iterator, Array, Hash, Object]`)
+			d.OptionalParam(treeOptionsType)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				treeIterator(args[0], newTreeOptions(treeOptionsArg(args))).Each(func(v eval.Value) { block.Call(c, nil, v) })
+				return args[0]
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Variant[Iterator, Array, Hash, Object]`)
+			d.OptionalParam(treeOptionsType)
+			d.Block(`Callable[2,2]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				treeIterator(args[0], newTreeOptions(treeOptionsArg(args))).Each(func(v eval.Value) {
+					pv := v.(eval.List)
+					block.Call(c, nil, pv.At(0), pv.At(1))
+				})
+				return args[0]
+			})
+		},
+	)
+}
@@ -0,0 +1,102 @@
+package functions
+
+import (
+	"bytes"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// uniqueValues returns the values that are unique. The uniqueness is determined by the value
+// returned from the block when a block is given.
+func uniqueValues(c eval.Context, values eval.List, block eval.Lambda) eval.List {
+	if block == nil {
+		return values.Unique()
+	}
+	result := make([]eval.Value, 0, values.Len())
+	exists := make(map[eval.HashKey]bool, values.Len())
+	values.Each(func(v eval.Value) {
+		key := eval.ToKey(block.Call(c, nil, v))
+		if !exists[key] {
+			exists[key] = true
+			result = append(result, v)
+		}
+	})
+	return types.WrapValues(result)
+}
+
+// uniqueHash returns a hash where the keys of the given hash that have equal values are collected
+// into one array key that is mapped to the value.
+func uniqueHash(c eval.Context, hash eval.OrderedMap, block eval.Lambda) eval.Value {
+	keys := make([][]eval.Value, 0, hash.Len())
+	values := make([]eval.Value, 0, hash.Len())
+	index := make(map[eval.HashKey]int, hash.Len())
+	hash.EachPair(func(k, v eval.Value) {
+		kv := v
+		if block != nil {
+			kv = block.Call(c, nil, v)
+		}
+		key := eval.ToKey(kv)
+		if i, ok := index[key]; ok {
+			keys[i] = append(keys[i], k)
+			return
+		}
+		index[key] = len(keys)
+		keys = append(keys, []eval.Value{k})
+		values = append(values, v)
+	})
+	entries := make([]*types.HashEntry, len(keys))
+	for i, k := range keys {
+		entries[i] = types.WrapHashEntry(types.WrapValues(k), values[i])
+	}
+	return types.WrapHash(entries)
+}
+
+// uniqueString returns a string with the unique characters of the given string
+func uniqueString(c eval.Context, str string, block eval.Lambda) eval.Value {
+	chars := make([]eval.Value, 0, len(str))
+	for _, r := range str {
+		chars = append(chars, types.WrapString(string(r)))
+	}
+	b := bytes.NewBufferString(``)
+	uniqueValues(c, types.WrapValues(chars), block).Each(func(v eval.Value) {
+		b.WriteString(v.String())
+	})
+	return types.WrapString(b.String())
+}
+
+func init() {
+	eval.NewGoFunction(`unique`,
+		func(d eval.Dispatch) {
+			d.Param(`String`)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return uniqueString(c, args[0].String(), block)
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Hash`)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return uniqueHash(c, args[0].(eval.OrderedMap), block)
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Array`)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return uniqueValues(c, args[0].(eval.List), block)
+			})
+		},
+
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+				return uniqueValues(c, args[0].(eval.IterableValue).Iterator().AsArray(), block)
+			})
+		},
+	)
+}
@@ -0,0 +1,80 @@
+package functions
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+var versionSegment = regexp.MustCompile(`[-.]|\d+|[^-.\d]+`)
+var trailingZeroes = regexp.MustCompile(`(\.0+)+$`)
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeVersion removes trailing zero segments from the part of the version that precedes the
+// first hyphen
+func normalizeVersion(v string) string {
+	parts := strings.SplitN(v, `-`, 2)
+	parts[0] = trailingZeroes.ReplaceAllString(parts[0], ``)
+	return strings.Join(parts, `-`)
+}
+
+// versioncmp compares two versions in the same way as the versioncmp function of Puppet. It returns
+// -1, 0, or 1 when a is less than, equal to, or greater than b.
+func versioncmp(a, b string, ignoreTrailingZeroes bool) int {
+	if ignoreTrailingZeroes {
+		a = normalizeVersion(a)
+		b = normalizeVersion(b)
+	}
+	as := versionSegment.FindAllString(a, -1)
+	bs := versionSegment.FindAllString(b, -1)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		sa, sb := as[i], bs[i]
+		switch {
+		case sa == sb:
+			continue
+		case sa == `-`:
+			return -1
+		case sb == `-`:
+			return 1
+		case sa == `.`:
+			return -1
+		case sb == `.`:
+			return 1
+		case isDigits(sa) && isDigits(sb) && sa[0] != '0' && sb[0] != '0':
+			// Compare as integers of arbitrary size
+			if len(sa) != len(sb) {
+				if len(sa) < len(sb) {
+					return -1
+				}
+				return 1
+			}
+			return strings.Compare(sa, sb)
+		}
+		return strings.Compare(strings.ToUpper(sa), strings.ToUpper(sb))
+	}
+	return strings.Compare(a, b)
+}
+
+func init() {
+	eval.NewGoFunction(`versioncmp`,
+		func(d eval.Dispatch) {
+			d.Param(`String`)
+			d.Param(`String`)
+			d.OptionalParam(`Boolean`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				ignoreTrailingZeroes := len(args) > 2 && args[2].(eval.BooleanValue).Bool()
+				return types.WrapInteger(int64(versioncmp(args[0].String(), args[1].String(), ignoreTrailingZeroes)))
+			})
+		},
+	)
+}
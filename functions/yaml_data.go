@@ -0,0 +1,19 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-evaluator/yaml"
+)
+
+func init() {
+	eval.NewGoFunction(`yaml_data`,
+		func(d eval.Dispatch) {
+			d.Param(`Hash[String[1], Any]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				path := args[0].(eval.OrderedMap).Get5(`path`, eval.EMPTY_STRING).String()
+				data := yaml.Unmarshal(c, types.BinaryFromFile(c, path).Bytes())
+				return eval.AssertInstance(path, types.DefaultHashType(), data)
+			})
+		})
+}
@@ -0,0 +1,40 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// zip returns an array with one tuple for each element of the first iterable. The tuple contains
+// the elements at the same position in all iterables, or undef where an iterable is shorter.
+func zip(iterables []eval.Value) eval.Value {
+	lists := make([]eval.List, len(iterables))
+	for i, iter := range iterables {
+		lists[i] = iter.(eval.IterableValue).Iterator().AsArray()
+	}
+	result := make([]eval.Value, lists[0].Len())
+	for i := range result {
+		tuple := make([]eval.Value, len(lists))
+		for j, l := range lists {
+			if i < l.Len() {
+				tuple[j] = l.At(i)
+			} else {
+				tuple[j] = eval.UNDEF
+			}
+		}
+		result[i] = types.WrapValues(tuple)
+	}
+	return types.WrapValues(result)
+}
+
+func init() {
+	eval.NewGoFunction(`zip`,
+		func(d eval.Dispatch) {
+			d.Param(`Iterable`)
+			d.RequiredRepeatedParam(`Iterable`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				return zip(args)
+			})
+		},
+	)
+}
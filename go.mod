@@ -6,4 +6,5 @@ require (
 	github.com/lyraproj/puppet-parser v0.0.0-20190220160521-d5f541fd6c57
 	github.com/lyraproj/semver v0.0.0-20181213164306-02ecea2cd6a2
 	gopkg.in/yaml.v2 v2.2.2
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,613 @@
+// Package gogen generates Go source from Puppet Object types and TypeSets. The generated Go types
+// can be given to the eval.Reflector to create Puppet types that are equal to the originals.
+package gogen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"math"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+const (
+	evalPath   = `github.com/lyraproj/puppet-evaluator/eval`
+	typesPath  = `github.com/lyraproj/puppet-evaluator/types`
+	semverPath = `github.com/lyraproj/semver/semver`
+)
+
+// reflectedTypes are the Go types that are used when the Puppet type that they reflect into is
+// equal to the type of an attribute. The first match wins.
+var reflectedTypes = []reflect.Type{
+	reflect.TypeOf(``),
+	reflect.TypeOf(int64(0)),
+	reflect.TypeOf(int32(0)),
+	reflect.TypeOf(int16(0)),
+	reflect.TypeOf(int8(0)),
+	reflect.TypeOf(uint64(0)),
+	reflect.TypeOf(uint32(0)),
+	reflect.TypeOf(uint16(0)),
+	reflect.TypeOf(uint8(0)),
+	reflect.TypeOf(float64(0)),
+	reflect.TypeOf(float32(0)),
+	reflect.TypeOf(false),
+	reflect.TypeOf(time.Time{}),
+	reflect.TypeOf(time.Duration(0)),
+	reflect.TypeOf((*eval.Value)(nil)).Elem(),
+	reflect.TypeOf((*eval.Type)(nil)).Elem(),
+	reflect.TypeOf((*eval.PuppetObject)(nil)).Elem(),
+	reflect.TypeOf((*eval.ObjectType)(nil)).Elem(),
+	reflect.TypeOf((*eval.TypeSet)(nil)).Elem(),
+	reflect.TypeOf(&types.BinaryValue{}),
+	reflect.TypeOf(&types.RegexpValue{}),
+	reflect.TypeOf(&types.SemVerValue{}),
+	reflect.TypeOf(&types.SensitiveValue{}),
+	reflect.TypeOf(&types.UriValue{}),
+}
+
+var (
+	valueType      = reflect.TypeOf((*eval.Value)(nil)).Elem()
+	listType       = reflect.TypeOf((*eval.List)(nil)).Elem()
+	orderedMapType = reflect.TypeOf((*eval.OrderedMap)(nil)).Elem()
+)
+
+// goType is the Go source for a type. It is exact when the Go type reflects into the Puppet type
+// that it was created from and nilable when it is a pointer or an interface.
+type goType struct {
+	src     string
+	exact   bool
+	nilable bool
+}
+
+type field struct {
+	name string
+	typ  string
+	tag  string
+}
+
+type method struct {
+	name      string
+	signature string
+}
+
+// declaration is the Go declaration of an Object type
+type declaration struct {
+	t       eval.ObjectType
+	name    string
+	iface   bool
+	parent  string
+	fields  []field
+	methods []method
+	deps    []string
+}
+
+type generator struct {
+	c       eval.Context
+	typeSet eval.TypeSet
+	names   map[string]string
+	goNames map[string]string
+	queue   []eval.ObjectType
+	decls   map[string]*declaration
+	current *declaration
+	imports map[string]string
+}
+
+// Generate returns the formatted source of a Go file in the given package that declares Go types
+// for the given ObjectType or TypeSet.
+//
+// An Object type becomes a struct with one field per attribute. The parent type becomes an
+// embedded struct in the first field and the functions of the type are declared as the methods
+// of an interface named after the type with the suffix Functions. The methods must be implemented
+// elsewhere with a pointer to the struct as the receiver. The file asserts that they are, since the
+// Reflector finds the functions of a type only among the methods of its Go type. An Object type
+// that is an interface and has no parent becomes a Go interface.
+//
+// A field with a Go type that doesn't reflect into the type of the attribute has a puppet tag that
+// declares the type. Parameter and return types of functions that have no exact Go counterpart
+// are widened since there is no way to declare them.
+//
+// The file also declares two functions. InitTypes creates the types from the Go types using the
+// Reflector of a context and adds them to the loader of that context. RegisterImplementations
+// registers the Go types with the ImplementationRegistry of a context as the implementations of
+// Puppet types with the same names that are already known to that context.
+//
+// The types that an ObjectType depends on are generated too unless they are registered with the
+// ImplementationRegistry of the given context. The types that a TypeSet depends on must be
+// members of the TypeSet or registered.
+//
+// An EVAL_GO_GENERATION_UNSUPPORTED error is raised when a type uses features that cannot be
+// reflected, such as type parameters, equality or serialization declarations, final or overriding
+// members, functions with optional parameters or a block, and TypeSets with references or a
+// pcore_uri, pcore_version, or name_authority that differs from those of this runtime.
+func Generate(c eval.Context, packageName string, t eval.Type) []byte {
+	g := &generator{
+		c:       c,
+		names:   make(map[string]string),
+		goNames: make(map[string]string),
+		decls:   make(map[string]*declaration),
+		imports: map[string]string{`reflect`: `reflect`, evalPath: `eval`}}
+
+	switch t.(type) {
+	case eval.TypeSet:
+		ts := t.(eval.TypeSet)
+		g.typeSet = ts
+		ih := initHash(ts)
+		if !ih.IncludesKey2(`version`) {
+			unsupported(t, `a TypeSet without a version cannot be reflected`)
+		}
+		if ih.IncludesKey2(`references`) {
+			unsupported(t, `the references of a TypeSet cannot be reflected`)
+		}
+		// The Reflector always declares the pcore_uri, pcore_version, and name_authority of this runtime
+		if ts.NameAuthority() != eval.RUNTIME_NAME_AUTHORITY ||
+			ih.Get5(eval.KEY_PCORE_URI, eval.EMPTY_STRING).String() != string(eval.PCORE_URI) ||
+			ih.Get5(eval.KEY_PCORE_VERSION, eval.EMPTY_STRING).String() != eval.PCORE_VERSION.String() {
+			unsupported(t, fmt.Sprintf(`a TypeSet must declare the pcore_uri '%s', the pcore_version '%s', and the name_authority '%s'`,
+				eval.PCORE_URI, eval.PCORE_VERSION, eval.RUNTIME_NAME_AUTHORITY))
+		}
+		ih.Get5(`types`, eval.EMPTY_MAP).(eval.OrderedMap).EachPair(func(k, v eval.Value) {
+			ot, ok := v.(eval.ObjectType)
+			if !ok || ot.Name() != ts.Name()+`::`+k.String() {
+				unsupported(t, fmt.Sprintf(`the member %s is not an Object type`, k))
+			}
+			g.add(ot, k.String())
+		})
+		g.imports[semverPath] = `semver`
+	case eval.ObjectType:
+		g.add(t.(eval.ObjectType), ``)
+	default:
+		unsupported(t, `only Object types and TypeSets can be generated`)
+	}
+
+	// The queue grows when types that an ObjectType depends on are found
+	for i := 0; i < len(g.queue); i++ {
+		g.declare(g.queue[i])
+	}
+
+	// The imports are known once the body has been written
+	order := g.order()
+	body := bytes.NewBufferString(``)
+	for _, d := range order {
+		d.write(body)
+	}
+	g.writeInitTypes(body, order)
+	g.writeRegisterImplementations(body, order)
+
+	b := bytes.NewBufferString(``)
+	fmt.Fprintf(b, "// Code generated from the Puppet type %s. DO NOT EDIT.\n\npackage %s\n\n", t.Name(), packageName)
+	g.writeImports(b)
+	b.Write(body.Bytes())
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: err.Error()}))
+	}
+	return src
+}
+
+func initHash(t eval.Type) eval.OrderedMap {
+	return t.(eval.PuppetObject).InitHash()
+}
+
+func unsupported(t eval.Type, detail string) {
+	panic(eval.Error(eval.EVAL_GO_GENERATION_UNSUPPORTED, issue.H{`type`: t.Name(), `detail`: detail}))
+}
+
+// add queues the given type for generation using the given Go name or, when the name is empty,
+// the last segment of the type name
+func (g *generator) add(ot eval.ObjectType, goName string) string {
+	name := ot.Name()
+	if goName == `` {
+		goName = name[strings.LastIndex(name, `::`)+1:]
+		goName = strings.TrimLeft(goName, `:`)
+	}
+	if other, ok := g.goNames[goName]; ok {
+		unsupported(ot, fmt.Sprintf(`the Go name %s is also the name of %s`, goName, other))
+	}
+	g.names[name] = goName
+	g.goNames[goName] = name
+	g.queue = append(g.queue, ot)
+	return goName
+}
+
+func (g *generator) declare(ot eval.ObjectType) {
+	d := &declaration{t: ot, name: g.names[ot.Name()], iface: isInterface(ot)}
+	g.decls[ot.Name()] = d
+	g.current = d
+
+	ih := initHash(ot)
+	for _, k := range []string{`type_parameters`, `equality`, `equality_include_type`, `serialization`} {
+		if ih.IncludesKey2(k) {
+			unsupported(ot, fmt.Sprintf(`the %s of an Object type cannot be reflected`, k))
+		}
+	}
+
+	used := make(map[string]string)
+	if p := ot.Parent(); p != nil {
+		pt, ok := p.(eval.ObjectType)
+		if !ok || pt.IsInterface() {
+			unsupported(ot, `the parent must be an Object type that is not an interface`)
+		}
+		d.parent = g.objectType(pt).src
+		used[d.parent[strings.LastIndex(d.parent, `.`)+1:]] = `the parent`
+	}
+
+	ot.(attributeIterator).EachAttribute(false, func(a eval.Attribute) {
+		d.fields = append(d.fields, g.field(ot, a, used))
+	})
+	for _, f := range ot.Functions(false) {
+		d.methods = append(d.methods, g.method(ot, f, used))
+	}
+}
+
+type attributeIterator interface {
+	EachAttribute(includeParent bool, consumer func(attr eval.Attribute))
+}
+
+// isInterface returns true if the given type becomes a Go interface
+func isInterface(ot eval.ObjectType) bool {
+	return ot.IsInterface() && ot.Parent() == nil
+}
+
+// claim asserts that the given Go name isn't used by another member of the same struct
+func claim(ot eval.ObjectType, used map[string]string, goName, name string) {
+	if other, ok := used[goName]; ok {
+		unsupported(ot, fmt.Sprintf(`the Go name %s of the member '%s' is also used for %s`, goName, name, other))
+	}
+	used[goName] = fmt.Sprintf(`the member '%s'`, name)
+}
+
+func (g *generator) field(ot eval.ObjectType, a eval.Attribute, used map[string]string) field {
+	if a.Override() || a.Final() && a.Kind() != types.CONSTANT {
+		unsupported(ot, fmt.Sprintf(`the attribute '%s' is final or overrides an attribute of the parent`, a.Name()))
+	}
+	name := a.GoName()
+	if name == `` {
+		name = issue.SnakeToCamelCase(strings.TrimLeft(a.Name(), `_`))
+	}
+	claim(ot, used, name, a.Name())
+
+	at := a.Type()
+	gt := g.goType(at)
+	optional := at.IsInstance(eval.UNDEF, nil)
+	if optional && !gt.nilable {
+		gt = g.reflected(valueType)
+		gt.exact = false
+	}
+
+	var tag []string
+	if issue.FirstToLower(name) != a.Name() {
+		tag = append(tag, `name=>`+literal(types.WrapString(a.Name())))
+	}
+	if a.Kind() != `` {
+		tag = append(tag, `kind=>`+literal(types.WrapString(string(a.Kind()))))
+	}
+	if a.HasValue() && !(optional && a.Value() == eval.UNDEF) {
+		tag = append(tag, `value=>`+literal(a.Value()))
+	}
+	if !gt.exact {
+		tag = append(tag, `type=>`+at.String())
+	}
+
+	f := field{name: name, typ: gt.src}
+	if len(tag) > 0 {
+		f.tag = `puppet:` + strconv.Quote(strings.Join(tag, `, `))
+		if strings.ContainsRune(f.tag, '`') {
+			f.tag = strconv.Quote(f.tag)
+		} else {
+			f.tag = "`" + f.tag + "`"
+		}
+	}
+	return f
+}
+
+func literal(v eval.Value) string {
+	return eval.ToString2(v, types.PROGRAM)
+}
+
+func (g *generator) method(ot eval.ObjectType, f eval.ObjFunc, used map[string]string) method {
+	if f.Override() || f.Final() {
+		unsupported(ot, fmt.Sprintf(`the function '%s' is final or overrides a function of the parent`, f.Name()))
+	}
+	name := strings.ToUpper(f.Name()[:1]) + f.Name()[1:]
+	if issue.FirstToLower(name) != f.Name() {
+		unsupported(ot, fmt.Sprintf(`the function '%s' cannot be named as a Go method`, f.Name()))
+	}
+	claim(ot, used, name, f.Name())
+
+	ct, ok := f.Type().(*types.CallableType)
+	if !ok || ct.ParametersType() == nil {
+		unsupported(ot, fmt.Sprintf(`the function '%s' has no parameter types`, f.Name()))
+	}
+	if ct.BlockType() != nil {
+		unsupported(ot, fmt.Sprintf(`the function '%s' takes a block`, f.Name()))
+	}
+
+	pt := ct.ParametersType().(*types.TupleType)
+	ps := pt.Types()
+	n := int64(len(ps))
+	variadic := false
+	switch sz := pt.Size(); {
+	case sz.Min() == n && sz.Max() == n:
+	case n > 0 && sz.Min() == n-1 && sz.Max() == math.MaxInt64:
+		variadic = true
+	default:
+		unsupported(ot, fmt.Sprintf(`the function '%s' has optional parameters`, f.Name()))
+	}
+	args := make([]string, len(ps))
+	for i, p := range ps {
+		args[i] = g.goType(p).src
+	}
+	if variadic {
+		args[n-1] = `...` + args[n-1]
+	}
+
+	var results []string
+	if rt := ct.ReturnType(); rt != nil {
+		if tt, ok := rt.(*types.TupleType); ok && len(tt.Types()) > 1 && tt.Size().Min() == int64(len(tt.Types())) {
+			for _, t := range tt.Types() {
+				results = append(results, g.goType(t).src)
+			}
+		} else {
+			results = append(results, g.goType(rt).src)
+		}
+	}
+	if f.ReturnsError() {
+		results = append(results, `error`)
+	}
+
+	sig := name + `(` + strings.Join(args, `, `) + `)`
+	switch len(results) {
+	case 0:
+	case 1:
+		sig += ` ` + results[0]
+	default:
+		sig += ` (` + strings.Join(results, `, `) + `)`
+	}
+	return method{name, sig}
+}
+
+// goType returns the Go type for the given type
+func (g *generator) goType(t eval.Type) goType {
+	switch t.(type) {
+	case *types.OptionalType:
+		gt := g.goType(t.(*types.OptionalType).ContainedType())
+		if gt.nilable {
+			// Pointers to pointers and interfaces are avoided
+			return goType{gt.src, false, true}
+		}
+		return goType{`*` + gt.src, gt.exact, true}
+	case *types.ArrayType:
+		at := t.(*types.ArrayType)
+		et := g.goType(at.ElementType())
+		return goType{`[]` + et.src, et.exact && isDefaultSize(at.Size()), false}
+	case *types.HashType:
+		ht := t.(*types.HashType)
+		kt := g.goType(ht.KeyType())
+		if !(strings.HasPrefix(kt.src, `[]`) || strings.HasPrefix(kt.src, `map[`)) {
+			vt := g.goType(ht.ValueType())
+			return goType{`map[` + kt.src + `]` + vt.src, kt.exact && vt.exact && isDefaultSize(ht.Size()), false}
+		}
+	}
+
+	for _, rt := range reflectedTypes {
+		if pt, err := eval.WrapReflectedType(g.c, rt); err == nil && pt.Equals(t, nil) {
+			return g.reflected(rt)
+		}
+	}
+	if ot, ok := t.(eval.ObjectType); ok {
+		return g.objectType(ot)
+	}
+	return g.widened(t)
+}
+
+func isDefaultSize(sz *types.IntegerType) bool {
+	return sz == nil || sz.Equals(types.IntegerTypePositive, nil)
+}
+
+// widened returns a Go type that can hold all instances of the given type
+func (g *generator) widened(t eval.Type) goType {
+	rt := valueType
+	switch {
+	case t.IsInstance(eval.UNDEF, nil):
+	case types.DefaultStringType().IsAssignable(t, nil):
+		rt = reflect.TypeOf(``)
+	case types.DefaultIntegerType().IsAssignable(t, nil):
+		rt = reflect.TypeOf(int64(0))
+	case types.DefaultFloatType().IsAssignable(t, nil):
+		rt = reflect.TypeOf(float64(0))
+	case types.DefaultBooleanType().IsAssignable(t, nil):
+		rt = reflect.TypeOf(false)
+	case types.DefaultArrayType().IsAssignable(t, nil):
+		rt = listType
+	case types.DefaultHashType().IsAssignable(t, nil):
+		rt = orderedMapType
+	}
+	gt := g.reflected(rt)
+	gt.exact = false
+	return gt
+}
+
+// objectType returns the Go type for the given Object type. The type is either generated or
+// registered with the ImplementationRegistry.
+func (g *generator) objectType(ot eval.ObjectType) goType {
+	name, ok := g.names[ot.Name()]
+	if !ok {
+		if rt, ok := g.c.ImplementationRegistry().TypeToReflected(ot); ok {
+			return g.reflected(rt)
+		}
+		if g.typeSet != nil {
+			unsupported(g.typeSet, fmt.Sprintf(`%s is neither a member of the TypeSet nor registered with the ImplementationRegistry`, ot.Name()))
+		}
+		name = g.add(ot, ``)
+	}
+	if g.current != nil && g.current.t != ot {
+		g.current.deps = append(g.current.deps, ot.Name())
+	}
+	return goType{name, true, isInterface(ot)}
+}
+
+// reflected returns the Go type for an existing Go type and adds the import of its package. A
+// pointer to a struct is represented by the struct.
+func (g *generator) reflected(rt reflect.Type) goType {
+	nilable := true
+	et := rt
+	switch rt.Kind() {
+	case reflect.Ptr:
+		et = rt.Elem()
+		if et.Kind() == reflect.Struct {
+			rt = et
+			nilable = false
+		}
+	case reflect.Interface:
+	default:
+		nilable = false
+	}
+	src := rt.String()
+	if p := et.PkgPath(); p != `` {
+		g.imports[p] = strings.TrimLeft(src[:strings.IndexByte(src, '.')], `*`)
+	}
+	return goType{src, true, nilable}
+}
+
+// order returns the declarations so that each declaration precedes the declarations that depend
+// on it
+func (g *generator) order() []*declaration {
+	order := make([]*declaration, 0, len(g.queue))
+	visited := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		d, ok := g.decls[name]
+		if !ok || visited[name] {
+			return
+		}
+		visited[name] = true
+		for _, dep := range d.deps {
+			visit(dep)
+		}
+		order = append(order, d)
+	}
+	for _, ot := range g.queue {
+		visit(ot.Name())
+	}
+	return order
+}
+
+// writeImports writes the imports with the standard library packages in a group of their own
+func (g *generator) writeImports(b *bytes.Buffer) {
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		si, sj := isStandard(paths[i]), isStandard(paths[j])
+		if si != sj {
+			return si
+		}
+		return paths[i] < paths[j]
+	})
+	b.WriteString("import (\n")
+	for i, p := range paths {
+		if i > 0 && isStandard(paths[i-1]) && !isStandard(p) {
+			b.WriteByte('\n')
+		}
+		if n := g.imports[p]; n != path.Base(p) {
+			fmt.Fprintf(b, "\t%s %q\n", n, p)
+		} else {
+			fmt.Fprintf(b, "\t%q\n", p)
+		}
+	}
+	b.WriteString(")\n")
+}
+
+func isStandard(importPath string) bool {
+	return !strings.Contains(strings.Split(importPath, `/`)[0], `.`)
+}
+
+func (d *declaration) write(b *bytes.Buffer) {
+	if d.iface {
+		fmt.Fprintf(b, "\n// %s represents the Puppet type %s\ntype %s interface {\n", d.name, d.t.Name(), d.name)
+		d.writeMethods(b)
+		b.WriteString("}\n")
+		return
+	}
+
+	fmt.Fprintf(b, "\n// %s represents the Puppet type %s\ntype %s struct {\n", d.name, d.t.Name(), d.name)
+	if d.parent != `` {
+		fmt.Fprintf(b, "\t%s\n", d.parent)
+	}
+	for _, f := range d.fields {
+		fmt.Fprintf(b, "\t%s %s", f.name, f.typ)
+		if f.tag != `` {
+			fmt.Fprintf(b, " %s", f.tag)
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString("}\n")
+
+	if len(d.methods) > 0 {
+		fmt.Fprintf(b, "\n// %sFunctions declares the functions of %s. The methods must be implemented by *%s\ntype %sFunctions interface {\n",
+			d.name, d.t.Name(), d.name, d.name)
+		d.writeMethods(b)
+		fmt.Fprintf(b, "}\n\nvar _ %sFunctions = (*%s)(nil)\n", d.name, d.name)
+	}
+}
+
+func (d *declaration) writeMethods(b *bytes.Buffer) {
+	for _, m := range d.methods {
+		fmt.Fprintf(b, "\t%s\n", m.signature)
+	}
+}
+
+func (d *declaration) reflectType() string {
+	if d.iface {
+		return fmt.Sprintf(`reflect.TypeOf((*%s)(nil)).Elem()`, d.name)
+	}
+	return fmt.Sprintf(`reflect.TypeOf(&%s{})`, d.name)
+}
+
+func (g *generator) writeInitTypes(b *bytes.Buffer, order []*declaration) {
+	b.WriteString("\n// InitTypes creates the Puppet types from the Go types in this file and adds them to the loader of the\n" +
+		"// given context. The Go types are registered as the implementations of the created types. The functions\n" +
+		"// of the created types are the methods of the Go types, so the file compiles only when those are implemented.\n" +
+		"func InitTypes(c eval.Context) {\n")
+	if g.typeSet != nil {
+		fmt.Fprintf(b, "\tc.AddTypes(c.Reflector().TypeSetFromReflect(`%s`, semver.MustParseVersion(`%s`), nil",
+			g.typeSet.Name(), initHash(g.typeSet).Get5(`version`, eval.UNDEF))
+		for _, d := range order {
+			fmt.Fprintf(b, ",\n\t\t%s", d.reflectType())
+		}
+		b.WriteString("))\n}\n")
+		return
+	}
+
+	b.WriteString("\tr := c.Reflector()\n\tc.AddTypes(")
+	for _, d := range order {
+		parent := `nil`
+		if p := d.t.Parent(); p != nil {
+			g.imports[typesPath] = `types`
+			parent = fmt.Sprintf("types.NewTypeReferenceType(`%s`)", p.Name())
+		}
+		fmt.Fprintf(b, "\n\t\tr.TypeFromReflect(`%s`, %s, %s),", d.t.Name(), parent, d.reflectType())
+	}
+	b.WriteString(")\n}\n")
+}
+
+func (g *generator) writeRegisterImplementations(b *bytes.Buffer, order []*declaration) {
+	b.WriteString("\n// RegisterImplementations registers the Go types in this file as the implementations of the Puppet\n" +
+		"// types with the same names. The Puppet types must be known to the loader of the given context.\n" +
+		"func RegisterImplementations(c eval.Context) {\n\tir := c.ImplementationRegistry()\n")
+	for _, d := range order {
+		fmt.Fprintf(b, "\tir.RegisterType(c, c.ParseType2(`%s`), %s)\n", d.t.Name(), d.reflectType())
+	}
+	b.WriteString("}\n")
+}
@@ -0,0 +1,192 @@
+package gogen_test
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/gogen"
+	"github.com/lyraproj/puppet-evaluator/gogen/internal/own"
+
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func ExampleGenerate() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		ctx.AddDefinitions(ctx.ParseAndValidate(`site.pp`, `
+			type My::Address = Object[attributes => {street => String, zip_code => String[5, 5]}]
+			type My::Person = Object[
+				attributes => {
+					name => String,
+					address => Optional[My::Address],
+					kind => {type => Enum[friend, foe], value => friend}},
+				functions => {
+					visit => Callable[[My::Address, Integer, 1, default], Tuple[String, Integer]]}]`, false))
+		ctx.ResolveDefinitions()
+
+		fmt.Print(string(gogen.Generate(ctx, `own`, ctx.ParseType2(`My::Person`))))
+	})
+	// Output:
+	// // Code generated from the Puppet type My::Person. DO NOT EDIT.
+	//
+	// package own
+	//
+	// import (
+	// 	"reflect"
+	//
+	// 	"github.com/lyraproj/puppet-evaluator/eval"
+	// )
+	//
+	// // Address represents the Puppet type My::Address
+	// type Address struct {
+	// 	Street  string
+	// 	ZipCode string `puppet:"name=>'zip_code', type=>String[5, 5]"`
+	// }
+	//
+	// // Person represents the Puppet type My::Person
+	// type Person struct {
+	// 	Name    string
+	// 	Address *Address
+	// 	Kind    string `puppet:"value=>'friend', type=>Enum['friend', 'foe']"`
+	// }
+	//
+	// // PersonFunctions declares the functions of My::Person. The methods must be implemented by *Person
+	// type PersonFunctions interface {
+	// 	Visit(Address, ...int64) (string, int64)
+	// }
+	//
+	// var _ PersonFunctions = (*Person)(nil)
+	//
+	// // InitTypes creates the Puppet types from the Go types in this file and adds them to the loader of the
+	// // given context. The Go types are registered as the implementations of the created types. The functions
+	// // of the created types are the methods of the Go types, so the file compiles only when those are implemented.
+	// func InitTypes(c eval.Context) {
+	// 	r := c.Reflector()
+	// 	c.AddTypes(
+	// 		r.TypeFromReflect(`My::Address`, nil, reflect.TypeOf(&Address{})),
+	// 		r.TypeFromReflect(`My::Person`, nil, reflect.TypeOf(&Person{})))
+	// }
+	//
+	// // RegisterImplementations registers the Go types in this file as the implementations of the Puppet
+	// // types with the same names. The Puppet types must be known to the loader of the given context.
+	// func RegisterImplementations(c eval.Context) {
+	// 	ir := c.ImplementationRegistry()
+	// 	ir.RegisterType(c, c.ParseType2(`My::Address`), reflect.TypeOf(&Address{}))
+	// 	ir.RegisterType(c, c.ParseType2(`My::Person`), reflect.TypeOf(&Person{}))
+	// }
+}
+
+func ExampleGenerate_typeSet() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		ctx.AddDefinitions(ctx.ParseAndValidate(`site.pp`, `
+			type My = TypeSet[{
+				pcore_uri => 'http://puppet.com/2016.1/pcore',
+				pcore_version => '1.0.0',
+				version => '1.0.0',
+				types => {
+					Shape => {functions => {area => Callable[[0, 0], Float]}},
+					Circle => {attributes => {radius => Float[0.0]}, functions => {area => Callable[[0, 0], Float]}}}}]`, false))
+		ctx.ResolveDefinitions()
+
+		fmt.Print(string(gogen.Generate(ctx, `my`, ctx.ParseType2(`My`))))
+	})
+	// Output:
+	// // Code generated from the Puppet type My. DO NOT EDIT.
+	//
+	// package my
+	//
+	// import (
+	// 	"reflect"
+	//
+	// 	"github.com/lyraproj/puppet-evaluator/eval"
+	// 	"github.com/lyraproj/semver/semver"
+	// )
+	//
+	// // Circle represents the Puppet type My::Circle
+	// type Circle struct {
+	// 	Radius float64 `puppet:"type=>Float[0.00000]"`
+	// }
+	//
+	// // CircleFunctions declares the functions of My::Circle. The methods must be implemented by *Circle
+	// type CircleFunctions interface {
+	// 	Area() float64
+	// }
+	//
+	// var _ CircleFunctions = (*Circle)(nil)
+	//
+	// // Shape represents the Puppet type My::Shape
+	// type Shape interface {
+	// 	Area() float64
+	// }
+	//
+	// // InitTypes creates the Puppet types from the Go types in this file and adds them to the loader of the
+	// // given context. The Go types are registered as the implementations of the created types. The functions
+	// // of the created types are the methods of the Go types, so the file compiles only when those are implemented.
+	// func InitTypes(c eval.Context) {
+	// 	c.AddTypes(c.Reflector().TypeSetFromReflect(`My`, semver.MustParseVersion(`1.0.0`), nil,
+	// 		reflect.TypeOf(&Circle{}),
+	// 		reflect.TypeOf((*Shape)(nil)).Elem()))
+	// }
+	//
+	// // RegisterImplementations registers the Go types in this file as the implementations of the Puppet
+	// // types with the same names. The Puppet types must be known to the loader of the given context.
+	// func RegisterImplementations(c eval.Context) {
+	// 	ir := c.ImplementationRegistry()
+	// 	ir.RegisterType(c, c.ParseType2(`My::Circle`), reflect.TypeOf(&Circle{}))
+	// 	ir.RegisterType(c, c.ParseType2(`My::Shape`), reflect.TypeOf((*Shape)(nil)).Elem())
+	// }
+}
+
+func ExampleGenerate_unsupported() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		ctx.AddDefinitions(ctx.ParseAndValidate(`site.pp`, `
+			type My::Counter = Object[functions => {next => Callable[[Integer, 0, 1], Integer]}]`, false))
+		ctx.ResolveDefinitions()
+
+		defer func() {
+			fmt.Println(recover())
+		}()
+		gogen.Generate(ctx, `my`, ctx.ParseType2(`My::Counter`))
+	})
+	// Output: Unable to generate Go source for My::Counter: the function 'next' has optional parameters
+}
+
+func ExampleGenerate_roundTrip() {
+	var reflected []eval.Type
+	eval.Puppet.Do(func(ctx eval.Context) {
+		own.InitTypes(ctx)
+		reflected = []eval.Type{ctx.ParseType2(`My::Address`), ctx.ParseType2(`My::Person`)}
+	})
+
+	eval.Puppet.Do(func(ctx eval.Context) {
+		ctx.AddDefinitions(ctx.ParseAndValidate(`site.pp`, `
+			type My::Address = Object[attributes => {street => String, zip_code => String[5, 5]}]
+			type My::Person = Object[
+				attributes => {
+					name => String,
+					address => Optional[My::Address],
+					kind => {type => Enum[friend, foe], value => friend}},
+				functions => {
+					visit => Callable[[My::Address, Integer, 1, default], Tuple[String, Integer]]}]`, false))
+		ctx.ResolveDefinitions()
+
+		// The generated file is compiled into the package own
+		source, err := ioutil.ReadFile(`internal/own/own.go`)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(gogen.Generate(ctx, `own`, ctx.ParseType2(`My::Person`))) == string(source))
+
+		for _, t := range reflected {
+			fmt.Println(t.Name(), t.Equals(ctx.ParseType2(t.Name()), nil))
+		}
+		for _, f := range reflected[1].(eval.ObjectType).Functions(false) {
+			fmt.Println(f.Name(), f.Type())
+		}
+	})
+	// Output:
+	// true
+	// My::Address true
+	// My::Person true
+	// visit Callable[[My::Address, Integer, 1, default], Tuple[String, Integer]]
+}
@@ -0,0 +1,14 @@
+// Package own holds the Go source that gogen generates for the Puppet type My::Person together with the
+// methods that implement its functions. It is compiled to test that the types survive the round trip.
+package own
+
+import "fmt"
+
+// Visit returns a greeting and the number of times that the person was visited
+func (p *Person) Visit(a Address, times ...int64) (string, int64) {
+	n := int64(1)
+	if len(times) > 0 {
+		n = times[0]
+	}
+	return fmt.Sprintf(`%s visited %s at %s`, p.Name, a.Street, a.ZipCode), n
+}
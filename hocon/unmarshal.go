@@ -0,0 +1,697 @@
+package hocon
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// Unmarshal parses the given HOCON document and returns the resulting value. The order of all
+// objects is retained. Substitutions are resolved against the document and then against the
+// environment. Include statements are not supported.
+func Unmarshal(c eval.Context, data []byte) eval.Value {
+	p := &parser{src: string(data), root: &object{}, resolving: make(map[*substitution]bool)}
+	p.skipSpace(true)
+	var v interface{}
+	switch p.peek() {
+	case '[':
+		v = p.parseArray()
+	case '{':
+		p.pos++
+		p.parseFields(p.root, '}')
+		v = p.root
+	default:
+		p.parseFields(p.root, 0)
+		v = p.root
+	}
+	p.skipSpace(true)
+	if p.pos < len(p.src) {
+		p.fail(fmt.Sprintf(`unexpected '%c'`, p.peek()))
+	}
+	return toValue(p.resolve(v))
+}
+
+// object is an ordered and mutable HOCON object
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// array is a HOCON array. It is a pointer type so that += can append to it
+type array struct {
+	elements []interface{}
+}
+
+// substitution is an unresolved ${path} or ${?path}
+type substitution struct {
+	path     []string
+	optional bool
+}
+
+// concatenation is a value composed of several parts that are joined once resolved
+type concatenation []interface{}
+
+type quoted string
+
+type unquoted string
+
+type whitespace string
+
+func (o *object) get(key string) (interface{}, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+func (o *object) put(key string, v interface{}) {
+	if o.values == nil {
+		o.values = make(map[string]interface{})
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+func (o *object) remove(key string) {
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// merge returns a new object with the fields of b merged into the fields of a. Fields that are
+// objects in both are merged recursively
+func merge(a, b *object) *object {
+	r := &object{}
+	for _, k := range a.keys {
+		r.put(k, a.values[k])
+	}
+	for _, k := range b.keys {
+		v := b.values[k]
+		if ov, ok := r.values[k].(*object); ok {
+			if nv, ok := v.(*object); ok {
+				v = merge(ov, nv)
+			}
+		}
+		r.put(k, v)
+	}
+	return r
+}
+
+type parser struct {
+	src       string
+	pos       int
+	root      *object
+	prefix    []string
+	resolving map[*substitution]bool
+}
+
+func (p *parser) fail(msg string) {
+	line := strings.Count(p.src[:p.pos], "\n") + 1
+	panic(eval.Error(eval.EVAL_PARSE_ERROR, issue.H{`language`: `HOCON`, `detail`: fmt.Sprintf(`%s at line %d`, msg, line)}))
+}
+
+func (p *parser) peek() rune {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	return r
+}
+
+func (p *parser) startsWith(s string) bool {
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+func (p *parser) atComment() bool {
+	return p.startsWith(`#`) || p.startsWith(`//`)
+}
+
+// skipSpace skips whitespace and comments. Newlines are skipped only when newlines is true
+func (p *parser) skipSpace(newlines bool) {
+	for p.pos < len(p.src) {
+		r := p.peek()
+		switch {
+		case r == '\n':
+			if !newlines {
+				return
+			}
+			p.pos++
+		case unicode.IsSpace(r) || r == '\uFEFF':
+			p.pos += utf8.RuneLen(r)
+		case p.atComment():
+			if nl := strings.IndexByte(p.src[p.pos:], '\n'); nl >= 0 {
+				p.pos += nl
+			} else {
+				p.pos = len(p.src)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// skipSeparator skips whitespace, comments, newlines, and at most one comma
+func (p *parser) skipSeparator() {
+	p.skipSpace(true)
+	if p.peek() == ',' {
+		p.pos++
+		p.skipSpace(true)
+	}
+}
+
+func (p *parser) parseFields(o *object, end rune) {
+	for {
+		p.skipSpace(true)
+		if p.pos >= len(p.src) {
+			if end != 0 {
+				p.fail(fmt.Sprintf(`expected '%c'`, end))
+			}
+			return
+		}
+		if p.peek() == end {
+			p.pos++
+			return
+		}
+		path := p.parseKey()
+		p.skipSpace(false)
+		appendValue := false
+		switch {
+		case p.peek() == '{':
+		case p.peek() == ':' || p.peek() == '=':
+			p.pos++
+		case p.startsWith(`+=`):
+			p.pos += 2
+			appendValue = true
+		default:
+			p.fail(fmt.Sprintf(`expected ':' or '=' after key '%s'`, strings.Join(path, `.`)))
+		}
+		p.skipSpace(true)
+
+		top := len(p.prefix)
+		p.prefix = append(p.prefix, path...)
+		v := p.parseValue()
+		if v == nil {
+			p.fail(fmt.Sprintf(`expected a value for key '%s'`, strings.Join(path, `.`)))
+		}
+		v = p.resolveSelf(v, p.prefix)
+		p.prefix = p.prefix[:top]
+
+		p.set(o, path, v, appendValue)
+		p.skipSpace(false)
+		if r := p.peek(); r != ',' && r != '\n' && r != end && r != 0 {
+			p.fail(fmt.Sprintf(`unexpected '%c'`, r))
+		}
+		p.skipSeparator()
+	}
+}
+
+func (p *parser) set(o *object, path []string, v interface{}, appendValue bool) {
+	key := path[0]
+	old, found := o.get(key)
+	if len(path) > 1 {
+		if ov, ok := old.(*object); ok {
+			child := merge(ov, &object{})
+			p.set(child, path[1:], v, appendValue)
+			v = child
+		} else {
+			child := &object{}
+			p.set(child, path[1:], v, appendValue)
+			if isUnresolved(old) {
+				// The old value might resolve to an object that the child must be merged with
+				v = concatenation{old, child}
+			} else {
+				v = child
+			}
+		}
+		o.put(key, v)
+		return
+	}
+
+	if appendValue {
+		switch old.(type) {
+		case nil:
+			v = &array{[]interface{}{v}}
+		case *array:
+			v = &array{append(append([]interface{}{}, old.(*array).elements...), v)}
+		default:
+			v = concatenation{old, &array{[]interface{}{v}}}
+		}
+	} else if nv, ok := v.(*object); ok && found {
+		if ov, ok := old.(*object); ok {
+			v = merge(ov, nv)
+		} else if isUnresolved(old) {
+			v = concatenation{old, nv}
+		}
+	}
+	o.put(key, v)
+}
+
+func isUnresolved(v interface{}) bool {
+	switch v.(type) {
+	case *substitution, concatenation:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *parser) parseKey() []string {
+	path := make([]string, 0, 2)
+	segment := bytes.NewBufferString(``)
+	space := ``
+	started := false
+	for {
+		r := p.peek()
+		switch {
+		case r == '"':
+			segment.WriteString(space)
+			space = ``
+			segment.WriteString(string(p.parseQuoted()))
+			started = true
+			continue
+		case r == '.':
+			if !started {
+				p.fail(`empty key segment`)
+			}
+			p.pos++
+			path = append(path, segment.String())
+			segment.Reset()
+			space = ``
+			started = false
+			continue
+		case r == ' ' || r == '\t':
+			if started {
+				space += string(r)
+			}
+			p.pos++
+			continue
+		case r == ':' || r == '=' || r == '{' || r == '}' || p.startsWith(`+=`) || r == '\n' || r == 0 || p.atComment():
+		case isUnquotedChar(r):
+			segment.WriteString(space)
+			space = ``
+			segment.WriteString(p.parseUnquoted(true))
+			started = true
+			continue
+		default:
+			p.fail(fmt.Sprintf(`unexpected '%c' in key`, r))
+		}
+		break
+	}
+	if !started {
+		p.fail(`expected a key`)
+	}
+	return append(path, segment.String())
+}
+
+// parseValue parses a value and all values that are concatenated with it on the same line. It
+// returns nil when no value is found.
+func (p *parser) parseValue() interface{} {
+	var parts concatenation
+	for {
+		start := p.pos
+		p.skipSpace(false)
+		space := p.src[start:p.pos]
+		r := p.peek()
+		var v interface{}
+		switch {
+		case r == 0 || r == '\n' || r == ',' || r == '}' || r == ']' || p.atComment():
+			p.pos = start
+			switch len(parts) {
+			case 0:
+				return nil
+			case 1:
+				return parts[0]
+			default:
+				return parts
+			}
+		case r == '{':
+			p.pos++
+			o := &object{}
+			p.parseFields(o, '}')
+			v = o
+		case r == '[':
+			v = p.parseArray()
+		case r == '"':
+			v = p.parseQuoted()
+		case p.startsWith(`${`):
+			v = p.parseSubstitution()
+		case isUnquotedChar(r):
+			v = unquoted(p.parseUnquoted(false))
+		default:
+			p.fail(fmt.Sprintf(`unexpected '%c'`, r))
+		}
+		if len(parts) > 0 && space != `` {
+			parts = append(parts, whitespace(space))
+		}
+		parts = append(parts, v)
+	}
+}
+
+func (p *parser) parseArray() *array {
+	p.pos++
+	a := &array{}
+	for {
+		p.skipSpace(true)
+		if p.peek() == ']' {
+			p.pos++
+			return a
+		}
+		if p.pos >= len(p.src) {
+			p.fail(`expected ']'`)
+		}
+		v := p.parseValue()
+		if v == nil {
+			p.fail(fmt.Sprintf(`unexpected '%c'`, p.peek()))
+		}
+		a.elements = append(a.elements, p.resolveSelf(v, p.prefix))
+		p.skipSpace(false)
+		if r := p.peek(); r != ',' && r != '\n' && r != ']' && r != 0 {
+			p.fail(fmt.Sprintf(`unexpected '%c'`, r))
+		}
+		p.skipSeparator()
+	}
+}
+
+func (p *parser) parseSubstitution() *substitution {
+	p.pos += 2
+	s := &substitution{}
+	if p.peek() == '?' {
+		p.pos++
+		s.optional = true
+	}
+	p.skipSpace(false)
+	s.path = p.parseKey()
+	p.skipSpace(false)
+	if p.peek() != '}' {
+		p.fail(`expected '}' to end substitution`)
+	}
+	p.pos++
+	return s
+}
+
+func (p *parser) parseQuoted() quoted {
+	if p.startsWith(`"""`) {
+		end := strings.Index(p.src[p.pos+3:], `"""`)
+		if end < 0 {
+			p.fail(`unterminated multi-line string`)
+		}
+		end += p.pos + 3
+		// Quotes that immediately precede the terminating triple quote belong to the string
+		for end+3 < len(p.src) && p.src[end+3] == '"' {
+			end++
+		}
+		s := p.src[p.pos+3 : end]
+		p.pos = end + 3
+		return quoted(s)
+	}
+
+	p.pos++
+	b := bytes.NewBufferString(``)
+	for {
+		if p.pos >= len(p.src) {
+			p.fail(`unterminated string`)
+		}
+		r, sz := utf8.DecodeRuneInString(p.src[p.pos:])
+		p.pos += sz
+		switch r {
+		case '"':
+			return quoted(b.String())
+		case '\n':
+			p.fail(`unterminated string`)
+		case '\\':
+			if p.pos >= len(p.src) {
+				p.fail(`unterminated string`)
+			}
+			e := p.src[p.pos]
+			p.pos++
+			switch e {
+			case '"', '\\', '/':
+				b.WriteByte(e)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if p.pos+4 > len(p.src) {
+					p.fail(`invalid unicode escape`)
+				}
+				u, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+				if err != nil {
+					p.fail(`invalid unicode escape`)
+				}
+				p.pos += 4
+				b.WriteRune(rune(u))
+			default:
+				p.fail(fmt.Sprintf(`invalid escape '\%c'`, e))
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+func isUnquotedChar(r rune) bool {
+	return r != 0 && !unicode.IsSpace(r) && !strings.ContainsRune("$\"{}[]:=,+#`^?!@*&\\.", r)
+}
+
+// parseUnquoted parses an unquoted string. Periods are included unless the string is a key
+// segment, in which case the caller deals with them.
+func (p *parser) parseUnquoted(key bool) string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		r := p.peek()
+		if !(isUnquotedChar(r) || r == '.' && !key) || p.startsWith(`//`) {
+			break
+		}
+		p.pos += utf8.RuneLen(r)
+	}
+	return p.src[start:p.pos]
+}
+
+// resolveSelf resolves substitutions that refer to the given path using the value that the
+// path currently has. This allows a value to be defined in terms of its previous value.
+func (p *parser) resolveSelf(v interface{}, path []string) interface{} {
+	switch v.(type) {
+	case *substitution:
+		s := v.(*substitution)
+		if strings.Join(s.path, `.`) == strings.Join(path, `.`) {
+			if old, ok := p.lookup(s.path); ok {
+				return old
+			}
+			if s.optional {
+				return concatenation{}
+			}
+		}
+	case concatenation:
+		cv := v.(concatenation)
+		rv := make(concatenation, len(cv))
+		for i, e := range cv {
+			rv[i] = p.resolveSelf(e, path)
+		}
+		return rv
+	}
+	return v
+}
+
+// lookup finds the resolved value at the given path in the root object. Objects along the path
+// are not resolved since they may contain the substitution that is being resolved.
+func (p *parser) lookup(path []string) (interface{}, bool) {
+	var v interface{} = p.root
+	last := len(path) - 1
+	for i, key := range path {
+		o, ok := v.(*object)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = o.get(key); !ok {
+			return nil, false
+		}
+		if _, ok = v.(*object); ok && i < last {
+			continue
+		}
+		v = p.resolve(v)
+		if v == nil {
+			return nil, false
+		}
+		o.put(key, v)
+	}
+	return v, true
+}
+
+// resolve returns the given value with all substitutions resolved, or nil when the value is an
+// optional substitution that cannot be resolved
+func (p *parser) resolve(v interface{}) interface{} {
+	switch v.(type) {
+	case *object:
+		o := v.(*object)
+		for _, k := range append([]string{}, o.keys...) {
+			if rv := p.resolve(o.values[k]); rv == nil {
+				o.remove(k)
+			} else {
+				o.put(k, rv)
+			}
+		}
+	case *array:
+		a := v.(*array)
+		es := make([]interface{}, 0, len(a.elements))
+		for _, e := range a.elements {
+			if re := p.resolve(e); re != nil {
+				es = append(es, re)
+			}
+		}
+		a.elements = es
+	case *substitution:
+		s := v.(*substitution)
+		if p.resolving[s] {
+			p.fail(fmt.Sprintf(`cyclic substitution of '%s'`, strings.Join(s.path, `.`)))
+		}
+		p.resolving[s] = true
+		rv, ok := p.lookup(s.path)
+		delete(p.resolving, s)
+		if ok {
+			return rv
+		}
+		if env, ok := os.LookupEnv(strings.Join(s.path, `.`)); ok {
+			return quoted(env)
+		}
+		if !s.optional {
+			p.fail(fmt.Sprintf(`unable to resolve substitution '%s'`, strings.Join(s.path, `.`)))
+		}
+		return nil
+	case concatenation:
+		return p.concatenate(v.(concatenation))
+	}
+	return v
+}
+
+func (p *parser) concatenate(parts concatenation) interface{} {
+	var objects []*object
+	var arrays []*array
+	var strs []interface{}
+	for _, part := range parts {
+		rv := p.resolve(part)
+		switch rv.(type) {
+		case nil:
+		case *object:
+			objects = append(objects, rv.(*object))
+		case *array:
+			arrays = append(arrays, rv.(*array))
+		case whitespace:
+			if len(strs) > 0 {
+				strs = append(strs, rv)
+			}
+		default:
+			strs = append(strs, rv)
+		}
+	}
+
+	switch {
+	case len(objects) > 0:
+		if len(arrays) > 0 || hasValue(strs) {
+			p.fail(`cannot concatenate an object with a value that is not an object`)
+		}
+		r := objects[0]
+		for _, o := range objects[1:] {
+			r = merge(r, o)
+		}
+		return r
+	case len(arrays) > 0:
+		if hasValue(strs) {
+			p.fail(`cannot concatenate an array with a value that is not an array`)
+		}
+		r := &array{}
+		for _, a := range arrays {
+			r.elements = append(r.elements, a.elements...)
+		}
+		return r
+	case len(strs) == 0:
+		return nil
+	case len(strs) == 1:
+		return strs[0]
+	}
+
+	b := bytes.NewBufferString(``)
+	end := len(strs)
+	if _, ok := strs[end-1].(whitespace); ok {
+		end--
+	}
+	for _, s := range strs[:end] {
+		switch s.(type) {
+		case quoted:
+			b.WriteString(string(s.(quoted)))
+		case unquoted:
+			b.WriteString(string(s.(unquoted)))
+		case whitespace:
+			b.WriteString(string(s.(whitespace)))
+		}
+	}
+	return quoted(b.String())
+}
+
+func hasValue(parts []interface{}) bool {
+	for _, part := range parts {
+		if _, ok := part.(whitespace); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// toValue converts a resolved value into an eval.Value
+func toValue(v interface{}) eval.Value {
+	switch v.(type) {
+	case *object:
+		o := v.(*object)
+		es := make([]*types.HashEntry, len(o.keys))
+		for i, k := range o.keys {
+			es[i] = types.WrapHashEntry2(k, toValue(o.values[k]))
+		}
+		return types.WrapHash(es)
+	case *array:
+		a := v.(*array)
+		vs := make([]eval.Value, len(a.elements))
+		for i, e := range a.elements {
+			vs[i] = toValue(e)
+		}
+		return types.WrapValues(vs)
+	case quoted:
+		return types.WrapString(string(v.(quoted)))
+	case unquoted:
+		s := string(v.(unquoted))
+		switch s {
+		case `true`:
+			return types.BooleanTrue
+		case `false`:
+			return types.BooleanFalse
+		case `null`:
+			return eval.UNDEF
+		}
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return types.WrapInteger(i)
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return types.WrapFloat(f)
+		}
+		return types.WrapString(s)
+	default:
+		return eval.UNDEF
+	}
+}
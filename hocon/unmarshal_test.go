@@ -0,0 +1,48 @@
+package hocon_test
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/hocon"
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func ExampleUnmarshal() {
+	eval.Puppet.Do(func(c eval.Context) {
+		fmt.Println(hocon.Unmarshal(c, []byte(`
+// Objects with the same key are merged
+defaults { timeout = 30s, retries = 3 }
+defaults.retries = 5
+service = ${defaults} { name = "web" }
+path = /usr/bin
+path = ${path}":/bin"
+ports = [80]
+ports += 443
+empty = ${?NO_SUCH_VARIABLE}
+`)))
+	})
+	// Output:
+	// {'defaults' => {'timeout' => '30s', 'retries' => 5}, 'service' => {'timeout' => '30s', 'retries' => 5, 'name' => 'web'}, 'path' => '/usr/bin:/bin', 'ports' => [80, 443]}
+}
+
+func ExampleUnmarshal_error() {
+	eval.Puppet.Do(func(c eval.Context) {
+		defer func() {
+			fmt.Println(recover())
+		}()
+		hocon.Unmarshal(c, []byte("a = ${b}\nb = ${a}\n"))
+	})
+	// Output:
+	// Unable to parse HOCON. Detail: cyclic substitution of 'b' at line 3
+}
+
+func ExampleUnmarshal_siblingReference() {
+	eval.Puppet.Do(func(c eval.Context) {
+		fmt.Println(hocon.Unmarshal(c, []byte(`a { b = 1, c = ${a.b} }`)))
+		fmt.Println(hocon.Unmarshal(c, []byte("a {b=1, c=${a.b}}\na {z=2}\n")))
+	})
+	// Output:
+	// {'a' => {'b' => 1, 'c' => 1}}
+	// {'a' => {'b' => 1, 'c' => 1, 'z' => 2}}
+}
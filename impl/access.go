@@ -38,6 +38,9 @@ func evalAccessExpression(e eval.Evaluator, expr *parser.AccessExpression) (resu
 				args[idx] = e.Eval(key)
 			}
 		})
+		if refs, ok := resourceReferenceExpression(e, qr, args); ok {
+			return refs
+		}
 		return eval_ParameterizedTypeExpression(e, qr, args, expr)
 	}
 
@@ -158,6 +161,36 @@ func accessIndexedValue(expr *parser.AccessExpression, lhs eval.List, args []eva
 	return lhs.At(pos)
 }
 
+// resourceReferenceExpression returns a reference to a resource when the given name does not
+// appoint a parameterized type and all arguments are strings, i.e. File['/tmp/x']. An array
+// of references is returned when more than one title is given.
+func resourceReferenceExpression(e eval.Evaluator, qr *parser.QualifiedReference, args []eval.Value) (eval.Value, bool) {
+	if len(args) == 0 || qr.Name() == `Object` {
+		return nil, false
+	}
+	switch t := types.Resolve(e, qr.Name()).(type) {
+	case *types.TypeReferenceType:
+	case eval.ObjectType:
+		if t.IsParameterized() {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+
+	refs := make([]eval.Value, len(args))
+	for i, arg := range args {
+		if _, ok := arg.(eval.StringValue); !ok {
+			return nil, false
+		}
+		refs[i] = types.NewResourceType(qr.Name(), arg.String())
+	}
+	if len(refs) == 1 {
+		return refs[0], true
+	}
+	return types.WrapValues(refs), true
+}
+
 func eval_ParameterizedTypeExpression(e eval.Evaluator, qr *parser.QualifiedReference, args []eval.Value, expr *parser.AccessExpression) (tp eval.Type) {
 	defer func() {
 		if err := recover(); err != nil {
@@ -0,0 +1,414 @@
+package impl
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+type (
+	catalog struct {
+		lock       sync.RWMutex
+		resources  []eval.Resource
+		index      map[string]int
+		virtual    map[string]bool
+		exported   map[string]bool
+		imported   map[string]bool
+		realized   []eval.ResourceReference
+		overrides  []*pendingOverride
+		collectors []eval.Collector
+		edges      []eval.Edge
+	}
+
+	// pendingOverride is an override of a resource that hasn't been added yet
+	pendingOverride struct {
+		ref      eval.ResourceReference
+		override func(r eval.Resource) eval.OrderedMap
+	}
+
+	edge struct {
+		source    eval.ResourceReference
+		target    eval.ResourceReference
+		subscribe bool
+	}
+)
+
+var Catalog_Type eval.Type
+var Edge_Type eval.Type
+
+func init() {
+	Edge_Type = eval.NewObjectType(`Catalog::Edge`, `{
+    attributes => {
+      'source' => Type[Resource],
+      'target' => Type[Resource],
+      'subscribe' => { type => Boolean, value => false },
+    }
+  }`, func(ctx eval.Context, args []eval.Value) eval.Value {
+		subscribe := false
+		if len(args) > 2 {
+			subscribe = args[2].(eval.BooleanValue).Bool()
+		}
+		return &edge{args[0].(eval.ResourceReference), args[1].(eval.ResourceReference), subscribe}
+	}, func(ctx eval.Context, args []eval.Value) eval.Value {
+		h := args[0].(*types.HashValue)
+		subscribe := h.Get5(`subscribe`, types.BooleanFalse).(eval.BooleanValue).Bool()
+		return &edge{h.Get5(`source`, nil).(eval.ResourceReference), h.Get5(`target`, nil).(eval.ResourceReference), subscribe}
+	})
+
+	Catalog_Type = eval.NewObjectType(`Catalog`, `{
+    attributes => {
+      'resources' => { type => Array[Catalog::Resource], value => [] },
+      'edges' => { type => Array[Catalog::Edge], value => [] },
+    }
+  }`, func(ctx eval.Context, args []eval.Value) eval.Value {
+		c := newCatalog()
+		if len(args) > 0 {
+			c.addAll(args[0].(eval.List), eval.EMPTY_ARRAY)
+			if len(args) > 1 {
+				c.addAll(eval.EMPTY_ARRAY, args[1].(eval.List))
+			}
+		}
+		return c
+	}, func(ctx eval.Context, args []eval.Value) eval.Value {
+		h := args[0].(*types.HashValue)
+		c := newCatalog()
+		c.addAll(h.Get5(`resources`, eval.EMPTY_ARRAY).(eval.List), h.Get5(`edges`, eval.EMPTY_ARRAY).(eval.List))
+		return c
+	})
+
+	eval.NewCatalog = func() eval.Catalog {
+		return newCatalog()
+	}
+}
+
+func newCatalog() *catalog {
+	return &catalog{
+		resources: make([]eval.Resource, 0, 16),
+		index:     make(map[string]int, 16),
+		virtual:   make(map[string]bool),
+		exported:  make(map[string]bool),
+		imported:  make(map[string]bool),
+		edges:     make([]eval.Edge, 0, 8)}
+}
+
+func resourceKey(typeName, title string) string {
+	return strings.ToLower(typeName) + `[` + title + `]`
+}
+
+func (c *catalog) AddEdge(source, target eval.ResourceReference, subscribe bool) {
+	c.lock.Lock()
+	c.edges = append(c.edges, &edge{source, target, subscribe})
+	c.lock.Unlock()
+}
+
+func (c *catalog) AddResource(r eval.Resource) {
+	c.add(r, false, false, false)
+}
+
+func (c *catalog) AddVirtualResource(r eval.Resource, exported bool) {
+	c.add(r, true, exported, false)
+}
+
+func (c *catalog) ImportResource(r eval.Resource) {
+	c.add(r, true, false, true)
+}
+
+func (c *catalog) add(r eval.Resource, virtual, exported, imported bool) {
+	key := resourceKey(r.TypeName(), r.Title())
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if idx, ok := c.index[key]; ok {
+		old := c.resources[idx]
+		location := r.Origin()
+		if location == nil {
+			location = eval.StackTop()
+		}
+		panic(evalError(eval.EVAL_DUPLICATE_RESOURCE, location, issue.H{`ref`: r.Reference().String(), `origin`: locationString(old.Origin())}))
+	}
+	idx := len(c.resources)
+	c.index[key] = idx
+	c.resources = append(c.resources, r)
+	if exported {
+		c.exported[key] = true
+	}
+	if imported {
+		c.imported[key] = true
+	}
+	if virtual {
+		c.virtual[key] = true
+		for _, ref := range c.realized {
+			if resourceKey(ref.TypeName(), ref.Title()) == key {
+				delete(c.virtual, key)
+				break
+			}
+		}
+	}
+	// The overrides are filtered into a new slice since c.override may panic
+	pending := make([]*pendingOverride, 0, len(c.overrides))
+	for _, po := range c.overrides {
+		if resourceKey(po.ref.TypeName(), po.ref.Title()) == key {
+			c.override(idx, po.override)
+		} else {
+			pending = append(pending, po)
+		}
+	}
+	c.overrides = pending
+	for _, cl := range c.collectors {
+		c.collect(cl, idx)
+	}
+}
+
+func (c *catalog) Collect(cl eval.Collector) []eval.ResourceReference {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.collectors = append(c.collectors, cl)
+	refs := make([]eval.ResourceReference, 0)
+	for idx := range c.resources {
+		if c.collect(cl, idx) {
+			refs = append(refs, c.resources[idx].Reference())
+		}
+	}
+	return refs
+}
+
+// collect realizes the resource at the given index and applies the overrides of the given
+// collector to it if the collector matches the resource. The lock must be held by the caller.
+func (c *catalog) collect(cl eval.Collector, idx int) bool {
+	r := c.resources[idx]
+	key := resourceKey(r.TypeName(), r.Title())
+	if !strings.EqualFold(r.TypeName(), cl.TypeName()) || cl.Exported() && !(c.exported[key] || c.imported[key]) || !cl.Matches(r) {
+		return false
+	}
+	delete(c.virtual, key)
+	if overrides := cl.Overrides(); !overrides.IsEmpty() {
+		c.resources[idx] = newResource(r.TypeName(), r.Title(), r.Parameters().Merge(overrides), r.Container(), r.Origin())
+	}
+	return true
+}
+
+func (c *catalog) Contain(container, contained eval.ResourceReference) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	idx, ok := c.index[resourceKey(contained.TypeName(), contained.Title())]
+	if !ok {
+		panic(eval.Error(eval.EVAL_UNRESOLVED_RESOURCE_REFERENCE, issue.H{`ref`: contained.String()}))
+	}
+	r := c.resources[idx]
+	c.resources[idx] = newResource(r.TypeName(), r.Title(), r.Parameters(), container, r.Origin())
+}
+
+func (c *catalog) Edges() []eval.Edge {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	es := make([]eval.Edge, len(c.edges))
+	copy(es, c.edges)
+	return es
+}
+
+func (c *catalog) ExportedResources() []eval.Resource {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.filter(func(key string) bool { return c.exported[key] })
+}
+
+func (c *catalog) Equals(other interface{}, guard eval.Guard) bool {
+	return c == other
+}
+
+func (c *catalog) Get(key string) (value eval.Value, ok bool) {
+	switch key {
+	case `resources`:
+		return c.resourceList(), true
+	case `edges`:
+		return c.edgeList(), true
+	}
+	return nil, false
+}
+
+func (c *catalog) InitHash() eval.OrderedMap {
+	es := make([]*types.HashEntry, 0, 2)
+	if rs := c.resourceList(); !rs.IsEmpty() {
+		es = append(es, types.WrapHashEntry2(`resources`, rs))
+	}
+	if eds := c.edgeList(); !eds.IsEmpty() {
+		es = append(es, types.WrapHashEntry2(`edges`, eds))
+	}
+	return types.WrapHash(es)
+}
+
+func (c *catalog) PType() eval.Type {
+	return Catalog_Type
+}
+
+func (c *catalog) Override(ref eval.ResourceReference, override func(r eval.Resource) eval.OrderedMap) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if idx, ok := c.index[resourceKey(ref.TypeName(), ref.Title())]; ok {
+		c.override(idx, override)
+	} else {
+		c.overrides = append(c.overrides, &pendingOverride{ref, override})
+	}
+}
+
+func (c *catalog) override(idx int, override func(r eval.Resource) eval.OrderedMap) {
+	r := c.resources[idx]
+	c.resources[idx] = newResource(r.TypeName(), r.Title(), override(r), r.Container(), r.Origin())
+}
+
+func (c *catalog) Realize(ref eval.ResourceReference) {
+	c.lock.Lock()
+	c.realized = append(c.realized, ref)
+	delete(c.virtual, resourceKey(ref.TypeName(), ref.Title()))
+	c.lock.Unlock()
+}
+
+func (c *catalog) Resource(typeName, title string) (eval.Resource, bool) {
+	key := resourceKey(typeName, title)
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if idx, ok := c.index[key]; ok && !c.virtual[key] {
+		return c.resources[idx], true
+	}
+	return nil, false
+}
+
+func (c *catalog) Resources() []eval.Resource {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.filter(func(key string) bool { return !c.virtual[key] })
+}
+
+func (c *catalog) String() string {
+	return eval.ToString(c)
+}
+
+func (c *catalog) ToString(bld io.Writer, format eval.FormatContext, g eval.RDetect) {
+	types.ObjectToString(c, format, bld, g)
+}
+
+func (c *catalog) Validate() {
+	c.lock.RLock()
+	realized := make([]eval.ResourceReference, len(c.realized))
+	copy(realized, c.realized)
+	for _, po := range c.overrides {
+		realized = append(realized, po.ref)
+	}
+	c.lock.RUnlock()
+	for _, ref := range realized {
+		if _, ok := c.Resource(ref.TypeName(), ref.Title()); !ok {
+			panic(eval.Error(eval.EVAL_UNRESOLVED_RESOURCE_REFERENCE, issue.H{`ref`: ref.String()}))
+		}
+	}
+	for _, e := range c.Edges() {
+		for _, ref := range []eval.ResourceReference{e.Source(), e.Target()} {
+			if _, ok := c.Resource(ref.TypeName(), ref.Title()); !ok {
+				panic(eval.Error(eval.EVAL_UNRESOLVED_RESOURCE_REFERENCE, issue.H{`ref`: ref.String()}))
+			}
+		}
+	}
+}
+
+func (c *catalog) VirtualResources() []eval.Resource {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.filter(func(key string) bool { return c.virtual[key] })
+}
+
+func (c *catalog) addAll(resources, edges eval.List) {
+	resources.Each(func(r eval.Value) { c.AddResource(r.(eval.Resource)) })
+	edges.Each(func(e eval.Value) { c.edges = append(c.edges, e.(eval.Edge)) })
+}
+
+// filter returns the resources whose keys are accepted by the given function. The lock must be
+// held by the caller.
+func (c *catalog) filter(accept func(key string) bool) []eval.Resource {
+	rs := make([]eval.Resource, 0, len(c.resources))
+	for _, r := range c.resources {
+		if accept(resourceKey(r.TypeName(), r.Title())) {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+func (c *catalog) edgeList() eval.List {
+	es := c.Edges()
+	vs := make([]eval.Value, len(es))
+	for i, e := range es {
+		vs[i] = e
+	}
+	return types.WrapValues(vs)
+}
+
+func (c *catalog) resourceList() eval.List {
+	rs := c.Resources()
+	vs := make([]eval.Value, len(rs))
+	for i, r := range rs {
+		vs[i] = r
+	}
+	return types.WrapValues(vs)
+}
+
+func (e *edge) Equals(other interface{}, guard eval.Guard) bool {
+	if oe, ok := other.(*edge); ok {
+		return e.subscribe == oe.subscribe && e.source.Equals(oe.source, guard) && e.target.Equals(oe.target, guard)
+	}
+	return false
+}
+
+func (e *edge) Get(key string) (value eval.Value, ok bool) {
+	switch key {
+	case `source`:
+		return e.source, true
+	case `target`:
+		return e.target, true
+	case `subscribe`:
+		return types.WrapBoolean(e.subscribe), true
+	}
+	return nil, false
+}
+
+func (e *edge) InitHash() eval.OrderedMap {
+	es := make([]*types.HashEntry, 0, 3)
+	es = append(es, types.WrapHashEntry2(`source`, e.source))
+	es = append(es, types.WrapHashEntry2(`target`, e.target))
+	if e.subscribe {
+		es = append(es, types.WrapHashEntry2(`subscribe`, types.BooleanTrue))
+	}
+	return types.WrapHash(es)
+}
+
+func (e *edge) PType() eval.Type {
+	return Edge_Type
+}
+
+func (e *edge) Source() eval.ResourceReference {
+	return e.source
+}
+
+func (e *edge) String() string {
+	return eval.ToString(e)
+}
+
+func (e *edge) Subscribe() bool {
+	return e.subscribe
+}
+
+func (e *edge) Target() eval.ResourceReference {
+	return e.target
+}
+
+func (e *edge) ToString(bld io.Writer, format eval.FormatContext, g eval.RDetect) {
+	types.ObjectToString(e, format, bld, g)
+}
+
+func locationString(location issue.Location) string {
+	if location == nil {
+		return `unknown location`
+	}
+	return issue.LocationString(location)
+}
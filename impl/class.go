@@ -0,0 +1,257 @@
+package impl
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	puppetResourceDefinition struct {
+		expression parser.NamedDefinition
+		parameters []eval.Parameter
+	}
+
+	puppetClass struct {
+		puppetResourceDefinition
+	}
+
+	puppetDefinedType struct {
+		puppetResourceDefinition
+	}
+)
+
+const classScopesKey = `puppet.classScopes`
+
+// metaParameters are accepted by all classes and defined types without being declared as parameters
+var metaParameters = map[string]bool{
+	`alias`:     true,
+	`audit`:     true,
+	`before`:    true,
+	`loglevel`:  true,
+	`noop`:      true,
+	`notify`:    true,
+	`require`:   true,
+	`schedule`:  true,
+	`stage`:     true,
+	`subscribe`: true,
+	`tag`:       true,
+}
+
+func init() {
+	eval.DeclareClass = func(c eval.Context, name string, parameters eval.OrderedMap) eval.ResourceReference {
+		return declareClass(c, c.StackTop(), name, parameters)
+	}
+}
+
+func NewPuppetClass(expr *parser.HostClassDefinition) *puppetClass {
+	return &puppetClass{puppetResourceDefinition{expression: expr}}
+}
+
+func NewPuppetDefinedType(expr *parser.ResourceTypeDefinition) *puppetDefinedType {
+	return &puppetDefinedType{puppetResourceDefinition{expression: expr}}
+}
+
+func (d *puppetResourceDefinition) Equals(other interface{}, guard eval.Guard) bool {
+	return d == other
+}
+
+func (d *puppetResourceDefinition) Expression() parser.Definition {
+	return d.expression
+}
+
+func (d *puppetResourceDefinition) Name() string {
+	return d.expression.Name()
+}
+
+func (d *puppetResourceDefinition) Parameters() []eval.Parameter {
+	return d.parameters
+}
+
+func (d *puppetResourceDefinition) PType() eval.Type {
+	return types.DefaultAnyType()
+}
+
+func (d *puppetResourceDefinition) Resolve(c eval.Context) {
+	if d.parameters != nil {
+		panic(fmt.Sprintf(`Attempt to resolve already resolved definition %s`, d.Name()))
+	}
+	d.parameters = ResolveParameters(c, d.expression.Parameters())
+}
+
+// evaluate evaluates the body of the definition in the given scope after binding the given arguments. The
+// title and name variables are assigned before the parameters so that they can be used in default expressions.
+func (d *puppetResourceDefinition) evaluate(c eval.Context, scope eval.Scope, ref eval.ResourceReference, name string, args eval.OrderedMap, location issue.Location) {
+	label := ref.String()
+	ps, vs := namedArguments(label, location, d.parameters, args, metaParameters)
+	withContainer(c, ref, func() {
+		withResourceDefaults(c, func() {
+			c.DoWithScope(scope, func() {
+				scope.Set(`title`, types.WrapString(ref.Title()))
+				scope.Set(`name`, types.WrapString(name))
+				BindParameters(c, label, ps, vs)
+				if body := d.expression.Body(); body != nil {
+					eval.Evaluate(c, body)
+				}
+			})
+		})
+	})
+}
+
+func (c *puppetClass) ParentName() string {
+	return c.expression.(*parser.HostClassDefinition).ParentClass()
+}
+
+func (c *puppetClass) String() string {
+	return eval.ToString(c)
+}
+
+func (c *puppetClass) ToString(bld io.Writer, format eval.FormatContext, g eval.RDetect) {
+	io.WriteString(bld, `class `)
+	io.WriteString(bld, c.Name())
+}
+
+func (d *puppetDefinedType) String() string {
+	return eval.ToString(d)
+}
+
+func (d *puppetDefinedType) ToString(bld io.Writer, format eval.FormatContext, g eval.RDetect) {
+	io.WriteString(bld, `define `)
+	io.WriteString(bld, d.Name())
+}
+
+// declareClass declares the class with the given name. A nil parameters argument indicates an include-like
+// declaration that is ignored when the class has already been declared.
+func declareClass(c eval.Context, location issue.Location, name string, parameters eval.OrderedMap) eval.ResourceReference {
+	ref := types.NewResourceType(`Class`, name)
+	cat := eval.CatalogOf(c)
+	if _, ok := cat.Resource(ref.TypeName(), ref.Title()); ok && parameters == nil {
+		return ref
+	}
+
+	name = strings.ToLower(strings.TrimPrefix(name, `::`))
+	var cls *puppetClass
+	if cv, ok := eval.Load(c, eval.NewTypedName(eval.NsClass, name)); ok {
+		cls = cv.(*puppetClass)
+	} else {
+		panic(evalError(eval.EVAL_UNKNOWN_CLASS, location, issue.H{`name`: name}))
+	}
+
+	var parentScope eval.Scope
+	if pn := cls.ParentName(); pn != `` {
+		declareClass(c, location, pn, nil)
+		parentScope = classScopes(c)[strings.ToLower(strings.TrimPrefix(pn, `::`))]
+	}
+
+	if parameters == nil {
+		parameters = eval.EMPTY_MAP
+	}
+	parameters = lookupParameters(c, name, cls, parameters)
+	cat.AddResource(newResource(ref.TypeName(), ref.Title(), parameters, nil, location))
+
+	scope := newNamedScope(c.Scope(), parentScope, name)
+	classScopes(c)[name] = scope
+	cls.evaluate(c, scope, ref, name, parameters, location)
+	return ref
+}
+
+// declareDefinedType adds a resource of the given defined type to the catalog and evaluates the body
+// of the defined type.
+func declareDefinedType(c eval.Context, location issue.Location, dt *puppetDefinedType, title string, parameters eval.OrderedMap) eval.ResourceReference {
+	r := newResource(dt.Name(), title, parameters, eval.ContainerOf(c), location)
+	eval.CatalogOf(c).AddResource(r)
+
+	name := title
+	if nv, ok := parameters.Get4(`name`); ok {
+		name = nv.String()
+		parameters = parameters.RejectPairs(func(k, v eval.Value) bool { return k.String() == `name` })
+	}
+	ref := r.Reference()
+	dt.evaluate(c, newNamedScope(c.Scope(), nil, ``), ref, name, parameters, location)
+	return ref
+}
+
+// classScopes returns the map of scopes of evaluated classes that is stored in the given context
+func classScopes(c eval.Context) map[string]eval.Scope {
+	if sv, ok := c.Get(classScopesKey); ok {
+		return sv.(map[string]eval.Scope)
+	}
+	scopes := make(map[string]eval.Scope)
+	c.Set(classScopesKey, scopes)
+	return scopes
+}
+
+// lookupParameters returns the given parameters extended with the values that the data binding provides
+// for the parameters of the class that are not present in the given parameters
+func lookupParameters(c eval.Context, name string, cls *puppetClass, parameters eval.OrderedMap) eval.OrderedMap {
+	entries := make([]*types.HashEntry, 0)
+	for _, p := range cls.Parameters() {
+		if !parameters.IncludesKey2(p.Name()) {
+			if v, ok := eval.Lookup(c, name+`::`+p.Name(), nil); ok {
+				entries = append(entries, types.WrapHashEntry2(p.Name(), v))
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return parameters
+	}
+	return parameters.Merge(types.WrapHash(entries))
+}
+
+// namedArguments validates the given arguments against the given parameters and returns the parameters
+// and the arguments in an order suitable for BindParameters, i.e. with all parameters that will be assigned
+// their default value last. Arguments named in accepted are allowed without being declared as parameters
+// and are not returned.
+func namedArguments(label string, location issue.Location, parameters []eval.Parameter, args eval.OrderedMap, accepted map[string]bool) ([]eval.Parameter, []eval.Value) {
+	args.EachKey(func(k eval.Value) {
+		pn := k.String()
+		if accepted[pn] {
+			return
+		}
+		for _, p := range parameters {
+			if p.Name() == pn {
+				return
+			}
+		}
+		panic(evalError(eval.EVAL_UNKNOWN_PARAMETER, location, issue.H{`label`: label, `name`: pn}))
+	})
+
+	ps := make([]eval.Parameter, 0, len(parameters))
+	vs := make([]eval.Value, 0, len(parameters))
+	defaulted := make([]eval.Parameter, 0, len(parameters))
+	for _, p := range parameters {
+		if v, ok := args.Get4(p.Name()); ok {
+			if !eval.IsInstance(p.Type(), v) {
+				panic(evalError(eval.EVAL_TYPE_MISMATCH, location, issue.H{
+					`detail`: eval.DescribeMismatch(fmt.Sprintf(`%s parameter '%s'`, label, p.Name()), p.Type(), eval.DetailedValueType(v))}))
+			}
+			ps = append(ps, p)
+			vs = append(vs, v)
+			continue
+		}
+		if !p.HasValue() {
+			panic(evalError(eval.EVAL_MISSING_REQUIRED_PARAMETER, location, issue.H{`label`: label, `name`: p.Name()}))
+		}
+		defaulted = append(defaulted, p)
+	}
+	return append(ps, defaulted...), vs
+}
+
+// withContainer makes the given resource the container of all resources that are declared by the doer
+func withContainer(c eval.Context, container eval.ResourceReference, doer eval.Doer) {
+	old, hadOld := c.Get(eval.ContainerKey)
+	c.Set(eval.ContainerKey, container)
+	defer func() {
+		if hadOld {
+			c.Set(eval.ContainerKey, old)
+		} else {
+			c.Delete(eval.ContainerKey)
+		}
+	}()
+	doer()
+}
@@ -0,0 +1,120 @@
+package impl
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type collector struct {
+	typeName  string
+	exported  bool
+	query     func(r eval.Resource) bool
+	overrides eval.OrderedMap
+}
+
+func (c *collector) Exported() bool {
+	return c.exported
+}
+
+func (c *collector) Matches(r eval.Resource) bool {
+	return c.query(r)
+}
+
+func (c *collector) Overrides() eval.OrderedMap {
+	return c.overrides
+}
+
+func (c *collector) TypeName() string {
+	return c.typeName
+}
+
+func evalCollectExpression(e eval.Evaluator, expr *parser.CollectExpression) eval.Value {
+	typeName := resourceTypeName(e, expr.ResourceType())
+	_, exported := expr.Query().(*parser.ExportedQuery)
+
+	cat := eval.CatalogOf(e)
+	if exported {
+		importResources(e, expr, cat, typeName)
+	}
+
+	cl := &collector{typeName, exported, evalQuery(e, expr.Query().(parser.QueryExpression).Expr()), evalAttributeOperations(e, expr.Operations())}
+	refs := cat.Collect(cl)
+	vs := make([]eval.Value, len(refs))
+	for i, ref := range refs {
+		vs[i] = ref
+	}
+	return types.WrapValues(vs)
+}
+
+// importResources adds the resources of the given type that are found in the export store of the
+// given evaluator, and not already declared, to the catalog as imported resources
+func importResources(e eval.Evaluator, expr parser.Expression, cat eval.Catalog, typeName string) {
+	store := eval.ExportStoreOf(e)
+	if store == nil {
+		return
+	}
+	declared := make(map[string]bool)
+	for _, r := range append(cat.Resources(), cat.VirtualResources()...) {
+		declared[resourceKey(r.TypeName(), r.Title())] = true
+	}
+	for _, r := range store.Import(e, typeName) {
+		if !declared[resourceKey(r.TypeName(), r.Title())] {
+			cat.ImportResource(newResource(r.TypeName(), r.Title(), r.Parameters(), nil, expr))
+		}
+	}
+}
+
+// evalQuery evaluates the values of the given collector query and returns a function that tests
+// if a resource matches the query. A Nop query matches all resources.
+func evalQuery(e eval.Evaluator, expr parser.Expression) func(r eval.Resource) bool {
+	switch expr.(type) {
+	case *parser.Nop:
+		return func(r eval.Resource) bool { return true }
+	case *parser.ParenthesizedExpression:
+		return evalQuery(e, expr.(*parser.ParenthesizedExpression).Expr())
+	case *parser.AndExpression:
+		ae := expr.(*parser.AndExpression)
+		lhs, rhs := evalQuery(e, ae.Lhs()), evalQuery(e, ae.Rhs())
+		return func(r eval.Resource) bool { return lhs(r) && rhs(r) }
+	case *parser.OrExpression:
+		oe := expr.(*parser.OrExpression)
+		lhs, rhs := evalQuery(e, oe.Lhs()), evalQuery(e, oe.Rhs())
+		return func(r eval.Resource) bool { return lhs(r) || rhs(r) }
+	case *parser.ComparisonExpression:
+		ce := expr.(*parser.ComparisonExpression)
+		if qn, ok := ce.Lhs().(*parser.QualifiedName); ok {
+			name := qn.Name()
+			value := e.Eval(ce.Rhs())
+			switch ce.Operator() {
+			case `==`:
+				return func(r eval.Resource) bool { return attributeMatches(r, name, value) }
+			case `!=`:
+				return func(r eval.Resource) bool { return !attributeMatches(r, name, value) }
+			}
+		}
+	}
+	panic(evalError(eval.EVAL_ILLEGAL_QUERY_EXPRESSION, expr, issue.H{`expression`: expr}))
+}
+
+// attributeMatches returns true if the named attribute of the given resource is equal to the given
+// value. An array attribute matches when one of its elements is equal to the value.
+func attributeMatches(r eval.Resource, name string, value eval.Value) bool {
+	var av eval.Value
+	if name == `title` {
+		av = types.WrapString(r.Title())
+	} else {
+		var ok bool
+		if av, ok = r.Parameters().Get4(name); !ok {
+			return false
+		}
+	}
+	if eval.Equals(av, value) {
+		return true
+	}
+	if a, ok := av.(*types.ArrayValue); ok {
+		return a.Any(func(ev eval.Value) bool { return eval.Equals(ev, value) })
+	}
+	return false
+}
@@ -2,11 +2,11 @@ package impl
 
 import (
 	"fmt"
-	"regexp"
 	"strings"
 
 	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/regex"
 	"github.com/lyraproj/puppet-evaluator/types"
 	"github.com/lyraproj/puppet-parser/parser"
 	"github.com/lyraproj/semver/semver"
@@ -16,6 +16,17 @@ func init() {
 	eval.PuppetMatch = func(c eval.Context, a, b eval.Value) bool {
 		return match(c, nil, nil, `=~`, false, a, b)
 	}
+
+	eval.PuppetCompare = func(a, b eval.Value, caseSensitive bool) int {
+		location := eval.StackTop()
+		switch {
+		case compareMagnitude(location, `<`, a, b, caseSensitive):
+			return -1
+		case compareMagnitude(location, `>`, a, b, caseSensitive):
+			return 1
+		}
+		return 0
+	}
 }
 
 func evalComparisonExpression(e eval.Evaluator, expr *parser.ComparisonExpression) eval.Value {
@@ -43,7 +54,7 @@ func compare(expr parser.Expression, op string, a eval.Value, b eval.Value) bool
 	return result
 }
 
-func compareMagnitude(expr parser.Expression, op string, a eval.Value, b eval.Value, caseSensitive bool) bool {
+func compareMagnitude(expr issue.Location, op string, a eval.Value, b eval.Value, caseSensitive bool) bool {
 	switch a.(type) {
 	case eval.Type:
 		left := a.(eval.Type)
@@ -135,15 +146,15 @@ func match(c eval.Context, lhs parser.Expression, rhs parser.Expression, operato
 		result = eval.IsInstance(b.(eval.Type), a)
 
 	case eval.StringValue, *types.RegexpValue:
-		var rx *regexp.Regexp
+		var rx regex.Regexp
 		if s, ok := b.(eval.StringValue); ok {
 			var err error
-			rx, err = regexp.Compile(s.String())
+			rx, err = types.CompileRegexp(s.String())
 			if err != nil {
 				panic(eval.Error2(rhs, eval.EVAL_MATCH_NOT_REGEXP, issue.H{`detail`: err.Error()}))
 			}
 		} else {
-			rx = b.(*types.RegexpValue).Regexp()
+			rx = b.(*types.RegexpValue).Matcher()
 		}
 
 		sv, ok := a.(eval.StringValue)
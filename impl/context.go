@@ -211,6 +211,12 @@ func (c *evalCtx) Logger() eval.Logger {
 }
 
 func (c *evalCtx) ParseAndValidate(filename, str string, singleExpression bool) parser.Expression {
+	return parseAndValidate(c, filename, str, singleExpression)
+}
+
+// parseAndValidate parses the given string using the parser options given by the current settings
+// together with the given extra options and validates the result
+func parseAndValidate(c eval.Context, filename, str string, singleExpression bool, extraOptions ...parser.Option) parser.Expression {
 	var parserOptions []parser.Option
 	if eval.GetSetting(`workflow`, types.BooleanFalse).(eval.BooleanValue).Bool() {
 		parserOptions = append(parserOptions, parser.PARSER_WORKFLOW_ENABLED)
@@ -218,17 +224,18 @@ func (c *evalCtx) ParseAndValidate(filename, str string, singleExpression bool)
 	if eval.GetSetting(`tasks`, types.BooleanFalse).(eval.BooleanValue).Bool() {
 		parserOptions = append(parserOptions, parser.PARSER_TASKS_ENABLED)
 	}
+	parserOptions = append(parserOptions, extraOptions...)
 	expr, err := parser.CreateParser(parserOptions...).Parse(filename, str, singleExpression)
 	if err != nil {
 		panic(err)
 	}
 	checker := validator.NewChecker(validator.STRICT_ERROR)
-	checker.Validate(expr)
+	validator.Validate(checker, expr)
 	issues := checker.Issues()
 	if len(issues) > 0 {
 		severity := issue.SEVERITY_IGNORE
 		for _, i := range issues {
-			c.Logger().Log(eval.LogLevel(i.Severity()), types.WrapString(i.String()))
+			c.Logger().LogIssue(i)
 			if i.Severity() > severity {
 				severity = i.Severity()
 			}
@@ -237,6 +244,9 @@ func (c *evalCtx) ParseAndValidate(filename, str string, singleExpression bool)
 			panic(c.Fail(fmt.Sprintf(`Error validating %s`, filename)))
 		}
 	}
+	if cv := eval.CoverageOf(c); cv != nil {
+		cv.Parsed(expr)
+	}
 	return expr
 }
 
@@ -381,10 +391,28 @@ func (c *evalCtx) define(loader eval.DefiningLoader, d parser.Definition) {
 		fe := d.(*parser.FunctionDefinition)
 		tn = eval.NewTypedName2(eval.NsFunction, fe.Name(), loader.NameAuthority())
 		ta = NewPuppetFunction(fe)
+	case *parser.HostClassDefinition:
+		ce := d.(*parser.HostClassDefinition)
+		tn = eval.NewTypedName2(eval.NsClass, ce.Name(), loader.NameAuthority())
+		ta = NewPuppetClass(ce)
+	case *parser.ResourceTypeDefinition:
+		de := d.(*parser.ResourceTypeDefinition)
+		tn = eval.NewTypedName2(eval.NsDefinedType, de.Name(), loader.NameAuthority())
+		ta = NewPuppetDefinedType(de)
+	case *parser.NodeDefinition:
+		// Nodes have no names and are therefore not registered with the loader
+		node := NewPuppetNode(d.(*parser.NodeDefinition))
+		addNode(c, node)
+		c.addDefinition(node)
+		return
 	default:
 		ta, tn = types.CreateTypeDefinition(d, loader.NameAuthority())
 	}
 	loader.SetEntry(tn, eval.NewLoaderEntry(ta, d))
+	c.addDefinition(ta)
+}
+
+func (c *evalCtx) addDefinition(ta interface{}) {
 	if c.definitions == nil {
 		c.definitions = []interface{}{ta}
 	} else {
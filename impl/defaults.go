@@ -0,0 +1,61 @@
+package impl
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+const resourceDefaultsKey = `puppet.resourceDefaults`
+
+// resourceDefaults holds the resource defaults that are declared in one dynamic scope, i.e. at the
+// top level or in the body of a class or defined type. The defaults of a scope apply to resources
+// that are declared after them in the same scope or in scopes that are entered from that scope.
+type resourceDefaults struct {
+	parent   *resourceDefaults
+	defaults map[string]eval.OrderedMap
+}
+
+func evalResourceDefaultsExpression(e eval.Evaluator, expr *parser.ResourceDefaultsExpression) eval.Value {
+	key := strings.ToLower(resourceTypeName(e, expr.TypeRef()))
+	params := evalAttributeOperations(e, expr.Operations())
+	rd := currentResourceDefaults(e)
+	if old, ok := rd.defaults[key]; ok {
+		params = old.Merge(params)
+	}
+	rd.defaults[key] = params
+	return eval.UNDEF
+}
+
+// currentResourceDefaults returns the resource defaults of the current dynamic scope
+func currentResourceDefaults(c eval.Context) *resourceDefaults {
+	if rv, ok := c.Get(resourceDefaultsKey); ok {
+		return rv.(*resourceDefaults)
+	}
+	rd := &resourceDefaults{defaults: make(map[string]eval.OrderedMap)}
+	c.Set(resourceDefaultsKey, rd)
+	return rd
+}
+
+// scopedResourceDefaults returns the resource defaults for the given resource type that are in
+// effect in the current dynamic scope. Defaults of inner scopes override those of outer scopes.
+func scopedResourceDefaults(c eval.Context, typeName string) eval.OrderedMap {
+	key := strings.ToLower(typeName)
+	params := eval.EMPTY_MAP
+	for rd := currentResourceDefaults(c); rd != nil; rd = rd.parent {
+		if dp, ok := rd.defaults[key]; ok {
+			params = dp.Merge(params)
+		}
+	}
+	return params
+}
+
+// withResourceDefaults enters a new dynamic scope for resource defaults and calls the doer. The scope
+// inherits the defaults of the current scope.
+func withResourceDefaults(c eval.Context, doer eval.Doer) {
+	parent := currentResourceDefaults(c)
+	c.Set(resourceDefaultsKey, &resourceDefaults{parent: parent, defaults: make(map[string]eval.OrderedMap)})
+	defer c.Set(resourceDefaultsKey, parent)
+	doer()
+}
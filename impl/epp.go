@@ -0,0 +1,69 @@
+package impl
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+const eppBufferKey = `puppet.eppBuffer`
+
+func init() {
+	eval.EvaluateEpp = func(c eval.Context, filename, template string, arguments eval.OrderedMap) string {
+		expr := parseAndValidate(c, filename, template, false, parser.PARSER_EPP_MODE)
+		lambda := expr.(*parser.Program).Body().(*parser.LambdaExpression)
+		label := fmt.Sprintf(`EPP template '%s'`, filename)
+
+		var result eval.Value
+		scope := newNamedScope(c.Scope(), nil, ``)
+		c.DoWithScope(scope, func() {
+			if len(lambda.Parameters()) > 0 {
+				ps, vs := namedArguments(label, lambda, ResolveParameters(c, lambda.Parameters()), arguments, nil)
+				BindParameters(c, label, ps, vs)
+			} else {
+				// A template without parameters sees all arguments as local variables
+				arguments.EachPair(func(k, v eval.Value) { scope.Set(k.String(), v) })
+			}
+			result = eval.Evaluate(c, lambda.Body())
+		})
+		return result.String()
+	}
+}
+
+// evalEppExpression evaluates the body of the EPP expression and returns the text that it renders
+func evalEppExpression(e eval.Evaluator, expr *parser.EppExpression) eval.Value {
+	old, hadOld := e.Get(eppBufferKey)
+	buf := bytes.NewBufferString(``)
+	e.Set(eppBufferKey, buf)
+	defer func() {
+		if hadOld {
+			e.Set(eppBufferKey, old)
+		} else {
+			e.Delete(eppBufferKey)
+		}
+	}()
+	e.Eval(expr.Body())
+	return types.WrapString(buf.String())
+}
+
+func evalRenderExpression(e eval.Evaluator, expr *parser.RenderExpression) eval.Value {
+	eppBuffer(e, expr).WriteString(e.Eval(expr.Expr()).String())
+	return eval.UNDEF
+}
+
+func evalRenderStringExpression(e eval.Evaluator, expr *parser.RenderStringExpression) eval.Value {
+	eppBuffer(e, expr).WriteString(expr.StringValue())
+	return eval.UNDEF
+}
+
+// eppBuffer returns the buffer of the EPP expression that is currently being evaluated
+func eppBuffer(e eval.Evaluator, expr parser.Expression) *bytes.Buffer {
+	if buf, ok := e.Get(eppBufferKey); ok {
+		return buf.(*bytes.Buffer)
+	}
+	panic(evalError(eval.EVAL_RENDER_OUTSIDE_EPP, expr, issue.NO_ARGS))
+}
@@ -253,8 +253,10 @@ func evalCallNamedFunctionExpression(e eval.Evaluator, call *parser.CallNamedFun
 func evalIfExpression(e eval.Evaluator, expr *parser.IfExpression) eval.Value {
 	return e.Scope().WithLocalScope(func() eval.Value {
 		if eval.IsTruthy(e.Eval(expr.Test())) {
+			branchTaken(e, expr, 0)
 			return e.Eval(expr.Then())
 		}
+		branchTaken(e, expr, 1)
 		return e.Eval(expr.Else())
 	})
 }
@@ -278,8 +280,10 @@ func evalInExpression(e eval.Evaluator, expr *parser.InExpression) eval.Value {
 func evalUnlessExpression(e eval.Evaluator, expr *parser.UnlessExpression) eval.Value {
 	return e.Scope().WithLocalScope(func() eval.Value {
 		if !eval.IsTruthy(e.Eval(expr.Test())) {
+			branchTaken(e, expr, 0)
 			return e.Eval(expr.Then())
 		}
+		branchTaken(e, expr, 1)
 		return e.Eval(expr.Else())
 	})
 }
@@ -351,9 +355,33 @@ func evalProgram(e eval.Evaluator, expr *parser.Program) eval.Value {
 	defer func() {
 		e.StackPop()
 	}()
+	if p := eval.ProfilerOf(e); p != nil {
+		return profileProgram(e, p, expr.Body())
+	}
 	return e.Eval(expr.Body())
 }
 
+// profileProgram evaluates the expressions at the top level of a program one by one so that each
+// of them is profiled. Definitions are not profiled since their evaluation is a no-op.
+func profileProgram(e eval.Evaluator, p eval.Profiler, body parser.Expression) (result eval.Value) {
+	statements := []parser.Expression{body}
+	if block, ok := body.(*parser.BlockExpression); ok {
+		statements = block.Statements()
+	}
+	result = eval.UNDEF
+	for _, statement := range statements {
+		if _, ok := statement.(parser.Definition); ok {
+			result = e.Eval(statement)
+			continue
+		}
+		func() {
+			defer p.Enter(eval.ProfileExpression, statement.Label(), statement)()
+			result = e.Eval(statement)
+		}()
+	}
+	return
+}
+
 func evalQualifiedName(expr *parser.QualifiedName) eval.Value {
 	return types.WrapString(expr.Name())
 }
@@ -369,70 +397,76 @@ func evalRegexpExpression(expr *parser.RegexpExpression) eval.Value {
 func evalCaseExpression(e eval.Evaluator, expr *parser.CaseExpression) eval.Value {
 	return e.Scope().WithLocalScope(func() eval.Value {
 		test := e.Eval(expr.Test())
-		var theDefault *parser.CaseOption
-		var selected *parser.CaseOption
+		options := expr.Options()
+		theDefault := -1
+		selected := -1
 	options:
-		for _, o := range expr.Options() {
+		for i, o := range options {
 			co := o.(*parser.CaseOption)
 			for _, cv := range co.Values() {
 				cv = unwindParenthesis(cv)
 				switch cv.(type) {
 				case *parser.LiteralDefault:
-					theDefault = co
+					theDefault = i
 				case *parser.UnfoldExpression:
 					if eval.Any2(e.Eval(cv).(eval.List), func(v eval.Value) bool { return match(e, expr.Test(), cv, `match`, true, test, v) }) {
-						selected = co
+						selected = i
 						break options
 					}
 				default:
 					if match(e, expr.Test(), cv, `match`, true, test, e.Eval(cv)) {
-						selected = co
+						selected = i
 						break options
 					}
 				}
 			}
 		}
-		if selected == nil {
+		if selected < 0 {
 			selected = theDefault
 		}
-		if selected == nil {
+		if selected < 0 {
+			branchTaken(e, expr, len(options))
 			return eval.UNDEF
 		}
-		return e.Eval(selected.Then())
+		branchTaken(e, expr, selected)
+		return e.Eval(options[selected].(*parser.CaseOption).Then())
 	})
 }
 
 func evalSelectorExpression(e eval.Evaluator, expr *parser.SelectorExpression) eval.Value {
 	return e.Scope().WithLocalScope(func() eval.Value {
 		test := e.Eval(expr.Lhs())
-		var theDefault *parser.SelectorEntry
-		var selected *parser.SelectorEntry
+		selectors := expr.Selectors()
+		theDefault := -1
+		selected := -1
 	selectors:
-		for _, s := range expr.Selectors() {
+		for i, s := range selectors {
 			se := s.(*parser.SelectorEntry)
 			me := unwindParenthesis(se.Matching())
 			switch me.(type) {
 			case *parser.LiteralDefault:
-				theDefault = se
+				theDefault = i
 			case *parser.UnfoldExpression:
 				if eval.Any2(e.Eval(me).(eval.List), func(v eval.Value) bool { return match(e, expr.Lhs(), me, `match`, true, test, v) }) {
-					selected = se
+					selected = i
 					break selectors
 				}
 			default:
 				if match(e, expr.Lhs(), me, `match`, true, test, e.Eval(me)) {
-					selected = se
+					selected = i
 					break selectors
 				}
 			}
 		}
-		if selected == nil {
+		if selected < 0 {
 			selected = theDefault
 		}
-		if selected == nil {
+		if selected < 0 {
+			branchTaken(e, expr, len(selectors))
 			return eval.UNDEF
 		}
-		return e.Eval(selected.Value())
+		branchTaken(e, expr, selected)
+		return e.Eval(selectors[selected].(*parser.SelectorEntry).Value())
 	})
 }
 
@@ -477,6 +511,39 @@ func evalError(code issue.Code, location issue.Location, args issue.H) issue.Rep
 
 // BasicEval is exported to enable the evaluator to be extended
 func BasicEval(e eval.Evaluator, expr parser.Expression) eval.Value {
+	if cv := eval.CoverageOf(e); cv != nil {
+		cv.Covered(expr)
+	}
+	if d := eval.DebuggerOf(e); d != nil {
+		return debugEval(e, d, expr)
+	}
+	return basicEval(e, expr)
+}
+
+// branchTaken notifies the coverage, if any, that the given conditional expression selected the
+// given branch
+func branchTaken(e eval.Evaluator, expr parser.Expression, branch int) {
+	if cv := eval.CoverageOf(e); cv != nil {
+		cv.Branch(expr, branch)
+	}
+}
+
+// debugEval evaluates the given expression and notifies the given debugger before the evaluation
+// starts and when it raises an issue
+func debugEval(e eval.Evaluator, d eval.Debugger, expr parser.Expression) eval.Value {
+	d.Before(e, expr)
+	defer func() {
+		if r := recover(); r != nil {
+			if ri, ok := r.(issue.Reported); ok {
+				d.Raised(e, expr, ri)
+			}
+			panic(r)
+		}
+	}()
+	return basicEval(e, expr)
+}
+
+func basicEval(e eval.Evaluator, expr parser.Expression) eval.Value {
 	switch expr.(type) {
 	case *parser.AccessExpression:
 		return evalAccessExpression(e, expr.(*parser.AccessExpression))
@@ -541,8 +608,12 @@ func BasicEval(e eval.Evaluator, expr parser.Expression) eval.Value {
 		return evalCallNamedFunctionExpression(e, expr.(*parser.CallNamedFunctionExpression))
 	case *parser.CaseExpression:
 		return evalCaseExpression(e, expr.(*parser.CaseExpression))
+	case *parser.CollectExpression:
+		return evalCollectExpression(e, expr.(*parser.CollectExpression))
 	case *parser.ConcatenatedString:
 		return evalConcatenatedString(e, expr.(*parser.ConcatenatedString))
+	case *parser.EppExpression:
+		return evalEppExpression(e, expr.(*parser.EppExpression))
 	case *parser.IfExpression:
 		return evalIfExpression(e, expr.(*parser.IfExpression))
 	case *parser.LambdaExpression:
@@ -553,9 +624,22 @@ func BasicEval(e eval.Evaluator, expr parser.Expression) eval.Value {
 		return evalParameter(e, expr.(*parser.Parameter))
 	case *parser.Program:
 		return evalProgram(e, expr.(*parser.Program))
+	case *parser.RelationshipExpression:
+		return evalRelationshipExpression(e, expr.(*parser.RelationshipExpression))
+	case *parser.RenderExpression:
+		return evalRenderExpression(e, expr.(*parser.RenderExpression))
+	case *parser.RenderStringExpression:
+		return evalRenderStringExpression(e, expr.(*parser.RenderStringExpression))
+	case *parser.ResourceDefaultsExpression:
+		return evalResourceDefaultsExpression(e, expr.(*parser.ResourceDefaultsExpression))
+	case *parser.ResourceExpression:
+		return evalResourceExpression(e, expr.(*parser.ResourceExpression))
+	case *parser.ResourceOverrideExpression:
+		return evalResourceOverrideExpression(e, expr.(*parser.ResourceOverrideExpression))
 	case *parser.SelectorExpression:
 		return evalSelectorExpression(e, expr.(*parser.SelectorExpression))
-	case *parser.FunctionDefinition, *parser.PlanDefinition, *parser.ActivityExpression, *parser.TypeAlias, *parser.TypeMapping:
+	case *parser.FunctionDefinition, *parser.PlanDefinition, *parser.ActivityExpression, *parser.TypeAlias, *parser.TypeMapping,
+		*parser.HostClassDefinition, *parser.ResourceTypeDefinition, *parser.NodeDefinition:
 		// All definitions must be processed at this time
 		return eval.UNDEF
 	case *parser.UnfoldExpression:
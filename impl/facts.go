@@ -0,0 +1,21 @@
+package impl
+
+import (
+	"regexp"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+var legacyFactName = regexp.MustCompile(`\A[a-z_]\w*\z`)
+
+// AssignFacts assigns the given facts to the global variable $facts of the given scope. Each fact
+// that has a valid variable name is also assigned to a top-scope variable of that name unless such
+// a variable already exists.
+func AssignFacts(scope eval.Scope, facts eval.OrderedMap) {
+	scope.Set(`::facts`, facts)
+	facts.EachPair(func(k, v eval.Value) {
+		if name, ok := k.(eval.StringValue); ok && legacyFactName.MatchString(name.String()) {
+			scope.Set(`::`+name.String(), v)
+		}
+	})
+}
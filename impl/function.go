@@ -335,6 +335,9 @@ func (db *dispatchBuilder) assertNotAfterRepeated() {
 }
 
 func (f *goFunction) Call(c eval.Context, block eval.Lambda, args ...eval.Value) eval.Value {
+	if p := eval.ProfilerOf(c); p != nil {
+		defer p.Enter(eval.ProfileGoFunction, f.name, nil)()
+	}
 	for _, d := range f.dispatchers {
 		if d.Signature().CallableWith(args, block) {
 			return d.Call(c, block, args...)
@@ -400,6 +403,9 @@ func (l *puppetLambda) Call(c eval.Context, block eval.Lambda, args ...eval.Valu
 	if block != nil {
 		panic(errors.NewArgumentsError(`lambda`, `nested lambdas are not supported`))
 	}
+	if p := eval.ProfilerOf(c); p != nil {
+		defer p.Enter(eval.ProfileLambda, `lambda`, l.expression)()
+	}
 	defer func() {
 		if err := recover(); err != nil {
 			if ni, ok := err.(*errors.NextIteration); ok {
@@ -443,10 +449,28 @@ func NewPuppetFunction(expr *parser.FunctionDefinition) *puppetFunction {
 	return &puppetFunction{expression: expr}
 }
 
-func (f *puppetFunction) Call(c eval.Context, block eval.Lambda, args ...eval.Value) (v eval.Value) {
+func (f *puppetFunction) Call(c eval.Context, block eval.Lambda, args ...eval.Value) eval.Value {
+	return f.call(c, block, func() eval.Value {
+		return CallBlock(c, f.Name(), f.parameters, f.signature, f.expression.Body(), args)
+	})
+}
+
+// CallNamed calls the function with arguments that are given by name. Parameters that have no
+// corresponding argument are assigned their default value.
+func (f *puppetFunction) CallNamed(c eval.Context, block eval.Lambda, args eval.OrderedMap) eval.Value {
+	return f.call(c, block, func() eval.Value {
+		ps, vs := namedArguments(fmt.Sprintf(`function '%s'`, f.Name()), f.expression, f.parameters, args, nil)
+		return CallBlock(c, f.Name(), ps, f.signature, f.expression.Body(), vs)
+	})
+}
+
+func (f *puppetFunction) call(c eval.Context, block eval.Lambda, doCall eval.Producer) (v eval.Value) {
 	if block != nil {
 		panic(errors.NewArgumentsError(f.Name(), `Puppet functions does not yet support lambdas`))
 	}
+	if p := eval.ProfilerOf(c); p != nil {
+		defer p.Enter(eval.ProfileFunction, f.Name(), f.expression)()
+	}
 	defer func() {
 		if err := recover(); err != nil {
 			switch err.(type) {
@@ -459,7 +483,7 @@ func (f *puppetFunction) Call(c eval.Context, block eval.Lambda, args ...eval.Va
 			}
 		}
 	}()
-	v = CallBlock(c, f.Name(), f.parameters, f.signature, f.expression.Body(), args)
+	v = doCall()
 	return
 }
 
@@ -469,41 +493,7 @@ func (f *puppetFunction) Signature() eval.Signature {
 
 func CallBlock(c eval.Context, name string, parameters []eval.Parameter, signature *types.CallableType, body parser.Expression, args []eval.Value) eval.Value {
 	return c.Scope().WithLocalScope(func() (v eval.Value) {
-		na := len(args)
-		np := len(parameters)
-		if np > na {
-			// Resolve parameter defaults in special parameter scope and assign values to function scope
-			c.Scope().WithLocalScope(func() eval.Value {
-				ap := make([]eval.Value, np)
-				copy(ap, args)
-				for idx := na; idx < np; idx++ {
-					p := parameters[idx]
-					if !p.HasValue() {
-						ap[idx] = eval.UNDEF
-						continue
-					}
-					d := p.Value()
-					if df, ok := d.(types.Deferred); ok {
-						d = df.Resolve(c)
-					}
-					if !eval.IsInstance(p.Type(), d) {
-						panic(errors.NewArgumentsError(name, fmt.Sprintf("expected default for parameter 1 to be %s, got %s", p.Type(), d.PType())))
-					}
-					ap[idx] = d
-				}
-				args = ap
-				return eval.UNDEF
-			})
-		}
-
-		for idx, arg := range args {
-			AssertArgument(name, idx, parameters[idx].Type(), arg)
-		}
-
-		scope := c.Scope()
-		for idx, p := range parameters {
-			scope.Set(p.Name(), args[idx])
-		}
+		BindParameters(c, name, parameters, args)
 		v = eval.Evaluate(c, body)
 		if !eval.IsInstance(signature.ReturnType(), v) {
 			panic(fmt.Sprintf(`Value returned from function '%s' has incorrect type. Expected %s, got %s`,
@@ -513,6 +503,52 @@ func CallBlock(c eval.Context, name string, parameters []eval.Parameter, signatu
 	})
 }
 
+// BindParameters assigns the given arguments to the given parameters in the current scope of the given
+// context. Defaults are used for parameters that have no corresponding argument.
+func BindParameters(c eval.Context, name string, parameters []eval.Parameter, args []eval.Value) {
+	na := len(args)
+	np := len(parameters)
+	if np > na {
+		// Resolve parameter defaults in special parameter scope and assign values to function scope
+		// Parameters that precede a parameter are visible when its default is resolved
+		c.Scope().WithLocalScope(func() eval.Value {
+			ap := make([]eval.Value, np)
+			copy(ap, args)
+			pScope := c.Scope()
+			for idx := 0; idx < na; idx++ {
+				pScope.Set(parameters[idx].Name(), args[idx])
+			}
+			for idx := na; idx < np; idx++ {
+				p := parameters[idx]
+				if !p.HasValue() {
+					ap[idx] = eval.UNDEF
+					continue
+				}
+				d := p.Value()
+				if df, ok := d.(types.Deferred); ok {
+					d = df.Resolve(c)
+				}
+				if !eval.IsInstance(p.Type(), d) {
+					panic(errors.NewArgumentsError(name, fmt.Sprintf("expected default for parameter 1 to be %s, got %s", p.Type(), d.PType())))
+				}
+				ap[idx] = d
+				pScope.Set(p.Name(), d)
+			}
+			args = ap
+			return eval.UNDEF
+		})
+	}
+
+	for idx, arg := range args {
+		AssertArgument(name, idx, parameters[idx].Type(), arg)
+	}
+
+	scope := c.Scope()
+	for idx, p := range parameters {
+		scope.Set(p.Name(), args[idx])
+	}
+}
+
 func AssertArgument(name string, index int, pt eval.Type, arg eval.Value) {
 	if !eval.IsInstance(pt, arg) {
 		panic(types.NewIllegalArgumentType2(name, index, pt.String(), arg))
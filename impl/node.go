@@ -0,0 +1,159 @@
+package impl
+
+import (
+	"io"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type puppetNode struct {
+	expression  *parser.NodeDefinition
+	hostMatches []eval.Value
+}
+
+const nodesKey = `puppet.nodes`
+
+func init() {
+	eval.FindNode = func(c eval.Context, nodeName string) (eval.Node, bool) {
+		if n := findNode(c, nodeName); n != nil {
+			return n, true
+		}
+		return nil, false
+	}
+
+	eval.EvaluateNode = func(c eval.Context, nodeName string) {
+		n := findNode(c, nodeName)
+		if n == nil {
+			if len(nodes(c)) > 0 {
+				panic(eval.Error(eval.EVAL_NO_MATCHING_NODE, issue.H{`name`: nodeName}))
+			}
+			return
+		}
+		n.evaluate(c)
+	}
+}
+
+func NewPuppetNode(expr *parser.NodeDefinition) *puppetNode {
+	return &puppetNode{expression: expr}
+}
+
+// addNode adds the given node to the node definitions that are stored in the given context
+func addNode(c eval.Context, node *puppetNode) {
+	c.Set(nodesKey, append(nodes(c), node))
+}
+
+// nodes returns the node definitions that are stored in the given context in the order they were added
+func nodes(c eval.Context) []*puppetNode {
+	if nv, ok := c.Get(nodesKey); ok {
+		return nv.([]*puppetNode)
+	}
+	return nil
+}
+
+func findNode(c eval.Context, nodeName string) *puppetNode {
+	ns := nodes(c)
+	name := strings.ToLower(nodeName)
+	for n := name; ; {
+		for _, node := range ns {
+			if node.MatchName(n) {
+				return node
+			}
+		}
+		i := strings.LastIndexByte(n, '.')
+		if i < 0 {
+			break
+		}
+		n = n[:i]
+	}
+	for _, node := range ns {
+		if node.MatchRegexp(name) {
+			return node
+		}
+	}
+	for _, node := range ns {
+		if node.IsDefault() {
+			return node
+		}
+	}
+	return nil
+}
+
+func (n *puppetNode) Equals(other interface{}, guard eval.Guard) bool {
+	return n == other
+}
+
+func (n *puppetNode) Expression() parser.Definition {
+	return n.expression
+}
+
+func (n *puppetNode) HostMatches() []eval.Value {
+	return n.hostMatches
+}
+
+func (n *puppetNode) IsDefault() bool {
+	for _, hm := range n.hostMatches {
+		if _, ok := hm.(*types.DefaultValue); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *puppetNode) MatchName(name string) bool {
+	for _, hm := range n.hostMatches {
+		if s, ok := hm.(eval.StringValue); ok && strings.EqualFold(s.String(), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *puppetNode) MatchRegexp(name string) bool {
+	for _, hm := range n.hostMatches {
+		if rx, ok := hm.(*types.RegexpValue); ok && rx.Matcher().MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *puppetNode) PType() eval.Type {
+	return types.DefaultAnyType()
+}
+
+func (n *puppetNode) Resolve(c eval.Context) {
+	if n.expression.Parent() != nil {
+		panic(evalError(eval.EVAL_ILLEGAL_NODE_INHERITANCE, n.expression.Parent(), issue.NO_ARGS))
+	}
+	hms := n.expression.HostMatches()
+	n.hostMatches = make([]eval.Value, len(hms))
+	for i, hm := range hms {
+		n.hostMatches[i] = eval.Evaluate(c, hm)
+	}
+}
+
+func (n *puppetNode) String() string {
+	return eval.ToString(n)
+}
+
+func (n *puppetNode) ToString(bld io.Writer, format eval.FormatContext, g eval.RDetect) {
+	io.WriteString(bld, `node `)
+	for i, hm := range n.hostMatches {
+		if i > 0 {
+			io.WriteString(bld, `, `)
+		}
+		hm.ToString(bld, format, g)
+	}
+}
+
+func (n *puppetNode) evaluate(c eval.Context) {
+	if body := n.expression.Body(); body != nil {
+		c.DoWithScope(newNamedScope(c.Scope(), nil, ``), func() {
+			eval.Evaluate(c, body)
+		})
+	}
+}
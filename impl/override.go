@@ -0,0 +1,105 @@
+package impl
+
+import (
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// attributeOverride is an evaluated attribute operation of a resource override expression
+type attributeOverride struct {
+	name   string
+	value  eval.Value
+	append bool
+}
+
+func evalResourceOverrideExpression(e eval.Evaluator, expr *parser.ResourceOverrideExpression) eval.Value {
+	rv := e.Eval(expr.Resources())
+	refs := resourceReferences(expr.Resources(), rv, eval.EVAL_ILLEGAL_OVERRIDE_OPERAND, nil)
+	overrides := evalAttributeOverrides(e, expr.Operations())
+	overrider := eval.ContainerOf(e)
+	cat := eval.CatalogOf(e)
+	for _, ref := range refs {
+		cat.Override(ref, func(r eval.Resource) eval.OrderedMap {
+			allowed := mayOverride(e, overrider, r.Container())
+			params := r.Parameters()
+			for _, o := range overrides {
+				old, set := params.Get4(o.name)
+				if set && !allowed {
+					panic(evalError(eval.EVAL_PARAMETER_ALREADY_SET, expr, issue.H{`name`: o.name, `ref`: ref.String()}))
+				}
+				v := o.value
+				if set && o.append {
+					v = types.WrapValues(append(valueSlice(old), valueSlice(v)...))
+				}
+				if v == eval.UNDEF {
+					name := o.name
+					params = params.RejectPairs(func(k, _ eval.Value) bool { return k.String() == name })
+				} else {
+					params = params.Merge(types.SingletonHash2(o.name, v))
+				}
+			}
+			return params
+		})
+	}
+	return rv
+}
+
+// evalAttributeOverrides evaluates the attribute operations of a resource override expression. In
+// contrast to evalAttributeOperations, attributes that evaluate to undef are retained since they
+// remove the attribute from the overridden resource.
+func evalAttributeOverrides(e eval.Evaluator, ops []parser.Expression) []*attributeOverride {
+	overrides := make([]*attributeOverride, 0, len(ops))
+	for _, op := range ops {
+		switch op.(type) {
+		case *parser.AttributeOperation:
+			ao := op.(*parser.AttributeOperation)
+			overrides = append(overrides, &attributeOverride{ao.Name(), e.Eval(ao.Value()), ao.Operator() == `+>`})
+		case *parser.AttributesOperation:
+			ao := op.(*parser.AttributesOperation)
+			v := e.Eval(ao.Expr())
+			h, ok := v.(eval.OrderedMap)
+			if !ok {
+				panic(evalError(eval.EVAL_ILLEGAL_ATTRIBUTES_SPLAT, ao, issue.H{`actual`: v.PType()}))
+			}
+			h.EachPair(func(k, v eval.Value) { overrides = append(overrides, &attributeOverride{k.String(), v, false}) })
+		}
+	}
+	return overrides
+}
+
+// mayOverride returns true if code that is contained in the given overrider may redefine parameters
+// of a resource that is contained in the given container. That is the case when both are the same or
+// when the overrider is a class that inherits, directly or indirectly, the class of the container.
+func mayOverride(c eval.Context, overrider, container eval.ResourceReference) bool {
+	if overrider == nil || container == nil {
+		return overrider == nil && container == nil
+	}
+	for !overrider.Equals(container, nil) {
+		if overrider.TypeName() != `Class` {
+			return false
+		}
+		cv, ok := eval.Load(c, eval.NewTypedName(eval.NsClass, strings.ToLower(overrider.Title())))
+		if !ok {
+			return false
+		}
+		pn := cv.(*puppetClass).ParentName()
+		if pn == `` {
+			return false
+		}
+		overrider = types.NewResourceType(`Class`, strings.TrimPrefix(pn, `::`))
+	}
+	return true
+}
+
+// valueSlice returns the elements of the given value if it is an array, or else a slice containing
+// the value itself
+func valueSlice(v eval.Value) []eval.Value {
+	if a, ok := v.(*types.ArrayValue); ok {
+		return a.AppendTo(nil)
+	}
+	return []eval.Value{v}
+}
@@ -0,0 +1,59 @@
+package impl
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func evalRelationshipExpression(e eval.Evaluator, expr *parser.RelationshipExpression) eval.Value {
+	lhs := e.Eval(expr.Lhs())
+	rhs := e.Eval(expr.Rhs())
+	lrefs := resourceReferences(expr.Lhs(), lhs, eval.EVAL_ILLEGAL_RELATIONSHIP_OPERAND, nil)
+	rrefs := resourceReferences(expr.Rhs(), rhs, eval.EVAL_ILLEGAL_RELATIONSHIP_OPERAND, nil)
+
+	var sources, targets []eval.ResourceReference
+	subscribe := false
+	switch expr.Operator() {
+	case `->`:
+		sources, targets = lrefs, rrefs
+	case `~>`:
+		sources, targets, subscribe = lrefs, rrefs, true
+	case `<-`:
+		sources, targets = rrefs, lrefs
+	case `<~`:
+		sources, targets, subscribe = rrefs, lrefs, true
+	default:
+		panic(evalError(eval.EVAL_OPERATOR_NOT_APPLICABLE, expr, issue.H{`operator`: expr.Operator(), `left`: lhs.PType()}))
+	}
+
+	cat := eval.CatalogOf(e)
+	for _, s := range sources {
+		for _, t := range targets {
+			cat.AddEdge(s, t, subscribe)
+		}
+	}
+	return rhs
+}
+
+// resourceReferences converts the given value into a slice of resource references. Arrays are
+// flattened. Each element must be a resource or a reference to a resource with a title. A panic using the
+// given issue code is raised when that is not the case.
+func resourceReferences(expr parser.Expression, v eval.Value, code issue.Code, refs []eval.ResourceReference) []eval.ResourceReference {
+	if refs == nil {
+		refs = make([]eval.ResourceReference, 0, 4)
+	}
+	switch v.(type) {
+	case *types.ArrayValue:
+		v.(*types.ArrayValue).Each(func(ev eval.Value) { refs = resourceReferences(expr, ev, code, refs) })
+		return refs
+	case eval.Resource:
+		return append(refs, v.(eval.Resource).Reference())
+	case eval.ResourceReference:
+		if rr := v.(eval.ResourceReference); rr.TypeName() != `` && rr.Title() != `` {
+			return append(refs, rr)
+		}
+	}
+	panic(evalError(code, expr, issue.H{`actual`: v}))
+}
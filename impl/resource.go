@@ -0,0 +1,312 @@
+package impl
+
+import (
+	"io"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-evaluator/utils"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type resource struct {
+	typeName   string
+	title      string
+	parameters eval.OrderedMap
+	container  eval.ResourceReference
+	origin     issue.Location
+}
+
+var Resource_Type eval.Type
+
+func init() {
+	Resource_Type = eval.NewObjectType(`Catalog::Resource`, `{
+    attributes => {
+      'type' => String[1],
+      'title' => String,
+      'parameters' => { type => Hash[String, RichData], value => {} },
+      'container' => { type => Optional[Type[Resource]], value => undef },
+    }
+  }`, func(ctx eval.Context, args []eval.Value) eval.Value {
+		var params eval.OrderedMap
+		var container eval.ResourceReference
+		if len(args) > 2 {
+			params = args[2].(eval.OrderedMap)
+			if len(args) > 3 {
+				container, _ = args[3].(eval.ResourceReference)
+			}
+		}
+		return newResource(args[0].String(), args[1].String(), params, container, nil)
+	}, func(ctx eval.Context, args []eval.Value) eval.Value {
+		h := args[0].(*types.HashValue)
+		params, _ := h.Get5(`parameters`, eval.EMPTY_MAP).(eval.OrderedMap)
+		container, _ := h.Get5(`container`, eval.UNDEF).(eval.ResourceReference)
+		return newResource(h.Get5(`type`, eval.EMPTY_STRING).String(), h.Get5(`title`, eval.EMPTY_STRING).String(), params, container, nil)
+	})
+
+	eval.NewResource = newResource
+}
+
+func newResource(typeName, title string, parameters eval.OrderedMap, container eval.ResourceReference, origin issue.Location) eval.Resource {
+	if parameters == nil {
+		parameters = eval.EMPTY_MAP
+	}
+	ref := types.NewResourceType(typeName, title)
+	return &resource{ref.TypeName(), ref.Title(), parameters, container, origin}
+}
+
+func (r *resource) Container() eval.ResourceReference {
+	return r.container
+}
+
+func (r *resource) Equals(other interface{}, guard eval.Guard) bool {
+	if or, ok := other.(*resource); ok {
+		return r.typeName == or.typeName && r.title == or.title && r.parameters.Equals(or.parameters, guard)
+	}
+	return false
+}
+
+func (r *resource) Get(key string) (value eval.Value, ok bool) {
+	switch key {
+	case `type`:
+		return types.WrapString(r.typeName), true
+	case `title`:
+		return types.WrapString(r.title), true
+	case `parameters`:
+		return r.parameters, true
+	case `container`:
+		if r.container == nil {
+			return eval.UNDEF, true
+		}
+		return r.container, true
+	}
+	return nil, false
+}
+
+func (r *resource) InitHash() eval.OrderedMap {
+	es := make([]*types.HashEntry, 0, 4)
+	es = append(es, types.WrapHashEntry2(`type`, types.WrapString(r.typeName)))
+	es = append(es, types.WrapHashEntry2(`title`, types.WrapString(r.title)))
+	if !r.parameters.IsEmpty() {
+		es = append(es, types.WrapHashEntry2(`parameters`, r.parameters))
+	}
+	if r.container != nil {
+		es = append(es, types.WrapHashEntry2(`container`, r.container))
+	}
+	return types.WrapHash(es)
+}
+
+func (r *resource) Origin() issue.Location {
+	return r.origin
+}
+
+func (r *resource) Parameters() eval.OrderedMap {
+	return r.parameters
+}
+
+func (r *resource) PType() eval.Type {
+	return Resource_Type
+}
+
+func (r *resource) Reference() eval.ResourceReference {
+	return types.NewResourceType(r.typeName, r.title)
+}
+
+func (r *resource) String() string {
+	return eval.ToString(r)
+}
+
+func (r *resource) Title() string {
+	return r.title
+}
+
+func (r *resource) ToString(bld io.Writer, format eval.FormatContext, g eval.RDetect) {
+	types.ObjectToString(r, format, bld, g)
+}
+
+func (r *resource) TypeName() string {
+	return r.typeName
+}
+
+func evalResourceExpression(e eval.Evaluator, expr *parser.ResourceExpression) eval.Value {
+	typeName := resourceTypeName(e, expr.TypeName())
+	cat := eval.CatalogOf(e)
+
+	defaults := eval.EMPTY_MAP
+	if typeName != `Class` {
+		defaults = scopedResourceDefaults(e, typeName)
+	}
+	bodies := make([]*parser.ResourceBody, 0, len(expr.Bodies()))
+	for _, b := range expr.Bodies() {
+		body := b.(*parser.ResourceBody)
+		if _, ok := body.Title().(*parser.LiteralDefault); ok {
+			defaults = defaults.Merge(evalAttributeOperations(e, body.Operations()))
+			continue
+		}
+		bodies = append(bodies, body)
+	}
+
+	var dt *puppetDefinedType
+	if typeName != `Class` {
+		if dv, ok := eval.Load(e, eval.NewTypedName(eval.NsDefinedType, typeName)); ok {
+			dt = dv.(*puppetDefinedType)
+		}
+	}
+
+	form := expr.Form()
+	if form != parser.REGULAR && (typeName == `Class` || dt != nil) {
+		panic(evalError(eval.EVAL_NOT_VIRTUALIZABLE, expr, issue.H{`type`: typeName}))
+	}
+
+	refs := make([]eval.Value, 0, len(bodies))
+	for _, body := range bodies {
+		params := defaults.Merge(evalAttributeOperations(e, body.Operations()))
+		for _, title := range resourceTitles(e, body.Title()) {
+			switch {
+			case typeName == `Class`:
+				refs = append(refs, declareClass(e, body, title, params))
+			case dt != nil:
+				refs = append(refs, declareDefinedType(e, body, dt, title, params))
+			default:
+				r := newResource(typeName, title, typedResourceParameters(e, body, typeName, title, params), eval.ContainerOf(e), body)
+				switch form {
+				case parser.VIRTUAL:
+					cat.AddVirtualResource(r, false)
+				case parser.EXPORTED:
+					cat.AddVirtualResource(r, true)
+					if store := eval.ExportStoreOf(e); store != nil {
+						store.Export(e, r)
+					}
+				default:
+					cat.AddResource(r)
+				}
+				refs = append(refs, r.Reference())
+			}
+		}
+	}
+	return types.WrapValues(refs)
+}
+
+// evalAttributeOperations evaluates the attribute operations of a resource body into a hash. Attributes
+// that evaluate to undef are excluded.
+func evalAttributeOperations(e eval.Evaluator, ops []parser.Expression) eval.OrderedMap {
+	entries := make([]*types.HashEntry, 0, len(ops))
+	add := func(expr parser.Expression, key string, value eval.Value) {
+		for _, en := range entries {
+			if en.Key().String() == key {
+				panic(evalError(eval.EVAL_DUPLICATE_ATTRIBUTE, expr, issue.H{`attribute`: key}))
+			}
+		}
+		if value != eval.UNDEF {
+			entries = append(entries, types.WrapHashEntry2(key, value))
+		}
+	}
+
+	for _, op := range ops {
+		switch op.(type) {
+		case *parser.AttributeOperation:
+			ao := op.(*parser.AttributeOperation)
+			add(ao, ao.Name(), e.Eval(ao.Value()))
+		case *parser.AttributesOperation:
+			ao := op.(*parser.AttributesOperation)
+			v := e.Eval(ao.Expr())
+			h, ok := v.(eval.OrderedMap)
+			if !ok {
+				panic(evalError(eval.EVAL_ILLEGAL_ATTRIBUTES_SPLAT, ao, issue.H{`actual`: v.PType()}))
+			}
+			h.EachPair(func(k, v eval.Value) { add(ao, k.String(), v) })
+		}
+	}
+	return types.WrapHash(entries)
+}
+
+// resourceTypeName evaluates the type name expression of a resource expression into a
+// capitalized resource type name
+func resourceTypeName(e eval.Evaluator, expr parser.Expression) string {
+	switch expr.(type) {
+	case *parser.QualifiedName:
+		return utils.CapitalizeSegments(expr.(*parser.QualifiedName).Name())
+	case *parser.QualifiedReference:
+		return utils.CapitalizeSegments(expr.(*parser.QualifiedReference).Name())
+	}
+	tv := e.Eval(expr)
+	switch tv.(type) {
+	case eval.StringValue:
+		return utils.CapitalizeSegments(tv.String())
+	case eval.ResourceReference:
+		if rr := tv.(eval.ResourceReference); rr.TypeName() != `` && rr.Title() == `` {
+			return rr.TypeName()
+		}
+	case *types.TypeReferenceType:
+		return utils.CapitalizeSegments(tv.(*types.TypeReferenceType).TypeString())
+	}
+	panic(evalError(eval.EVAL_ILLEGAL_RESOURCE_TYPE, expr, issue.H{`actual`: tv}))
+}
+
+// resourceTitles evaluates the given title expression into a slice of strings. The expression
+// must evaluate to a String or to an Array of strings.
+func resourceTitles(e eval.Evaluator, expr parser.Expression) []string {
+	tv := e.Eval(expr)
+	if a, ok := tv.(*types.ArrayValue); ok {
+		tv = a.Flatten()
+		titles := make([]string, 0, a.Len())
+		tv.(eval.List).Each(func(t eval.Value) {
+			if _, ok := t.(eval.StringValue); !ok {
+				panic(evalError(eval.EVAL_ILLEGAL_RESOURCE_TITLE, expr, issue.H{`actual`: t.PType()}))
+			}
+			titles = append(titles, t.String())
+		})
+		return titles
+	}
+	if _, ok := tv.(eval.StringValue); !ok {
+		panic(evalError(eval.EVAL_ILLEGAL_RESOURCE_TITLE, expr, issue.H{`actual`: tv.PType()}))
+	}
+	return []string{tv.String()}
+}
+
+// typedResourceParameters validates the given parameters when the resource type name appoints
+// an ObjectType. The validation is performed by creating an instance of the ObjectType, and the
+// parameters of the created instance are returned. The parameters are returned verbatim when no
+// such ObjectType exists.
+func typedResourceParameters(e eval.Evaluator, expr parser.Expression, typeName, title string, params eval.OrderedMap) eval.OrderedMap {
+	t, ok := eval.Load(e, eval.NewTypedName(eval.NsType, typeName))
+	if !ok {
+		return params
+	}
+	ot, ok := t.(eval.ObjectType)
+	if !ok {
+		return params
+	}
+
+	hasTitle := false
+	for _, a := range ot.AttributesInfo().Attributes() {
+		if a.Name() == `title` {
+			hasTitle = true
+			break
+		}
+	}
+	if hasTitle {
+		params = types.SingletonHash2(`title`, types.WrapString(title)).Merge(params)
+	}
+
+	var instance eval.Value
+	e.StackPush(expr)
+	func() {
+		defer func() {
+			e.StackPop()
+			if err := recover(); err != nil {
+				convertCallError(err, expr, []parser.Expression{expr})
+			}
+		}()
+		instance = eval.New(e, ot, params)
+	}()
+
+	if po, ok := instance.(eval.PuppetObject); ok {
+		params = po.InitHash()
+		if hasTitle {
+			params = params.RejectPairs(func(k, v eval.Value) bool { return k.String() == `title` })
+		}
+	}
+	return params
+}
@@ -17,6 +17,13 @@ type (
 		BasicScope
 		parent eval.Scope
 	}
+
+	namedScope struct {
+		BasicScope
+		global    eval.Scope
+		parent    eval.Scope
+		qualifier string
+	}
 )
 
 // NewScope creates a new Scope instance that in turn consists of a stack of ephemeral scopes. If
@@ -35,6 +42,22 @@ func NewParentedScope(parent eval.Scope, mutable bool) eval.Scope {
 	return &parentedScope{BasicScope{[]map[string]eval.Value{make(map[string]eval.Value, 8)}, mutable}, parent}
 }
 
+// newNamedScope creates the scope of a class, a defined type, or a node. Names that are not found in
+// the new scope are searched for in the parent scope, which is the scope of an inherited class, or
+// when parent is nil, in the global scope. Local variables of the scope that declared the class are
+// never visible.
+//
+// When the qualifier is not empty, variables that are assigned in the outermost level of the scope
+// are also assigned to the global scope using the qualified name <qualifier>::<name> so that they
+// can be referenced from other scopes.
+func newNamedScope(current, parent eval.Scope, qualifier string) eval.Scope {
+	global := current
+	if ns, ok := current.(*namedScope); ok {
+		global = ns.global
+	}
+	return &namedScope{BasicScope{[]map[string]eval.Value{make(map[string]eval.Value, 8)}, false}, global, parent, qualifier}
+}
+
 func NewScope2(h *types.HashValue, mutable bool) eval.Scope {
 	top := make(map[string]eval.Value, h.Len())
 	h.EachPair(func(k, v eval.Value) { top[k.String()] = v })
@@ -67,6 +90,31 @@ func (e *BasicScope) WithLocalScope(producer eval.Producer) eval.Value {
 	return result
 }
 
+func (e *BasicScope) Each(consumer func(name string, value eval.Value)) {
+	e.each(make(map[string]bool), consumer)
+}
+
+// eachVariable calls the consumer with all variables of the given scope when it is an
+// eval.VariableIterator
+func eachVariable(s eval.Scope, consumer func(name string, value eval.Value)) {
+	if vi, ok := s.(eval.VariableIterator); ok {
+		vi.Each(consumer)
+	}
+}
+
+// each calls the consumer with all variables that are not already present in the seen map and
+// adds their names to that map
+func (e *BasicScope) each(seen map[string]bool, consumer func(name string, value eval.Value)) {
+	for idx := len(e.scopes) - 1; idx >= 0; idx-- {
+		for k, v := range e.scopes[idx] {
+			if k != groupKey && !seen[k] {
+				seen[k] = true
+				consumer(k, v)
+			}
+		}
+	}
+}
+
 func (e *BasicScope) Fork() eval.Scope {
 	clone := &BasicScope{}
 	clone.copyFrom(e)
@@ -160,6 +208,16 @@ func (e *parentedScope) Fork() eval.Scope {
 	return clone
 }
 
+func (e *parentedScope) Each(consumer func(name string, value eval.Value)) {
+	seen := make(map[string]bool)
+	e.each(seen, consumer)
+	eachVariable(e.parent, func(name string, value eval.Value) {
+		if !seen[name] {
+			consumer(name, value)
+		}
+	})
+}
+
 func (e *parentedScope) Get(name string) (value eval.Value, found bool) {
 	value, found = e.BasicScope.Get(name)
 	if !found {
@@ -205,3 +263,77 @@ func (e *parentedScope) State(name string) eval.VariableState {
 	}
 	return e.parent.State(name)
 }
+
+func (e *namedScope) Fork() eval.Scope {
+	clone := &namedScope{}
+	clone.copyFrom(&e.BasicScope)
+	clone.global = e.global.Fork()
+	if e.parent != nil {
+		clone.parent = e.parent.Fork()
+	}
+	clone.qualifier = e.qualifier
+	return clone
+}
+
+func (e *namedScope) Each(consumer func(name string, value eval.Value)) {
+	seen := make(map[string]bool)
+	e.each(seen, consumer)
+	if e.parent != nil {
+		eachVariable(e.parent, func(name string, value eval.Value) {
+			if !seen[name] {
+				seen[name] = true
+				consumer(name, value)
+			}
+		})
+		return
+	}
+	eachVariable(e.global, func(name string, value eval.Value) {
+		if !seen[name] && e.global.State(`::`+name) == eval.Global {
+			consumer(name, value)
+		}
+	})
+}
+
+func (e *namedScope) Get(name string) (value eval.Value, found bool) {
+	if strings.HasPrefix(name, `::`) {
+		return e.global.Get(name)
+	}
+	for idx := len(e.scopes) - 1; idx >= 0; idx-- {
+		if value, found = e.scopes[idx][name]; found {
+			return
+		}
+	}
+	if e.parent != nil {
+		return e.parent.Get(name)
+	}
+	return e.global.Get(`::` + name)
+}
+
+func (e *namedScope) Set(name string, value eval.Value) bool {
+	if strings.HasPrefix(name, `::`) {
+		// Global variables cannot be assigned from within a named scope
+		return false
+	}
+	if !e.BasicScope.Set(name, value) {
+		return false
+	}
+	if e.qualifier != `` && len(e.scopes) == 1 {
+		e.global.Set(`::`+e.qualifier+`::`+name, value)
+	}
+	return true
+}
+
+func (e *namedScope) State(name string) eval.VariableState {
+	if strings.HasPrefix(name, `::`) {
+		return e.global.State(name)
+	}
+	for idx := len(e.scopes) - 1; idx >= 0; idx-- {
+		if _, ok := e.scopes[idx][name]; ok {
+			return eval.Local
+		}
+	}
+	if e.parent != nil {
+		return e.parent.State(name)
+	}
+	return e.global.State(`::` + name)
+}
@@ -0,0 +1,340 @@
+// Package jsonschema converts Puppet types into JSON Schema (draft 2020-12) and JSON Schema into
+// Puppet types.
+package jsonschema
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// Draft is the meta schema of the schemas produced by SchemaFromType
+const Draft = `https://json-schema.org/draft/2020-12/schema`
+
+// defsPrefix is the prefix of all references produced by SchemaFromType
+const defsPrefix = `#/$defs/`
+
+type schemaWriter struct {
+	names []string
+	defs  map[string]eval.Value
+}
+
+// SchemaFromType returns a JSON Schema that validates the Data representation of the instances of
+// the given type. Type aliases and Object types become entries in $defs that are referenced using
+// $ref so recursive aliases are supported. The attributes of an Object type become properties and
+// attributes with a value become properties with a default.
+//
+// An EVAL_JSON_SCHEMA_UNREPRESENTABLE_TYPE error is raised when the type, or a type that it
+// contains, cannot be represented in JSON Schema without loss. Examples of such types are
+// Timestamp, Sensitive, case insensitive Enum types, Pattern types with regexps that use syntax
+// unknown to ECMA-262, and Hash types with non String keys.
+func SchemaFromType(t eval.Type) eval.OrderedMap {
+	w := &schemaWriter{defs: make(map[string]eval.Value)}
+	entries := append([]*types.HashEntry{entry(`$schema`, types.WrapString(Draft))}, w.schema(t, `#`)...)
+	if len(w.names) > 0 {
+		defs := make([]*types.HashEntry, len(w.names))
+		for i, name := range w.names {
+			defs[i] = entry(name, w.defs[name])
+		}
+		entries = append(entries, entry(`$defs`, types.WrapHash(defs)))
+	}
+	return types.WrapHash(entries)
+}
+
+func entry(key string, value eval.Value) *types.HashEntry {
+	return types.WrapHashEntry2(key, value)
+}
+
+func typeEntry(name string) []*types.HashEntry {
+	return []*types.HashEntry{entry(`type`, types.WrapString(name))}
+}
+
+// escapePointer escapes the given name for use as a JSON Pointer token
+func escapePointer(name string) string {
+	return strings.Replace(strings.Replace(name, `~`, `~0`, -1), `/`, `~1`, -1)
+}
+
+// isEcmaCompatible returns false if the given pattern uses syntax that the ECMA-262 regular
+// expressions used by the JSON Schema pattern keyword lack or interpret differently. Examples are
+// the \A and \z anchors, inline flags such as (?i), POSIX classes, and possessive quantifiers.
+func isEcmaCompatible(pattern string) bool {
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\':
+			if i++; i < len(pattern) && strings.IndexByte(`AzZGhHKRXQE`, pattern[i]) >= 0 {
+				return false
+			}
+		case inClass:
+			if c == ']' {
+				inClass = false
+			} else if c == '[' && strings.HasPrefix(pattern[i+1:], `:`) {
+				return false
+			}
+		case c == '[':
+			inClass = true
+			if strings.HasPrefix(pattern[i+1:], `^`) {
+				i++
+			}
+			if strings.HasPrefix(pattern[i+1:], `]`) {
+				// A literal ']' here is an empty class in ECMA-262
+				return false
+			}
+		case c == '(':
+			// ECMA-262 supports non capturing groups, lookarounds, and named groups
+			if strings.HasPrefix(pattern[i+1:], `?`) && (i+2 >= len(pattern) || strings.IndexByte(`:=!<`, pattern[i+2]) < 0) {
+				return false
+			}
+		case c == '*' || c == '+' || c == '?' || c == '}':
+			if strings.HasPrefix(pattern[i+1:], `+`) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func unrepresentable(t eval.Type, path string) {
+	panic(eval.Error(eval.EVAL_JSON_SCHEMA_UNREPRESENTABLE_TYPE, issue.H{`type`: t.String(), `path`: path}))
+}
+
+// schema returns the keywords of the schema for the given type. The path is the JSON Pointer of
+// the schema and is used in error messages.
+func (w *schemaWriter) schema(t eval.Type, path string) []*types.HashEntry {
+	switch t.(type) {
+	case *types.AnyType:
+		return nil
+	case *types.UndefType:
+		return typeEntry(`null`)
+	case *types.BooleanType:
+		if ps := t.(*types.BooleanType).Parameters(); len(ps) == 1 {
+			return []*types.HashEntry{entry(`const`, ps[0])}
+		}
+		return typeEntry(`boolean`)
+	case *types.IntegerType:
+		it := t.(*types.IntegerType)
+		es := typeEntry(`integer`)
+		if it.Min() != math.MinInt64 {
+			es = append(es, entry(`minimum`, types.WrapInteger(it.Min())))
+		}
+		if it.Max() != math.MaxInt64 {
+			es = append(es, entry(`maximum`, types.WrapInteger(it.Max())))
+		}
+		return es
+	case *types.FloatType:
+		ft := t.(*types.FloatType)
+		es := typeEntry(`number`)
+		if ft.Min() != -math.MaxFloat64 {
+			es = append(es, entry(`minimum`, types.WrapFloat(ft.Min())))
+		}
+		if ft.Max() != math.MaxFloat64 {
+			es = append(es, entry(`maximum`, types.WrapFloat(ft.Max())))
+		}
+		return es
+	case *types.NumericType:
+		return []*types.HashEntry{entry(`type`, types.WrapValues([]eval.Value{types.WrapString(`integer`), types.WrapString(`number`)}))}
+	case *types.ScalarDataType:
+		return []*types.HashEntry{entry(`type`, types.WrapValues([]eval.Value{
+			types.WrapString(`string`), types.WrapString(`integer`), types.WrapString(`number`), types.WrapString(`boolean`)}))}
+	case *types.EnumType:
+		values := t.(*types.EnumType).Parameters()
+		if n := len(values); n > 0 {
+			if _, ok := values[n-1].(eval.BooleanValue); ok {
+				// Case insensitive
+				unrepresentable(t, path)
+			}
+		}
+		es := typeEntry(`string`)
+		if len(values) > 0 {
+			es = append(es, entry(`enum`, types.WrapValues(values)))
+		}
+		return es
+	case *types.PatternType:
+		es := typeEntry(`string`)
+		patterns := t.(*types.PatternType).Patterns()
+		patterns.Each(func(p eval.Value) {
+			if !isEcmaCompatible(p.(*types.RegexpType).PatternString()) {
+				unrepresentable(t, path)
+			}
+		})
+		switch patterns.Len() {
+		case 0:
+		case 1:
+			es = append(es, entry(`pattern`, types.WrapString(patterns.At(0).(*types.RegexpType).PatternString())))
+		default:
+			alts := make([]eval.Value, patterns.Len())
+			patterns.EachWithIndex(func(p eval.Value, i int) {
+				alts[i] = types.WrapHash([]*types.HashEntry{entry(`pattern`, types.WrapString(p.(*types.RegexpType).PatternString()))})
+			})
+			es = append(es, entry(`anyOf`, types.WrapValues(alts)))
+		}
+		return es
+	case eval.StringType:
+		st := t.(eval.StringType)
+		if v := st.Value(); v != `` {
+			return []*types.HashEntry{entry(`const`, types.WrapString(v))}
+		}
+		es := typeEntry(`string`)
+		if size, ok := st.Size().(*types.IntegerType); ok {
+			if size.Min() > 0 {
+				es = append(es, entry(`minLength`, types.WrapInteger(size.Min())))
+			}
+			if size.Max() != math.MaxInt64 {
+				es = append(es, entry(`maxLength`, types.WrapInteger(size.Max())))
+			}
+		}
+		return es
+	case *types.ArrayType:
+		at := t.(*types.ArrayType)
+		es := typeEntry(`array`)
+		if _, ok := at.ElementType().(*types.AnyType); !ok {
+			es = append(es, entry(`items`, w.subSchema(at.ElementType(), path+`/items`)))
+		}
+		return append(es, sizeEntries(at.Size(), `minItems`, `maxItems`)...)
+	case *types.TupleType:
+		return w.tuple(t.(*types.TupleType), path)
+	case *types.HashType:
+		ht := t.(*types.HashType)
+		es := typeEntry(`object`)
+		kt := ht.KeyType()
+		if !kt.IsAssignable(types.DefaultStringType(), nil) {
+			if !types.DefaultStringType().IsAssignable(kt, nil) {
+				unrepresentable(t, path)
+			}
+			es = append(es, entry(`propertyNames`, w.subSchema(kt, path+`/propertyNames`)))
+		}
+		if _, ok := ht.ValueType().(*types.AnyType); !ok {
+			es = append(es, entry(`additionalProperties`, w.subSchema(ht.ValueType(), path+`/additionalProperties`)))
+		}
+		return append(es, sizeEntries(ht.Size(), `minProperties`, `maxProperties`)...)
+	case *types.StructType:
+		elements := t.(*types.StructType).Elements()
+		props := make([]*types.HashEntry, len(elements))
+		required := make([]eval.Value, 0, len(elements))
+		for i, e := range elements {
+			props[i] = entry(e.Name(), w.subSchema(e.Value(), path+`/properties/`+escapePointer(e.Name())))
+			if !e.Optional() {
+				required = append(required, types.WrapString(e.Name()))
+			}
+		}
+		return objectEntries(props, required)
+	case *types.OptionalType:
+		ct := t.(*types.OptionalType).ContainedType()
+		if _, ok := ct.(*types.AnyType); ok {
+			return nil
+		}
+		return []*types.HashEntry{entry(`anyOf`, types.WrapValues([]eval.Value{
+			types.WrapHash(typeEntry(`null`)), w.subSchema(ct, path+`/anyOf/1`)}))}
+	case *types.NotUndefType:
+		ct := t.(*types.NotUndefType).ContainedType()
+		notNull := entry(`not`, types.WrapHash(typeEntry(`null`)))
+		if _, ok := ct.(*types.AnyType); ok {
+			return []*types.HashEntry{notNull}
+		}
+		if !ct.IsAssignable(types.DefaultUndefType(), nil) {
+			return w.schema(ct, path)
+		}
+		return []*types.HashEntry{entry(`allOf`, types.WrapValues([]eval.Value{w.subSchema(ct, path+`/allOf/0`)})), notNull}
+	case *types.VariantType:
+		vts := t.(*types.VariantType).Types()
+		alts := make([]eval.Value, len(vts))
+		for i, vt := range vts {
+			alts[i] = w.subSchema(vt, path+`/anyOf/`+strconv.Itoa(i))
+		}
+		return []*types.HashEntry{entry(`anyOf`, types.WrapValues(alts))}
+	case *types.TypeAliasType:
+		at := t.(*types.TypeAliasType)
+		return w.ref(at.Name(), func(defPath string) []*types.HashEntry { return w.schema(at.ResolvedType(), defPath) })
+	case eval.ObjectType:
+		ot := t.(eval.ObjectType)
+		return w.ref(ot.Name(), func(defPath string) []*types.HashEntry { return w.object(ot, defPath) })
+	default:
+		unrepresentable(t, path)
+		return nil
+	}
+}
+
+func (w *schemaWriter) subSchema(t eval.Type, path string) eval.Value {
+	return types.WrapHash(w.schema(t, path))
+}
+
+// ref adds the definition with the given name unless it has already been added and returns a $ref
+// to the definition. The definition is added before it is built so that it can refer to itself.
+func (w *schemaWriter) ref(name string, build func(defPath string) []*types.HashEntry) []*types.HashEntry {
+	ptr := defsPrefix + escapePointer(name)
+	if _, ok := w.defs[name]; !ok {
+		w.defs[name] = eval.EMPTY_MAP
+		w.names = append(w.names, name)
+		w.defs[name] = types.WrapHash(build(ptr))
+	}
+	return []*types.HashEntry{entry(`$ref`, types.WrapString(ptr))}
+}
+
+// tuple returns the schema of a Tuple. The last type of a Tuple applies to all elements beyond the
+// given types so it becomes the items schema when more elements are allowed.
+func (w *schemaWriter) tuple(t *types.TupleType, path string) []*types.HashEntry {
+	tts := t.Types()
+	prefix := make([]eval.Value, len(tts))
+	for i, tt := range tts {
+		prefix[i] = w.subSchema(tt, path+`/prefixItems/`+strconv.Itoa(i))
+	}
+	es := append(typeEntry(`array`), entry(`prefixItems`, types.WrapValues(prefix)))
+	size := t.Size()
+	if size.Max() > int64(len(tts)) && len(tts) > 0 {
+		es = append(es, entry(`items`, w.subSchema(tts[len(tts)-1], path+`/items`)))
+	} else {
+		es = append(es, entry(`items`, types.BooleanFalse))
+	}
+	return append(es, sizeEntries(size, `minItems`, `maxItems`)...)
+}
+
+// object returns the schema of the init hash of an Object type
+func (w *schemaWriter) object(t eval.ObjectType, path string) []*types.HashEntry {
+	attrs := t.AttributesInfo().Attributes()
+	props := make([]*types.HashEntry, len(attrs))
+	required := make([]eval.Value, 0, len(attrs))
+	for i, a := range attrs {
+		ap := path + `/properties/` + escapePointer(a.Name())
+		ps := w.schema(a.Type(), ap)
+		switch {
+		case a.Kind() == types.GIVEN_OR_DERIVED:
+		case a.HasValue():
+			v := a.Value()
+			if !types.DefaultDataType().IsInstance(v, nil) {
+				unrepresentable(v.PType(), ap+`/default`)
+			}
+			ps = append(ps, entry(`default`, v))
+		default:
+			required = append(required, types.WrapString(a.Name()))
+		}
+		props[i] = entry(a.Name(), types.WrapHash(ps))
+	}
+	return objectEntries(props, required)
+}
+
+func objectEntries(props []*types.HashEntry, required []eval.Value) []*types.HashEntry {
+	es := typeEntry(`object`)
+	if len(props) > 0 {
+		es = append(es, entry(`properties`, types.WrapHash(props)))
+	}
+	if len(required) > 0 {
+		es = append(es, entry(`required`, types.WrapValues(required)))
+	}
+	return append(es, entry(`additionalProperties`, types.BooleanFalse))
+}
+
+func sizeEntries(size *types.IntegerType, minKey, maxKey string) []*types.HashEntry {
+	var es []*types.HashEntry
+	if size.Min() > 0 {
+		es = append(es, entry(minKey, types.WrapInteger(size.Min())))
+	}
+	if size.Max() != math.MaxInt64 {
+		es = append(es, entry(maxKey, types.WrapInteger(size.Max())))
+	}
+	return es
+}
@@ -0,0 +1,666 @@
+package jsonschema
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// annotations are the keywords that do not affect validation and therefore are ignored
+var annotations = map[string]bool{
+	`$schema`: true, `$id`: true, `$comment`: true, `$defs`: true, `title`: true, `description`: true,
+	`examples`: true, `default`: true, `deprecated`: true, `readOnly`: true, `writeOnly`: true, `format`: true}
+
+// keywordTypes maps the keywords that are specific to one type to the name of that type
+var keywordTypes = map[string]string{
+	`properties`: `object`, `required`: `object`, `additionalProperties`: `object`, `unevaluatedProperties`: `object`,
+	`propertyNames`: `object`, `minProperties`: `object`, `maxProperties`: `object`,
+	`items`: `array`, `prefixItems`: `array`, `minItems`: `array`, `maxItems`: `array`,
+	`pattern`: `string`, `minLength`: `string`, `maxLength`: `string`,
+	`minimum`: `number`, `maximum`: `number`, `exclusiveMinimum`: `number`, `exclusiveMaximum`: `number`}
+
+// reference is a definition in $defs that has been referenced
+type reference struct {
+	token       string
+	name        string
+	placeholder eval.Type
+}
+
+type typeReader struct {
+	root  eval.Value
+	refs  map[string]*reference
+	queue []*reference
+}
+
+// TypeFromSchema returns the Puppet type that corresponds to the given JSON Schema, which must be
+// a hash or a boolean. This is the inverse of SchemaFromType.
+//
+// Each entry in $defs that is referenced using $ref becomes a type named after the entry with the
+// first letter of each name segment in upper case. The entry becomes an Object type when its
+// properties declare default values and a type alias otherwise. The types are added to the loader
+// of the given context unless a type with the same name already exists, in which case the existing
+// type is used.
+//
+// An EVAL_JSON_SCHEMA_UNSUPPORTED error is raised when the schema uses keywords, or combinations
+// of keywords, that cannot be represented as a Puppet type. Properties are only supported
+// together with additionalProperties false since a Struct doesn't permit other keys.
+func TypeFromSchema(c eval.Context, schema eval.Value) eval.Type {
+	r := &typeReader{root: schema, refs: make(map[string]*reference)}
+	t := r.typ(schema, `#`)
+	if len(r.queue) == 0 {
+		return t
+	}
+
+	// The queue grows while definitions are produced
+	b := bytes.NewBufferString(``)
+	for i := 0; i < len(r.queue); i++ {
+		if _, ok := eval.Load(c, eval.NewTypedName(eval.NsType, r.queue[i].name)); !ok {
+			r.definition(b, r.queue[i])
+		}
+	}
+	if b.Len() > 0 {
+		c.AddDefinitions(c.ParseAndValidate(``, b.String(), false))
+		c.ResolveDefinitions()
+	}
+
+	// The placeholders in the type are replaced with the defined types when the type is parsed
+	return c.ParseType2(t.String())
+}
+
+func unsupported(path, detail string) {
+	panic(eval.Error(eval.EVAL_JSON_SCHEMA_UNSUPPORTED, issue.H{`path`: path, `detail`: detail}))
+}
+
+// schemaObject is a schema hash that keeps track of the keywords that have been used
+type schemaObject struct {
+	hash eval.OrderedMap
+	used map[string]bool
+}
+
+func (s *schemaObject) has(key string) bool {
+	return s.hash.IncludesKey2(key)
+}
+
+func (s *schemaObject) get(key string) (eval.Value, bool) {
+	v, ok := s.hash.Get4(key)
+	if ok {
+		s.used[key] = true
+	}
+	return v, ok
+}
+
+// unused returns the first keyword that is neither used nor an annotation
+func (s *schemaObject) unused() string {
+	k := ``
+	s.hash.EachKey(func(kv eval.Value) {
+		if ks := kv.String(); k == `` && !(s.used[ks] || annotations[ks]) {
+			k = ks
+		}
+	})
+	return k
+}
+
+func (r *typeReader) typ(v eval.Value, path string) eval.Type {
+	switch v.(type) {
+	case eval.BooleanValue:
+		if !v.(eval.BooleanValue).Bool() {
+			unsupported(path, `the false schema matches nothing`)
+		}
+		return types.DefaultAnyType()
+	case eval.OrderedMap:
+		s := &schemaObject{hash: v.(eval.OrderedMap), used: make(map[string]bool)}
+		t := r.content(s, path)
+		if k := s.unused(); k != `` {
+			unsupported(path, fmt.Sprintf(`the keyword '%s' is not supported in this context`, k))
+		}
+		return t
+	default:
+		unsupported(path, `a schema must be a hash or a boolean`)
+		return nil
+	}
+}
+
+func (r *typeReader) content(s *schemaObject, path string) eval.Type {
+	if ref, ok := s.get(`$ref`); ok {
+		return r.ref(ref, path)
+	}
+	notUndef := false
+	if not, ok := s.get(`not`); ok {
+		if !isNullSchema(not) {
+			unsupported(path+`/not`, `only the null type can be negated`)
+		}
+		notUndef = true
+	}
+
+	var t eval.Type
+	switch {
+	case s.has(`anyOf`):
+		alts, _ := s.get(`anyOf`)
+		if patterns, ok := patternList(alts); ok {
+			if tn, ok := s.get(`type`); !ok || tn.String() != `string` {
+				unsupported(path, `alternative patterns require the string type`)
+			}
+			t = types.NewPatternType(patterns)
+		} else {
+			t = r.alternatives(alts, path+`/anyOf`)
+		}
+	case s.has(`oneOf`):
+		alts, _ := s.get(`oneOf`)
+		t = r.alternatives(alts, path+`/oneOf`)
+	case s.has(`allOf`):
+		all, _ := s.get(`allOf`)
+		if l, ok := all.(eval.List); !ok || l.Len() != 1 {
+			unsupported(path+`/allOf`, `only one schema is supported`)
+		}
+		t = r.typ(all.(eval.List).At(0), path+`/allOf/0`)
+	case s.has(`const`):
+		s.get(`type`)
+		v, _ := s.get(`const`)
+		t = constant(v, path+`/const`)
+	case s.has(`enum`):
+		s.get(`type`)
+		v, _ := s.get(`enum`)
+		t = enum(v, path+`/enum`)
+	default:
+		t = r.typed(s, path)
+	}
+	if notUndef {
+		t = types.NewNotUndefType(t)
+	}
+	return t
+}
+
+// ref returns a placeholder for the type of the given reference. The placeholder is a type alias
+// with the name of the definition so it can be written as a type string and parsed once the
+// definition exists.
+func (r *typeReader) ref(v eval.Value, path string) eval.Type {
+	ptr := v.String()
+	if !strings.HasPrefix(ptr, defsPrefix) || strings.Contains(ptr[len(defsPrefix):], `/`) {
+		unsupported(path, fmt.Sprintf(`the reference '%s' is not a reference to an entry in $defs`, ptr))
+	}
+	token := strings.Replace(strings.Replace(ptr[len(defsPrefix):], `~1`, `/`, -1), `~0`, `~`, -1)
+	name := typeName(token)
+	if ref, ok := r.refs[name]; ok {
+		if ref.token != token {
+			unsupported(path, fmt.Sprintf(`the definitions '%s' and '%s' have the same type name`, ref.token, token))
+		}
+		return ref.placeholder
+	}
+	if !types.TYPE_TYPE_NAME.IsInstance(types.WrapString(name), nil) {
+		unsupported(path, fmt.Sprintf(`the definition '%s' cannot be named as a type`, token))
+	}
+	if _, ok := r.definitionSchema(token); !ok {
+		unsupported(path, fmt.Sprintf(`the reference '%s' refers to a missing definition`, ptr))
+	}
+	ref := &reference{token, name, types.NewTypeAliasType(name, nil, types.DefaultNotUndefType())}
+	r.refs[name] = ref
+	r.queue = append(r.queue, ref)
+	return ref.placeholder
+}
+
+// typeName returns the given name with the first letter of each segment in upper case
+func typeName(token string) string {
+	segments := strings.Split(token, `::`)
+	for i, s := range segments {
+		if s != `` {
+			segments[i] = strings.ToUpper(s[:1]) + s[1:]
+		}
+	}
+	return strings.Join(segments, `::`)
+}
+
+func (r *typeReader) definitionSchema(token string) (eval.Value, bool) {
+	if root, ok := r.root.(eval.OrderedMap); ok {
+		if defs, ok := root.Get4(`$defs`); ok {
+			if dh, ok := defs.(eval.OrderedMap); ok {
+				return dh.Get4(token)
+			}
+		}
+	}
+	return nil, false
+}
+
+// definition writes the declaration of the type of the given reference to the given buffer
+func (r *typeReader) definition(b *bytes.Buffer, ref *reference) {
+	path := defsPrefix + escapePointer(ref.token)
+	ds, _ := r.definitionSchema(ref.token)
+	fmt.Fprintf(b, "type %s = ", ref.name)
+	if isObjectSchema(ds) {
+		r.object(b, ds.(eval.OrderedMap), path)
+	} else {
+		b.WriteString(r.typ(ds, path).String())
+	}
+	b.WriteByte('\n')
+}
+
+// isObjectSchema returns true when the given schema has properties with default values
+func isObjectSchema(v eval.Value) bool {
+	if h, ok := v.(eval.OrderedMap); ok {
+		if props, ok := h.Get4(`properties`); ok {
+			if ph, ok := props.(eval.OrderedMap); ok {
+				return ph.AnyPair(func(_, p eval.Value) bool {
+					if pm, ok := p.(eval.OrderedMap); ok {
+						return pm.IncludesKey2(`default`)
+					}
+					return false
+				})
+			}
+		}
+	}
+	return false
+}
+
+// object writes an Object type with the properties of the given schema as attributes
+func (r *typeReader) object(b *bytes.Buffer, h eval.OrderedMap, path string) {
+	s := &schemaObject{hash: h, used: make(map[string]bool)}
+	if tn, ok := s.get(`type`); ok && tn.String() != `object` {
+		unsupported(path+`/type`, `properties require the object type`)
+	}
+	props, _ := s.get(`properties`)
+	required := r.required(s, props.(eval.OrderedMap), path)
+
+	b.WriteString(`Object[{attributes => {`)
+	props.(eval.OrderedMap).EachWithIndex(func(v eval.Value, i int) {
+		e := v.(eval.MapEntry)
+		name := e.Key().String()
+		pp := path + `/properties/` + escapePointer(name)
+		if !types.TYPE_MEMBER_NAME.IsInstance(e.Key(), nil) {
+			unsupported(pp, fmt.Sprintf(`'%s' is not a valid attribute name`, name))
+		}
+		if i > 0 {
+			b.WriteString(`, `)
+		}
+		at := r.typ(e.Value(), pp)
+		if dv, ok := e.Value().(eval.OrderedMap).Get4(`default`); ok {
+			fmt.Fprintf(b, `%s => {type => %s, value => %s}`, name, at, eval.ToString2(dv, types.PROGRAM))
+		} else if required[name] {
+			fmt.Fprintf(b, `%s => %s`, name, at)
+		} else {
+			if !at.IsAssignable(types.DefaultUndefType(), nil) {
+				at = types.NewOptionalType(at)
+			}
+			fmt.Fprintf(b, `%s => {type => %s, value => undef}`, name, at)
+		}
+	})
+	b.WriteString(`}}]`)
+	if k := s.unused(); k != `` {
+		unsupported(path, fmt.Sprintf(`the keyword '%s' is not supported in this context`, k))
+	}
+}
+
+// required returns the names of the required properties after asserting that no other properties
+// are permitted
+func (r *typeReader) required(s *schemaObject, props eval.OrderedMap, path string) map[string]bool {
+	ap, ok := s.get(`additionalProperties`)
+	if !ok {
+		ap, ok = s.get(`unevaluatedProperties`)
+	}
+	if b, isBool := ap.(eval.BooleanValue); !ok || !isBool || b.Bool() {
+		unsupported(path, `properties require additionalProperties false`)
+	}
+	required := make(map[string]bool)
+	if rv, ok := s.get(`required`); ok {
+		l, ok := rv.(eval.List)
+		if !ok {
+			unsupported(path+`/required`, `required must be an array`)
+		}
+		l.Each(func(n eval.Value) {
+			if !props.IncludesKey(n) {
+				unsupported(path+`/required`, fmt.Sprintf(`the required property '%s' is not declared`, n))
+			}
+			required[n.String()] = true
+		})
+	}
+	return required
+}
+
+// typed returns the type for the type keyword and the keywords that are specific to that type
+func (r *typeReader) typed(s *schemaObject, path string) eval.Type {
+	var names []string
+	if tv, ok := s.get(`type`); ok {
+		switch tv.(type) {
+		case eval.StringValue:
+			names = []string{tv.String()}
+		case eval.List:
+			tv.(eval.List).Each(func(n eval.Value) { names = append(names, n.String()) })
+		default:
+			unsupported(path+`/type`, `type must be a string or an array`)
+		}
+	} else {
+		names = inferTypes(s, path)
+	}
+	if len(names) == 0 {
+		return types.DefaultAnyType()
+	}
+
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[n] = true
+	}
+	hasNumberKeywords := s.has(`minimum`) || s.has(`maximum`) || s.has(`exclusiveMinimum`) || s.has(`exclusiveMaximum`)
+	if len(set) == 4 && set[`string`] && set[`integer`] && set[`number`] && set[`boolean`] && !hasNumberKeywords &&
+		!(s.has(`pattern`) || s.has(`minLength`) || s.has(`maxLength`)) {
+		return types.DefaultScalarDataType()
+	}
+	numeric := set[`integer`] && set[`number`] && !hasNumberKeywords
+
+	nullable := false
+	var ts []eval.Type
+	for _, n := range names {
+		switch n {
+		case `null`:
+			nullable = true
+		case `boolean`:
+			ts = append(ts, types.DefaultBooleanType())
+		case `integer`:
+			if !numeric {
+				ts = append(ts, integerType(s, path))
+			}
+		case `number`:
+			if numeric {
+				ts = append(ts, types.DefaultNumericType())
+			} else {
+				ts = append(ts, numberType(s, path))
+			}
+		case `string`:
+			ts = append(ts, stringType(s, path))
+		case `array`:
+			ts = append(ts, r.arrayType(s, path))
+		case `object`:
+			ts = append(ts, r.objectType(s, path))
+		default:
+			unsupported(path+`/type`, fmt.Sprintf(`'%s' is not a type`, n))
+		}
+	}
+
+	var t eval.Type
+	switch len(ts) {
+	case 0:
+		return types.DefaultUndefType()
+	case 1:
+		t = ts[0]
+	default:
+		t = types.NewVariantType(ts...)
+	}
+	if nullable {
+		t = types.NewOptionalType(t)
+	}
+	return t
+}
+
+// inferTypes returns the type that is implied by the keywords of a schema without a type keyword
+func inferTypes(s *schemaObject, path string) []string {
+	var names []string
+	s.hash.EachKey(func(k eval.Value) {
+		if tn, ok := keywordTypes[k.String()]; ok && (len(names) == 0 || names[0] != tn) {
+			names = append(names, tn)
+		}
+	})
+	if len(names) > 1 {
+		unsupported(path, `the schema has keywords for different types but no type`)
+	}
+	return names
+}
+
+func (r *typeReader) alternatives(v eval.Value, path string) eval.Type {
+	l, ok := v.(eval.List)
+	if !ok {
+		unsupported(path, `alternatives must be an array`)
+	}
+	nullable := false
+	ts := make([]eval.Type, 0, l.Len())
+	l.EachWithIndex(func(e eval.Value, i int) {
+		t := r.typ(e, path+`/`+strconv.Itoa(i))
+		if _, ok := t.(*types.UndefType); ok {
+			nullable = true
+		} else {
+			ts = append(ts, t)
+		}
+	})
+	var t eval.Type
+	switch len(ts) {
+	case 0:
+		return types.DefaultUndefType()
+	case 1:
+		t = ts[0]
+	default:
+		t = types.NewVariantType(ts...)
+	}
+	if nullable {
+		t = types.NewOptionalType(t)
+	}
+	return t
+}
+
+// patternList returns the patterns of a list of schemas that each only have a pattern keyword
+func patternList(v eval.Value) ([]*types.RegexpType, bool) {
+	l, ok := v.(eval.List)
+	if !ok || l.Len() == 0 {
+		return nil, false
+	}
+	patterns := make([]*types.RegexpType, 0, l.Len())
+	l.Each(func(e eval.Value) {
+		if h, ok := e.(eval.OrderedMap); ok && h.Len() == 1 {
+			if p, ok := h.Get4(`pattern`); ok {
+				patterns = append(patterns, types.NewRegexpType(p.String()))
+			}
+		}
+	})
+	return patterns, len(patterns) == l.Len()
+}
+
+func isNullSchema(v eval.Value) bool {
+	if h, ok := v.(eval.OrderedMap); ok && h.Len() == 1 {
+		t, ok := h.Get4(`type`)
+		return ok && t.String() == `null`
+	}
+	return false
+}
+
+func constant(v eval.Value, path string) eval.Type {
+	switch v.(type) {
+	case eval.StringValue:
+		if s := v.String(); s != `` {
+			return types.NewStringType(nil, s)
+		}
+		return types.NewEnumType([]string{``}, false)
+	case eval.IntegerValue:
+		i := v.(eval.IntegerValue).Int()
+		return types.NewIntegerType(i, i)
+	case eval.FloatValue:
+		f := v.(eval.FloatValue).Float()
+		return types.NewFloatType(f, f)
+	case eval.BooleanValue:
+		return types.NewBooleanType(v.(eval.BooleanValue).Bool())
+	case *types.UndefValue:
+		return types.DefaultUndefType()
+	default:
+		unsupported(path, `only scalar constants are supported`)
+		return nil
+	}
+}
+
+func enum(v eval.Value, path string) eval.Type {
+	l, ok := v.(eval.List)
+	if !ok || l.Len() == 0 {
+		unsupported(path, `enum must be a non empty array`)
+	}
+	if l.All(func(e eval.Value) bool { _, ok := e.(eval.StringValue); return ok }) {
+		values := make([]string, l.Len())
+		l.EachWithIndex(func(e eval.Value, i int) { values[i] = e.String() })
+		return types.NewEnumType(values, false)
+	}
+	nullable := false
+	ts := make([]eval.Type, 0, l.Len())
+	l.EachWithIndex(func(e eval.Value, i int) {
+		if e == eval.UNDEF {
+			nullable = true
+		} else {
+			ts = append(ts, constant(e, path+`/`+strconv.Itoa(i)))
+		}
+	})
+	var t eval.Type
+	if len(ts) == 1 {
+		t = ts[0]
+	} else {
+		t = types.NewVariantType(ts...)
+	}
+	if nullable {
+		t = types.NewOptionalType(t)
+	}
+	return t
+}
+
+func number(s *schemaObject, key, path string) (float64, bool) {
+	v, ok := s.get(key)
+	if !ok {
+		return 0, false
+	}
+	switch v.(type) {
+	case eval.IntegerValue:
+		return float64(v.(eval.IntegerValue).Int()), true
+	case eval.FloatValue:
+		return v.(eval.FloatValue).Float(), true
+	default:
+		unsupported(path+`/`+key, `a number is required`)
+		return 0, false
+	}
+}
+
+func integerType(s *schemaObject, path string) eval.Type {
+	min, max := int64(math.MinInt64), int64(math.MaxInt64)
+	if f, ok := number(s, `minimum`, path); ok {
+		min = int64(math.Ceil(f))
+	}
+	if f, ok := number(s, `exclusiveMinimum`, path); ok {
+		min = int64(math.Floor(f)) + 1
+	}
+	if f, ok := number(s, `maximum`, path); ok {
+		max = int64(math.Floor(f))
+	}
+	if f, ok := number(s, `exclusiveMaximum`, path); ok {
+		max = int64(math.Ceil(f)) - 1
+	}
+	return types.NewIntegerType(min, max)
+}
+
+func numberType(s *schemaObject, path string) eval.Type {
+	if s.has(`exclusiveMinimum`) || s.has(`exclusiveMaximum`) {
+		unsupported(path, `exclusive limits are only supported for integers`)
+	}
+	min, max := -math.MaxFloat64, math.MaxFloat64
+	if f, ok := number(s, `minimum`, path); ok {
+		min = f
+	}
+	if f, ok := number(s, `maximum`, path); ok {
+		max = f
+	}
+	return types.NewFloatType(min, max)
+}
+
+func stringType(s *schemaObject, path string) eval.Type {
+	if p, ok := s.get(`pattern`); ok {
+		if s.has(`minLength`) || s.has(`maxLength`) {
+			unsupported(path, `a pattern cannot be combined with length limits`)
+		}
+		return types.NewPatternType([]*types.RegexpType{types.NewRegexpType(p.String())})
+	}
+	return types.NewStringType(size(s, `minLength`, `maxLength`, path), ``)
+}
+
+func (r *typeReader) arrayType(s *schemaObject, path string) eval.Type {
+	sz := size(s, `minItems`, `maxItems`, path)
+	if pv, ok := s.get(`prefixItems`); ok {
+		l, ok := pv.(eval.List)
+		if !ok {
+			unsupported(path+`/prefixItems`, `prefixItems must be an array`)
+		}
+		ts := make([]eval.Type, l.Len())
+		l.EachWithIndex(func(e eval.Value, i int) { ts[i] = r.typ(e, path+`/prefixItems/`+strconv.Itoa(i)) })
+		items, ok := s.get(`items`)
+		switch {
+		case !ok:
+			unsupported(path, `prefixItems require items false or items equal to the last prefixItems schema`)
+		case items.Equals(types.BooleanFalse, nil):
+			if !s.has(`maxItems`) {
+				sz = types.NewIntegerType(sz.Min(), int64(len(ts)))
+			}
+		case len(ts) == 0 || !r.typ(items, path+`/items`).Equals(ts[len(ts)-1], nil):
+			unsupported(path+`/items`, `items must be false or equal to the last prefixItems schema`)
+		}
+		if n := int64(len(ts)); sz.Min() == n && sz.Max() == n {
+			// The size is implied by the types
+			sz = nil
+		}
+		return types.NewTupleType(ts, sz)
+	}
+	var et eval.Type
+	if items, ok := s.get(`items`); ok {
+		et = r.typ(items, path+`/items`)
+	}
+	return types.NewArrayType(et, sz)
+}
+
+func (r *typeReader) objectType(s *schemaObject, path string) eval.Type {
+	if pv, ok := s.get(`properties`); ok {
+		props, ok := pv.(eval.OrderedMap)
+		if !ok {
+			unsupported(path+`/properties`, `properties must be a hash`)
+		}
+		required := r.required(s, props, path)
+		elements := make([]*types.StructElement, 0, props.Len())
+		props.EachPair(func(k, v eval.Value) {
+			name := k.String()
+			t := r.typ(v, path+`/properties/`+escapePointer(name))
+			if required[name] {
+				elements = append(elements, types.NewStructElement2(name, t))
+			} else {
+				elements = append(elements, types.NewStructElement(types.NewOptionalType3(name), t))
+			}
+		})
+		return types.NewStructType(elements)
+	}
+
+	var kt eval.Type = types.DefaultStringType()
+	if pn, ok := s.get(`propertyNames`); ok {
+		kt = r.typ(pn, path+`/propertyNames`)
+		if !types.DefaultStringType().IsAssignable(kt, nil) {
+			unsupported(path+`/propertyNames`, `property names must be strings`)
+		}
+	}
+	var vt eval.Type
+	sz := size(s, `minProperties`, `maxProperties`, path)
+	if ap, ok := s.get(`additionalProperties`); ok {
+		if ap.Equals(types.BooleanFalse, nil) {
+			sz = types.NewIntegerType(0, 0)
+		} else {
+			vt = r.typ(ap, path+`/additionalProperties`)
+		}
+	}
+	return types.NewHashType(kt, vt, sz)
+}
+
+func size(s *schemaObject, minKey, maxKey, path string) *types.IntegerType {
+	min, max := int64(0), int64(math.MaxInt64)
+	if v, ok := s.get(minKey); ok {
+		iv, ok := v.(eval.IntegerValue)
+		if !ok {
+			unsupported(path+`/`+minKey, `an integer is required`)
+		}
+		min = iv.Int()
+	}
+	if v, ok := s.get(maxKey); ok {
+		iv, ok := v.(eval.IntegerValue)
+		if !ok {
+			unsupported(path+`/`+maxKey, `an integer is required`)
+		}
+		max = iv.Int()
+	}
+	return types.NewIntegerType(min, max)
+}
@@ -0,0 +1,121 @@
+package jsonschema_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/jsonschema"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func ExampleSchemaFromType() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		ctx.AddDefinitions(ctx.ParseAndValidate(`site.pp`, `
+			type Tree = Struct[{value => Enum[a, b], Optional[children] => Array[Tree, 1]}]
+			type My::Point = Object[attributes => { x => Integer[0], y => { type => Integer[0], value => 0 } }]`, false))
+		ctx.ResolveDefinitions()
+
+		buf := bytes.NewBufferString(``)
+		serialization.DataToJson(jsonschema.SchemaFromType(ctx.ParseType2(`Tuple[Tree, Optional[My::Point]]`)), buf)
+		fmt.Println(buf)
+	})
+	// Output: {"$schema":"https://json-schema.org/draft/2020-12/schema","type":"array","prefixItems":[{"$ref":"#/$defs/Tree"},{"anyOf":[{"type":"null"},{"$ref":"#/$defs/My::Point"}]}],"items":false,"minItems":2,"maxItems":2,"$defs":{"Tree":{"type":"object","properties":{"value":{"type":"string","enum":["a","b"]},"children":{"type":"array","items":{"$ref":"#/$defs/Tree"},"minItems":1}},"required":["value"],"additionalProperties":false},"My::Point":{"type":"object","properties":{"x":{"type":"integer","minimum":0},"y":{"type":"integer","minimum":0,"default":0}},"required":["x"],"additionalProperties":false}}}
+}
+
+func ExampleSchemaFromType_unrepresentable() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		defer func() {
+			fmt.Println(recover())
+		}()
+		jsonschema.SchemaFromType(ctx.ParseType2(`Struct[{when => Timestamp}]`))
+	})
+	// Output: Timestamp at #/properties/when cannot be represented in JSON Schema
+}
+
+func ExampleSchemaFromType_patterns() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		for _, pattern := range []string{`/^[a-z]+(?:-\d+)?$/`, `/\A[a-z]+\z/`, `/(?i)abc/`, `/[[:alpha:]]/`, `/[]a]/`, `/(?P<x>a)/`} {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						fmt.Println(strings.TrimSpace(fmt.Sprint(r)))
+					}
+				}()
+				buf := bytes.NewBufferString(``)
+				serialization.DataToJson(jsonschema.SchemaFromType(ctx.ParseType2(`Struct[{id => Pattern[`+pattern+`]}]`)), buf)
+				fmt.Print(buf)
+			}()
+		}
+	})
+	// Output:
+	// {"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","properties":{"id":{"type":"string","pattern":"^[a-z]+(?:-\\d+)?$"}},"required":["id"],"additionalProperties":false}
+	// Pattern[/\\A[a-z]+\\z/] at #/properties/id cannot be represented in JSON Schema
+	// Pattern[/(?i)abc/] at #/properties/id cannot be represented in JSON Schema
+	// Pattern[/[[:alpha:]]/] at #/properties/id cannot be represented in JSON Schema
+	// Pattern[/[]a]/] at #/properties/id cannot be represented in JSON Schema
+	// Pattern[/(?P<x>a)/] at #/properties/id cannot be represented in JSON Schema
+}
+
+func ExampleTypeFromSchema() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		schema := serialization.UnmarshalJson([]byte(`{
+			"type": "array",
+			"items": {"$ref": "#/$defs/person"},
+			"$defs": {
+				"person": {
+					"type": "object",
+					"properties": {
+						"name": {"type": "string", "minLength": 1},
+						"age": {"type": "integer", "minimum": 0, "default": 18},
+						"friends": {"type": "array", "items": {"$ref": "#/$defs/person"}}
+					},
+					"required": ["name"],
+					"additionalProperties": false
+				}
+			}
+		}`))
+		t := jsonschema.TypeFromSchema(ctx, schema)
+		fmt.Println(t)
+		fmt.Println(eval.ToString2(ctx.ParseType2(`Person`), eval.PRETTY_EXPANDED))
+	})
+	// Output:
+	// Array[Person]
+	// Object[{
+	//   name => 'Person',
+	//   attributes => {
+	//     'name' => String[1],
+	//     'age' => {
+	//       'type' => Integer[0],
+	//       'value' => 18
+	//     },
+	//     'friends' => {
+	//       'type' => Optional[Array[Person]],
+	//       'value' => undef
+	//     }
+	//   }
+	// }]
+}
+
+func ExampleTypeFromSchema_roundtrip() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		t := ctx.ParseType2(`Struct[{a => Pattern[/^x/], Optional[b] => Variant[Integer[1, 9], Boolean]}]`)
+		t2 := jsonschema.TypeFromSchema(ctx, jsonschema.SchemaFromType(t))
+		fmt.Println(t2, t.Equals(t2, nil))
+	})
+	// Output: Struct[{'a' => Pattern[/^x/], Optional['b'] => Variant[Integer[1, 9], Boolean]}] true
+}
+
+func ExampleTypeFromSchema_unsupported() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		defer func() {
+			fmt.Println(recover())
+		}()
+		jsonschema.TypeFromSchema(ctx, serialization.UnmarshalJson([]byte(
+			`{"type": "object", "properties": {"a": {"type": "string", "uniqueItems": true}}, "additionalProperties": false}`)))
+	})
+	// Output: JSON Schema at #/properties/a cannot be represented as a Puppet type: the keyword 'uniqueItems' is not supported in this context
+}
@@ -25,11 +25,13 @@ type (
 		parentedLoader
 		path            string
 		moduleName      string
+		initClassName   eval.TypedName
 		initPlanName    eval.TypedName
 		initTaskName    eval.TypedName
 		initTypeSetName eval.TypedName
 		paths           map[eval.Namespace][]SmartPath
 		index           map[string][]string
+		files           map[string]string
 	}
 )
 
@@ -44,25 +46,36 @@ func newFileBasedLoader(parent eval.Loader, path, moduleName string, loadables .
 			basicLoader: basicLoader{namedEntries: make(map[string]eval.LoaderEntry, 64)},
 			parent:      parent},
 		path:            path,
+		initClassName:   eval.NewTypedName2(eval.NsClass, `init`, parent.NameAuthority()),
 		initPlanName:    eval.NewTypedName2(eval.NsPlan, `init`, parent.NameAuthority()),
 		initTaskName:    eval.NewTypedName2(eval.NsTask, `init`, parent.NameAuthority()),
 		initTypeSetName: eval.NewTypedName2(eval.NsType, `init_typeset`, parent.NameAuthority()),
 		moduleName:      moduleName,
 		paths:           paths}
 
-	for _, p := range loadables {
-		path := loader.newSmartPath(p, !(moduleName == `` || moduleName == `environment`))
+	addPath := func(path SmartPath) {
 		if sa, ok := paths[path.Namespace()]; ok {
 			paths[path.Namespace()] = append(sa, path)
 		} else {
 			paths[path.Namespace()] = []SmartPath{path}
 		}
 	}
+
+	for _, p := range loadables {
+		moduleNameRelative := !(moduleName == `` || moduleName == `environment`)
+		addPath(loader.newSmartPath(p, moduleNameRelative))
+		if p == eval.MANIFEST_PATH {
+			// The manifests directory contains both classes and defined types
+			addPath(loader.newDefinedTypePath(moduleNameRelative))
+		}
+	}
 	return loader
 }
 
 func (l *fileBasedLoader) newSmartPath(pathType eval.PathType, moduleNameRelative bool) SmartPath {
 	switch pathType {
+	case eval.MANIFEST_PATH:
+		return l.newClassPath(moduleNameRelative)
 	case eval.PUPPET_FUNCTION_PATH:
 		return l.newPuppetFunctionPath(moduleNameRelative)
 	case eval.PUPPET_DATA_TYPE_PATH:
@@ -88,6 +101,30 @@ func (l *fileBasedLoader) newPuppetActivityPath(moduleNameRelative bool) SmartPa
 	}
 }
 
+func (l *fileBasedLoader) newClassPath(moduleNameRelative bool) SmartPath {
+	return &smartPath{
+		relativePath:       `manifests`,
+		loader:             l,
+		namespace:          eval.NsClass,
+		extension:          `.pp`,
+		moduleNameRelative: moduleNameRelative,
+		matchMany:          false,
+		instantiator:       InstantiatePuppetManifest,
+	}
+}
+
+func (l *fileBasedLoader) newDefinedTypePath(moduleNameRelative bool) SmartPath {
+	return &smartPath{
+		relativePath:       `manifests`,
+		loader:             l,
+		namespace:          eval.NsDefinedType,
+		extension:          `.pp`,
+		moduleNameRelative: moduleNameRelative,
+		matchMany:          false,
+		instantiator:       InstantiatePuppetManifest,
+	}
+}
+
 func (l *fileBasedLoader) newPuppetFunctionPath(moduleNameRelative bool) SmartPath {
 	return &smartPath{
 		relativePath:       `functions`,
@@ -152,6 +189,30 @@ func (l *fileBasedLoader) ModuleName() string {
 	return l.moduleName
 }
 
+func (l *fileBasedLoader) Path() string {
+	return l.path
+}
+
+// FilePath returns the path of the given file in the given subdirectory of the module. The paths of
+// existing files are cached. Files that are not found are not cached since they may be added later.
+func (l *fileBasedLoader) FilePath(subDir, file string) (string, bool) {
+	key := subDir + `/` + file
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if path, ok := l.files[key]; ok {
+		return path, true
+	}
+	path := filepath.Join(l.path, subDir, filepath.FromSlash(file))
+	if _, err := os.Stat(path); err != nil {
+		return path, false
+	}
+	if l.files == nil {
+		l.files = make(map[string]string)
+	}
+	l.files[key] = path
+	return path, true
+}
+
 func (l *fileBasedLoader) isGlobal() bool {
 	return l.moduleName == `` || l.moduleName == `environment`
 }
@@ -174,6 +235,21 @@ func (l *fileBasedLoader) find(c eval.Context, name eval.TypedName) eval.LoaderE
 		switch name.Namespace() {
 		case eval.NsFunction:
 			// Can be defined in module using a global name. No action required
+		case eval.NsClass:
+			if !l.isGlobal() {
+				// Global name must be the name of the module
+				if l.moduleName != name.Parts()[0] {
+					// Global name must be the name of the module
+					return nil
+				}
+
+				// Look for special 'init' class
+				origins, smartPath := l.findExistingPath(l.initClassName)
+				if smartPath == nil {
+					return nil
+				}
+				return l.instantiate(c, smartPath, name, origins)
+			}
 		case eval.NsPlan:
 			if !l.isGlobal() {
 				// Global name must be the name of the module
@@ -51,6 +51,38 @@ func instantiatePuppetFunction(ctx eval.Context, loader ContentProvidingLoader,
 	ctx.ResolveDefinitions()
 }
 
+// InstantiatePuppetManifest instantiates a class or a defined type from a file in the manifests directory. The
+// file is ignored when it contains a definition that is not of the kind appointed by the given name, since
+// classes and defined types share the same directory.
+func InstantiatePuppetManifest(ctx eval.Context, loader ContentProvidingLoader, tn eval.TypedName, sources []string) {
+	source := sources[0]
+	content := string(loader.GetContent(ctx, source))
+	expr := ctx.ParseAndValidate(source, content, false)
+	name := tn.Name()
+	var dn string
+	switch def := getDefinition(expr, tn.Namespace(), name).(type) {
+	case *parser.HostClassDefinition:
+		if tn.Namespace() != eval.NsClass {
+			return
+		}
+		dn = def.Name()
+	case *parser.ResourceTypeDefinition:
+		if tn.Namespace() != eval.NsDefinedType {
+			return
+		}
+		dn = def.Name()
+	default:
+		panic(ctx.Error(expr, eval.EVAL_NO_DEFINITION, issue.H{`source`: expr.File(), `type`: tn.Namespace(), `name`: name}))
+	}
+	if strings.ToLower(dn) != strings.ToLower(name) {
+		panic(ctx.Error(expr, eval.EVAL_WRONG_DEFINITION, issue.H{`source`: expr.File(), `type`: tn.Namespace(), `expected`: name, `actual`: dn}))
+	}
+	ctx.DoWithLoader(loader, func() {
+		ctx.AddDefinitions(expr)
+		ctx.ResolveDefinitions()
+	})
+}
+
 func InstantiatePuppetType(ctx eval.Context, loader ContentProvidingLoader, tn eval.TypedName, sources []string) {
 	content := string(loader.GetContent(ctx, sources[0]))
 	expr := ctx.ParseAndValidate(sources[0], content, false)
@@ -0,0 +1,164 @@
+package lookup
+
+import (
+	"path/filepath"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-evaluator/yaml"
+)
+
+type (
+	// Config is the contents of a hiera.yaml file of version 5
+	Config interface {
+		// Path returns the path of the configuration file
+		Path() string
+
+		// Hierarchy returns the levels of the hierarchy, highest priority first
+		Hierarchy() []Level
+	}
+
+	// A Level is one level in the hierarchy of a Config
+	Level interface {
+		// Name returns the name of the level
+		Name() string
+
+		// DataHash returns the name of the function that reads the data files of the level
+		DataHash() string
+
+		// Options returns the options that are passed to the data hash function
+		Options() eval.OrderedMap
+
+		// Locations returns the absolute paths of the data files of the level. Interpolation
+		// expressions in the paths are resolved using the given context and globs are expanded.
+		Locations(c eval.Context) []string
+	}
+
+	config struct {
+		path      string
+		hierarchy []Level
+	}
+
+	level struct {
+		name     string
+		dataDir  string
+		dataHash string
+		options  eval.OrderedMap
+		paths    []string
+		globs    []string
+	}
+)
+
+const configType = `Struct[
+  version => Integer[5, 5],
+  Optional[defaults] => Struct[
+    Optional[datadir] => String[1],
+    Optional[data_hash] => String[1],
+    Optional[options] => Hash[String[1], Any]],
+  Optional[hierarchy] => Array[Struct[
+    name => String[1],
+    Optional[datadir] => String[1],
+    Optional[data_hash] => String[1],
+    Optional[path] => String[1],
+    Optional[paths] => Array[String[1], 1],
+    Optional[glob] => String[1],
+    Optional[globs] => Array[String[1], 1],
+    Optional[options] => Hash[String[1], Any]]]]`
+
+// ConfigFromFile reads the configuration in the file appointed by the given path and returns it
+// together with a boolean indicating if the file was found or not.
+//
+// The function will only return false if the given file does not exist. It will panic with an
+// issue.Reported when the file cannot be read or when its contents is not a valid configuration.
+func ConfigFromFile(c eval.Context, path string) (Config, bool) {
+	bf, ok := types.BinaryFromFile2(c, path)
+	if !ok {
+		return nil, false
+	}
+	hv := eval.AssertInstance(path, c.ParseType2(configType), yaml.Unmarshal(c, bf.Bytes())).(eval.OrderedMap)
+
+	dataDir := `data`
+	dataHash := `yaml_data`
+	options := eval.EMPTY_MAP
+	if dv, ok := hv.Get4(`defaults`); ok {
+		defaults := dv.(eval.OrderedMap)
+		dataDir = defaults.Get5(`datadir`, types.WrapString(dataDir)).String()
+		dataHash = defaults.Get5(`data_hash`, types.WrapString(dataHash)).String()
+		options = defaults.Get5(`options`, options).(eval.OrderedMap)
+	}
+
+	cfg := &config{path: path}
+	root := filepath.Dir(path)
+	if lv, ok := hv.Get4(`hierarchy`); ok {
+		levels := lv.(eval.List)
+		cfg.hierarchy = make([]Level, levels.Len())
+		levels.EachWithIndex(func(v eval.Value, i int) {
+			lh := v.(eval.OrderedMap)
+			l := &level{
+				name:     lh.Get5(`name`, eval.EMPTY_STRING).String(),
+				dataDir:  filepath.Join(root, lh.Get5(`datadir`, types.WrapString(dataDir)).String()),
+				dataHash: lh.Get5(`data_hash`, types.WrapString(dataHash)).String(),
+				options:  options.Merge(lh.Get5(`options`, eval.EMPTY_MAP).(eval.OrderedMap)),
+				paths:    stringsOf(lh, `path`, `paths`),
+				globs:    stringsOf(lh, `glob`, `globs`)}
+			cfg.hierarchy[i] = l
+		})
+	} else {
+		cfg.hierarchy = []Level{&level{
+			name:     `Common`,
+			dataDir:  filepath.Join(root, dataDir),
+			dataHash: dataHash,
+			options:  options,
+			paths:    []string{`common.yaml`}}}
+	}
+	return cfg, true
+}
+
+func (c *config) Hierarchy() []Level {
+	return c.hierarchy
+}
+
+func (c *config) Path() string {
+	return c.path
+}
+
+func (l *level) DataHash() string {
+	return l.dataHash
+}
+
+func (l *level) Locations(c eval.Context) []string {
+	ic := newInvocation(c)
+	locations := make([]string, 0, len(l.paths))
+	for _, p := range l.paths {
+		locations = append(locations, filepath.Join(l.dataDir, ic.interpolateString(p).String()))
+	}
+	for _, g := range l.globs {
+		if matches, err := filepath.Glob(filepath.Join(l.dataDir, ic.interpolateString(g).String())); err == nil {
+			locations = append(locations, matches...)
+		}
+	}
+	return locations
+}
+
+func (l *level) Name() string {
+	return l.name
+}
+
+func (l *level) Options() eval.OrderedMap {
+	return l.options
+}
+
+// stringsOf returns the string found under the singular key or the strings found under
+// the plural key of the given hash
+func stringsOf(hash eval.OrderedMap, singular, plural string) []string {
+	if v, ok := hash.Get4(singular); ok {
+		return []string{v.String()}
+	}
+	if v, ok := hash.Get4(plural); ok {
+		vs := v.(eval.List)
+		ss := make([]string, vs.Len())
+		vs.EachWithIndex(func(v eval.Value, i int) { ss[i] = v.String() })
+		return ss
+	}
+	return []string{}
+}
@@ -0,0 +1,332 @@
+package lookup
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+type invocation struct {
+	c         eval.Context
+	nameStack []string
+}
+
+const (
+	configsKey = `lookup.configs`
+	dataKey    = `lookup.data`
+)
+
+func init() {
+	eval.Lookup = Lookup
+}
+
+// Lookup looks up the given key using the configuration appointed by the 'hiera_config' setting
+// followed by the configuration in the hiera.yaml file of the module that the key belongs to.
+//
+// The key may contain dot separated segments that are used for digging into the found value. A
+// segment that contains dots must be quoted. The options may contain a 'merge' entry that determines
+// how the values found in the hierarchy are merged. The merge strategy declared for the key in the
+// lookup_options of the data is used when no 'merge' entry is present.
+//
+// The function returns the found value together with a boolean indicating if a value was found.
+func Lookup(c eval.Context, key string, options eval.OrderedMap) (eval.Value, bool) {
+	var ms MergeStrategy
+	if options != nil {
+		if mv, ok := options.Get4(`merge`); ok {
+			ms = NewMergeStrategy(c, mv)
+		}
+	}
+	return newInvocation(c).lookup(key, ms)
+}
+
+// Interpolate returns a copy of the given value where all interpolation expressions in strings
+// have been resolved. Arrays and hashes are interpolated recursively. An expression can be a
+// variable name with optional dot separated segments for digging into the value of the variable,
+// or one of the methods alias, hiera, literal, lookup, or scope.
+func Interpolate(c eval.Context, value eval.Value) eval.Value {
+	return newInvocation(c).interpolate(value)
+}
+
+func newInvocation(c eval.Context) *invocation {
+	return &invocation{c: c, nameStack: []string{}}
+}
+
+func (ic *invocation) lookup(key string, ms MergeStrategy) (eval.Value, bool) {
+	for _, n := range ic.nameStack {
+		if n == key {
+			panic(eval.Error(eval.EVAL_LOOKUP_RECURSION, issue.H{`name_stack`: strings.Join(append(ic.nameStack, key), `, `)}))
+		}
+	}
+	ic.nameStack = append(ic.nameStack, key)
+	defer func() { ic.nameStack = ic.nameStack[:len(ic.nameStack)-1] }()
+
+	segments := splitKey(key)
+	root := segments[0]
+	module := moduleName(root)
+	if ms == nil {
+		ms = ic.mergeStrategy(root, module)
+	}
+	return ms.Lookup(func(consumer func(value eval.Value) bool) {
+		ic.each(root, module, func(value eval.Value) bool {
+			if v, ok := dig(value, segments[1:]); ok {
+				return consumer(ic.interpolate(v))
+			}
+			return true
+		})
+	})
+}
+
+// each calls the consumer with each value that is found for the given root key until the
+// consumer returns false
+func (ic *invocation) each(root, module string, consumer func(value eval.Value) bool) {
+	for _, cfg := range ic.configs(module) {
+		for _, l := range cfg.Hierarchy() {
+			for _, path := range l.Locations(ic.c) {
+				if v, ok := ic.data(l, path).Get4(root); ok && !consumer(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// mergeStrategy returns the merge strategy that the lookup_options of the data declares for the
+// given root key
+func (ic *invocation) mergeStrategy(root, module string) MergeStrategy {
+	if root != `lookup_options` {
+		lo, ok := mergeLookup(&hashStrategy{}, func(consumer func(value eval.Value) bool) {
+			ic.each(`lookup_options`, module, consumer)
+		})
+		if ok {
+			if opts, ok := optionsFor(lo.(eval.OrderedMap), root); ok {
+				if mv, ok := opts.Get4(`merge`); ok {
+					return NewMergeStrategy(ic.c, mv)
+				}
+			}
+		}
+	}
+	return &firstStrategy{}
+}
+
+// configs returns the global configuration followed by the configuration of the given module
+func (ic *invocation) configs(module string) []Config {
+	var cache map[string]Config
+	if cv, ok := ic.c.Get(configsKey); ok {
+		cache = cv.(map[string]Config)
+	} else {
+		cache = make(map[string]Config)
+		ic.c.Set(configsKey, cache)
+	}
+
+	configs := make([]Config, 0, 2)
+	if hc := eval.GetSetting(`hiera_config`, eval.UNDEF); hc != eval.UNDEF {
+		if cfg := ic.config(cache, hc.String()); cfg != nil {
+			configs = append(configs, cfg)
+		}
+	}
+	if module != `` {
+		if mp := eval.GetSetting(`module_path`, eval.UNDEF); mp != eval.UNDEF {
+			if cfg := ic.config(cache, filepath.Join(mp.String(), module, `hiera.yaml`)); cfg != nil {
+				configs = append(configs, cfg)
+			}
+		}
+	}
+	return configs
+}
+
+func (ic *invocation) config(cache map[string]Config, path string) Config {
+	cfg, ok := cache[path]
+	if !ok {
+		cfg, _ = ConfigFromFile(ic.c, path)
+		cache[path] = cfg
+	}
+	return cfg
+}
+
+// data returns the data hash that the data hash function of the given level produces for the
+// given path. An empty hash is returned if the path doesn't exist.
+func (ic *invocation) data(l Level, path string) eval.OrderedMap {
+	var cache map[string]eval.OrderedMap
+	if dv, ok := ic.c.Get(dataKey); ok {
+		cache = dv.(map[string]eval.OrderedMap)
+	} else {
+		cache = make(map[string]eval.OrderedMap)
+		ic.c.Set(dataKey, cache)
+	}
+
+	if data, ok := cache[path]; ok {
+		return data
+	}
+	data := eval.EMPTY_MAP
+	if _, err := os.Stat(path); err == nil {
+		options := l.Options().Merge(types.SingletonHash2(`path`, types.WrapString(path)))
+		data = eval.AssertInstance(path, hashType, eval.Call(ic.c, l.DataHash(), []eval.Value{options}, nil)).(eval.OrderedMap)
+	}
+	cache[path] = data
+	return data
+}
+
+var interpolationPattern = regexp.MustCompile(`%\{[^}]*\}`)
+var methodPattern = regexp.MustCompile(`\A(\w+)\((?:'([^']*)'|"([^"]*)")\)\z`)
+
+func (ic *invocation) interpolate(value eval.Value) eval.Value {
+	switch value.(type) {
+	case eval.StringValue:
+		return ic.interpolateString(value.String())
+	case *types.ArrayValue:
+		return value.(*types.ArrayValue).Map(ic.interpolate)
+	case *types.HashValue:
+		return value.(*types.HashValue).MapEntries(func(e eval.MapEntry) eval.MapEntry {
+			return types.WrapHashEntry(ic.interpolate(e.Key()), ic.interpolate(e.Value()))
+		})
+	}
+	return value
+}
+
+func (ic *invocation) interpolateString(str string) eval.Value {
+	if !strings.Contains(str, `%{`) {
+		return types.WrapString(str)
+	}
+	var alias eval.Value
+	result := interpolationPattern.ReplaceAllStringFunc(str, func(match string) string {
+		expr := strings.TrimSpace(match[2 : len(match)-1])
+		if expr == `` {
+			return ``
+		}
+		m := methodPattern.FindStringSubmatch(expr)
+		if m == nil {
+			return ic.variable(expr)
+		}
+		arg := m[2] + m[3]
+		switch m[1] {
+		case `alias`:
+			if match != str {
+				panic(eval.Error(eval.EVAL_ALIAS_NOT_ENTIRE_STRING, issue.NO_ARGS))
+			}
+			if v, ok := ic.lookup(arg, nil); ok {
+				alias = v
+			} else {
+				alias = eval.UNDEF
+			}
+			return ``
+		case `hiera`, `lookup`:
+			if v, ok := ic.lookup(arg, nil); ok && v != eval.UNDEF {
+				return v.String()
+			}
+			return ``
+		case `literal`:
+			return arg
+		case `scope`:
+			return ic.variable(arg)
+		default:
+			panic(eval.Error(eval.EVAL_UNKNOWN_INTERPOLATION_METHOD, issue.H{`name`: m[1]}))
+		}
+	})
+	if alias != nil {
+		return alias
+	}
+	return types.WrapString(result)
+}
+
+// variable returns the string representation of the variable appointed by the given expression
+// or an empty string if no such variable exists
+func (ic *invocation) variable(expr string) string {
+	segments := splitKey(strings.TrimPrefix(expr, `::`))
+	v, ok := ic.c.Scope().Get(segments[0])
+	if ok {
+		v, ok = dig(v, segments[1:])
+	}
+	if !ok || v == eval.UNDEF {
+		return ``
+	}
+	return v.String()
+}
+
+// dig digs into the given value using the given segments. A segment is either a hash key or,
+// when the value is an array, an index
+func dig(value eval.Value, segments []string) (eval.Value, bool) {
+	for _, s := range segments {
+		switch value.(type) {
+		case eval.OrderedMap:
+			v, ok := value.(eval.OrderedMap).Get4(s)
+			if !ok {
+				return nil, false
+			}
+			value = v
+		case eval.List:
+			idx, err := strconv.Atoi(s)
+			av := value.(eval.List)
+			if err != nil || idx < 0 || idx >= av.Len() {
+				return nil, false
+			}
+			value = av.At(idx)
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// optionsFor returns the options declared for the given key in the given lookup_options. Options
+// declared for the exact key take precedence over options declared using a regular expression.
+func optionsFor(lookupOptions eval.OrderedMap, key string) (eval.OrderedMap, bool) {
+	if ov, ok := lookupOptions.Get4(key); ok {
+		om, ok := ov.(eval.OrderedMap)
+		return om, ok
+	}
+	var found eval.OrderedMap
+	lookupOptions.Find(func(e eval.Value) bool {
+		me := e.(eval.MapEntry)
+		pattern := me.Key().String()
+		if !strings.HasPrefix(pattern, `^`) {
+			return false
+		}
+		if rx, err := regexp.Compile(pattern); err == nil && rx.MatchString(key) {
+			found, _ = me.Value().(eval.OrderedMap)
+			return found != nil
+		}
+		return false
+	})
+	return found, found != nil
+}
+
+// moduleName returns the name of the module that the given qualified key belongs to or an
+// empty string if the key is not qualified
+func moduleName(key string) string {
+	if i := strings.Index(key, `::`); i > 0 {
+		return key[:i]
+	}
+	return ``
+}
+
+// splitKey splits the given key into its dot separated segments. Single or double quotes
+// are used for segments that contain dots
+func splitKey(key string) []string {
+	segments := make([]string, 0, 4)
+	b := strings.Builder{}
+	quote := rune(0)
+	for _, c := range key {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				b.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '.':
+			segments = append(segments, b.String())
+			b.Reset()
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return append(segments, b.String())
+}
@@ -0,0 +1,68 @@
+package lookup_test
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/lookup"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func ExampleLookup() {
+	eval.Puppet.Set(`hiera_config`, types.WrapString(`testdata/hiera.yaml`))
+	eval.Puppet.Do(func(ctx eval.Context) {
+		_, err := eval.TopEvaluate(ctx, ctx.ParseAndValidate(`site.pp`, `
+      $trusted = { certname => 'web01' }
+      $facts = { os => { family => 'RedHat' } }`, false))
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		for _, key := range []string{`greeting`, `message`, `packages`, `settings`, `settings.log.file`, `ntp::prefer`} {
+			fmt.Println(lookup.Lookup(ctx, key, nil))
+		}
+		fmt.Println(lookup.Lookup(ctx, `packages`, types.SingletonHash2(`merge`, types.WrapString(`first`))))
+		fmt.Println(lookup.Lookup(ctx, `packages`, types.SingletonHash2(`merge`, types.WrapStringToValueMap(map[string]eval.Value{
+			`strategy`: types.WrapString(`deep`), `knockout_prefix`: types.WrapString(`--`)}))))
+		fmt.Println(lookup.Lookup(ctx, `port`, nil))
+	})
+	// Output:
+	// hello true
+	// web01 runs RedHat and says hello true
+	// ['nginx', '--telnet', 'httpd', 'curl', 'telnet'] true
+	// {'log' => {'level' => 'debug', 'file' => '/var/log/app.log'}, 'port' => 8080} true
+	// /var/log/app.log true
+	// ['0.rhel.pool.ntp.org'] true
+	// ['nginx', '--telnet'] true
+	// ['nginx', 'httpd', 'curl'] true
+	// <nil> false
+}
+
+func ExampleLookup_classParameters() {
+	eval.Puppet.Set(`hiera_config`, types.WrapString(`testdata/hiera.yaml`))
+	eval.Puppet.Do(func(ctx eval.Context) {
+		expr := ctx.ParseAndValidate(`site.pp`, `
+      $facts = { os => { family => 'RedHat' } }
+      class ntp(Array[String] $servers, Boolean $iburst = true) {
+        notice("${servers} ${iburst}")
+      }
+      include ntp
+      notice(lookup('greeting', String, 'first', 'bye'))
+      notice(lookup('farewell', String, 'first', 'bye'))
+      notice(lookup(['farewell', 'greeting']))
+      notice(lookup('farewell') |$key| { "no ${key}" })`, false)
+		ctx.AddDefinitions(expr)
+		if _, err := eval.TopEvaluate(ctx, expr); err != nil {
+			fmt.Println(err)
+		}
+	})
+	// Output:
+	// notice: ['0.rhel.pool.ntp.org'] true
+	// notice: hello
+	// notice: bye
+	// notice: hello
+	// notice: no farewell
+}
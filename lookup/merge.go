@@ -0,0 +1,251 @@
+package lookup
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+type (
+	// A MergeStrategy determines how the values that are found at different levels of the
+	// hierarchy are combined into one value
+	MergeStrategy interface {
+		// Name returns the name of the strategy, i.e. first, unique, hash, or deep
+		Name() string
+
+		// Lookup calls the given function with a consumer that receives the found values, highest
+		// priority first. The consumer returns false when it doesn't need more values. The merged
+		// result is returned together with a boolean that indicates if any value was found.
+		Lookup(each func(consumer func(value eval.Value) bool)) (eval.Value, bool)
+
+		// Merge merges the given value with a value of lower priority. The lower value is nil
+		// when there is no such value.
+		Merge(value, lower eval.Value) eval.Value
+	}
+
+	firstStrategy struct{}
+
+	uniqueStrategy struct{}
+
+	hashStrategy struct{}
+
+	deepStrategy struct {
+		knockoutPrefix string
+	}
+)
+
+// MergeType is the type of the value that determines the merge strategy of a lookup
+const MergeType = `Variant[
+  Enum[first, unique, hash, deep],
+  Struct[strategy => Enum[first, unique, hash]],
+  Struct[strategy => Enum[deep], Optional[knockout_prefix] => String[1]]]`
+
+var hashType = types.NewHashType(types.DefaultAnyType(), types.DefaultAnyType(), nil)
+
+// NewMergeStrategy returns the merge strategy that corresponds to the given value. The value must
+// be nil, undef, the name of a strategy, or a hash with a 'strategy' entry and options specific to
+// that strategy. The first strategy is returned when the value is nil or undef.
+func NewMergeStrategy(c eval.Context, merge eval.Value) MergeStrategy {
+	if merge == nil || merge == eval.UNDEF {
+		return &firstStrategy{}
+	}
+	eval.AssertInstance(`merge`, c.ParseType2(MergeType), merge)
+
+	var options eval.OrderedMap
+	name := merge.String()
+	if hv, ok := merge.(eval.OrderedMap); ok {
+		options = hv
+		name = hv.Get5(`strategy`, eval.EMPTY_STRING).String()
+	}
+	switch name {
+	case `unique`:
+		return &uniqueStrategy{}
+	case `hash`:
+		return &hashStrategy{}
+	case `deep`:
+		ds := &deepStrategy{}
+		if options != nil {
+			if kp, ok := options.Get4(`knockout_prefix`); ok {
+				ds.knockoutPrefix = kp.String()
+			}
+		}
+		return ds
+	default:
+		return &firstStrategy{}
+	}
+}
+
+func (s *firstStrategy) Name() string {
+	return `first`
+}
+
+func (s *firstStrategy) Lookup(each func(consumer func(value eval.Value) bool)) (found eval.Value, ok bool) {
+	each(func(value eval.Value) bool {
+		found = value
+		ok = true
+		return false
+	})
+	return
+}
+
+func (s *firstStrategy) Merge(value, lower eval.Value) eval.Value {
+	return value
+}
+
+func (s *uniqueStrategy) Name() string {
+	return `unique`
+}
+
+func (s *uniqueStrategy) Lookup(each func(consumer func(value eval.Value) bool)) (eval.Value, bool) {
+	return mergeLookup(s, each)
+}
+
+// Merge flattens the value and the lower value into one array where duplicates are removed
+func (s *uniqueStrategy) Merge(value, lower eval.Value) eval.Value {
+	result := asArray(value).Flatten()
+	if lower != nil {
+		result = result.AddAll(asArray(lower).Flatten())
+	}
+	return result.Unique()
+}
+
+func (s *hashStrategy) Name() string {
+	return `hash`
+}
+
+func (s *hashStrategy) Lookup(each func(consumer func(value eval.Value) bool)) (eval.Value, bool) {
+	return mergeLookup(s, each)
+}
+
+// Merge merges the value and the lower value, which both must be hashes. Entries of the value take
+// precedence over entries of the lower value.
+func (s *hashStrategy) Merge(value, lower eval.Value) eval.Value {
+	eval.AssertInstance(`hash merge`, hashType, value)
+	if lower == nil {
+		return value
+	}
+	eval.AssertInstance(`hash merge`, hashType, lower)
+	return lower.(eval.OrderedMap).Merge(value.(eval.OrderedMap))
+}
+
+func (s *deepStrategy) Name() string {
+	return `deep`
+}
+
+func (s *deepStrategy) Lookup(each func(consumer func(value eval.Value) bool)) (eval.Value, bool) {
+	return mergeLookup(s, each)
+}
+
+// Merge merges the value and the lower value recursively. Hashes are merged by key and arrays are
+// merged into one array where duplicates are removed. Other values of the lower value are replaced.
+//
+// When a knockout prefix is configured, an array element that is a string that starts with the
+// prefix removes the rest of the string from the lower array, and a hash entry with a value that
+// is equal to the prefix removes the entry from the lower hash.
+func (s *deepStrategy) Merge(value, lower eval.Value) eval.Value {
+	if lower == nil {
+		return s.clean(value)
+	}
+	switch value.(type) {
+	case *types.HashValue:
+		if lh, ok := lower.(*types.HashValue); ok {
+			hv := value.(*types.HashValue)
+			entries := make([]*types.HashEntry, 0, lh.Len()+hv.Len())
+			lh.EachPair(func(k, lv eval.Value) {
+				if v, ok := hv.Get(k); ok {
+					if !s.isKnockout(v) {
+						entries = append(entries, types.WrapHashEntry(k, s.Merge(v, lv)))
+					}
+				} else {
+					entries = append(entries, types.WrapHashEntry(k, lv))
+				}
+			})
+			hv.EachPair(func(k, v eval.Value) {
+				if !lh.IncludesKey(k) && !s.isKnockout(v) {
+					entries = append(entries, types.WrapHashEntry(k, s.clean(v)))
+				}
+			})
+			return types.WrapHash(entries)
+		}
+	case *types.ArrayValue:
+		if la, ok := lower.(*types.ArrayValue); ok {
+			av := value.(*types.ArrayValue)
+			knockouts := make([]eval.Value, 0)
+			elements := make([]eval.Value, 0, av.Len()+la.Len())
+			av.Each(func(v eval.Value) {
+				if ko, ok := s.knockedOut(v); ok {
+					knockouts = append(knockouts, ko)
+				} else {
+					elements = append(elements, v)
+				}
+			})
+			la.Each(func(v eval.Value) {
+				for _, ko := range knockouts {
+					if ko.Equals(v, nil) {
+						return
+					}
+				}
+				elements = append(elements, v)
+			})
+			return types.WrapValues(elements).Unique()
+		}
+	}
+	return s.clean(value)
+}
+
+// clean removes knockout entries and elements from the given value
+func (s *deepStrategy) clean(value eval.Value) eval.Value {
+	if s.knockoutPrefix == `` {
+		return value
+	}
+	switch value.(type) {
+	case *types.HashValue:
+		return value.(*types.HashValue).RejectPairs(func(k, v eval.Value) bool { return s.isKnockout(v) })
+	case *types.ArrayValue:
+		return value.(*types.ArrayValue).Reject(func(v eval.Value) bool {
+			_, ok := s.knockedOut(v)
+			return ok
+		})
+	}
+	return value
+}
+
+func (s *deepStrategy) isKnockout(value eval.Value) bool {
+	if s.knockoutPrefix == `` {
+		return false
+	}
+	sv, ok := value.(eval.StringValue)
+	return ok && sv.String() == s.knockoutPrefix
+}
+
+func (s *deepStrategy) knockedOut(value eval.Value) (eval.Value, bool) {
+	if s.knockoutPrefix != `` {
+		if sv, ok := value.(eval.StringValue); ok && strings.HasPrefix(sv.String(), s.knockoutPrefix) {
+			return types.WrapString(sv.String()[len(s.knockoutPrefix):]), true
+		}
+	}
+	return nil, false
+}
+
+// mergeLookup collects all values produced by the given function and merges them using the
+// given strategy, starting with the value of the lowest priority
+func mergeLookup(s MergeStrategy, each func(consumer func(value eval.Value) bool)) (eval.Value, bool) {
+	values := make([]eval.Value, 0)
+	each(func(value eval.Value) bool {
+		values = append(values, value)
+		return true
+	})
+	var result eval.Value
+	for i := len(values) - 1; i >= 0; i-- {
+		result = s.Merge(values[i], result)
+	}
+	return result, result != nil
+}
+
+func asArray(value eval.Value) eval.List {
+	if av, ok := value.(*types.ArrayValue); ok {
+		return av
+	}
+	return types.SingletonArray(value)
+}
@@ -24,6 +24,7 @@ type (
 	pcoreImpl struct {
 		lock              sync.RWMutex
 		logger            eval.Logger
+		factsProvider     eval.FactsProvider
 		systemLoader      eval.Loader
 		environmentLoader eval.Loader
 		moduleLoaders     map[string]eval.Loader
@@ -39,7 +40,9 @@ func init() {
 	eval.Puppet = puppet
 	puppet.DefineSetting(`environment`, types.DefaultStringType(), types.WrapString(`production`))
 	puppet.DefineSetting(`environmentpath`, types.DefaultStringType(), nil)
+	puppet.DefineSetting(`hiera_config`, types.DefaultStringType(), nil)
 	puppet.DefineSetting(`module_path`, types.DefaultStringType(), nil)
+	puppet.DefineSetting(`regexp_engine`, types.NewEnumType([]string{`go`, `ruby`}, false), types.WrapString(`go`))
 	puppet.DefineSetting(`strict`, types.NewEnumType([]string{`off`, `warning`, `error`}, true), types.WrapString(`warning`))
 	puppet.DefineSetting(`tasks`, types.DefaultBooleanType(), types.WrapBoolean(false))
 	puppet.DefineSetting(`workflow`, types.DefaultBooleanType(), types.WrapBoolean(false))
@@ -81,6 +84,10 @@ func (p *pcoreImpl) SetLogger(logger eval.Logger) {
 	p.logger = logger
 }
 
+func (p *pcoreImpl) SetFactsProvider(provider eval.FactsProvider) {
+	p.factsProvider = provider
+}
+
 func (p *pcoreImpl) SystemLoader() eval.Loader {
 	p.lock.Lock()
 	p.ensureSystemLoader()
@@ -109,7 +116,7 @@ func (p *pcoreImpl) EnvironmentLoader() eval.Loader {
 		envLoader := p.systemLoader // TODO: Add proper environment loader
 		s := p.settings[`module_path`]
 		mds := make([]eval.ModuleLoader, 0)
-		loadables := []eval.PathType{eval.PUPPET_FUNCTION_PATH, eval.PUPPET_DATA_TYPE_PATH, eval.PLAN_PATH, eval.TASK_PATH}
+		loadables := []eval.PathType{eval.MANIFEST_PATH, eval.PUPPET_FUNCTION_PATH, eval.PUPPET_DATA_TYPE_PATH, eval.PLAN_PATH, eval.TASK_PATH}
 		if s.isSet() {
 			modulesPath := s.get().String()
 			fis, err := ioutil.ReadDir(modulesPath)
@@ -179,6 +186,7 @@ func (p *pcoreImpl) RootContext() eval.Context {
 	types.InitTypeSetType(c)
 	threadlocal.Init()
 	threadlocal.Set(eval.PuppetContextKey, c)
+	p.assignFacts(c)
 	return c
 }
 
@@ -193,10 +201,19 @@ func (p *pcoreImpl) DoWithParent(parentCtx context.Context, actor func(eval.Cont
 		ctx = ec.Fork()
 	} else {
 		ctx = impl.WithParent(parentCtx, impl.NewEvaluator, eval.NewParentedLoader(p.EnvironmentLoader()), p.logger, topImplRegistry)
+		p.assignFacts(ctx)
 	}
 	eval.DoWithContext(ctx, actor)
 }
 
+// assignFacts assigns the facts of the facts provider, if any, to the global scope of the given
+// root context
+func (p *pcoreImpl) assignFacts(c eval.Context) {
+	if p.factsProvider != nil {
+		impl.AssignFacts(c.Scope(), p.factsProvider.Facts(c))
+	}
+}
+
 func (p *pcoreImpl) Try(actor func(eval.Context) error) (err error) {
 	return p.TryWithParent(p.RootContext(), actor)
 }
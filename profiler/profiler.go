@@ -0,0 +1,193 @@
+// Package profiler implements an eval.Profiler that records the wall time and call count of the
+// functions, lambdas, and top-level expressions that are evaluated.
+package profiler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+type (
+	// Profiler is an eval.Profiler that aggregates the timings of each unit by its kind, name,
+	// and source location. A Profiler keeps track of one evaluation at a time.
+	Profiler struct {
+		lock    sync.Mutex
+		entries map[key]*Entry
+		stack   []*frame
+		folded  map[string]time.Duration
+	}
+
+	// Entry holds the aggregated timings of one unit
+	Entry struct {
+		Kind eval.ProfileKind
+		Name string
+		File string
+		Line int
+
+		// Calls is the number of times that the unit was evaluated
+		Calls int
+
+		// Total is the wall time spent in the unit, including the units it called. Time spent in
+		// recursive calls is only counted once.
+		Total time.Duration
+
+		// Self is the wall time spent in the unit, excluding the units that it called
+		Self time.Duration
+
+		active int
+	}
+
+	key struct {
+		kind eval.ProfileKind
+		name string
+		file string
+		line int
+	}
+
+	frame struct {
+		entry *Entry
+		start time.Time
+		calls time.Duration
+	}
+)
+
+// New creates a new Profiler
+func New() *Profiler {
+	return &Profiler{entries: make(map[key]*Entry), folded: make(map[string]time.Duration)}
+}
+
+// Attach attaches the profiler to the given context
+func (p *Profiler) Attach(c eval.Context) {
+	c.Set(eval.ProfilerKey, p)
+}
+
+// Detach detaches the profiler from the given context
+func (p *Profiler) Detach(c eval.Context) {
+	c.Delete(eval.ProfilerKey)
+}
+
+func (p *Profiler) Enter(kind eval.ProfileKind, name string, location issue.Location) func() {
+	k := key{kind: kind, name: name}
+	if location != nil {
+		k.file = location.File()
+		k.line = location.Line()
+	}
+
+	p.lock.Lock()
+	entry, ok := p.entries[k]
+	if !ok {
+		entry = &Entry{Kind: kind, Name: name, File: k.file, Line: k.line}
+		p.entries[k] = entry
+	}
+	entry.active++
+	f := &frame{entry: entry, start: time.Now()}
+	p.stack = append(p.stack, f)
+	p.lock.Unlock()
+
+	return func() {
+		elapsed := time.Since(f.start)
+
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		path := p.path()
+		p.stack = p.stack[:len(p.stack)-1]
+		if top := len(p.stack); top > 0 {
+			p.stack[top-1].calls += elapsed
+		}
+		entry.Calls++
+		entry.Self += elapsed - f.calls
+		entry.active--
+		if entry.active == 0 {
+			entry.Total += elapsed
+		}
+		p.folded[path] += elapsed - f.calls
+	}
+}
+
+// path returns the names of the frames on the stack joined by semicolons
+func (p *Profiler) path() string {
+	names := make([]string, len(p.stack))
+	for i, f := range p.stack {
+		names[i] = f.entry.String()
+	}
+	return strings.Join(names, `;`)
+}
+
+// Entries returns the recorded entries sorted by total time, longest first
+func (p *Profiler) Entries() []*Entry {
+	p.lock.Lock()
+	entries := make([]*Entry, 0, len(p.entries))
+	for _, e := range p.entries {
+		entries = append(entries, e)
+	}
+	p.lock.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		if a.Self != b.Self {
+			return a.Self > b.Self
+		}
+		return a.String() < b.String()
+	})
+	return entries
+}
+
+// WriteReport writes a table of all entries, sorted by total time, to the given writer
+func (p *Profiler) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "%10s\t%10s\t%8s\t%s\t%s\t%s\n", `Total`, `Self`, `Calls`, `Kind`, `Name`, `Location`)
+	for _, e := range p.Entries() {
+		fmt.Fprintf(tw, "%10s\t%10s\t%8d\t%s\t%s\t%s\n", round(e.Total), round(e.Self), e.Calls, e.Kind, e.Name, e.Location())
+	}
+	return tw.Flush()
+}
+
+// WriteFolded writes the stacks that were observed during the evaluation to the given writer in
+// the folded format that is understood by flame graph tools. Each line contains the frames of a
+// stack separated by semicolons followed by the self time of its innermost frame in microseconds.
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	p.lock.Lock()
+	paths := make([]string, 0, len(p.folded))
+	for path := range p.folded {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	bw := bufio.NewWriter(w)
+	for _, path := range paths {
+		fmt.Fprintf(bw, "%s %d\n", path, p.folded[path]/time.Microsecond)
+	}
+	p.lock.Unlock()
+	return bw.Flush()
+}
+
+// Location returns the source location of the entry on the form <file>:<line>, or an empty
+// string when the entry has no source
+func (e *Entry) Location() string {
+	if e.File == `` {
+		return ``
+	}
+	return fmt.Sprintf(`%s:%d`, e.File, e.Line)
+}
+
+func (e *Entry) String() string {
+	if l := e.Location(); l != `` {
+		return fmt.Sprintf(`%s (%s)`, e.Name, l)
+	}
+	return e.Name
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Microsecond)
+}
@@ -0,0 +1,55 @@
+package profiler_test
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/profiler"
+
+	// Ensure that pcore is initialized
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func ExampleProfiler() {
+	p := profiler.New()
+	eval.Puppet.Do(func(c eval.Context) {
+		expr := c.ParseAndValidate(`test.pp`, `function twice(Integer $x) {
+  $x * 2
+}
+$a = [1, 2, 3].map |$v| { twice($v) }
+notice($a)
+`, false)
+		c.AddDefinitions(expr)
+		p.Attach(c)
+		eval.TopEvaluate(c, expr)
+	})
+
+	entries := p.Entries()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].String() < entries[j].String() })
+	for _, e := range entries {
+		fmt.Printf("%s %s: %d\n", e.Kind, e, e.Calls)
+	}
+
+	b := bytes.NewBufferString(``)
+	p.WriteFolded(b)
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		fmt.Println(line[:strings.LastIndexByte(line, ' ')])
+	}
+	// Output:
+	// notice: [2, 4, 6]
+	// expression '=' expression (test.pp:4): 1
+	// expression Function Call (test.pp:5): 1
+	// lambda lambda (test.pp:4): 3
+	// go function map: 1
+	// go function notice: 1
+	// function twice (test.pp:1): 3
+	// '=' expression (test.pp:4)
+	// '=' expression (test.pp:4);map
+	// '=' expression (test.pp:4);map;lambda (test.pp:4)
+	// '=' expression (test.pp:4);map;lambda (test.pp:4);twice (test.pp:1)
+	// Function Call (test.pp:5)
+	// Function Call (test.pp:5);notice
+}
@@ -151,39 +151,34 @@ func ConsumePBData(v *datapb.Data, consumer serialization.ValueConsumer) {
 	case *datapb.Data_Reference:
 		consumer.AddRef(int(v.GetReference()))
 	default:
+		// Kind is not set
 		consumer.Add(eval.UNDEF)
 	}
 	return
 }
 
-func FromPBData(v *datapb.Data) (value eval.Value) {
-	switch v.Kind.(type) {
-	case *datapb.Data_BooleanValue:
-		value = types.WrapBoolean(v.GetBooleanValue())
-	case *datapb.Data_FloatValue:
-		value = types.WrapFloat(v.GetFloatValue())
-	case *datapb.Data_IntegerValue:
-		value = types.WrapInteger(v.GetIntegerValue())
-	case *datapb.Data_StringValue:
-		value = types.WrapString(v.GetStringValue())
-	case *datapb.Data_UndefValue:
-		value = eval.UNDEF
-	case *datapb.Data_ArrayValue:
-		av := v.GetArrayValue().GetValues()
-		vals := make([]eval.Value, len(av))
-		for i, elem := range av {
-			vals[i] = FromPBData(elem)
-		}
-		value = types.WrapValues(vals)
-	case *datapb.Data_HashValue:
-		av := v.GetHashValue().Entries
-		vals := make([]*types.HashEntry, len(av))
-		for i, val := range av {
-			vals[i] = types.WrapHashEntry(FromPBData(val.Key), FromPBData(val.Value))
-		}
-		value = types.WrapHash(vals)
-	default:
-		value = eval.UNDEF
-	}
-	return
+// FromPBData converts a datapb.Data into the Data value that it represents. References are
+// resolved.
+func FromPBData(v *datapb.Data) eval.Value {
+	c := serialization.NewCollector()
+	ConsumePBData(v, c)
+	return c.Value()
+}
+
+// ToPBRichData streams the given value through a serialization.Serializer created with the
+// given options and returns the resulting datapb.Data.
+func ToPBRichData(c eval.Context, value eval.Value, options eval.OrderedMap) *datapb.Data {
+	pc := NewProtoConsumer()
+	serialization.NewSerializer(c, options).Convert(value, pc)
+	return pc.Value()
+}
+
+// FromPBRichData converts a datapb.Data produced by a serialization.Serializer back into the
+// rich value that was serialized. References are resolved and the data is passed through a
+// serialization.Deserializer created with the given options, so that Timestamps, SemVers,
+// Sensitive values, Objects, and other rich values are reconstructed with their types.
+func FromPBRichData(c eval.Context, v *datapb.Data, options eval.OrderedMap) eval.Value {
+	ds := serialization.NewDeserializer(c, options)
+	ConsumePBData(v, ds)
+	return ds.Value()
 }
@@ -0,0 +1,91 @@
+package proto_test
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/proto"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func ExampleFromPBData() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		shared := eval.Wrap(ctx, []interface{}{`a`, `b`})
+		v := eval.Wrap(ctx, []interface{}{shared, shared, types.WrapBinary([]byte{1, 2, 3})})
+
+		// The serializer will produce a reference for the second occurrence of shared
+		fmt.Println(proto.FromPBData(proto.ToPBRichData(ctx, v, eval.EMPTY_MAP)))
+	})
+	// Output: [['a', 'b'], ['a', 'b'], Binary('AQID')]
+}
+
+func ExampleFromPBRichData() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		expr := ctx.ParseAndValidate(`site.pp`, `
+			type MyObject = Object[attributes => { name => String, value => Integer }]
+			[
+				undef,
+				true,
+				42,
+				3.14,
+				'hello',
+				Binary('aGVsbG8='),
+				default,
+				/^a+$/,
+				Timestamp('2018-10-17T12:00:00.123 UTC'),
+				Timespan(3723.5),
+				SemVer('1.2.3'),
+				SemVerRange('>=1.0.0 <2.0.0'),
+				URI('https://example.com/x'),
+				Sensitive('secret'),
+				{ 1 => 'one', 'two' => 2 },
+				Integer[1, 10],
+				Optional[String],
+				MyObject('name' => 'a', 'value' => 1),
+			]`, false)
+		ctx.AddDefinitions(expr)
+		v, err := eval.TopEvaluate(ctx, expr)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		v2 := proto.FromPBRichData(ctx, proto.ToPBRichData(ctx, v, eval.EMPTY_MAP), eval.EMPTY_MAP)
+		v.(eval.List).EachWithIndex(func(e eval.Value, i int) {
+			e2 := v2.(eval.List).At(i)
+			if s, ok := e.(*types.SensitiveValue); ok {
+				// Sensitive values are never equal
+				fmt.Printf("%s %v\n", e2.PType().Name(), s.Unwrap().Equals(e2.(*types.SensitiveValue).Unwrap(), nil))
+			} else {
+				fmt.Printf("%s %v\n", e2.PType().Name(), e.Equals(e2, nil))
+			}
+		})
+
+		sym := types.WrapRuntime(serialization.Symbol(`sym`))
+		sym2 := proto.FromPBRichData(ctx, proto.ToPBRichData(ctx, sym, eval.EMPTY_MAP), eval.EMPTY_MAP)
+		fmt.Printf("%T %v\n", sym2.(*types.RuntimeValue).Interface(), sym.Equals(sym2, nil))
+	})
+	// Output:
+	// Undef true
+	// Boolean true
+	// Integer true
+	// Float true
+	// String true
+	// Binary true
+	// Default true
+	// Regexp true
+	// Timestamp true
+	// Timespan true
+	// SemVer true
+	// SemVerRange true
+	// URI true
+	// Sensitive true
+	// Hash true
+	// Type true
+	// Type true
+	// MyObject true
+	// serialization.Symbol true
+}
@@ -0,0 +1,210 @@
+package regex
+
+import (
+	"strings"
+	"unicode"
+)
+
+// posixClasses are the tests of the POSIX brackets. They are also available as properties, e.g.
+// [[:alpha:]] is equivalent to \p{Alpha}. In contrast to \d, \s, \w, and \h, they are Unicode aware.
+var posixClasses = map[string]func(rune) bool{
+	`alnum`: func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.Is(unicode.Nd, r)
+	},
+	`alpha`: func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsMark(r)
+	},
+	`ascii`: func(r rune) bool {
+		return r < unicode.MaxASCII+1
+	},
+	`blank`: func(r rune) bool {
+		return r == '\t' || unicode.Is(unicode.Zs, r)
+	},
+	`cntrl`: unicode.IsControl,
+	`digit`: func(r rune) bool {
+		return unicode.Is(unicode.Nd, r)
+	},
+	`graph`: func(r rune) bool {
+		return unicode.IsGraphic(r) && !unicode.IsSpace(r)
+	},
+	`lower`: unicode.IsLower,
+	`print`: func(r rune) bool {
+		return unicode.IsGraphic(r) && !unicode.IsControl(r)
+	},
+	`punct`: func(r rune) bool {
+		return unicode.IsPunct(r) || r <= unicode.MaxASCII && unicode.IsSymbol(r)
+	},
+	`space`: unicode.IsSpace,
+	`upper`: unicode.IsUpper,
+	`word`: func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.Is(unicode.Nd, r) || unicode.Is(unicode.Pc, r)
+	},
+	`xdigit`: isHexDigit,
+}
+
+// categoryAliases maps the long names of the Unicode general categories to their short names
+var categoryAliases = map[string]string{
+	`letter`:               `L`,
+	`uppercaseletter`:      `Lu`,
+	`lowercaseletter`:      `Ll`,
+	`titlecaseletter`:      `Lt`,
+	`modifierletter`:       `Lm`,
+	`otherletter`:          `Lo`,
+	`mark`:                 `M`,
+	`nonspacingmark`:       `Mn`,
+	`spacingmark`:          `Mc`,
+	`enclosingmark`:        `Me`,
+	`number`:               `N`,
+	`decimalnumber`:        `Nd`,
+	`letternumber`:         `Nl`,
+	`othernumber`:          `No`,
+	`punctuation`:          `P`,
+	`connectorpunctuation`: `Pc`,
+	`dashpunctuation`:      `Pd`,
+	`openpunctuation`:      `Ps`,
+	`closepunctuation`:     `Pe`,
+	`initialpunctuation`:   `Pi`,
+	`finalpunctuation`:     `Pf`,
+	`otherpunctuation`:     `Po`,
+	`symbol`:               `S`,
+	`mathsymbol`:           `Sm`,
+	`currencysymbol`:       `Sc`,
+	`modifiersymbol`:       `Sk`,
+	`othersymbol`:          `So`,
+	`separator`:            `Z`,
+	`spaceseparator`:       `Zs`,
+	`lineseparator`:        `Zl`,
+	`paragraphseparator`:   `Zp`,
+	`other`:                `C`,
+	`control`:              `Cc`,
+	`format`:               `Cf`,
+	`privateuse`:           `Co`,
+	`surrogate`:            `Cs`,
+}
+
+// propertyTest returns the test for the property with the given name, or nil if no such property
+// exists. The name is matched without regard to case, spaces, hyphens, and underscores.
+func propertyTest(name string) func(rune) bool {
+	key := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_':
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+
+	if test, ok := posixClasses[key]; ok {
+		return test
+	}
+	switch key {
+	case `any`:
+		return func(r rune) bool { return true }
+	case `assigned`:
+		return func(r rune) bool { return !unicode.Is(unicode.Cn, r) }
+	}
+	if alias, ok := categoryAliases[key]; ok {
+		key = strings.ToLower(alias)
+	}
+	for _, tables := range []map[string]*unicode.RangeTable{unicode.Categories, unicode.Scripts} {
+		for n, table := range tables {
+			if strings.ToLower(strings.Replace(n, `_`, ``, -1)) == key {
+				t := table
+				return func(r rune) bool { return unicode.Is(t, r) }
+			}
+		}
+	}
+	return nil
+}
+
+// escapeTest returns the test for the class escapes \d, \D, \h, \H, \s, \S, \w, and \W, or nil if
+// the given rune does not denote such an escape. As in Ruby, these classes only match ASCII.
+func escapeTest(c rune) func(rune) bool {
+	switch c {
+	case 'd':
+		return isDigit
+	case 'D':
+		return not(isDigit)
+	case 'h':
+		return isHexDigit
+	case 'H':
+		return not(isHexDigit)
+	case 's':
+		return isSpace
+	case 'S':
+		return not(isSpace)
+	case 'w':
+		return isWord
+	case 'W':
+		return not(isWord)
+	}
+	return nil
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+func isHexDigit(r rune) bool {
+	return isDigit(r) || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F'
+}
+
+func isSpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+func isWord(r rune) bool {
+	return r < unicode.MaxASCII+1 && isWordByte(byte(r))
+}
+
+func isLineBreak(r rune) bool {
+	switch r {
+	case '\n', '\v', '\f', '\r', 0x85, 0x2028, 0x2029:
+		return true
+	}
+	return false
+}
+
+func not(test func(rune) bool) func(rune) bool {
+	return func(r rune) bool { return !test(r) }
+}
+
+// union returns a test that matches when one of the given tests matches or when the rune is within
+// one of the given ranges. The ranges are given as pairs of low and high runes.
+func union(tests []func(rune) bool, ranges []rune) func(rune) bool {
+	return func(r rune) bool {
+		for i := 0; i < len(ranges); i += 2 {
+			if ranges[i] <= r && r <= ranges[i+1] {
+				return true
+			}
+		}
+		for _, test := range tests {
+			if test(r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// foldTest returns a test that also matches the case variants of the runes matched by the given
+// test when fold is true
+func foldTest(test func(rune) bool, fold bool) func(rune) bool {
+	if !fold {
+		return test
+	}
+	return func(r rune) bool {
+		if test(r) {
+			return true
+		}
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if test(f) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,323 @@
+package regex
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+type (
+	// machine holds the state of one match attempt
+	machine struct {
+		input       string
+		searchStart int
+		caps        []int
+	}
+
+	// A node is a compiled part of a regular expression. The match method tries to match the node
+	// at the given position and calls the continuation with the position where the match ended. It
+	// backtracks into the node when the continuation returns false.
+	node interface {
+		match(m *machine, pos int, k func(int) bool) bool
+	}
+
+	// single is a node that always matches exactly one rune
+	single interface {
+		node
+		matchRune(r rune) bool
+	}
+
+	empty struct{}
+
+	seq []node
+
+	alt []node
+
+	literal struct {
+		r    rune
+		fold bool
+	}
+
+	charSet struct {
+		test func(r rune) bool
+	}
+
+	group struct {
+		index int
+		sub   node
+	}
+
+	backref struct {
+		index int
+		fold  bool
+	}
+
+	atomic struct {
+		sub node
+	}
+
+	look struct {
+		sub    node
+		behind bool
+		negate bool
+	}
+
+	repeat struct {
+		sub        node
+		min        int
+		max        int
+		greedy     bool
+		possessive bool
+	}
+
+	assertion int
+)
+
+const (
+	assertBeginLine = assertion(iota)
+	assertEndLine
+	assertBeginText
+	assertEndText
+	assertEndTextOptionalNL
+	assertWordBoundary
+	assertNotWordBoundary
+	assertSearchStart
+)
+
+func (empty) match(m *machine, pos int, k func(int) bool) bool {
+	return k(pos)
+}
+
+func (s seq) match(m *machine, pos int, k func(int) bool) bool {
+	return s.matchFrom(0, m, pos, k)
+}
+
+func (s seq) matchFrom(i int, m *machine, pos int, k func(int) bool) bool {
+	if i == len(s) {
+		return k(pos)
+	}
+	return s[i].match(m, pos, func(p int) bool { return s.matchFrom(i+1, m, p, k) })
+}
+
+func (a alt) match(m *machine, pos int, k func(int) bool) bool {
+	for _, n := range a {
+		if n.match(m, pos, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *literal) match(m *machine, pos int, k func(int) bool) bool {
+	return matchSingle(l, m, pos, k)
+}
+
+func (l *literal) matchRune(r rune) bool {
+	return r == l.r || l.fold && equalFold(r, l.r)
+}
+
+func (c *charSet) match(m *machine, pos int, k func(int) bool) bool {
+	return matchSingle(c, m, pos, k)
+}
+
+func (c *charSet) matchRune(r rune) bool {
+	return c.test(r)
+}
+
+func matchSingle(s single, m *machine, pos int, k func(int) bool) bool {
+	if pos >= len(m.input) {
+		return false
+	}
+	r, w := utf8.DecodeRuneInString(m.input[pos:])
+	return s.matchRune(r) && k(pos+w)
+}
+
+func (g *group) match(m *machine, pos int, k func(int) bool) bool {
+	return g.sub.match(m, pos, func(end int) bool {
+		i := 2 * g.index
+		os, oe := m.caps[i], m.caps[i+1]
+		m.caps[i], m.caps[i+1] = pos, end
+		if k(end) {
+			return true
+		}
+		m.caps[i], m.caps[i+1] = os, oe
+		return false
+	})
+}
+
+func (b *backref) match(m *machine, pos int, k func(int) bool) bool {
+	i := 2 * b.index
+	if m.caps[i] < 0 {
+		return false
+	}
+	captured := m.input[m.caps[i]:m.caps[i+1]]
+	if !b.fold {
+		end := pos + len(captured)
+		return end <= len(m.input) && m.input[pos:end] == captured && k(end)
+	}
+	for _, c := range captured {
+		if pos >= len(m.input) {
+			return false
+		}
+		r, w := utf8.DecodeRuneInString(m.input[pos:])
+		if r != c && !equalFold(r, c) {
+			return false
+		}
+		pos += w
+	}
+	return k(pos)
+}
+
+func (a *atomic) match(m *machine, pos int, k func(int) bool) bool {
+	saved := append([]int(nil), m.caps...)
+	end := -1
+	if !a.sub.match(m, pos, func(e int) bool { end = e; return true }) {
+		return false
+	}
+	if k(end) {
+		return true
+	}
+	copy(m.caps, saved)
+	return false
+}
+
+func (l *look) match(m *machine, pos int, k func(int) bool) bool {
+	saved := append([]int(nil), m.caps...)
+	found := false
+	if l.behind {
+		// Try each possible start position, the closest first, and require that the match ends
+		// where the look-behind starts
+		for start := pos; start >= 0; {
+			if l.sub.match(m, start, func(e int) bool { return e == pos }) {
+				found = true
+				break
+			}
+			if start == 0 {
+				break
+			}
+			_, w := utf8.DecodeLastRuneInString(m.input[:start])
+			start -= w
+		}
+	} else {
+		found = l.sub.match(m, pos, func(int) bool { return true })
+	}
+	if found == l.negate {
+		copy(m.caps, saved)
+		return false
+	}
+	if l.negate {
+		// Captures are never retained from a negative look-around
+		copy(m.caps, saved)
+	}
+	if k(pos) {
+		return true
+	}
+	copy(m.caps, saved)
+	return false
+}
+
+func (r *repeat) match(m *machine, pos int, k func(int) bool) bool {
+	if s, ok := r.sub.(single); ok {
+		return r.matchSingles(s, m, pos, k)
+	}
+	if r.possessive {
+		return (&atomic{&repeat{r.sub, r.min, r.max, true, false}}).match(m, pos, k)
+	}
+	return r.iterate(m, 0, pos, k)
+}
+
+// matchSingles matches a repetition of a node that matches exactly one rune without recursion
+func (r *repeat) matchSingles(s single, m *machine, pos int, k func(int) bool) bool {
+	positions := []int{pos}
+	for p := pos; (r.max < 0 || len(positions) <= r.max) && p < len(m.input); {
+		c, w := utf8.DecodeRuneInString(m.input[p:])
+		if !s.matchRune(c) {
+			break
+		}
+		p += w
+		positions = append(positions, p)
+	}
+	n := len(positions) - 1
+	if n < r.min {
+		return false
+	}
+	switch {
+	case r.possessive:
+		return k(positions[n])
+	case r.greedy:
+		for i := n; i >= r.min; i-- {
+			if k(positions[i]) {
+				return true
+			}
+		}
+	default:
+		for i := r.min; i <= n; i++ {
+			if k(positions[i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *repeat) iterate(m *machine, count, pos int, k func(int) bool) bool {
+	if r.max >= 0 && count >= r.max {
+		return k(pos)
+	}
+	next := func() bool {
+		return r.sub.match(m, pos, func(p int) bool {
+			if p == pos && count >= r.min {
+				// An empty iteration can be repeated forever without making progress
+				return false
+			}
+			return r.iterate(m, count+1, p, k)
+		})
+	}
+	if count < r.min {
+		return next()
+	}
+	if r.greedy {
+		return next() || k(pos)
+	}
+	return k(pos) || next()
+}
+
+func (a assertion) match(m *machine, pos int, k func(int) bool) bool {
+	s := m.input
+	ok := false
+	switch a {
+	case assertBeginLine:
+		ok = pos == 0 || s[pos-1] == '\n' && pos < len(s)
+	case assertEndLine:
+		ok = pos == len(s) || s[pos] == '\n'
+	case assertBeginText:
+		ok = pos == 0
+	case assertEndText:
+		ok = pos == len(s)
+	case assertEndTextOptionalNL:
+		ok = pos == len(s) || pos == len(s)-1 && s[pos] == '\n'
+	case assertWordBoundary, assertNotWordBoundary:
+		before := pos > 0 && isWordByte(s[pos-1])
+		after := pos < len(s) && isWordByte(s[pos])
+		ok = (before != after) == (a == assertWordBoundary)
+	case assertSearchStart:
+		ok = pos == m.searchStart
+	}
+	return ok && k(pos)
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// equalFold returns true if the two runes are equal under simple Unicode case folding
+func equalFold(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for f := unicode.SimpleFold(a); f != a; f = unicode.SimpleFold(f) {
+		if f == b {
+			return true
+		}
+	}
+	return false
+}
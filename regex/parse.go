@@ -0,0 +1,688 @@
+package regex
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+type (
+	parser struct {
+		src      string
+		pos      int
+		names    []string
+		named    bool
+		backrefs []*namedBackref
+	}
+
+	// flags are the options that are in effect at some point of the expression
+	flags struct {
+		fold     bool
+		dotall   bool
+		extended bool
+	}
+
+	// namedBackref is a back reference that is resolved once all groups are known
+	namedBackref struct {
+		ref  *backref
+		name string
+		expr string
+	}
+)
+
+func newParser(src string) *parser {
+	return &parser{src: src, names: []string{``}, named: hasNamedGroup(src)}
+}
+
+// parse parses the source of the parser into a node
+func (p *parser) parse() (n node, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(*Error); ok {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	n = p.parseAlt(flags{})
+	if p.more() {
+		p.fail(ErrUnmatchedParen, p.src)
+	}
+	for _, nb := range p.backrefs {
+		nb.ref.index = -1
+		for i, name := range p.names {
+			if name == nb.name {
+				nb.ref.index = i
+			}
+		}
+		if nb.ref.index < 0 {
+			p.fail(ErrInvalidBackref, nb.expr)
+		}
+	}
+	return
+}
+
+func (p *parser) fail(code ErrorCode, expr string) {
+	panic(&Error{Code: code, Expr: expr})
+}
+
+func (p *parser) more() bool {
+	return p.pos < len(p.src)
+}
+
+func (p *parser) peek() rune {
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	return r
+}
+
+func (p *parser) next() rune {
+	r, w := utf8.DecodeRuneInString(p.src[p.pos:])
+	p.pos += w
+	return r
+}
+
+func (p *parser) eat(r rune) bool {
+	if p.more() && p.peek() == r {
+		p.pos += utf8.RuneLen(r)
+		return true
+	}
+	return false
+}
+
+// parseAlt parses alternatives up to an unbalanced right parenthesis or the end of the source
+func (p *parser) parseAlt(f flags) node {
+	branches := alt{p.parseSeq(f)}
+	for p.eat('|') {
+		branches = append(branches, p.parseSeq(f))
+	}
+	if len(branches) == 1 {
+		return branches[0]
+	}
+	return branches
+}
+
+// parseSeq parses a sequence of quantified atoms up to a '|', an unbalanced right parenthesis, or
+// the end of the source
+func (p *parser) parseSeq(f flags) node {
+	items := seq{}
+	for p.more() {
+		if f.extended && p.skipExtended() {
+			continue
+		}
+		c := p.peek()
+		if c == '|' || c == ')' {
+			break
+		}
+		atom, isolated := p.parseAtom(f)
+		if isolated != nil {
+			// An isolated option such as (?i) applies to the rest of the enclosing group, including
+			// subsequent alternatives
+			items = append(items, p.parseAlt(*isolated))
+			break
+		}
+		items = append(items, p.parseQuantifiers(atom, f))
+	}
+	switch len(items) {
+	case 0:
+		return empty{}
+	case 1:
+		return items[0]
+	default:
+		return items
+	}
+}
+
+// skipExtended skips whitespace and comments when the extended option is in effect. It returns
+// true if something was skipped
+func (p *parser) skipExtended() bool {
+	switch p.peek() {
+	case ' ', '\t', '\n', '\r', '\f', '\v':
+		p.pos++
+		return true
+	case '#':
+		for p.more() && p.next() != '\n' {
+		}
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseQuantifiers(atom node, f flags) node {
+	for p.more() {
+		if f.extended && p.skipExtended() {
+			continue
+		}
+		start := p.pos
+		min, max := 0, -1
+		interval := false
+		switch p.peek() {
+		case '*':
+			p.pos++
+		case '+':
+			p.pos++
+			min = 1
+		case '?':
+			p.pos++
+			max = 1
+		case '{':
+			var ok bool
+			if min, max, ok = p.parseInterval(); !ok {
+				return atom
+			}
+			interval = true
+		default:
+			return atom
+		}
+		r := &repeat{sub: atom, min: min, max: max, greedy: true}
+		if p.eat('?') {
+			r.greedy = false
+		} else if !interval && p.eat('+') {
+			r.possessive = true
+		}
+		if _, ok := atom.(empty); ok {
+			p.fail(ErrInvalidRepeat, p.src[start:p.pos])
+		}
+		atom = r
+	}
+	return atom
+}
+
+// parseInterval parses an interval such as {n}, {n,}, {,m}, or {n,m}. The position is retained and
+// false is returned when the text is not a valid interval, in which case the '{' is a literal.
+func (p *parser) parseInterval() (int, int, bool) {
+	start := p.pos
+	p.pos++
+	min, hasMin := p.parseInt()
+	max := min
+	if p.eat(',') {
+		var hasMax bool
+		if max, hasMax = p.parseInt(); !hasMax {
+			max = -1
+			if !hasMin {
+				p.pos = start
+				return 0, 0, false
+			}
+		}
+	} else if !hasMin {
+		p.pos = start
+		return 0, 0, false
+	}
+	if !p.eat('}') {
+		p.pos = start
+		return 0, 0, false
+	}
+	if min > maxRepeat || max > maxRepeat {
+		p.fail(ErrRepeatTooBig, p.src[start:p.pos])
+	}
+	if max >= 0 && max < min {
+		p.fail(ErrInvalidRepeatRange, p.src[start:p.pos])
+	}
+	return min, max, true
+}
+
+func (p *parser) parseInt() (int, bool) {
+	start := p.pos
+	for p.more() && '0' <= p.peek() && p.peek() <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, false
+	}
+	n, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil {
+		p.fail(ErrRepeatTooBig, p.src[start:p.pos])
+	}
+	return n, true
+}
+
+// parseAtom parses one atom. If the atom is an isolated option such as (?i), a nil node and the
+// flags that are in effect after the option are returned.
+func (p *parser) parseAtom(f flags) (node, *flags) {
+	start := p.pos
+	c := p.next()
+	switch c {
+	case '(':
+		return p.parseGroup(f, start)
+	case '[':
+		return &charSet{p.parseClass(f, start)}, nil
+	case '.':
+		if f.dotall {
+			return &charSet{func(r rune) bool { return true }}, nil
+		}
+		return &charSet{func(r rune) bool { return r != '\n' }}, nil
+	case '^':
+		return assertBeginLine, nil
+	case '$':
+		return assertEndLine, nil
+	case '\\':
+		return p.parseEscape(f, start), nil
+	case '*', '+', '?':
+		p.fail(ErrInvalidRepeat, string(c))
+	}
+	return &literal{c, f.fold}, nil
+}
+
+func (p *parser) parseGroup(f flags, start int) (node, *flags) {
+	if !p.eat('?') {
+		if p.named {
+			// Plain groups do not capture when the expression contains named groups
+			return p.endGroup(p.parseAlt(f), start), nil
+		}
+		return p.parseCapture(f, ``, start), nil
+	}
+
+	if !p.more() {
+		p.fail(ErrMissingParen, p.src[start:])
+	}
+	switch c := p.next(); c {
+	case ':':
+		return p.endGroup(p.parseAlt(f), start), nil
+	case '=', '!':
+		return &look{sub: p.endGroup(p.parseAlt(f), start), negate: c == '!'}, nil
+	case '>':
+		return &atomic{p.endGroup(p.parseAlt(f), start)}, nil
+	case '#':
+		for p.more() && p.peek() != ')' {
+			p.next()
+		}
+		p.endGroup(nil, start)
+		return empty{}, nil
+	case '<':
+		if p.eat('=') {
+			return &look{sub: p.endGroup(p.parseAlt(f), start), behind: true}, nil
+		}
+		if p.eat('!') {
+			return &look{sub: p.endGroup(p.parseAlt(f), start), behind: true, negate: true}, nil
+		}
+		return p.parseCapture(f, p.parseName('>', start), start), nil
+	case '\'':
+		return p.parseCapture(f, p.parseName('\'', start), start), nil
+	default:
+		p.pos -= utf8.RuneLen(c)
+		return p.parseOptions(f, start)
+	}
+}
+
+func (p *parser) parseCapture(f flags, name string, start int) node {
+	index := len(p.names)
+	p.names = append(p.names, name)
+	return &group{index, p.endGroup(p.parseAlt(f), start)}
+}
+
+// endGroup consumes the right parenthesis that ends a group and returns the given node
+func (p *parser) endGroup(n node, start int) node {
+	if !p.eat(')') {
+		p.fail(ErrMissingParen, p.src[start:])
+	}
+	return n
+}
+
+// parseName parses a group name or a back reference name up to the given terminator
+func (p *parser) parseName(end rune, start int) string {
+	nameStart := p.pos
+	for p.more() && p.peek() != end {
+		p.next()
+	}
+	name := p.src[nameStart:p.pos]
+	if !p.eat(end) || !isValidName(name) {
+		p.fail(ErrInvalidGroupName, p.src[start:p.pos])
+	}
+	return name
+}
+
+func isValidName(name string) bool {
+	for i, c := range name {
+		if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9' || c >= utf8.RuneSelf) {
+			return false
+		}
+	}
+	return name != ``
+}
+
+// parseOptions parses an option group such as (?i), (?m-x), or (?ix:...)
+func (p *parser) parseOptions(f flags, start int) (node, *flags) {
+	on := true
+	for p.more() {
+		switch c := p.next(); c {
+		case '-':
+			on = false
+		case 'i':
+			f.fold = on
+		case 'm':
+			f.dotall = on
+		case 'x':
+			f.extended = on
+		case ')':
+			return nil, &f
+		case ':':
+			return p.endGroup(p.parseAlt(f), start), nil
+		default:
+			p.fail(ErrInvalidGroupOption, p.src[start:p.pos])
+		}
+	}
+	p.fail(ErrMissingParen, p.src[start:])
+	return nil, nil
+}
+
+// parseEscape parses an escape sequence outside of a character class. The backslash has been
+// consumed.
+func (p *parser) parseEscape(f flags, start int) node {
+	if !p.more() {
+		p.fail(ErrInvalidEscape, p.src[start:])
+	}
+	c := p.next()
+	switch c {
+	case 'A':
+		return assertBeginText
+	case 'z':
+		return assertEndText
+	case 'Z':
+		return assertEndTextOptionalNL
+	case 'b':
+		return assertWordBoundary
+	case 'B':
+		return assertNotWordBoundary
+	case 'G':
+		return assertSearchStart
+	case 'R':
+		return &atomic{alt{seq{&literal{'\r', false}, &literal{'\n', false}}, &charSet{isLineBreak}}}
+	case 'p', 'P':
+		return &charSet{foldTest(p.parseProperty(c == 'P', start), f.fold)}
+	case 'k':
+		return p.parseNamedBackref(f, start)
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		p.pos--
+		n, _ := p.parseInt()
+		if p.named {
+			p.fail(ErrNumberedBackrefNamed, p.src[start:p.pos])
+		}
+		return p.numberedBackref(n, f, start)
+	}
+	if test := escapeTest(c); test != nil {
+		return &charSet{test}
+	}
+	return &literal{p.parseRuneEscape(c, start), f.fold}
+}
+
+func (p *parser) parseNamedBackref(f flags, start int) node {
+	var end rune
+	switch {
+	case p.eat('<'):
+		end = '>'
+	case p.eat('\''):
+		end = '\''
+	default:
+		p.fail(ErrInvalidBackref, p.src[start:p.pos])
+	}
+	nameStart := p.pos
+	for p.more() && p.peek() != end {
+		p.next()
+	}
+	name := p.src[nameStart:p.pos]
+	if !p.eat(end) || name == `` {
+		p.fail(ErrInvalidBackref, p.src[start:p.pos])
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		if p.named {
+			p.fail(ErrNumberedBackrefNamed, p.src[start:p.pos])
+		}
+		return p.numberedBackref(n, f, start)
+	}
+	ref := &backref{fold: f.fold}
+	p.backrefs = append(p.backrefs, &namedBackref{ref, name, p.src[start:p.pos]})
+	return ref
+}
+
+// numberedBackref returns a back reference to the group with the given number. The group must have
+// been opened before the reference.
+func (p *parser) numberedBackref(n int, f flags, start int) node {
+	if n <= 0 || n >= len(p.names) {
+		p.fail(ErrInvalidBackref, p.src[start:p.pos])
+	}
+	return &backref{n, f.fold}
+}
+
+// parseRuneEscape parses an escape sequence that denotes a single rune. The backslash and the given
+// rune have been consumed.
+func (p *parser) parseRuneEscape(c rune, start int) rune {
+	switch c {
+	case 't':
+		return '\t'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 'f':
+		return '\f'
+	case 'v':
+		return '\v'
+	case 'a':
+		return '\a'
+	case 'e':
+		return 0x1b
+	case '0':
+		return p.parseNumber(8, 0, 2, start)
+	case 'x':
+		if p.eat('{') {
+			r := p.parseNumber(16, 1, 8, start)
+			if !p.eat('}') {
+				p.fail(ErrInvalidEscape, p.src[start:p.pos])
+			}
+			return r
+		}
+		return p.parseNumber(16, 1, 2, start)
+	case 'u':
+		if p.eat('{') {
+			r := p.parseNumber(16, 1, 6, start)
+			if !p.eat('}') {
+				p.fail(ErrInvalidEscape, p.src[start:p.pos])
+			}
+			return r
+		}
+		return p.parseNumber(16, 4, 4, start)
+	case 'c':
+		if !p.more() {
+			p.fail(ErrInvalidEscape, p.src[start:])
+		}
+		return p.next() & 0x1f
+	}
+	// Ruby accepts unknown escapes of letters and treats them as the letter itself
+	return c
+}
+
+// parseNumber parses between min and max digits in the given base
+func (p *parser) parseNumber(base, min, max int, start int) rune {
+	n, digits := 0, 0
+	for digits < max && p.more() {
+		d := digitValue(p.peek())
+		if d < 0 || d >= base {
+			break
+		}
+		n = n*base + d
+		digits++
+		p.pos++
+	}
+	if digits < min || n > utf8.MaxRune {
+		p.fail(ErrInvalidEscape, p.src[start:p.pos])
+	}
+	return rune(n)
+}
+
+func digitValue(c rune) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c-'a') + 10
+	case 'A' <= c && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return -1
+}
+
+// parseProperty parses the {Name} or {^Name} that follows \p or \P
+func (p *parser) parseProperty(negate bool, start int) func(rune) bool {
+	if !p.eat('{') {
+		p.fail(ErrInvalidProperty, p.src[start:p.pos])
+	}
+	if p.eat('^') {
+		negate = !negate
+	}
+	nameStart := p.pos
+	for p.more() && p.peek() != '}' {
+		p.next()
+	}
+	name := p.src[nameStart:p.pos]
+	if !p.eat('}') {
+		p.fail(ErrInvalidProperty, p.src[start:p.pos])
+	}
+	test := propertyTest(name)
+	if test == nil {
+		p.fail(ErrInvalidProperty, p.src[start:p.pos])
+	}
+	if negate {
+		return not(test)
+	}
+	return test
+}
+
+// parseClass parses a bracketed character class. The left bracket has been consumed.
+func (p *parser) parseClass(f flags, start int) func(rune) bool {
+	negate := p.eat('^')
+	test := p.parseClassUnion(f, start)
+	if !p.eat(']') {
+		p.fail(ErrMissingBracket, p.src[start:])
+	}
+	test = foldTest(test, f.fold)
+	if negate {
+		return not(test)
+	}
+	return test
+}
+
+// parseClassUnion parses the items of a character class up to the right bracket that ends it. An
+// intersection operator && makes the union parsed so far the left operand of the intersection.
+func (p *parser) parseClassUnion(f flags, start int) func(rune) bool {
+	tests := make([]func(rune) bool, 0, 4)
+	var ranges []rune
+	for first := true; ; first = false {
+		if !p.more() {
+			p.fail(ErrMissingBracket, p.src[start:])
+		}
+		if p.peek() == ']' && !first {
+			break
+		}
+		if p.pos+1 < len(p.src) && p.src[p.pos:p.pos+2] == `&&` {
+			p.pos += 2
+			left := union(tests, ranges)
+			right := p.parseClassUnion(f, start)
+			return func(r rune) bool { return left(r) && right(r) }
+		}
+
+		itemStart := p.pos
+		lo, test := p.parseClassItem(f, start)
+		if test != nil {
+			tests = append(tests, test)
+			continue
+		}
+		hi := lo
+		if p.pos+1 < len(p.src) && p.src[p.pos] == '-' && p.src[p.pos+1] != ']' {
+			p.pos++
+			var hiTest func(rune) bool
+			if hi, hiTest = p.parseClassItem(f, start); hiTest != nil || hi < lo {
+				p.fail(ErrInvalidCharRange, p.src[itemStart:p.pos])
+			}
+		}
+		ranges = append(ranges, lo, hi)
+	}
+	return union(tests, ranges)
+}
+
+// parseClassItem parses one item of a character class. It returns either a rune or a test for a
+// nested class, a POSIX bracket, or a class escape such as \d.
+func (p *parser) parseClassItem(f flags, start int) (rune, func(rune) bool) {
+	itemStart := p.pos
+	c := p.next()
+	switch c {
+	case '[':
+		if p.eat(':') {
+			if test := p.parsePosixBracket(); test != nil {
+				return 0, test
+			}
+			p.pos = itemStart + 1
+		}
+		return 0, p.parseClass(f, itemStart)
+	case '\\':
+		if !p.more() {
+			p.fail(ErrMissingBracket, p.src[start:])
+		}
+		e := p.next()
+		switch e {
+		case 'p', 'P':
+			return 0, p.parseProperty(e == 'P', itemStart)
+		case 'b':
+			return '\b', nil
+		case 'R':
+			return 0, isLineBreak
+		}
+		if test := escapeTest(e); test != nil {
+			return 0, test
+		}
+		return p.parseRuneEscape(e, itemStart), nil
+	}
+	return c, nil
+}
+
+// parsePosixBracket parses the remainder of a POSIX bracket such as [:alpha:] or [:^digit:]. The
+// leading "[:" has been consumed. Nil is returned when the text is not a valid POSIX bracket.
+func (p *parser) parsePosixBracket() func(rune) bool {
+	negate := p.eat('^')
+	nameStart := p.pos
+	for p.more() && 'a' <= p.peek() && p.peek() <= 'z' {
+		p.pos++
+	}
+	name := p.src[nameStart:p.pos]
+	if !(p.eat(':') && p.eat(']')) {
+		return nil
+	}
+	test := posixClasses[name]
+	if test == nil {
+		p.fail(ErrInvalidCharClass, `[:`+name+`:]`)
+	}
+	if negate {
+		return not(test)
+	}
+	return test
+}
+
+// hasNamedGroup returns true if the given expression contains a named group. The scan is performed
+// before parsing since plain groups do not capture when there are named groups.
+func hasNamedGroup(src string) bool {
+	depth := 0
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			if depth > 0 {
+				depth--
+			}
+		case '(':
+			if depth > 0 || i+3 >= len(src) || src[i+1] != '?' {
+				continue
+			}
+			switch src[i+2] {
+			case '\'':
+				return true
+			case '<':
+				if src[i+3] != '=' && src[i+3] != '!' {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
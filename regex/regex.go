@@ -0,0 +1,82 @@
+// Package regex defines the Regexp interface that is shared by the regular expression engines that
+// the evaluator can use, and implements a pure Go engine that is compatible with the regular
+// expressions of Ruby (Onigmo).
+//
+// The Go engine is the standard library regexp package, i.e. RE2. It guarantees linear time
+// matching but lacks features that are common in Puppet code written for Ruby. The Ruby engine is a
+// backtracking engine that supports look-ahead and look-behind, back references, atomic groups,
+// possessive quantifiers, character class intersections, and the Ruby semantics of anchors, named
+// groups, and options.
+package regex
+
+import (
+	"regexp"
+)
+
+// Engine is the name of a regular expression engine
+type Engine string
+
+const (
+	// GoEngine is the RE2 engine of the Go standard library
+	GoEngine = Engine(`go`)
+
+	// RubyEngine is the Ruby compatible engine implemented by this package
+	RubyEngine = Engine(`ruby`)
+)
+
+// Regexp is a compiled regular expression. The methods have the same semantics as the methods with
+// the same names in the standard library *regexp.Regexp, which implements this interface.
+type Regexp interface {
+	// String returns the source text used to compile the regular expression
+	String() string
+
+	// NumSubexp returns the number of capture groups
+	NumSubexp() int
+
+	// SubexpNames returns the names of the capture groups. The name at index 0, which represents
+	// the whole match, and the names of unnamed groups are empty strings.
+	SubexpNames() []string
+
+	// MatchString reports whether the string s contains any match of the regular expression
+	MatchString(s string) bool
+
+	// FindStringIndex returns a two-element slice of integers defining the location of the leftmost
+	// match of the regular expression in s, or nil if there is no match
+	FindStringIndex(s string) []int
+
+	// FindStringSubmatch returns a slice of strings holding the text of the leftmost match of the
+	// regular expression in s and the matches of its capture groups, or nil if there is no match
+	FindStringSubmatch(s string) []string
+
+	// FindStringSubmatchIndex returns a slice holding the index pairs identifying the leftmost match
+	// of the regular expression in s and the matches of its capture groups, or nil if there is no
+	// match
+	FindStringSubmatchIndex(s string) []int
+
+	// FindAllStringIndex returns the index pairs of at most n successive matches, or of all matches
+	// when n is negative
+	FindAllStringIndex(s string, n int) [][]int
+
+	// FindAllStringSubmatchIndex returns the index pairs of the matches and capture groups of at most
+	// n successive matches, or of all matches when n is negative
+	FindAllStringSubmatchIndex(s string, n int) [][]int
+
+	// Split slices s into substrings separated by the matches of the regular expression
+	Split(s string, n int) []string
+}
+
+// Compile compiles the given expression using the given engine
+func Compile(engine Engine, expr string) (Regexp, error) {
+	if engine == RubyEngine {
+		rx, err := CompileRuby(expr)
+		if err != nil {
+			return nil, err
+		}
+		return rx, nil
+	}
+	rx, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return rx, nil
+}
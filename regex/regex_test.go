@@ -0,0 +1,81 @@
+package regex_test
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/puppet-evaluator/regex"
+)
+
+func ExampleCompileRuby() {
+	rx := regex.MustCompileRuby(`(?<year>\d{4})-(?<month>\d\d)(?=-\d\d)`)
+	fmt.Println(rx.SubexpNames()[1:])
+	fmt.Println(rx.FindStringSubmatch(`released 2018-09-27`))
+	// Output:
+	// [year month]
+	// [2018-09 2018 09]
+}
+
+func ExampleCompileRuby_features() {
+	for _, t := range [][]string{
+		{`(?<!\$)\b\d+`, `$12 or 34`},
+		{`(['"]).*?\1`, `say "hi" 'there'`},
+		{`a++b`, `aaab`},
+		{`a++a`, `aaaa`},
+		{`[a-z&&[^aeiou]]+`, `aeixyz`},
+		{`[[:upper:]]\p{Greek}`, `xAβ`},
+		{`^b`, "a\nb"},
+		{`a.c`, "a\nc"},
+		{`(?m)a.c`, "a\nc"},
+		{`(?x) a b  # comment`, `ab`},
+	} {
+		fmt.Printf("%q\n", regex.MustCompileRuby(t[0]).FindStringSubmatch(t[1]))
+	}
+	// Output:
+	// ["34"]
+	// ["\"hi\"" "\""]
+	// ["aaab"]
+	// []
+	// ["xyz"]
+	// ["Aβ"]
+	// ["b"]
+	// []
+	// ["a\nc"]
+	// ["ab"]
+}
+
+func ExampleCompileRuby_split() {
+	rx := regex.MustCompileRuby(`\s*,\s*`)
+	fmt.Printf("%q\n", rx.Split(`a , b,c ,d`, -1))
+	fmt.Printf("%q\n", rx.Split(`a , b,c ,d`, 2))
+	// Output:
+	// ["a" "b" "c" "d"]
+	// ["a" "b,c ,d"]
+}
+
+func ExampleCompileRuby_error() {
+	for _, expr := range []string{`(a`, `a)`, `[b-a]`, `a{3,2}`, `(?<n>a)\1`, `\p{Foo}`} {
+		_, err := regex.CompileRuby(expr)
+		fmt.Println(err)
+	}
+	// Output:
+	// error parsing regexp: end pattern with unmatched parenthesis: `(a`
+	// error parsing regexp: unmatched close parenthesis: `a)`
+	// error parsing regexp: invalid character class range: `b-a`
+	// error parsing regexp: upper is smaller than lower in repeat range: `{3,2}`
+	// error parsing regexp: numbered backref/call is not allowed. (use name): `\1`
+	// error parsing regexp: invalid character property name: `\p{Foo}`
+}
+
+func ExampleCompile() {
+	for _, engine := range []regex.Engine{regex.GoEngine, regex.RubyEngine} {
+		rx, err := regex.Compile(engine, `(?<=a)b`)
+		if err != nil {
+			fmt.Println(engine, `unsupported`)
+			continue
+		}
+		fmt.Println(engine, rx.FindStringIndex(`abab`))
+	}
+	// Output:
+	// go unsupported
+	// ruby [1 2]
+}
@@ -0,0 +1,208 @@
+package regex
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// RubyRegexp is a regular expression compiled by the Ruby compatible engine. It is safe for
+// concurrent use.
+type RubyRegexp struct {
+	expr     string
+	prog     node
+	names    []string
+	anchored bool
+}
+
+// An ErrorCode describes a failure to compile a regular expression
+type ErrorCode string
+
+const (
+	ErrInvalidBackref       = ErrorCode(`invalid backref number/name`)
+	ErrInvalidCharClass     = ErrorCode(`invalid character class`)
+	ErrInvalidCharRange     = ErrorCode(`invalid character class range`)
+	ErrInvalidEscape        = ErrorCode(`invalid escape sequence`)
+	ErrInvalidGroupName     = ErrorCode(`invalid group name`)
+	ErrInvalidGroupOption   = ErrorCode(`undefined group option`)
+	ErrInvalidProperty      = ErrorCode(`invalid character property name`)
+	ErrInvalidRepeat        = ErrorCode(`target of repeat operator is not specified`)
+	ErrInvalidRepeatRange   = ErrorCode(`upper is smaller than lower in repeat range`)
+	ErrMissingBracket       = ErrorCode(`premature end of char-class`)
+	ErrMissingParen         = ErrorCode(`end pattern with unmatched parenthesis`)
+	ErrNumberedBackrefNamed = ErrorCode(`numbered backref/call is not allowed. (use name)`)
+	ErrRepeatTooBig         = ErrorCode(`too big number for repeat range`)
+	ErrUnmatchedParen       = ErrorCode(`unmatched close parenthesis`)
+)
+
+// An Error describes a failure to compile a regular expression and the offending part of it
+type Error struct {
+	Code ErrorCode
+	Expr string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("error parsing regexp: %s: `%s`", e.Code, e.Expr)
+}
+
+// maxRepeat is the largest count that is accepted in a repeat range such as {n,m}
+const maxRepeat = 100000
+
+// CompileRuby compiles the given expression using the Ruby compatible engine
+func CompileRuby(expr string) (*RubyRegexp, error) {
+	p := newParser(expr)
+	prog, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	rx := &RubyRegexp{expr: expr, prog: prog, names: p.names}
+	if s, ok := prog.(seq); ok && len(s) > 0 {
+		prog = s[0]
+	}
+	if a, ok := prog.(assertion); ok && a == assertBeginText {
+		rx.anchored = true
+	}
+	return rx, nil
+}
+
+// MustCompileRuby is like CompileRuby but panics if the expression cannot be compiled
+func MustCompileRuby(expr string) *RubyRegexp {
+	rx, err := CompileRuby(expr)
+	if err != nil {
+		panic(`regex: CompileRuby(` + expr + `): ` + err.Error())
+	}
+	return rx
+}
+
+func (rx *RubyRegexp) String() string {
+	return rx.expr
+}
+
+func (rx *RubyRegexp) NumSubexp() int {
+	return len(rx.names) - 1
+}
+
+func (rx *RubyRegexp) SubexpNames() []string {
+	return rx.names
+}
+
+func (rx *RubyRegexp) MatchString(s string) bool {
+	return rx.execute(s, 0) != nil
+}
+
+func (rx *RubyRegexp) FindStringIndex(s string) []int {
+	if m := rx.execute(s, 0); m != nil {
+		return m[:2]
+	}
+	return nil
+}
+
+func (rx *RubyRegexp) FindStringSubmatch(s string) []string {
+	m := rx.execute(s, 0)
+	if m == nil {
+		return nil
+	}
+	groups := make([]string, len(m)/2)
+	for i := range groups {
+		if m[2*i] >= 0 {
+			groups[i] = s[m[2*i]:m[2*i+1]]
+		}
+	}
+	return groups
+}
+
+func (rx *RubyRegexp) FindStringSubmatchIndex(s string) []int {
+	return rx.execute(s, 0)
+}
+
+func (rx *RubyRegexp) FindAllStringIndex(s string, n int) [][]int {
+	var result [][]int
+	rx.all(s, n, func(m []int) { result = append(result, m[:2]) })
+	return result
+}
+
+func (rx *RubyRegexp) FindAllStringSubmatchIndex(s string, n int) [][]int {
+	var result [][]int
+	rx.all(s, n, func(m []int) { result = append(result, m) })
+	return result
+}
+
+func (rx *RubyRegexp) Split(s string, n int) []string {
+	if n == 0 {
+		return nil
+	}
+	if len(rx.expr) > 0 && len(s) == 0 {
+		return []string{``}
+	}
+
+	matches := rx.FindAllStringIndex(s, n)
+	parts := make([]string, 0, len(matches))
+	beg, end := 0, 0
+	for _, m := range matches {
+		if n > 0 && len(parts) == n-1 {
+			break
+		}
+		end = m[0]
+		if m[1] != 0 {
+			parts = append(parts, s[beg:end])
+		}
+		beg = m[1]
+	}
+	if end != len(s) {
+		parts = append(parts, s[beg:])
+	}
+	return parts
+}
+
+// all calls deliver with each successive match, at most n times unless n is negative. As with the
+// standard library, an empty match that abuts a preceding match is ignored.
+func (rx *RubyRegexp) all(s string, n int, deliver func(m []int)) {
+	prevEnd := -1
+	for pos, i := 0, 0; (n < 0 || i < n) && pos <= len(s); {
+		m := rx.execute(s, pos)
+		if m == nil {
+			break
+		}
+		accept := true
+		if m[1] == pos {
+			if m[0] == prevEnd {
+				accept = false
+			}
+			if pos < len(s) {
+				_, w := utf8.DecodeRuneInString(s[pos:])
+				pos += w
+			} else {
+				pos++
+			}
+		} else {
+			pos = m[1]
+		}
+		prevEnd = m[1]
+		if accept {
+			deliver(m)
+			i++
+		}
+	}
+}
+
+// execute finds the leftmost match that starts at or after the given position and returns the
+// index pairs of the match and its capture groups, or nil if there is no match
+func (rx *RubyRegexp) execute(s string, pos int) []int {
+	m := &machine{input: s, searchStart: pos, caps: make([]int, 2*len(rx.names))}
+	for start := pos; start <= len(s); {
+		for i := range m.caps {
+			m.caps[i] = -1
+		}
+		end := -1
+		if rx.prog.match(m, start, func(e int) bool { end = e; return true }) {
+			m.caps[0] = start
+			m.caps[1] = end
+			return m.caps
+		}
+		if rx.anchored || start == len(s) {
+			break
+		}
+		_, w := utf8.DecodeRuneInString(s[start:])
+		start += w
+	}
+	return nil
+}
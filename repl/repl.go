@@ -0,0 +1,179 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+const (
+	prompt             = `> `
+	continuationPrompt = `>> `
+)
+
+const help = `Enter Puppet expressions to evaluate them. Input that is incomplete continues on the next line.
+Commands:
+  :type [expression]  show the detailed type of the expression or of the last result
+  :load <file>        evaluate the contents of a file
+  :reset              discard all variables and definitions and reset all settings
+  :help               show this text
+  :quit               end the session`
+
+// A REPL reads Puppet expressions from its input, evaluates them, and prints the results on its
+// output. All expressions of a session are evaluated using the same Context and Scope so that
+// variables, functions, and type aliases that are defined by one input are available to the next.
+type REPL struct {
+	in     *bufio.Scanner
+	out    io.Writer
+	setup  func()
+	last   eval.Value
+	reset  bool
+	quit   bool
+	buffer []string
+}
+
+// New creates a new REPL that reads from the given reader and writes to the given writer. The
+// optional setup function is called before the first session starts and after each reset. It
+// is typically used for assigning settings.
+func New(in io.Reader, out io.Writer, setup func()) *REPL {
+	return &REPL{in: bufio.NewScanner(in), out: out, setup: setup}
+}
+
+// Run runs sessions until the input is exhausted or a :quit command is given
+func (r *REPL) Run() {
+	for !r.quit {
+		if r.setup != nil {
+			r.setup()
+		}
+		r.reset = false
+		r.last = eval.UNDEF
+		eval.Puppet.Do(r.session)
+		if r.reset {
+			eval.Puppet.Reset()
+		}
+	}
+}
+
+// session reads and evaluates input using the given context until a reset or the session ends
+func (r *REPL) session(c eval.Context) {
+	for !(r.reset || r.quit) {
+		if len(r.buffer) == 0 {
+			fmt.Fprint(r.out, prompt)
+		} else {
+			fmt.Fprint(r.out, continuationPrompt)
+		}
+		if !r.in.Scan() {
+			r.quit = true
+			if len(r.buffer) > 0 {
+				// Evaluate what's left so that errors are reported
+				result, _ := r.evaluate(c, `<repl>`, strings.Join(r.buffer, "\n"), true)
+				r.show(result)
+			}
+			fmt.Fprintln(r.out)
+			return
+		}
+		line := r.in.Text()
+		if len(r.buffer) == 0 && strings.HasPrefix(strings.TrimSpace(line), `:`) {
+			r.command(c, strings.TrimSpace(line))
+			continue
+		}
+		r.buffer = append(r.buffer, line)
+		if result, done := r.evaluate(c, `<repl>`, strings.Join(r.buffer, "\n"), false); done {
+			r.buffer = r.buffer[:0]
+			r.show(result)
+		}
+	}
+}
+
+// command executes the given command
+func (r *REPL) command(c eval.Context, line string) {
+	name := line
+	arg := ``
+	if i := strings.IndexAny(line, " \t"); i > 0 {
+		name = line[:i]
+		arg = strings.TrimSpace(line[i+1:])
+	}
+	switch name {
+	case `:type`:
+		result := r.last
+		if arg != `` {
+			if result, _ = r.evaluate(c, `<repl>`, arg, true); result == nil {
+				return
+			}
+		}
+		fmt.Fprintln(r.out, eval.DetailedValueType(result).String())
+	case `:load`:
+		if arg == `` {
+			fmt.Fprintln(r.out, `:load requires a file name`)
+			return
+		}
+		content, err := ioutil.ReadFile(arg)
+		if err != nil {
+			fmt.Fprintln(r.out, err.Error())
+			return
+		}
+		result, _ := r.evaluate(c, arg, string(content), true)
+		r.show(result)
+	case `:reset`:
+		r.reset = true
+	case `:help`:
+		fmt.Fprintln(r.out, help)
+	case `:quit`, `:exit`:
+		r.quit = true
+	default:
+		fmt.Fprintf(r.out, "Unknown command '%s'. Use :help to list the available commands\n", name)
+	}
+}
+
+// evaluate parses and evaluates the given source and returns the result. Errors are printed and
+// yield a nil result. The returned boolean is false when the source is incomplete and final is
+// false. It is true in all other cases.
+func (r *REPL) evaluate(c eval.Context, filename, source string, final bool) (result eval.Value, done bool) {
+	done = true
+	defer func() {
+		if e := recover(); e != nil {
+			if ri, ok := e.(issue.Reported); ok && !final && incomplete(ri) {
+				done = false
+				return
+			}
+			fmt.Fprintln(r.out, e)
+		}
+	}()
+
+	expr := c.ParseAndValidate(filename, source, false)
+	c.AddDefinitions(expr)
+	result, err := eval.TopEvaluate(c, expr)
+	if err != nil {
+		fmt.Fprintln(r.out, err.Error())
+		return nil, true
+	}
+	r.last = result
+	return
+}
+
+// show prints the given result unless it is nil
+func (r *REPL) show(result eval.Value) {
+	if result != nil {
+		fmt.Fprintln(r.out, eval.ToPrettyString(result))
+	}
+}
+
+// incomplete returns true if the given issue was reported because the parser reached the end of
+// the input prematurely
+func incomplete(ri issue.Reported) bool {
+	switch ri.Code() {
+	case parser.LEX_UNTERMINATED_STRING, parser.LEX_UNTERMINATED_COMMENT, parser.LEX_HEREDOC_UNTERMINATED, parser.LEX_HEREDOC_DECL_UNTERMINATED:
+		return true
+	case parser.LEX_UNEXPECTED_TOKEN:
+		return fmt.Sprint(ri.Argument(`token`)) == `EOF`
+	case parser.PARSE_EXPECTED_TOKEN, parser.PARSE_EXPECTED_ONE_OF_TOKENS:
+		return fmt.Sprint(ri.Argument(`actual`)) == `EOF`
+	}
+	return false
+}
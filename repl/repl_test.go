@@ -0,0 +1,31 @@
+package repl_test
+
+import (
+	"os"
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/repl"
+
+	// Ensure that pcore is initialized
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func ExampleREPL_Run() {
+	input := strings.Join([]string{
+		`function twice(Integer $x) { $x * 2 }`,
+		`$x = [1,`,
+		`  2]`,
+		`$x.map |$v| { twice($v) }`,
+		`:type`,
+		`:reset`,
+		`$x`,
+	}, "\n")
+	repl.New(strings.NewReader(input), os.Stdout, nil).Run()
+	// Output:
+	// > undef
+	// > >> [1, 2]
+	// > [2, 4]
+	// > Tuple[Integer[2, 2], Integer[4, 4]]
+	// > > Unknown variable: '$x' (file: <repl>, line: 1, column: 1)
+	// >
+}
@@ -49,6 +49,8 @@ func (ds *dsContext) convert(value eval.Value) eval.Value {
 					return ds.convertSensitive(hash)
 				case PcoreTypeDefault:
 					return types.WrapDefault()
+				case PCORE_TYPE_SYMBOL:
+					return types.WrapRuntime(Symbol(hash.Get5(PcoreValueKey, eval.EMPTY_STRING).String()))
 				default:
 					v := ds.convertOther(hash, pcoreType)
 					switch v.(type) {
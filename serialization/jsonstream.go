@@ -0,0 +1,330 @@
+package serialization
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// DefaultJsonWindow is the default window of a JsonReader or JsonWriter, i.e. the maximum
+// distance between a reference and the value that it refers to.
+const DefaultJsonWindow = 1024
+
+// A JsonReader reads a JSON array or hash incrementally. Each call to Next reads one element of
+// the array or one entry of the hash so memory use is bounded by the size of the largest element
+// rather than by the size of the input.
+type JsonReader struct {
+	path      string
+	decoder   *json.Decoder
+	collector *windowCollector
+	tracker   *pathTracker
+	ds        *dsContext
+	started   bool
+	done      bool
+	hash      bool
+	index     int
+	key       eval.Value
+	value     eval.Value
+}
+
+// NewJsonReader creates a reader that reads a JSON array or hash from the given input. The path
+// is used in error messages. The options are:
+//
+// rich_data: convert each element from its Data representation into a rich value (default true)
+//
+// window: the maximum distance between a reference and the value that it refers to, or zero for
+// no limit (default DefaultJsonWindow)
+func NewJsonReader(ctx eval.Context, path string, in io.Reader, options eval.OrderedMap) *JsonReader {
+	d := json.NewDecoder(in)
+	d.UseNumber()
+	wc := &windowCollector{window: int(options.Get5(`window`, types.WrapInteger(DefaultJsonWindow)).(eval.IntegerValue).Int()), offset: 1}
+	wc.Init()
+	r := &JsonReader{path: path, decoder: d, collector: wc, tracker: &pathTracker{ValueConsumer: wc}}
+	if options.Get5(`rich_data`, types.BooleanTrue).(eval.BooleanValue).Bool() {
+		r.ds = NewDeserializer(ctx, options).(*dsContext)
+	}
+	return r
+}
+
+// Next reads the next element or entry and returns true, or returns false when the end of the
+// array or hash has been reached.
+func (r *JsonReader) Next() bool {
+	defer func() {
+		if e := recover(); e != nil {
+			r.done = true
+			if _, ok := e.(issue.Reported); ok {
+				panic(e)
+			}
+			panic(eval.Error(eval.EVAL_JSON_READ_ERROR, issue.H{`path`: r.location(), `detail`: e}))
+		}
+	}()
+
+	if r.done {
+		return false
+	}
+	if r.started {
+		r.index++
+	} else {
+		r.started = true
+		t := r.token()
+		if dl, ok := t.(json.Delim); ok && (dl == '[' || dl == '{') {
+			r.hash = dl == '{'
+		} else {
+			panic(fmt.Errorf(`expected an array or a hash, got %v`, t))
+		}
+	}
+
+	r.key = nil
+	r.value = nil
+	if !r.decoder.More() {
+		// Consume end delimiter
+		r.token()
+		r.done = true
+		return false
+	}
+
+	// The array or hash itself has reference index zero and is never collected so its elements
+	// and entries are collected one at a time
+	wc := r.collector
+	wc.next()
+	if r.hash {
+		r.key = types.WrapString(r.token().(string))
+		wc.Add(r.key)
+	} else {
+		r.key = types.WrapInteger(int64(r.index))
+	}
+	r.tracker.frames = r.tracker.frames[:0]
+	jsonValue(r.tracker, r.decoder, r.token())
+	v := wc.last()
+	if r.ds != nil {
+		v = r.ds.convert(v)
+		r.ds.context.AddTypes(r.ds.newTypes...)
+		r.ds.newTypes = r.ds.newTypes[:0]
+		r.ds.converted = make(map[eval.Value]eval.Value, 11)
+	}
+	r.value = v
+	return true
+}
+
+// Key returns the key of the current hash entry or the index of the current array element
+func (r *JsonReader) Key() eval.Value {
+	return r.key
+}
+
+// Value returns the current array element or hash entry value
+func (r *JsonReader) Value() eval.Value {
+	return r.value
+}
+
+func (r *JsonReader) token() json.Token {
+	t, err := r.decoder.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		panic(err)
+	}
+	return t
+}
+
+// location returns the path of the input qualified with the key of the current element and the
+// keys and indexes of the nested values that are being read
+func (r *JsonReader) location() string {
+	if r.key == nil {
+		return r.path
+	}
+	b := bytes.NewBufferString(r.path)
+	b.WriteByte('/')
+	r.key.ToString(b, types.PROGRAM, nil)
+	for _, f := range r.tracker.frames {
+		if e := f.element(); e != nil {
+			b.WriteByte('/')
+			e.ToString(b, types.PROGRAM, nil)
+		}
+	}
+	return b.String()
+}
+
+// pathTracker is a ValueConsumer that forwards to another consumer and keeps track of the arrays
+// and hashes that are being read so that errors can report the path to the value being read.
+type pathTracker struct {
+	ValueConsumer
+	frames []*pathFrame
+}
+
+// pathFrame is an array or hash that is being read
+type pathFrame struct {
+	hash bool
+
+	// count is the number of values that has been read, keys included
+	count int
+	key   eval.Value
+}
+
+// element returns the index of the array element or the key of the hash value being read, or nil
+// when a hash key is being read
+func (f *pathFrame) element() eval.Value {
+	if !f.hash {
+		return types.WrapInteger(int64(f.count))
+	}
+	if f.count%2 == 1 {
+		return f.key
+	}
+	return nil
+}
+
+func (p *pathTracker) Add(value eval.Value) {
+	p.ValueConsumer.Add(value)
+	p.added(value)
+}
+
+func (p *pathTracker) AddRef(ref int) {
+	p.ValueConsumer.AddRef(ref)
+	p.added(nil)
+}
+
+func (p *pathTracker) AddArray(len int, doer eval.Doer) {
+	p.ValueConsumer.AddArray(len, p.nested(false, doer))
+	p.added(nil)
+}
+
+func (p *pathTracker) AddHash(len int, doer eval.Doer) {
+	p.ValueConsumer.AddHash(len, p.nested(true, doer))
+	p.added(nil)
+}
+
+// nested returns a doer that calls the given doer within a new frame. The frame remains when the
+// doer panics so that the path to the failing value can be reported.
+func (p *pathTracker) nested(hash bool, doer eval.Doer) eval.Doer {
+	return func() {
+		p.frames = append(p.frames, &pathFrame{hash: hash})
+		doer()
+		p.frames = p.frames[:len(p.frames)-1]
+	}
+}
+
+// added counts the given value in the current frame and retains it when it is a hash key
+func (p *pathTracker) added(value eval.Value) {
+	if n := len(p.frames); n > 0 {
+		f := p.frames[n-1]
+		if f.hash && f.count%2 == 0 {
+			f.key = value
+		}
+		f.count++
+	}
+}
+
+// windowCollector is a collector that only retains the values within its window so that they
+// can be referenced.
+type windowCollector struct {
+	collector
+	window int
+
+	// offset is the reference index of the first retained value
+	offset int
+}
+
+func (wc *windowCollector) AddRef(ref int) {
+	end := wc.offset + len(wc.values)
+	if ref < wc.offset || wc.window > 0 && end-ref > wc.window {
+		panic(fmt.Errorf(`reference %d is outside of the window of %d values`, ref, wc.window))
+	}
+	if ref >= end {
+		panic(fmt.Errorf(`reference %d refers to a value that has not been read`, ref))
+	}
+	wc.collector.AddRef(ref - wc.offset)
+}
+
+// next prepares for the next element and drops the values that are outside of the window
+func (wc *windowCollector) next() {
+	if drop := len(wc.values) - wc.window; wc.window > 0 && drop > 0 {
+		n := copy(wc.values, wc.values[drop:])
+		for i := n; i < len(wc.values); i++ {
+			wc.values[i] = nil
+		}
+		wc.values = wc.values[:n]
+		wc.offset += drop
+	}
+	wc.stack = wc.stack[:1]
+	wc.stack[0] = wc.stack[0][:0]
+}
+
+// last returns the last value that was collected at the top level
+func (wc *windowCollector) last() eval.Value {
+	top := wc.stack[0]
+	return top[len(top)-1]
+}
+
+// A JsonWriter writes a JSON array or hash incrementally. Each value is serialized when it is
+// added so memory use is bounded by the size of the largest value rather than by the size of the
+// output.
+type JsonWriter struct {
+	out     io.Writer
+	context *context
+	hash    bool
+	index   int
+}
+
+// NewJsonArrayWriter creates a writer that writes a JSON array to the given output. The options
+// are those of NewSerializer. The window option defaults to DefaultJsonWindow.
+func NewJsonArrayWriter(ctx eval.Context, out io.Writer, options eval.OrderedMap) *JsonWriter {
+	return newJsonWriter(ctx, out, options, false)
+}
+
+// NewJsonHashWriter creates a writer that writes a JSON hash to the given output. The options
+// are those of NewSerializer. The window option defaults to DefaultJsonWindow.
+func NewJsonHashWriter(ctx eval.Context, out io.Writer, options eval.OrderedMap) *JsonWriter {
+	return newJsonWriter(ctx, out, options, true)
+}
+
+func newJsonWriter(ctx eval.Context, out io.Writer, options eval.OrderedMap, hash bool) *JsonWriter {
+	if _, ok := options.Get4(`window`); !ok {
+		options = options.Merge(types.SingletonHash2(`window`, types.WrapInteger(DefaultJsonWindow)))
+	}
+	state := firstInArray
+	start := byte('[')
+	if hash {
+		state = firstInObject
+		start = '{'
+	}
+	sc := NewSerializer(ctx, options).(*rdSerializer).newContext(&jsonStreamer{out, state})
+
+	// The array or hash itself has reference index zero
+	sc.refIndex = 1
+	assertOk(out.Write([]byte{start}))
+	return &JsonWriter{out: out, context: sc, hash: hash}
+}
+
+// Add writes the given value as the next element of the array
+func (w *JsonWriter) Add(value eval.Value) {
+	if w.hash {
+		panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: `Add called on a JsonWriter that writes a hash`}))
+	}
+	w.context.withPath(types.WrapInteger(int64(w.index)), func() { w.context.toData(1, value) })
+	w.index++
+}
+
+// AddEntry writes the given key and value as the next entry of the hash
+func (w *JsonWriter) AddEntry(key string, value eval.Value) {
+	if !w.hash {
+		panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: `AddEntry called on a JsonWriter that writes an array`}))
+	}
+	k := types.WrapString(key)
+	w.context.toData(2, k)
+	w.context.withPath(k, func() { w.context.toData(1, value) })
+	w.index++
+}
+
+// Close ends the array or hash. The writer cannot be used after it has been closed.
+func (w *JsonWriter) Close() {
+	end := byte(']')
+	if w.hash {
+		end = '}'
+	}
+	assertOk(w.out.Write([]byte{end}))
+}
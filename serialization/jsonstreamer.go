@@ -92,6 +92,7 @@ func (j *jsonStreamer) delimit(doer eval.Doer) {
 	default: // Element
 		assertOk(j.out.Write([]byte{','}))
 		doer()
+		j.state = afterElement
 	}
 }
 
@@ -1,6 +1,7 @@
 package serialization
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -29,6 +30,29 @@ func JsonToData(path string, in io.Reader, consumer ValueConsumer) {
 	jsonValues(consumer, d)
 }
 
+// UnmarshalJson parses the given JSON and returns the resulting value. The order of all
+// hashes is retained.
+func UnmarshalJson(data []byte) eval.Value {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(issue.Reported); ok {
+				panic(r)
+			}
+			panic(eval.Error(eval.EVAL_PARSE_ERROR, issue.H{`language`: `JSON`, `detail`: r}))
+		}
+	}()
+	// The streaming decoder doesn't detect truncated input so the syntax is validated first
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		panic(err)
+	}
+	collector := NewCollector()
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	jsonValues(collector, d)
+	return collector.Value()
+}
+
 func jsonValues(c ValueConsumer, d *json.Decoder) {
 	for {
 		t, err := d.Token()
@@ -39,56 +63,64 @@ func jsonValues(c ValueConsumer, d *json.Decoder) {
 			panic(err)
 		}
 		if dl, ok := t.(json.Delim); ok {
-			ds := dl.String()
-			if ds == `}` || ds == `]` {
+			if ds := dl.String(); ds == `}` || ds == `]` {
 				return
 			}
-			if ds == `{` {
-				t = nil
-				if d.More() {
+		}
+		jsonValue(c, d, t)
+	}
+}
+
+// jsonValue streams the value that starts with the given token to the given ValueConsumer
+func jsonValue(c ValueConsumer, d *json.Decoder, t json.Token) {
+	var err error
+	if dl, ok := t.(json.Delim); ok {
+		ds := dl.String()
+		if ds == `{` {
+			t = nil
+			if d.More() {
+				t, err = d.Token()
+				if err != nil {
+					panic(err)
+				}
+				if ds, ok = t.(string); ok && ds == PCORE_REF_KEY && d.More() {
 					t, err = d.Token()
 					if err != nil {
 						panic(err)
 					}
-					if ds, ok = t.(string); ok && ds == PCORE_REF_KEY && d.More() {
-						t, err = d.Token()
-						if err != nil {
-							panic(err)
-						}
-						var n int64
-						n, err = t.(json.Number).Int64()
-						if err != nil {
-							panic(err)
-						}
-						// Consume end delimiter
-						t, err = d.Token()
-						if err != nil {
-							panic(err)
-						}
-						if dl, ok = t.(json.Delim); ok && dl.String() == `}` {
-							c.AddRef(int(n))
-						} else {
-							panic(fmt.Errorf("invalid token %T %v", t, t))
-						}
-						continue
+					var n int64
+					n, err = t.(json.Number).Int64()
+					if err != nil {
+						panic(err)
 					}
-					c.AddHash(8, func() {
-						addValue(c, t)
-						jsonValues(c, d)
-					})
-				} else {
-					c.AddHash(8, func() {
-						jsonValues(c, d)
-					})
+					// Consume end delimiter
+					t, err = d.Token()
+					if err != nil {
+						panic(err)
+					}
+					if dl, ok = t.(json.Delim); ok && dl.String() == `}` {
+						c.AddRef(int(n))
+					} else {
+						panic(fmt.Errorf("invalid token %T %v", t, t))
+					}
+					return
 				}
+				c.AddHash(8, func() {
+					addValue(c, t)
+					jsonValues(c, d)
+				})
 			} else {
-				c.AddArray(8, func() {
+				c.AddHash(8, func() {
 					jsonValues(c, d)
 				})
 			}
 		} else {
-			addValue(c, t)
+			c.AddArray(8, func() {
+				jsonValues(c, d)
+			})
 		}
+	} else {
+		addValue(c, t)
 	}
 }
 
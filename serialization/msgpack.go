@@ -0,0 +1,218 @@
+package serialization
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"math/bits"
+	"time"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// msgPackTabulationThreshold is the minimum length of a string that is tabulated. Shorter strings
+// are always written verbatim since a reference wouldn't be much smaller.
+const msgPackTabulationThreshold = 4
+
+// NewMsgPackStreamer creates a new streamer that will produce MessagePack when receiving values.
+//
+// The streamer can handle binaries and complex keys. Default, Regexp, Timestamp, Timespan, SemVer,
+// SemVerRange, URI, and Symbol values are written natively as MessagePack extensions using the
+// Extension codes and references are written as an ExTabulation extension. Strings are tabulated by
+// the streamer so that each distinct string is written once. Subsequent occurrences are written as
+// an ExInnerTabulation extension containing the index of the string.
+func NewMsgPackStreamer(out io.Writer) ValueConsumer {
+	return &msgPackStreamer{out: out, strings: make(map[string]int, 63)}
+}
+
+type msgPackStreamer struct {
+	out     io.Writer
+	buf     []byte
+	strings map[string]int
+}
+
+func (m *msgPackStreamer) AddArray(len int, doer eval.Doer) {
+	m.flush(appendMsgPackContainer(m.buf, 0x90, 0xdc, len))
+	doer()
+}
+
+func (m *msgPackStreamer) AddHash(len int, doer eval.Doer) {
+	m.flush(appendMsgPackContainer(m.buf, 0x80, 0xde, len))
+	doer()
+}
+
+func (m *msgPackStreamer) Add(element eval.Value) {
+	b := m.buf
+	switch element.(type) {
+	case eval.StringValue:
+		s := element.String()
+		if len(s) < msgPackTabulationThreshold {
+			b = appendMsgPackString(b, s)
+		} else if idx, ok := m.strings[s]; ok {
+			b = appendMsgPackExtension(b, ExInnerTabulation, appendMsgPackInt(nil, int64(idx)))
+		} else {
+			m.strings[s] = len(m.strings)
+			b = appendMsgPackString(b, s)
+		}
+	case eval.IntegerValue:
+		b = appendMsgPackInt(b, element.(eval.IntegerValue).Int())
+	case eval.FloatValue:
+		b = append(b, 0xcb)
+		b = appendUint64(b, math.Float64bits(element.(eval.FloatValue).Float()))
+	case eval.BooleanValue:
+		if element.(eval.BooleanValue).Bool() {
+			b = append(b, 0xc3)
+		} else {
+			b = append(b, 0xc2)
+		}
+	case *types.BinaryValue:
+		bs := element.(*types.BinaryValue).Bytes()
+		b = append(appendMsgPackLength(b, 0xc4, len(bs)), bs...)
+	case *types.DefaultValue:
+		b = appendMsgPackExtension(b, ExDefault, nil)
+	case *types.RegexpValue:
+		b = appendMsgPackExtension(b, ExRegexp, appendMsgPackString(nil, element.(*types.RegexpValue).PatternString()))
+	case *types.TimestampValue:
+		t := element.(*types.TimestampValue).Time()
+		b = appendMsgPackExtension(b, ExTime, appendMsgPackInt(appendMsgPackInt(nil, t.Unix()), int64(t.Nanosecond())))
+	case types.TimespanValue:
+		d := element.(types.TimespanValue).Duration()
+		b = appendMsgPackExtension(b, ExTimespan, appendMsgPackInt(appendMsgPackInt(nil, int64(d/time.Second)), int64(d%time.Second)))
+	case *types.SemVerValue:
+		b = appendMsgPackExtension(b, ExVersion, appendMsgPackString(nil, element.(*types.SemVerValue).Version().String()))
+	case *types.SemVerRangeValue:
+		b = appendMsgPackExtension(b, ExVersionRange, appendMsgPackString(nil, element.(*types.SemVerRangeValue).VersionRange().String()))
+	case *types.UriValue:
+		b = appendMsgPackExtension(b, ExUri, appendMsgPackString(nil, element.(*types.UriValue).URL().String()))
+	case *types.RuntimeValue:
+		if sym, ok := element.(*types.RuntimeValue).Interface().(Symbol); ok {
+			b = appendMsgPackExtension(b, ExSymbol, appendMsgPackString(nil, string(sym)))
+		} else {
+			b = append(b, 0xc0)
+		}
+	default:
+		b = append(b, 0xc0)
+	}
+	m.flush(b)
+}
+
+func (m *msgPackStreamer) AddRef(ref int) {
+	m.flush(appendMsgPackExtension(m.buf, ExTabulation, appendMsgPackInt(nil, int64(ref))))
+}
+
+func (m *msgPackStreamer) CanDoBinary() bool {
+	return true
+}
+
+func (m *msgPackStreamer) CanDoComplexKeys() bool {
+	return true
+}
+
+// CanDoNative returns true for the rich values that have a MessagePack extension
+func (m *msgPackStreamer) CanDoNative(value eval.Value) bool {
+	switch value.(type) {
+	case *types.DefaultValue, *types.RegexpValue, *types.TimestampValue, types.TimespanValue,
+		*types.SemVerValue, *types.SemVerRangeValue, *types.UriValue:
+		return true
+	case *types.RuntimeValue:
+		_, ok := value.(*types.RuntimeValue).Interface().(Symbol)
+		return ok
+	default:
+		return false
+	}
+}
+
+func (m *msgPackStreamer) StringDedupThreshold() int {
+	// Strings are tabulated by the streamer
+	return math.MaxInt32
+}
+
+// flush writes the given bytes and retains the underlying buffer for reuse
+func (m *msgPackStreamer) flush(b []byte) {
+	assertOk(m.out.Write(b))
+	m.buf = b[:0]
+}
+
+// appendMsgPackLength appends the type byte and the length of a string, binary, or extension using
+// the 8, 16, or 32 bit format. The code is the type byte of the 8 bit format. The type bytes of the
+// other formats follow in sequence.
+func appendMsgPackLength(b []byte, code byte, n int) []byte {
+	switch {
+	case n <= math.MaxUint8:
+		return append(b, code, byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(append(b, code+1), uint16(n))
+	default:
+		return appendUint32(append(b, code+2), uint32(n))
+	}
+}
+
+// appendMsgPackContainer appends the header of an array or map with n elements. The fix is the type
+// byte of the fixed size format and code16 the type byte of the 16 bit format.
+func appendMsgPackContainer(b []byte, fix, code16 byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(append(b, code16), uint16(n))
+	default:
+		return appendUint32(append(b, code16+1), uint32(n))
+	}
+}
+
+func appendMsgPackString(b []byte, s string) []byte {
+	if len(s) < 32 {
+		b = append(b, 0xa0|byte(len(s)))
+	} else {
+		b = appendMsgPackLength(b, 0xd9, len(s))
+	}
+	return append(b, s...)
+}
+
+func appendMsgPackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		return append(b, byte(i))
+	case i >= -32 && i < 0:
+		return append(b, byte(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		return appendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		return appendUint32(append(b, 0xd2), uint32(i))
+	default:
+		return appendUint64(append(b, 0xd3), uint64(i))
+	}
+}
+
+// appendMsgPackExtension appends an extension with the given code and MessagePack encoded payload
+func appendMsgPackExtension(b []byte, ext Extension, payload []byte) []byte {
+	switch n := len(payload); n {
+	case 1, 2, 4, 8, 16:
+		// fixext 1, 2, 4, 8, and 16 are 0xd4 - 0xd8
+		b = append(b, 0xd4+byte(bits.Len(uint(n))-1), byte(ext))
+	default:
+		b = append(appendMsgPackLength(b, 0xc7, n), byte(ext))
+	}
+	return append(b, payload...)
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	var bs [2]byte
+	binary.BigEndian.PutUint16(bs[:], v)
+	return append(b, bs[:]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var bs [4]byte
+	binary.BigEndian.PutUint32(bs[:], v)
+	return append(b, bs[:]...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var bs [8]byte
+	binary.BigEndian.PutUint64(bs[:], v)
+	return append(b, bs[:]...)
+}
@@ -0,0 +1,274 @@
+package serialization
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/semver/semver"
+)
+
+// msgPackInput is the input of the MessagePack reader. It is satisfied by both the bufio.Reader
+// used for the stream and the bytes.Reader used for extension payloads
+type msgPackInput interface {
+	io.Reader
+	io.ByteReader
+}
+
+// msgPackMaxPrealloc limits what is allocated up front for a length read from the input. The input
+// cannot be trusted, so larger blobs are read into a buffer that grows as the data arrives and larger
+// array and hash lengths are passed to the consumer as this capacity hint instead.
+const msgPackMaxPrealloc = 4096
+
+type msgPackReader struct {
+	in       msgPackInput
+	consumer ValueConsumer
+	strings  []string
+}
+
+// MsgPackToData reads one value in the MessagePack format produced by a streamer created with
+// NewMsgPackStreamer from the given reader and streams the values to the given ValueConsumer
+func MsgPackToData(in io.Reader, consumer ValueConsumer) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(issue.Reported); ok {
+				panic(r)
+			}
+			panic(eval.Error(eval.EVAL_PARSE_ERROR, issue.H{`language`: `MessagePack`, `detail`: r}))
+		}
+	}()
+	br, ok := in.(msgPackInput)
+	if !ok {
+		br = bufio.NewReader(in)
+	}
+	r := &msgPackReader{in: br, consumer: consumer, strings: make([]string, 0, 63)}
+	r.readValue()
+}
+
+func (r *msgPackReader) readValue() {
+	c := readMsgPackByte(r.in)
+	switch {
+	case c <= 0x7f:
+		r.consumer.Add(types.WrapInteger(int64(c)))
+	case c >= 0xe0:
+		r.consumer.Add(types.WrapInteger(int64(int8(c))))
+	case c&0xf0 == 0x80:
+		r.readHash(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		r.readArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		r.addString(readMsgPackBytes(r.in, int(c&0x1f)))
+	default:
+		switch c {
+		case 0xc0:
+			r.consumer.Add(eval.UNDEF)
+		case 0xc2:
+			r.consumer.Add(types.BooleanFalse)
+		case 0xc3:
+			r.consumer.Add(types.BooleanTrue)
+		case 0xc4, 0xc5, 0xc6:
+			r.consumer.Add(types.WrapBinary(readMsgPackBytes(r.in, readMsgPackLength(r.in, c-0xc4))))
+		case 0xc7, 0xc8, 0xc9:
+			r.readExtension(readMsgPackLength(r.in, c-0xc7))
+		case 0xca:
+			r.consumer.Add(types.WrapFloat(float64(math.Float32frombits(uint32(readMsgPackUint(r.in, 4))))))
+		case 0xcb:
+			r.consumer.Add(types.WrapFloat(math.Float64frombits(readMsgPackUint(r.in, 8))))
+		case 0xcc, 0xcd, 0xce, 0xcf, 0xd0, 0xd1, 0xd2, 0xd3:
+			r.consumer.Add(types.WrapInteger(readMsgPackInt(r.in, c)))
+		case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+			r.readExtension(1 << (c - 0xd4))
+		case 0xd9, 0xda, 0xdb:
+			r.addString(readMsgPackBytes(r.in, readMsgPackLength(r.in, c-0xd9)))
+		case 0xdc, 0xdd:
+			r.readArray(readMsgPackLength(r.in, c-0xdc+1))
+		case 0xde, 0xdf:
+			r.readHash(readMsgPackLength(r.in, c-0xde+1))
+		default:
+			panic(fmt.Errorf(`unexpected format 0x%02x`, c))
+		}
+	}
+}
+
+// capacityHint returns the given length read from the input, limited by msgPackMaxPrealloc
+func capacityHint(n int) int {
+	if n > msgPackMaxPrealloc {
+		return msgPackMaxPrealloc
+	}
+	return n
+}
+
+func (r *msgPackReader) readArray(n int) {
+	r.consumer.AddArray(capacityHint(n), func() {
+		for i := 0; i < n; i++ {
+			r.readValue()
+		}
+	})
+}
+
+func (r *msgPackReader) readHash(n int) {
+	r.consumer.AddHash(capacityHint(n), func() {
+		for i := 0; i < n*2; i++ {
+			r.readValue()
+		}
+	})
+}
+
+// addString adds the given string and makes it available for subsequent ExInnerTabulation
+// references when it is long enough to have been tabulated by the streamer
+func (r *msgPackReader) addString(bs []byte) {
+	s := string(bs)
+	if len(s) >= msgPackTabulationThreshold {
+		r.strings = append(r.strings, s)
+	}
+	r.consumer.Add(types.WrapString(s))
+}
+
+func (r *msgPackReader) readExtension(n int) {
+	ext := Extension(readMsgPackByte(r.in))
+	p := bytes.NewReader(readMsgPackBytes(r.in, n))
+	switch ext {
+	case ExInnerTabulation:
+		idx := readMsgPackPayloadInt(p)
+		if idx < 0 || idx >= int64(len(r.strings)) {
+			panic(fmt.Errorf(`invalid string tabulation index %d`, idx))
+		}
+		r.consumer.Add(types.WrapString(r.strings[idx]))
+	case ExTabulation:
+		r.consumer.AddRef(int(readMsgPackPayloadInt(p)))
+	case ExDefault:
+		r.consumer.Add(types.WrapDefault())
+	case ExRegexp:
+		r.consumer.Add(types.WrapRegexp(readMsgPackPayloadString(p)))
+	case ExSymbol:
+		r.consumer.Add(types.WrapRuntime(Symbol(readMsgPackPayloadString(p))))
+	case ExTime:
+		secs := readMsgPackPayloadInt(p)
+		r.consumer.Add(types.WrapTimestamp(time.Unix(secs, readMsgPackPayloadInt(p)).UTC()))
+	case ExTimespan:
+		secs := readMsgPackPayloadInt(p)
+		r.consumer.Add(types.WrapTimespan(time.Duration(secs)*time.Second + time.Duration(readMsgPackPayloadInt(p))))
+	case ExVersion:
+		v, err := semver.ParseVersion(readMsgPackPayloadString(p))
+		if err != nil {
+			panic(err)
+		}
+		r.consumer.Add(types.WrapSemVer(v))
+	case ExVersionRange:
+		v, err := semver.ParseVersionRange(readMsgPackPayloadString(p))
+		if err != nil {
+			panic(err)
+		}
+		r.consumer.Add(types.WrapSemVerRange(v))
+	case ExUri:
+		r.consumer.Add(types.WrapURI2(readMsgPackPayloadString(p)))
+	default:
+		panic(fmt.Errorf(`unsupported extension 0x%02x`, byte(ext)))
+	}
+}
+
+// readMsgPackPayloadInt reads an integer from an extension payload
+func readMsgPackPayloadInt(in msgPackInput) int64 {
+	c := readMsgPackByte(in)
+	switch {
+	case c <= 0x7f:
+		return int64(c)
+	case c >= 0xe0:
+		return int64(int8(c))
+	case c >= 0xcc && c <= 0xd3:
+		return readMsgPackInt(in, c)
+	default:
+		panic(fmt.Errorf(`expected integer, got format 0x%02x`, c))
+	}
+}
+
+// readMsgPackPayloadString reads a string from an extension payload
+func readMsgPackPayloadString(in msgPackInput) string {
+	c := readMsgPackByte(in)
+	switch {
+	case c&0xe0 == 0xa0:
+		return string(readMsgPackBytes(in, int(c&0x1f)))
+	case c >= 0xd9 && c <= 0xdb:
+		return string(readMsgPackBytes(in, readMsgPackLength(in, c-0xd9)))
+	default:
+		panic(fmt.Errorf(`expected string, got format 0x%02x`, c))
+	}
+}
+
+// readMsgPackInt reads the integer that follows the given type byte, which must be one of
+// the uint 8 - 64 (0xcc - 0xcf) or int 8 - 64 (0xd0 - 0xd3) formats
+func readMsgPackInt(in msgPackInput, c byte) int64 {
+	if c <= 0xcf {
+		u := readMsgPackUint(in, 1<<(c-0xcc))
+		if u > math.MaxInt64 {
+			panic(fmt.Errorf(`integer %d is out of range`, u))
+		}
+		return int64(u)
+	}
+	switch c {
+	case 0xd0:
+		return int64(int8(readMsgPackUint(in, 1)))
+	case 0xd1:
+		return int64(int16(readMsgPackUint(in, 2)))
+	case 0xd2:
+		return int64(int32(readMsgPackUint(in, 4)))
+	default:
+		return int64(readMsgPackUint(in, 8))
+	}
+}
+
+// readMsgPackLength reads a length using the 8, 16, or 32 bit format indicated by size 0, 1, or 2
+func readMsgPackLength(in msgPackInput, size byte) int {
+	return int(readMsgPackUint(in, 1<<size))
+}
+
+func readMsgPackUint(in msgPackInput, n int) uint64 {
+	bs := readMsgPackBytes(in, n)
+	switch n {
+	case 1:
+		return uint64(bs[0])
+	case 2:
+		return uint64(binary.BigEndian.Uint16(bs))
+	case 4:
+		return uint64(binary.BigEndian.Uint32(bs))
+	default:
+		return binary.BigEndian.Uint64(bs)
+	}
+}
+
+func readMsgPackByte(in msgPackInput) byte {
+	c, err := in.ReadByte()
+	if err != nil {
+		panic(unexpectedEOF(err))
+	}
+	return c
+}
+
+func readMsgPackBytes(in msgPackInput, n int) []byte {
+	if n > msgPackMaxPrealloc {
+		b := bytes.NewBuffer(make([]byte, 0, msgPackMaxPrealloc))
+		if _, err := io.CopyN(b, in, int64(n)); err != nil {
+			panic(unexpectedEOF(err))
+		}
+		return b.Bytes()
+	}
+	bs := make([]byte, n)
+	if _, err := io.ReadFull(in, bs); err != nil {
+		panic(unexpectedEOF(err))
+	}
+	return bs
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -1,15 +1,21 @@
-package serialization
+// The examples are in an external test package because the pcore package that they need depends
+// on this package through the functions package. The Go types declared in this file are therefore
+// printed with the serialization_test prefix.
+package serialization_test
 
 import (
 	"bytes"
 	"fmt"
 	"github.com/lyraproj/puppet-evaluator/eval"
 	"github.com/lyraproj/puppet-evaluator/impl"
+	"github.com/lyraproj/puppet-evaluator/serialization"
 	"github.com/lyraproj/puppet-evaluator/types"
 	"github.com/lyraproj/semver/semver"
 
 	_ "github.com/lyraproj/puppet-evaluator/pcore"
 	"reflect"
+	"strings"
+	"time"
 )
 
 func ExampleRichDataSerializer_roundtrip() {
@@ -18,12 +24,12 @@ func ExampleRichDataSerializer_roundtrip() {
 		v := types.WrapSemVer(ver)
 		fmt.Printf("%T '%s'\n", v, v)
 
-		dc := NewSerializer(ctx, types.SingletonHash2(`rich_data`, types.BooleanTrue))
+		dc := serialization.NewSerializer(ctx, types.SingletonHash2(`rich_data`, types.BooleanTrue))
 		buf := bytes.NewBufferString(``)
-		dc.Convert(v, NewJsonStreamer(buf))
+		dc.Convert(v, serialization.NewJsonStreamer(buf))
 
-		fc := NewDeserializer(ctx, eval.EMPTY_MAP)
-		JsonToData(`/tmp/sample.json`, buf, fc)
+		fc := serialization.NewDeserializer(ctx, eval.EMPTY_MAP)
+		serialization.JsonToData(`/tmp/sample.json`, buf, fc)
 		v2 := fc.Value()
 
 		fmt.Printf("%T '%s'\n", v2, v2)
@@ -38,14 +44,14 @@ func ExampleRichDataSerializer_ObjectRoundtrip() {
 		p := impl.NewParameter(`p1`, ctx.ParseType2(`Type[String]`), nil, false)
 		fmt.Println(p)
 
-		dc := NewSerializer(ctx, eval.EMPTY_MAP)
+		dc := serialization.NewSerializer(ctx, eval.EMPTY_MAP)
 		buf := bytes.NewBufferString(``)
-		dc.Convert(types.WrapValues([]eval.Value{p, p}), NewJsonStreamer(buf))
+		dc.Convert(types.WrapValues([]eval.Value{p, p}), serialization.NewJsonStreamer(buf))
 
-		fc := NewDeserializer(ctx, eval.EMPTY_MAP)
+		fc := serialization.NewDeserializer(ctx, eval.EMPTY_MAP)
 		b := buf.String()
 		fmt.Println(b)
-		JsonToData(`/tmp/sample.json`, buf, fc)
+		serialization.JsonToData(`/tmp/sample.json`, buf, fc)
 		p2 := fc.Value().(eval.List).At(0)
 
 		fmt.Println(p2)
@@ -60,14 +66,14 @@ func ExampleRichDataSerializer_StructInArrayRoundtrip() {
 	eval.Puppet.Do(func(ctx eval.Context) {
 		p := types.WrapValues([]eval.Value{ctx.ParseType2(`Struct[a => String, b => Integer]`)})
 		fmt.Println(p)
-		dc := NewSerializer(ctx, eval.EMPTY_MAP)
+		dc := serialization.NewSerializer(ctx, eval.EMPTY_MAP)
 		buf := bytes.NewBufferString(``)
-		dc.Convert(p, NewJsonStreamer(buf))
+		dc.Convert(p, serialization.NewJsonStreamer(buf))
 
-		fc := NewDeserializer(ctx, eval.EMPTY_MAP)
+		fc := serialization.NewDeserializer(ctx, eval.EMPTY_MAP)
 		b := buf.String()
 		fmt.Println(b)
-		JsonToData(`/tmp/sample.json`, buf, fc)
+		serialization.JsonToData(`/tmp/sample.json`, buf, fc)
 		p2 := fc.Value()
 
 		fmt.Println(p2)
@@ -96,14 +102,14 @@ func ExampleRichDataSerializer_TypeSetRoundtrip() {
       }}]`)
 		ctx.AddTypes(p)
 		fmt.Println(p)
-		dc := NewSerializer(eval.Puppet.RootContext(), eval.EMPTY_MAP)
+		dc := serialization.NewSerializer(eval.Puppet.RootContext(), eval.EMPTY_MAP)
 		buf := bytes.NewBufferString(``)
-		dc.Convert(p, NewJsonStreamer(buf))
+		dc.Convert(p, serialization.NewJsonStreamer(buf))
 
-		fc := NewDeserializer(ctx, eval.EMPTY_MAP)
+		fc := serialization.NewDeserializer(ctx, eval.EMPTY_MAP)
 		b := buf.String()
 		fmt.Println(b)
-		JsonToData(`/tmp/sample.json`, buf, fc)
+		serialization.JsonToData(`/tmp/sample.json`, buf, fc)
 		p2 := fc.Value()
 		fmt.Println(p2)
 	})
@@ -123,12 +129,12 @@ func ExampleRichDataSerializer_goValueRoundtrip() {
 		v := eval.Wrap(ctx, mi)
 		fmt.Println(v)
 
-		dc := NewSerializer(eval.Puppet.RootContext(), eval.EMPTY_MAP)
+		dc := serialization.NewSerializer(eval.Puppet.RootContext(), eval.EMPTY_MAP)
 		buf := bytes.NewBufferString(``)
-		dc.Convert(v, NewJsonStreamer(buf))
+		dc.Convert(v, serialization.NewJsonStreamer(buf))
 
-		fc := NewDeserializer(ctx, eval.EMPTY_MAP)
-		JsonToData(`/tmp/sample.json`, buf, fc)
+		fc := serialization.NewDeserializer(ctx, eval.EMPTY_MAP)
+		serialization.JsonToData(`/tmp/sample.json`, buf, fc)
 		v2 := fc.Value()
 
 		fmt.Println(v2)
@@ -151,12 +157,12 @@ func ExampleRichDataSerializer_goStructRoundtrip() {
 		v := eval.Wrap(ctx, mi)
 		fmt.Println(v)
 
-		dc := NewSerializer(eval.Puppet.RootContext(), eval.EMPTY_MAP)
+		dc := serialization.NewSerializer(eval.Puppet.RootContext(), eval.EMPTY_MAP)
 		buf := bytes.NewBufferString(``)
-		dc.Convert(v, NewJsonStreamer(buf))
+		dc.Convert(v, serialization.NewJsonStreamer(buf))
 
-		fc := NewDeserializer(ctx, eval.EMPTY_MAP)
-		JsonToData(`/tmp/sample.json`, buf, fc)
+		fc := serialization.NewDeserializer(ctx, eval.EMPTY_MAP)
+		serialization.JsonToData(`/tmp/sample.json`, buf, fc)
 		v2 := fc.Value()
 
 		fmt.Println(v2)
@@ -166,7 +172,7 @@ func ExampleRichDataSerializer_goStructRoundtrip() {
 	// Output:
 	// Test::MyStruct('x' => 32, 'y' => 'hello')
 	// Test::MyStruct('x' => 32, 'y' => 'hello')
-	// serialization.MyStruct {32 hello}
+	// serialization_test.MyStruct {32 hello}
 }
 
 func ExampleRichDataSerializer_goStructWithDynamicRoundtrip() {
@@ -182,12 +188,12 @@ func ExampleRichDataSerializer_goStructWithDynamicRoundtrip() {
 		v := eval.Wrap(ctx, mi)
 		fmt.Println(v)
 
-		dc := NewSerializer(eval.Puppet.RootContext(), eval.EMPTY_MAP)
+		dc := serialization.NewSerializer(eval.Puppet.RootContext(), eval.EMPTY_MAP)
 		buf := bytes.NewBufferString(``)
-		dc.Convert(v, NewJsonStreamer(buf))
+		dc.Convert(v, serialization.NewJsonStreamer(buf))
 
-		fc := NewDeserializer(ctx, eval.EMPTY_MAP)
-		JsonToData(`/tmp/sample.json`, buf, fc)
+		fc := serialization.NewDeserializer(ctx, eval.EMPTY_MAP)
+		serialization.JsonToData(`/tmp/sample.json`, buf, fc)
 		v2 := fc.Value()
 
 		fmt.Println(v2)
@@ -197,14 +203,36 @@ func ExampleRichDataSerializer_goStructWithDynamicRoundtrip() {
 	// Output:
 	// Test::MyStruct('x' => [32], 'y' => {'msg' => 'hello'})
 	// Test::MyStruct('x' => [32], 'y' => {'msg' => 'hello'})
-	// serialization.MyStruct {[32] {'msg' => 'hello'}}
+	// serialization_test.MyStruct {[32] {'msg' => 'hello'}}
+}
+
+func ExampleNewSerializer_catalogRoundtrip() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		cat := eval.NewCatalog()
+		cat.AddResource(eval.NewResource(`file`, `/tmp/x`, types.SingletonHash2(`ensure`, types.WrapString(`present`)), nil, nil))
+		cat.AddResource(eval.NewResource(`service`, `x`, nil, nil, nil))
+		cat.AddEdge(types.NewResourceType(`File`, `/tmp/x`), types.NewResourceType(`Service`, `x`), true)
+
+		buf := bytes.NewBufferString(``)
+		serialization.NewSerializer(ctx, eval.EMPTY_MAP).Convert(cat, serialization.NewJsonStreamer(buf))
+		fmt.Println(buf)
+
+		fc := serialization.NewDeserializer(ctx, eval.EMPTY_MAP)
+		serialization.JsonToData(`/tmp/catalog.json`, buf, fc)
+		cat2 := fc.Value().(eval.Catalog)
+		cat2.Validate()
+		fmt.Println(cat2.Edges()[0])
+	})
+	// Output:
+	// {"__ptype":"Catalog","resources":[{"__ptype":"Catalog::Resource","type":"File","title":"/tmp/x","parameters":{"ensure":"present"}},{"__ptype":"Catalog::Resource","type":"Service","title":"x"}],"edges":[{"__ptype":"Catalog::Edge","source":{"__ptype":"Type","__pvalue":"File['/tmp/x']"},"target":{"__ptype":"Type","__pvalue":"Service['x']"},"subscribe":true}]}
+	// Catalog::Edge('source' => File['/tmp/x'], 'target' => Service['x'], 'subscribe' => true)
 }
 
 func ExampleRichDataSerializer_Convert() {
 	eval.Puppet.Do(func(ctx eval.Context) {
 		ver, _ := semver.NewVersion(1, 0, 0)
-		cl := NewCollector()
-		NewSerializer(ctx, types.SingletonHash2(`rich_data`, types.BooleanTrue)).Convert(types.WrapSemVer(ver), cl)
+		cl := serialization.NewCollector()
+		serialization.NewSerializer(ctx, types.SingletonHash2(`rich_data`, types.BooleanTrue)).Convert(types.WrapSemVer(ver), cl)
 		fmt.Println(cl.Value())
 	})
 	// Output: {'__ptype' => 'SemVer', '__pvalue' => '1.0.0'}
@@ -213,8 +241,8 @@ func ExampleRichDataSerializer_Convert() {
 func ExampleRichDataSerializer_ToJson() {
 	eval.Puppet.Do(func(ctx eval.Context) {
 		buf := bytes.NewBufferString(``)
-		NewSerializer(ctx, eval.EMPTY_MAP).Convert(
-			types.WrapStringToInterfaceMap(ctx, map[string]interface{}{`__ptype`: `SemVer`, `__pvalue`: `1.0.0`}), NewJsonStreamer(buf))
+		serialization.NewSerializer(ctx, eval.EMPTY_MAP).Convert(
+			types.WrapStringToInterfaceMap(ctx, map[string]interface{}{`__ptype`: `SemVer`, `__pvalue`: `1.0.0`}), serialization.NewJsonStreamer(buf))
 		fmt.Println(buf)
 	})
 	// Output: {"__ptype":"SemVer","__pvalue":"1.0.0"}
@@ -223,9 +251,137 @@ func ExampleRichDataSerializer_ToJson() {
 func ExampleJsonToData_Collector() {
 	eval.Puppet.Do(func(ctx eval.Context) {
 		buf := bytes.NewBufferString(`{"__ptype":"SemVer","__pvalue":"1.0.0"}`)
-		fc := NewCollector()
-		JsonToData(`/tmp/ver.json`, buf, fc)
+		fc := serialization.NewCollector()
+		serialization.JsonToData(`/tmp/ver.json`, buf, fc)
 		fmt.Println(fc.Value())
 	})
 	// Output: {'__ptype' => 'SemVer', '__pvalue' => '1.0.0'}
 }
+
+func ExampleMsgPackToData() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		v, err := eval.TopEvaluate(ctx, ctx.ParseAndValidate(`site.pp`, `
+			$list = ['a shared string', 'a shared string']
+			$common = {
+				'version' => SemVer('1.2.3'),
+				'range' => SemVerRange('>=1.0.0 <2.0.0'),
+				'time' => Timestamp('2018-10-17T12:00:00.123 UTC'),
+				'span' => Timespan(3723.5),
+				'uri' => URI('https://example.com/x'),
+				'default' => default,
+				'binary' => Binary('aGVsbG8='),
+				'sensitive' => Sensitive('secret'),
+				42 => $list,
+				'again' => $list,
+			}
+			[$common + { 'regexp' => /^a+$/ }, $common]`, false))
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		// The JSON serializer has no representation for Regexp values
+		h := v.(eval.List).At(0)
+		mp := bytes.NewBufferString(``)
+		serialization.NewSerializer(ctx, eval.EMPTY_MAP).Convert(h, serialization.NewMsgPackStreamer(mp))
+		js := bytes.NewBufferString(``)
+		serialization.NewSerializer(ctx, eval.EMPTY_MAP).Convert(v.(eval.List).At(1), serialization.NewJsonStreamer(js))
+		fmt.Println(mp.Len() < js.Len())
+
+		ds := serialization.NewDeserializer(ctx, eval.EMPTY_MAP)
+		serialization.MsgPackToData(mp, ds)
+		h2 := ds.Value()
+		fmt.Println(h2)
+		fmt.Println(fmt.Sprint(h) == fmt.Sprint(h2))
+	})
+	// Output:
+	// true
+	// {'version' => SemVer('1.2.3'), 'range' => SemVerRange('>=1.0.0 <2.0.0'), 'time' => 2018-10-17T12:00:00.123000000 UTC, 'span' => 0-01:02:03.5, 'uri' => URI('https://example.com/x'), 'default' => default, 'binary' => Binary('aGVsbG8='), 'sensitive' => Sensitive [value redacted], 42 => ['a shared string', 'a shared string'], 'again' => ['a shared string', 'a shared string'], 'regexp' => /^a+$/}
+	// true
+}
+
+func ExampleMsgPackToData_regexp() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		v, err := eval.TopEvaluate(ctx, ctx.ParseAndValidate(`site.pp`, `{ 'regexp' => /^a+$/ }`, false))
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		js := bytes.NewBufferString(``)
+		serialization.NewSerializer(ctx, eval.EMPTY_MAP).Convert(v, serialization.NewJsonStreamer(js))
+		fmt.Println(js)
+
+		mp := bytes.NewBufferString(``)
+		serialization.NewSerializer(ctx, eval.EMPTY_MAP).Convert(v, serialization.NewMsgPackStreamer(mp))
+		ds := serialization.NewDeserializer(ctx, eval.EMPTY_MAP)
+		serialization.MsgPackToData(mp, ds)
+		fmt.Println(ds.Value())
+	})
+	// Output:
+	// {"regexp":{"__ptype":"Regexp","__pvalue":"^a+$"}}
+	// {'regexp' => /^a+$/}
+}
+
+func ExampleMsgPackToData_truncated() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		// A binary, an array, and a hash that claim to contain 4294967295 elements
+		for _, in := range [][]byte{{0xc6, 0xff, 0xff, 0xff, 0xff}, {0xdd, 0xff, 0xff, 0xff, 0xff, 0x01}, {0xdf, 0xff, 0xff, 0xff, 0xff}} {
+			func() {
+				defer func() {
+					fmt.Println(strings.TrimSpace(fmt.Sprint(recover())))
+				}()
+				serialization.MsgPackToData(bytes.NewReader(in), serialization.NewDeserializer(ctx, eval.EMPTY_MAP))
+			}()
+		}
+	})
+	// Output:
+	// Unable to parse MessagePack. Detail: unexpected EOF
+	// Unable to parse MessagePack. Detail: unexpected EOF
+	// Unable to parse MessagePack. Detail: unexpected EOF
+}
+
+func ExampleJsonWriter() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		shared := eval.Wrap(ctx, map[string]interface{}{`os`: `linux`, `release`: `a long release string`})
+		buf := bytes.NewBufferString(``)
+		w := serialization.NewJsonArrayWriter(ctx, buf, types.SingletonHash2(`window`, types.WrapInteger(10)))
+		for i := 0; i < 3; i++ {
+			w.Add(eval.Wrap(ctx, map[string]interface{}{`id`: i, `facts`: shared}))
+		}
+		w.Add(types.WrapTimestamp(time.Date(2018, 10, 17, 12, 0, 0, 0, time.UTC)))
+		w.Close()
+		fmt.Println(buf)
+
+		r := serialization.NewJsonReader(ctx, `hosts.json`, buf, types.SingletonHash2(`window`, types.WrapInteger(10)))
+		for r.Next() {
+			fmt.Printf("%v: %v\n", r.Key(), r.Value())
+		}
+	})
+	// Output:
+	// [{"facts":{"os":"linux","release":"a long release string"},"id":0},{"facts":{"__pref":3},"id":1},{"facts":{"os":"linux","release":"a long release string"},"id":2},{"__ptype":"Timestamp","__pvalue":"2018-10-17T12:00:00.000000000 UTC"}]
+	// 0: {'facts' => {'os' => 'linux', 'release' => 'a long release string'}, 'id' => 0}
+	// 1: {'facts' => {'os' => 'linux', 'release' => 'a long release string'}, 'id' => 1}
+	// 2: {'facts' => {'os' => 'linux', 'release' => 'a long release string'}, 'id' => 2}
+	// 3: 2018-10-17T12:00:00.000000000 UTC
+}
+
+func ExampleJsonReader_error() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		for _, data := range []string{`{"a":[1,2],"b":{"c":[3,}}`, `[{"x":[1,{"y":2,"z":tru}]}]`} {
+			func() {
+				defer func() {
+					fmt.Println(strings.TrimSpace(fmt.Sprint(recover())))
+				}()
+				r := serialization.NewJsonReader(ctx, `data.json`, bytes.NewBufferString(data), eval.EMPTY_MAP)
+				for r.Next() {
+					fmt.Printf("%v: %v\n", r.Key(), r.Value())
+				}
+			}()
+		}
+	})
+	// Output:
+	// a: [1, 2]
+	// Unable to read JSON at data.json/'b'/'c'/1: invalid character ',' looking for beginning of value
+	// Unable to read JSON at data.json/0/'x'/1/'z': invalid character '}' in literal true (expecting 'e')
+}
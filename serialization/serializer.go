@@ -26,8 +26,10 @@ type rdSerializer struct {
 	context        eval.Context
 	symbolAsString bool
 	richData       bool
+	strict         bool
 	messagePrefix  string
 	dedupLevel     int
+	window         int
 }
 
 type context struct {
@@ -37,6 +39,15 @@ type context struct {
 	refIndex   int
 	dedupLevel int
 	consumer   ValueConsumer
+	native     NativeValueConsumer
+	recent     []recentValue
+}
+
+// recentValue is a deduplicated value and its reference index. A slice of recent values is
+// maintained in reference order when references are limited to a window.
+type recentValue struct {
+	value eval.Value
+	ref   int
 }
 
 // NewSerializer returns a new Serializer
@@ -44,6 +55,8 @@ func NewSerializer(ctx eval.Context, options eval.OrderedMap) Serializer {
 	t := &rdSerializer{context: ctx}
 	t.symbolAsString = options.Get5(`symbol_as_string`, types.BooleanFalse).(eval.BooleanValue).Bool()
 	t.richData = options.Get5(`rich_data`, types.BooleanTrue).(eval.BooleanValue).Bool()
+	// strict turns values that cannot be converted to Data into errors rather than strings
+	t.strict = options.Get5(`strict`, types.BooleanFalse).(eval.BooleanValue).Bool()
 	t.messagePrefix = options.Get5(`message_prefix`, eval.EMPTY_STRING).String()
 	if !options.Get5(`local_reference`, types.BooleanTrue).(eval.BooleanValue).Bool() {
 		// local_reference explicitly set to false
@@ -51,6 +64,8 @@ func NewSerializer(ctx eval.Context, options eval.OrderedMap) Serializer {
 	} else {
 		t.dedupLevel = int(options.Get5(`dedup_level`, types.WrapInteger(MaxDedup)).(eval.IntegerValue).Int())
 	}
+	// window limits the distance between a reference and the value that it refers to. Zero means no limit
+	t.window = int(options.Get5(`window`, types.WrapInteger(0)).(eval.IntegerValue).Int())
 	return t
 }
 
@@ -62,11 +77,18 @@ var sensitiveType = types.WrapString(PcoreTypeSensitive)
 var hashKey = types.WrapString(PcoreTypeHash)
 
 func (t *rdSerializer) Convert(value eval.Value, consumer ValueConsumer) {
-	c := context{config: t, values: make(map[eval.Value]int, 63), refIndex: 0, consumer: consumer, path: make([]eval.Value, 0, 16), dedupLevel: t.dedupLevel}
+	t.newContext(consumer).toData(1, value)
+}
+
+func (t *rdSerializer) newContext(consumer ValueConsumer) *context {
+	c := &context{config: t, values: make(map[eval.Value]int, 63), refIndex: 0, consumer: consumer, path: make([]eval.Value, 0, 16), dedupLevel: t.dedupLevel}
+	if t.richData {
+		c.native, _ = consumer.(NativeValueConsumer)
+	}
 	if c.dedupLevel >= MaxDedup && !consumer.CanDoComplexKeys() {
 		c.dedupLevel = NoKeyDedup
 	}
-	c.toData(1, value)
+	return c
 }
 
 func (sc *context) pathToString() string {
@@ -80,7 +102,7 @@ func (sc *context) pathToString() string {
 		} else if eval.IsInstance(types.DefaultScalarType(), v) {
 			v.ToString(s, types.PROGRAM, nil)
 		} else {
-			s.WriteString(issue.Label(s))
+			s.WriteString(eval.ToString(v))
 		}
 	}
 	return s.String()
@@ -107,12 +129,17 @@ func (sc *context) toData(level int, value eval.Value) {
 			sc.addData(value)
 		}
 	case *types.DefaultValue:
-		if sc.config.richData {
+		if sc.canDoNative(value) {
+			sc.addData(value)
+		} else if sc.config.richData {
 			sc.addHash(1, func() {
 				sc.toData(2, typeKey)
 				sc.toData(1, defaultType)
 			})
 		} else {
+			if sc.config.strict {
+				panic(eval.Error(eval.EVAL_SERIALIZATION_NOT_DATA, issue.H{`path`: sc.pathToString(), `klass`: `Default`}))
+			}
 			eval.LogWarning(eval.EVAL_SERIALIZATION_DEFAULT_CONVERTED_TO_STRING, issue.H{`path`: sc.pathToString()})
 			sc.toData(1, types.WrapString(`default`))
 		}
@@ -170,7 +197,11 @@ func (sc *context) toData(level int, value eval.Value) {
 			}
 		})
 	default:
-		if sc.config.richData {
+		if sc.canDoNative(value) {
+			sc.process(value, func() {
+				sc.addData(value)
+			})
+		} else if sc.config.richData {
 			sc.valueToDataHash(value)
 		} else {
 			sc.unknownToStringWithWarning(1, value)
@@ -178,7 +209,31 @@ func (sc *context) toData(level int, value eval.Value) {
 	}
 }
 
+// canDoNative returns true if the consumer has a native representation of the given value. Symbols
+// are never passed natively when they are to be converted to strings.
+func (sc *context) canDoNative(value eval.Value) bool {
+	if sc.native == nil {
+		return false
+	}
+	if rt, ok := value.(*types.RuntimeValue); ok && sc.config.symbolAsString {
+		if _, ok := rt.Interface().(Symbol); ok {
+			return false
+		}
+	}
+	return sc.native.CanDoNative(value)
+}
+
 func (sc *context) unknownToStringWithWarning(level int, value eval.Value) {
+	sc.unknownToString(level, value, eval.EVAL_SERIALIZATION_NOT_DATA)
+}
+
+// unknownKeyToStringWithWarning converts a hash key that is not a String. The key is added to the
+// path that is reported in the error or warning.
+func (sc *context) unknownKeyToStringWithWarning(key eval.Value) {
+	sc.withPath(key, func() { sc.unknownToString(2, key, eval.EVAL_SERIALIZATION_NOT_DATA_KEY) })
+}
+
+func (sc *context) unknownToString(level int, value eval.Value, notData issue.Code) {
 	warn := true
 	klass := ``
 	s := ``
@@ -196,6 +251,9 @@ func (sc *context) unknownToStringWithWarning(level int, value eval.Value) {
 		klass = value.PType().Name()
 	}
 	if warn {
+		if sc.config.strict {
+			panic(eval.Error(notData, issue.H{`path`: sc.pathToString(), `klass`: klass}))
+		}
 		eval.LogWarning(eval.EVAL_SERIALIZATION_UNKNOWN_CONVERTED_TO_STRING, issue.H{`path`: sc.pathToString(), `klass`: klass, `value`: s})
 	}
 	sc.toData(level, types.WrapString(s))
@@ -213,14 +271,33 @@ func (sc *context) process(value eval.Value, doer eval.Doer) {
 		return
 	}
 
-	if ref, ok := sc.values[value]; ok {
+	if ref, ok := sc.values[value]; ok && (sc.config.window == 0 || sc.refIndex-ref <= sc.config.window) {
 		sc.consumer.AddRef(ref)
 	} else {
 		sc.values[value] = sc.refIndex
+		if sc.config.window > 0 {
+			sc.recent = append(sc.recent, recentValue{value, sc.refIndex})
+			sc.forget()
+		}
 		doer()
 	}
 }
 
+// forget removes the values that are outside of the window from the deduplication map
+func (sc *context) forget() {
+	limit := sc.refIndex - sc.config.window
+	i := 0
+	for ; i < len(sc.recent) && sc.recent[i].ref < limit; i++ {
+		rv := sc.recent[i]
+		if sc.values[rv.value] == rv.ref {
+			// Value has not been added again since
+			delete(sc.values, rv.value)
+		}
+	}
+	// The backing array is released when append reallocates
+	sc.recent = sc.recent[i:]
+}
+
 func (sc *context) nonStringKeyedHashToData(hash eval.OrderedMap) {
 	if sc.config.richData {
 		sc.toKeyExtendedHash(hash)
@@ -232,7 +309,7 @@ func (sc *context) nonStringKeyedHashToData(hash eval.OrderedMap) {
 				if s, ok := key.(eval.StringValue); ok {
 					sc.toData(2, s)
 				} else {
-					sc.unknownToStringWithWarning(2, key)
+					sc.unknownKeyToStringWithWarning(key)
 				}
 				sc.withPath(key, func() { sc.toData(1, elem) })
 			})
@@ -331,10 +408,11 @@ func (sc *context) valueToDataHash(value eval.Value) {
 
 	if po, ok := value.(eval.PuppetObject); ok {
 		sc.process(value, func() {
-			sc.addHash(2, func() {
+			ih := po.InitHash()
+			sc.addHash(1+ih.Len(), func() {
 				sc.toData(2, typeKey)
 				sc.withPath(typeKey, func() { sc.pcoreTypeToData(vt) })
-				po.InitHash().EachPair(func(k, v eval.Value) {
+				ih.EachPair(func(k, v eval.Value) {
 					sc.toData(2, k) // No need to convert key. It's always a string
 					sc.withPath(k, func() { sc.toData(1, v) })
 				})
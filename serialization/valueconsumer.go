@@ -41,3 +41,14 @@ type ValueConsumer interface {
 	// Add a reference to a previously added afterElement, hash, or array.
 	AddRef(ref int)
 }
+
+// A NativeValueConsumer is a ValueConsumer that has a native representation for some of the
+// rich data values that would otherwise be converted into a hash with a __ptype key.
+type NativeValueConsumer interface {
+	ValueConsumer
+
+	// CanDoNative returns true if the consumer has a native representation of the given
+	// value. This tells the Serializer to pass the value verbatim to Add when rich_data is
+	// enabled
+	CanDoNative(value eval.Value) bool
+}
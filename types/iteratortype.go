@@ -33,6 +33,11 @@ type (
 		outcome   bool
 		base      eval.Iterator
 	}
+
+	producingIterator struct {
+		elementType eval.Type
+		next        func() (eval.Value, bool)
+	}
 )
 
 var iteratorType_DEFAULT = &IteratorType{typ: DefaultAnyType()}
@@ -115,8 +120,8 @@ func (t *IteratorType) IsAssignable(o eval.Type, g eval.Guard) bool {
 }
 
 func (t *IteratorType) IsInstance(o eval.Value, g eval.Guard) bool {
-	if it, ok := o.(eval.Iterator); ok {
-		return GuardedIsInstance(t.typ, it.ElementType(), g)
+	if it, ok := o.(*iteratorValue); ok {
+		return GuardedIsAssignable(t.typ, it.ElementType(), g)
 	}
 	return false
 }
@@ -169,10 +174,28 @@ func WrapIterator(iter eval.Iterator) eval.IteratorValue {
 	return &iteratorValue{iter}
 }
 
+// NewIterator returns an iterator that obtains its values lazily by calling the given function. The
+// function must return false when there are no more values.
+func NewIterator(elementType eval.Type, next func() (eval.Value, bool)) eval.Iterator {
+	return &producingIterator{elementType, next}
+}
+
 func (it *iteratorValue) AsArray() eval.List {
 	return it.iterator.AsArray()
 }
 
+func (it *iteratorValue) ElementType() eval.Type {
+	return it.iterator.ElementType()
+}
+
+func (it *iteratorValue) IsHashStyle() bool {
+	return false
+}
+
+func (it *iteratorValue) Iterator() eval.Iterator {
+	return it.iterator
+}
+
 func (it *iteratorValue) Equals(o interface{}, g eval.Guard) bool {
 	if ot, ok := o.(*iteratorValue); ok {
 		return it.iterator.ElementType().Equals(ot.iterator.ElementType(), g)
@@ -545,3 +568,67 @@ func (ai *mappingIterator) Select(predicate eval.Predicate) eval.IteratorValue {
 func (ai *mappingIterator) AsArray() eval.List {
 	return asArray(ai)
 }
+
+func (ai *producingIterator) All(predicate eval.Predicate) bool {
+	return all(ai, predicate)
+}
+
+func (ai *producingIterator) Any(predicate eval.Predicate) bool {
+	return any(ai, predicate)
+}
+
+func (ai *producingIterator) Next() (v eval.Value, ok bool) {
+	v, ok = ai.next()
+	if !ok {
+		v = _UNDEF
+	}
+	return
+}
+
+func (ai *producingIterator) Each(consumer eval.Consumer) {
+	each(ai, consumer)
+}
+
+func (ai *producingIterator) EachWithIndex(consumer eval.BiConsumer) {
+	eachWithIndex(ai, consumer)
+}
+
+func (ai *producingIterator) ElementType() eval.Type {
+	return ai.elementType
+}
+
+func (ai *producingIterator) Find(predicate eval.Predicate) eval.Value {
+	return find(ai, predicate, _UNDEF, nil)
+}
+
+func (ai *producingIterator) Find2(predicate eval.Predicate, dflt eval.Value) eval.Value {
+	return find(ai, predicate, dflt, nil)
+}
+
+func (ai *producingIterator) Find3(predicate eval.Predicate, dflt eval.Producer) eval.Value {
+	return find(ai, predicate, nil, dflt)
+}
+
+func (ai *producingIterator) Map(elementType eval.Type, mapFunc eval.Mapper) eval.IteratorValue {
+	return WrapIterator(&mappingIterator{elementType, mapFunc, ai})
+}
+
+func (ai *producingIterator) Reduce(redactor eval.BiMapper) eval.Value {
+	return reduce(ai, redactor)
+}
+
+func (ai *producingIterator) Reduce2(initialValue eval.Value, redactor eval.BiMapper) eval.Value {
+	return reduce2(ai, initialValue, redactor)
+}
+
+func (ai *producingIterator) Reject(predicate eval.Predicate) eval.IteratorValue {
+	return WrapIterator(&predicateIterator{predicate, false, ai})
+}
+
+func (ai *producingIterator) Select(predicate eval.Predicate) eval.IteratorValue {
+	return WrapIterator(&predicateIterator{predicate, true, ai})
+}
+
+func (ai *producingIterator) AsArray() eval.List {
+	return asArray(ai)
+}
@@ -256,6 +256,8 @@ func (o *reflectedObject) Reflect(c eval.Context) reflect.Value {
 func (o *reflectedObject) ReflectTo(c eval.Context, value reflect.Value) {
 	if o.value.Kind() == reflect.Struct && value.Kind() == reflect.Ptr {
 		value.Set(o.value.Addr())
+	} else if o.value.Kind() == reflect.Ptr && value.Kind() == reflect.Struct {
+		value.Set(o.value.Elem())
 	} else {
 		value.Set(o.value)
 	}
@@ -5,6 +5,7 @@ import (
 	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/puppet-evaluator/errors"
 	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/regex"
 	"github.com/lyraproj/puppet-evaluator/utils"
 	"io"
 	"reflect"
@@ -13,14 +14,14 @@ import (
 
 type (
 	RegexpType struct {
-		pattern *regexp.Regexp
+		pattern regex.Regexp
 	}
 
 	// RegexpValue represents RegexpType as a value
 	RegexpValue RegexpType
 )
 
-var regexpTypeDefault = &RegexpType{pattern: regexp.MustCompile(``)}
+var regexpTypeDefault = newRegexpType(regexp.MustCompile(``))
 
 var RegexpMetaType eval.ObjectType
 
@@ -59,18 +60,14 @@ func NewRegexpType(patternString string) *RegexpType {
 	if patternString == `` {
 		return DefaultRegexpType()
 	}
-	pattern, err := regexp.Compile(patternString)
-	if err != nil {
-		panic(eval.Error(eval.EVAL_INVALID_REGEXP, issue.H{`pattern`: patternString, `detail`: err.Error()}))
-	}
-	return &RegexpType{pattern: pattern}
+	return newRegexpType(mustCompileRegexp(patternString))
 }
 
 func NewRegexpTypeR(pattern *regexp.Regexp) *RegexpType {
 	if pattern.String() == `` {
 		return DefaultRegexpType()
 	}
-	return &RegexpType{pattern: pattern}
+	return newRegexpType(pattern)
 }
 
 func NewRegexpType2(args ...eval.Value) *RegexpType {
@@ -141,17 +138,24 @@ func (t *RegexpType) Parameters() []eval.Value {
 }
 
 func (t *RegexpType) ReflectType(c eval.Context) (reflect.Type, bool) {
-	return reflect.TypeOf(regexpTypeDefault.pattern), true
+	return reflect.TypeOf(&regexp.Regexp{}), true
 }
 
 func (t *RegexpType) PatternString() string {
 	return t.pattern.String()
 }
 
-func (t *RegexpType) Regexp() *regexp.Regexp {
+// Matcher returns the pattern compiled by the engine that was selected when the type was created
+func (t *RegexpType) Matcher() regex.Regexp {
 	return t.pattern
 }
 
+// Regexp returns the pattern compiled by the Go engine, or nil if it was compiled by the Ruby engine
+func (t *RegexpType) Regexp() *regexp.Regexp {
+	rx, _ := t.pattern.(*regexp.Regexp)
+	return rx
+}
+
 func (t *RegexpType) CanSerializeAsString() bool {
 	return true
 }
@@ -172,11 +176,11 @@ func (t *RegexpType) PType() eval.Type {
 	return &TypeType{t}
 }
 
-func MapToRegexps(regexpTypes []*RegexpType) []*regexp.Regexp {
+func MapToRegexps(regexpTypes []*RegexpType) []regex.Regexp {
 	top := len(regexpTypes)
-	result := make([]*regexp.Regexp, top)
+	result := make([]regex.Regexp, top)
 	for idx := 0; idx < top; idx++ {
-		result[idx] = regexpTypes[idx].Regexp()
+		result[idx] = regexpTypes[idx].Matcher()
 	}
 	return result
 }
@@ -199,16 +203,35 @@ func UniqueRegexps(regexpTypes []*RegexpType) []*RegexpType {
 	return result
 }
 
-func WrapRegexp(str string) *RegexpValue {
-	pattern, err := regexp.Compile(str)
+// RegexpEngine returns the regular expression engine that is selected by the regexp_engine setting
+func RegexpEngine() regex.Engine {
+	return regex.Engine(eval.GetSetting(`regexp_engine`, stringValue(regex.GoEngine)).String())
+}
+
+// CompileRegexp compiles the given pattern using the engine that is selected by the regexp_engine
+// setting
+func CompileRegexp(str string) (regex.Regexp, error) {
+	return regex.Compile(RegexpEngine(), str)
+}
+
+func mustCompileRegexp(str string) regex.Regexp {
+	pattern, err := CompileRegexp(str)
 	if err != nil {
 		panic(eval.Error(eval.EVAL_INVALID_REGEXP, issue.H{`pattern`: str, `detail`: err.Error()}))
 	}
-	return &RegexpValue{pattern}
+	return pattern
 }
 
-func WrapRegexp2(pattern *regexp.Regexp) *RegexpValue {
-	return &RegexpValue{pattern}
+func newRegexpType(pattern regex.Regexp) *RegexpType {
+	return &RegexpType{pattern: pattern}
+}
+
+func WrapRegexp(str string) *RegexpValue {
+	return (*RegexpValue)(newRegexpType(mustCompileRegexp(str)))
+}
+
+func WrapRegexp2(pattern regex.Regexp) *RegexpValue {
+	return (*RegexpValue)(newRegexpType(pattern))
 }
 
 func (r *RegexpValue) Equals(o interface{}, g eval.Guard) bool {
@@ -222,24 +245,45 @@ func (r *RegexpValue) Match(s string) []string {
 	return r.pattern.FindStringSubmatch(s)
 }
 
-func (r *RegexpValue) Regexp() *regexp.Regexp {
+// Matcher returns the pattern compiled by the engine that was selected when the value was created
+func (r *RegexpValue) Matcher() regex.Regexp {
 	return r.pattern
 }
 
+// Regexp returns the pattern compiled by the Go engine, or nil if it was compiled by the Ruby engine
+func (r *RegexpValue) Regexp() *regexp.Regexp {
+	return (*RegexpType)(r).Regexp()
+}
+
 func (r *RegexpValue) PatternString() string {
 	return r.pattern.String()
 }
 
+func (r *RegexpValue) CanSerializeAsString() bool {
+	return true
+}
+
+func (r *RegexpValue) SerializationString() string {
+	return r.PatternString()
+}
+
+// Reflect returns the pattern as compiled by the engine that was selected when the value was created.
+// A pattern compiled by the Ruby engine is never given to Go as a *regexp.Regexp since the two engines
+// give different meanings to the same pattern, e.g. to the ^ and $ anchors.
 func (r *RegexpValue) Reflect(c eval.Context) reflect.Value {
 	return reflect.ValueOf(r.pattern)
 }
 
 func (r *RegexpValue) ReflectTo(c eval.Context, dest reflect.Value) {
-	rv := r.Reflect(c).Elem()
-	if !rv.Type().AssignableTo(dest.Type()) {
+	rv := r.Reflect(c)
+	switch {
+	case rv.Type().AssignableTo(dest.Type()):
+		dest.Set(rv)
+	case rv.Elem().Type().AssignableTo(dest.Type()):
+		dest.Set(rv.Elem())
+	default:
 		panic(eval.Error(eval.EVAL_ATTEMPT_TO_SET_WRONG_KIND, issue.H{`expected`: rv.Type().String(), `actual`: dest.Type().String()}))
 	}
-	dest.Set(rv)
 }
 
 func (r *RegexpValue) String() string {
@@ -0,0 +1,213 @@
+package types
+
+import (
+	"io"
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/errors"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/utils"
+)
+
+// ResourceType is the type Resource[<type name>, <title>]. It is commonly written
+// as <Type name>['<title>'] and used when referencing resources in the catalog
+type ResourceType struct {
+	typeName string
+	title    string
+}
+
+var ResourceMetaType eval.ObjectType
+
+func init() {
+	ResourceMetaType = newObjectType(`Pcore::ResourceType`,
+		`Pcore::AnyType {
+	attributes => {
+		type_name => {
+      type => Optional[String[1]],
+      value => undef
+    },
+		title => {
+      type => Optional[String[1]],
+      value => undef
+    }
+	}
+}`, func(ctx eval.Context, args []eval.Value) eval.Value {
+			return NewResourceType2(args...)
+		})
+}
+
+func DefaultResourceType() *ResourceType {
+	return resourceType_DEFAULT
+}
+
+// NewResourceType returns a new ResourceType. The type name is capitalized segment by segment
+// so that 'my::thing' becomes 'My::Thing'. The title of a Class is a class name and is
+// capitalized in the same way.
+func NewResourceType(typeName, title string) *ResourceType {
+	if typeName == `` && title == `` {
+		return DefaultResourceType()
+	}
+	typeName = utils.CapitalizeSegments(strings.TrimPrefix(typeName, `::`))
+	if typeName == `Class` {
+		title = utils.CapitalizeSegments(strings.TrimPrefix(title, `::`))
+	}
+	return &ResourceType{typeName, title}
+}
+
+func NewResourceType2(args ...eval.Value) *ResourceType {
+	switch len(args) {
+	case 0:
+		return DefaultResourceType()
+	case 1, 2:
+		typeName := ``
+		switch arg := args[0].(type) {
+		case stringValue:
+			typeName = string(arg)
+		case *TypeReferenceType:
+			typeName = arg.typeString
+		case *ResourceType:
+			if arg.title != `` {
+				panic(NewIllegalArgumentType2(`Resource[]`, 0, `Variant[String,Type[Resource]]`, args[0]))
+			}
+			typeName = arg.typeName
+		case eval.Type:
+			typeName = arg.Name()
+		case *UndefValue:
+		default:
+			panic(NewIllegalArgumentType2(`Resource[]`, 0, `Variant[String,Type]`, args[0]))
+		}
+		if len(args) == 1 {
+			return NewResourceType(typeName, ``)
+		}
+		switch arg := args[1].(type) {
+		case stringValue:
+			return NewResourceType(typeName, string(arg))
+		case *UndefValue:
+			return NewResourceType(typeName, ``)
+		default:
+			panic(NewIllegalArgumentType2(`Resource[]`, 1, `String`, args[1]))
+		}
+	default:
+		panic(errors.NewIllegalArgumentCount(`Resource[]`, `0 - 2`, len(args)))
+	}
+}
+
+func (t *ResourceType) Accept(v eval.Visitor, g eval.Guard) {
+	v(t)
+}
+
+func (t *ResourceType) Default() eval.Type {
+	return resourceType_DEFAULT
+}
+
+func (t *ResourceType) Equals(o interface{}, g eval.Guard) bool {
+	if ot, ok := o.(*ResourceType); ok {
+		return strings.EqualFold(t.typeName, ot.typeName) && t.title == ot.title
+	}
+	return false
+}
+
+func (t *ResourceType) Get(key string) (eval.Value, bool) {
+	switch key {
+	case `type_name`:
+		if t.typeName == `` {
+			return _UNDEF, true
+		}
+		return stringValue(t.typeName), true
+	case `title`:
+		if t.title == `` {
+			return _UNDEF, true
+		}
+		return stringValue(t.title), true
+	default:
+		return nil, false
+	}
+}
+
+func (t *ResourceType) IsAssignable(o eval.Type, g eval.Guard) bool {
+	if ot, ok := o.(*ResourceType); ok {
+		return t.matches(ot.typeName, ot.title)
+	}
+	return false
+}
+
+func (t *ResourceType) IsInstance(o eval.Value, g eval.Guard) bool {
+	if r, ok := o.(eval.Resource); ok {
+		return t.matches(r.TypeName(), r.Title())
+	}
+	return false
+}
+
+func (t *ResourceType) MetaType() eval.ObjectType {
+	return ResourceMetaType
+}
+
+func (t *ResourceType) Name() string {
+	return `Resource`
+}
+
+func (t *ResourceType) Parameters() []eval.Value {
+	if t.typeName == `` {
+		return eval.EMPTY_VALUES
+	}
+	if t.title == `` {
+		return []eval.Value{stringValue(t.typeName)}
+	}
+	return []eval.Value{stringValue(t.typeName), stringValue(t.title)}
+}
+
+func (t *ResourceType) CanSerializeAsString() bool {
+	return true
+}
+
+func (t *ResourceType) SerializationString() string {
+	return t.String()
+}
+
+func (t *ResourceType) String() string {
+	return eval.ToString2(t, NONE)
+}
+
+// Title returns the title of the referenced resource or an empty string if the
+// receiver does not reference a specific resource
+func (t *ResourceType) Title() string {
+	return t.title
+}
+
+// TypeName returns the capitalized name of the resource type or an empty string
+// if the receiver is the default Resource type
+func (t *ResourceType) TypeName() string {
+	return t.typeName
+}
+
+func (t *ResourceType) ToString(b io.Writer, s eval.FormatContext, g eval.RDetect) {
+	switch {
+	case t.typeName == ``:
+		io.WriteString(b, `Resource`)
+	case t.title == ``:
+		io.WriteString(b, `Resource[`)
+		io.WriteString(b, t.typeName)
+		io.WriteString(b, `]`)
+	default:
+		io.WriteString(b, t.typeName)
+		io.WriteString(b, `[`)
+		utils.PuppetQuote(b, t.title)
+		io.WriteString(b, `]`)
+	}
+}
+
+func (t *ResourceType) PType() eval.Type {
+	return &TypeType{t}
+}
+
+func (t *ResourceType) matches(typeName, title string) bool {
+	if t.typeName == `` {
+		return true
+	}
+	if !strings.EqualFold(t.typeName, typeName) {
+		return false
+	}
+	return t.title == `` || t.title == title
+}
+
+var resourceType_DEFAULT = &ResourceType{}
@@ -9,9 +9,9 @@ import (
 
 	"github.com/lyraproj/puppet-evaluator/errors"
 	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/regex"
 	"github.com/lyraproj/puppet-evaluator/utils"
 	"reflect"
-	"regexp"
 )
 
 type (
@@ -527,7 +527,7 @@ func (sv stringValue) Slice(i int, j int) eval.List {
 	return stringValue(sv.String()[i:j])
 }
 
-func (sv stringValue) Split(pattern *regexp.Regexp) eval.List {
+func (sv stringValue) Split(pattern regex.Regexp) eval.List {
 	parts := pattern.Split(sv.String(), -1)
 	result := make([]eval.Value, len(parts))
 	for i, s := range parts {
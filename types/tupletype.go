@@ -1,6 +1,7 @@
 package types
 
 import (
+	"bytes"
 	"io"
 	"math"
 
@@ -185,7 +186,7 @@ func (t *TupleType) Default() eval.Type {
 }
 
 func (t *TupleType) Equals(o interface{}, g eval.Guard) bool {
-	if ot, ok := o.(*TupleType); ok && len(t.types) == len(ot.types) && eval.GuardedEquals(t.size, ot.size, g) {
+	if ot, ok := o.(*TupleType); ok && len(t.types) == len(ot.types) && t.givenOrActualSize.Equals(ot.givenOrActualSize, g) {
 		for idx, col := range t.types {
 			if !col.Equals(ot.types[idx], g) {
 				return false
@@ -369,6 +370,20 @@ func (t *TupleType) Parameters() []eval.Value {
 	return params
 }
 
+// ToKey writes the size that Equals compares, so that tuples with the same given or implied size
+// have the same hash key
+func (t *TupleType) ToKey(b *bytes.Buffer) {
+	b.WriteByte(1)
+	b.WriteByte(HK_TYPE)
+	b.WriteString(t.Name())
+	for _, c := range t.types {
+		appendTypeParamKey(b, c)
+	}
+	for _, p := range t.givenOrActualSize.SizeParameters() {
+		appendTypeParamKey(b, p)
+	}
+}
+
 func (t *TupleType) ToString(b io.Writer, s eval.FormatContext, g eval.RDetect) {
 	TypeToString(t, b, s, g)
 }
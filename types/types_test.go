@@ -2,9 +2,12 @@ package types_test
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
+	"strings"
 
 	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/regex"
 	"github.com/lyraproj/puppet-evaluator/types"
 
 	// Ensure that pcore is initialized
@@ -34,6 +37,18 @@ func ExampleNewTupleType() {
 	// Output: Tuple[String, Integer]
 }
 
+func ExampleTupleType_Equals() {
+	elements := []eval.Type{types.DefaultStringType(), types.DefaultIntegerType()}
+	implied := types.NewTupleType(elements, nil)
+	fmt.Println(implied.Equals(types.NewTupleType(elements, types.NewIntegerType(2, 2)), nil))
+	fmt.Println(implied.Equals(types.NewTupleType(elements, types.NewIntegerType(1, 2)), nil))
+	fmt.Println(eval.ToKey(implied) == eval.ToKey(types.NewTupleType(elements, types.NewIntegerType(2, 2))))
+	// Output:
+	// true
+	// false
+	// true
+}
+
 func ExampleWrapHash() {
 	a := eval.Wrap(nil, map[string]interface{}{
 		`foo`: 23,
@@ -55,3 +70,35 @@ func ExampleWrapHash() {
 	fmt.Println(eval.Equals(e, a))
 	// Output: true
 }
+
+func ExampleRegexpValue_Reflect() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		for _, rx := range []*types.RegexpValue{
+			types.WrapRegexp2(regexp.MustCompile(`\d+`)),
+			types.WrapRegexp2(regex.MustCompileRuby(`\d+`)),
+			types.WrapRegexp2(regex.MustCompileRuby(`(?<=\$)\d+`)),
+		} {
+			fmt.Println(rx.Regexp() != nil, rx.Reflect(ctx).Type(), rx.Reflect(ctx).Interface().(regex.Regexp).FindStringSubmatch(`$42`))
+		}
+	})
+	// Output:
+	// true *regexp.Regexp [42]
+	// false *regex.RubyRegexp [42]
+	// false *regex.RubyRegexp [42]
+}
+
+func ExampleRegexpValue_ReflectTo() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		var rx *regexp.Regexp
+		types.WrapRegexp2(regexp.MustCompile(`^\d+$`)).ReflectTo(ctx, reflect.ValueOf(&rx).Elem())
+		fmt.Println(rx)
+
+		defer func() {
+			fmt.Println(strings.TrimSpace(fmt.Sprint(recover())))
+		}()
+		types.WrapRegexp2(regex.MustCompileRuby(`^\d+$`)).ReflectTo(ctx, reflect.ValueOf(&rx).Elem())
+	})
+	// Output:
+	// ^\d+$
+	// attempt to assign a value of kind *regex.RubyRegexp to a reflect.Value of kind *regexp.Regexp
+}
@@ -51,6 +51,7 @@ func init() {
 		`Pattern`:       DefaultPatternType(),
 		`Regexp`:        DefaultRegexpType(),
 		`RegExp`:        DefaultRegexpType(),
+		`Resource`:      DefaultResourceType(),
 		`Richdata`:      DefaultRichDataType(),
 		`RichData`:      DefaultRichDataType(),
 		`Runtime`:       DefaultRuntimeType(),
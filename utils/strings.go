@@ -8,6 +8,7 @@ import (
 	"strings"
 	"unicode/utf8"
 
+	"github.com/lyraproj/puppet-evaluator/regex"
 	"github.com/lyraproj/puppet-parser/parser"
 )
 
@@ -56,7 +57,7 @@ func IsDecimalInteger(s string) bool {
 }
 
 // MatchesString returns true if at least one of the regexps matches str
-func MatchesString(regexps []*regexp.Regexp, str string) bool {
+func MatchesString(regexps []regex.Regexp, str string) bool {
 	if str != `` {
 		for _, v := range regexps {
 			if v.MatchString(str) {
@@ -68,7 +69,7 @@ func MatchesString(regexps []*regexp.Regexp, str string) bool {
 }
 
 // MatchesAllStrings returns true if all strings are matched by at least one of the regexps
-func MatchesAllStrings(regexps []*regexp.Regexp, strings []string) bool {
+func MatchesAllStrings(regexps []regex.Regexp, strings []string) bool {
 	for _, str := range strings {
 		if !MatchesString(regexps, str) {
 			return false
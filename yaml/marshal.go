@@ -0,0 +1,218 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Marshal streams the given value through a serialization.Serializer created with the given
+// options and returns the resulting YAML. The order of all hashes is retained. In addition to the
+// options of the serializer, the option tags (default false) makes hashes with a __ptype key
+// appear as YAML tags, e.g. !Timestamp '2019-01-01T00:00:00.000000000 UTC'.
+func Marshal(c eval.Context, value eval.Value, options eval.OrderedMap) []byte {
+	b := bytes.NewBufferString(``)
+	tags := options.Get5(`tags`, types.BooleanFalse).(eval.BooleanValue).Bool()
+	serialization.NewSerializer(c, options).Convert(value, NewYamlStreamer(b, tags))
+	return b.Bytes()
+}
+
+// NewYamlStreamer creates a streamer that writes YAML to the given writer when it has received a
+// complete value. A value that is referenced is written with an anchor and each reference is
+// written as an alias of that anchor.
+//
+// When tags is true, a hash with a __ptype key is written as a node with a local tag named after
+// the type and binaries are written using the standard !!binary tag.
+func NewYamlStreamer(out io.Writer, tags bool) serialization.ValueConsumer {
+	return &yamlStreamer{out: out, tags: tags, referenced: make(map[*yaml3.Node]bool), stack: make([][]*yaml3.Node, 1, 8)}
+}
+
+// yamlStreamer is a serialization.ValueConsumer that builds a tree of nodes and encodes it when the
+// top level value is complete.
+type yamlStreamer struct {
+	out        io.Writer
+	tags       bool
+	values     []*yaml3.Node
+	referenced map[*yaml3.Node]bool
+	stack      [][]*yaml3.Node
+}
+
+func (ys *yamlStreamer) AddArray(cap int, doer eval.Doer) {
+	ys.push(yaml3.SequenceNode, cap, doer)
+}
+
+func (ys *yamlStreamer) AddHash(cap int, doer eval.Doer) {
+	ys.push(yaml3.MappingNode, cap*2, doer)
+}
+
+func (ys *yamlStreamer) Add(element eval.Value) {
+	n := scalarNode(element)
+	ys.values = append(ys.values, n)
+	ys.append(n)
+}
+
+func (ys *yamlStreamer) AddRef(ref int) {
+	target := ys.values[ref]
+	ys.referenced[target] = true
+	ys.append(&yaml3.Node{Kind: yaml3.AliasNode, Alias: target})
+}
+
+func (ys *yamlStreamer) CanDoBinary() bool {
+	return ys.tags
+}
+
+func (ys *yamlStreamer) CanDoComplexKeys() bool {
+	return false
+}
+
+func (ys *yamlStreamer) StringDedupThreshold() int {
+	return 20
+}
+
+func (ys *yamlStreamer) push(kind yaml3.Kind, cap int, doer eval.Doer) {
+	// The reference index of a container precedes the indexes of its elements
+	n := &yaml3.Node{Kind: kind}
+	ys.values = append(ys.values, n)
+	top := len(ys.stack)
+	ys.stack = append(ys.stack, make([]*yaml3.Node, 0, cap))
+	doer()
+	n.Content = ys.stack[top]
+	ys.stack = ys.stack[0:top]
+	ys.append(n)
+}
+
+func (ys *yamlStreamer) append(n *yaml3.Node) {
+	top := len(ys.stack) - 1
+	if top == 0 {
+		ys.encode(n)
+		ys.values = ys.values[:0]
+		ys.referenced = make(map[*yaml3.Node]bool)
+		return
+	}
+	ys.stack[top] = append(ys.stack[top], n)
+}
+
+// encode writes the given complete value as a YAML document
+func (ys *yamlStreamer) encode(n *yaml3.Node) {
+	anchors := 0
+	var prepare func(n *yaml3.Node)
+	prepare = func(n *yaml3.Node) {
+		if ys.referenced[n] {
+			anchors++
+			n.Anchor = `ref` + strconv.Itoa(anchors)
+		}
+		if n.Kind == yaml3.AliasNode {
+			n.Value = n.Alias.Anchor
+			return
+		}
+		if ys.tags {
+			ys.typeTag(n)
+		}
+		for _, c := range n.Content {
+			prepare(c)
+		}
+	}
+	prepare(n)
+
+	e := yaml3.NewEncoder(ys.out)
+	e.SetIndent(2)
+	if err := e.Encode(n); err != nil {
+		panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: err}))
+	}
+	if err := e.Close(); err != nil {
+		panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: err}))
+	}
+}
+
+// typeTag turns a mapping with a __ptype key into a node with a local tag named after the type.
+// The content becomes the __pvalue when it is the only other entry and it can be tagged. Otherwise
+// it is a mapping of all other entries. The node is left unchanged when it cannot be tagged.
+func (ys *yamlStreamer) typeTag(n *yaml3.Node) {
+	if n.Kind != yaml3.MappingNode || len(n.Content) < 2 {
+		return
+	}
+	k, t := n.Content[0], n.Content[1]
+	if k.Kind != yaml3.ScalarNode || k.Value != serialization.PcoreTypeKey || ys.referenced[k] ||
+		t.Kind != yaml3.ScalarNode || t.Tag != `!!str` || ys.referenced[t] || !isTagName(t.Value) {
+		return
+	}
+	n.Tag = `!` + t.Value
+	rest := n.Content[2:]
+	if len(rest) == 2 && rest[0].Kind == yaml3.ScalarNode && rest[0].Value == serialization.PcoreValueKey {
+		if v := rest[1]; !ys.referenced[v] && (v.Kind == yaml3.ScalarNode && v.Tag != `!!binary` || v.Kind == yaml3.SequenceNode) {
+			n.Kind = v.Kind
+			n.Style = v.Style
+			n.Value = v.Value
+			n.Content = v.Content
+			return
+		}
+	}
+	n.Content = rest
+}
+
+func isTagName(s string) bool {
+	if s == `` {
+		return false
+	}
+	for _, c := range s {
+		if !(c == '_' || c == ':' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// scalarNode returns the node of the given value. Strings that a YAML 1.1 parser would not read
+// as strings, such as yes and off, are double quoted.
+func scalarNode(v eval.Value) *yaml3.Node {
+	n := &yaml3.Node{Kind: yaml3.ScalarNode}
+	switch v.(type) {
+	case eval.StringValue:
+		n.Tag = `!!str`
+		n.Value = v.String()
+		if _, ok := yaml11Booleans[n.Value]; ok {
+			n.Style = yaml3.DoubleQuotedStyle
+		}
+	case eval.IntegerValue:
+		n.Tag = `!!int`
+		n.Value = strconv.FormatInt(v.(eval.IntegerValue).Int(), 10)
+	case eval.FloatValue:
+		n.Tag = `!!float`
+		n.Value = formatFloat(v.(eval.FloatValue).Float())
+	case eval.BooleanValue:
+		n.Tag = `!!bool`
+		n.Value = strconv.FormatBool(v.(eval.BooleanValue).Bool())
+	case *types.BinaryValue:
+		n.Tag = `!!binary`
+		n.Value = base64.StdEncoding.EncodeToString(v.(*types.BinaryValue).Bytes())
+	default:
+		n.Tag = `!!null`
+		n.Value = `null`
+	}
+	return n
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return `.inf`
+	case math.IsInf(f, -1):
+		return `-.inf`
+	case math.IsNaN(f):
+		return `.nan`
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, `.e`) {
+		s += `.0`
+	}
+	return s
+}
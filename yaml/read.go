@@ -0,0 +1,275 @@
+package yaml
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// YamlToData parses the first document of the given YAML and streams its values to the given
+// consumer. A node with a local tag such as !Timestamp or !Pcore::Object is streamed as a hash
+// with a __ptype key. The value of a tagged scalar or sequence becomes the __pvalue of that hash
+// and the entries of a tagged mapping become its remaining entries. An alias is streamed as a
+// reference to the node with the corresponding anchor and merge keys are resolved.
+func YamlToData(data []byte, consumer serialization.ValueConsumer) {
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(data, &doc); err != nil {
+		panic(eval.Error(eval.EVAL_PARSE_ERROR, issue.H{`language`: `YAML`, `detail`: err.Error()}))
+	}
+	r := &yamlReader{consumer: consumer, refs: make(map[*yaml3.Node]int)}
+	if len(doc.Content) == 0 {
+		r.add(eval.UNDEF)
+		return
+	}
+	r.stream(doc.Content[0])
+}
+
+// UnmarshalRich parses the given YAML and converts the result into a rich value using a
+// serialization.Deserializer created with the given options. This is the inverse of Marshal.
+func UnmarshalRich(c eval.Context, data []byte, options eval.OrderedMap) eval.Value {
+	ds := serialization.NewDeserializer(c, options)
+	YamlToData(data, ds)
+	return ds.Value()
+}
+
+type yamlReader struct {
+	consumer serialization.ValueConsumer
+	index    int
+	refs     map[*yaml3.Node]int
+}
+
+func fail(n *yaml3.Node, msg string) {
+	panic(eval.Error(eval.EVAL_PARSE_ERROR, issue.H{`language`: `YAML`, `detail`: fmt.Sprintf(`%s at line %d`, msg, n.Line)}))
+}
+
+func (r *yamlReader) stream(n *yaml3.Node) {
+	if n.Kind == yaml3.AliasNode {
+		ref, ok := r.refs[n.Alias]
+		if !ok {
+			fail(n, fmt.Sprintf(`alias '%s' refers to an enclosing node`, n.Value))
+		}
+		r.consumer.AddRef(ref)
+		return
+	}
+	if n.Anchor != `` {
+		r.refs[n] = r.index
+	}
+
+	if typeName, ok := localTag(n.Tag); ok {
+		r.index++
+		switch {
+		case n.Kind == yaml3.MappingNode:
+			entries := r.entries(n)
+			r.consumer.AddHash(len(entries)/2+1, func() {
+				r.add(types.WrapString(serialization.PcoreTypeKey))
+				r.add(types.WrapString(typeName))
+				r.streamAll(entries)
+			})
+		case n.Kind == yaml3.ScalarNode && isPlain(n) && n.Value == ``:
+			r.consumer.AddHash(1, func() {
+				r.add(types.WrapString(serialization.PcoreTypeKey))
+				r.add(types.WrapString(typeName))
+			})
+		default:
+			r.consumer.AddHash(2, func() {
+				r.add(types.WrapString(serialization.PcoreTypeKey))
+				r.add(types.WrapString(typeName))
+				r.add(types.WrapString(serialization.PcoreValueKey))
+				r.content(n, ``)
+			})
+		}
+		return
+	}
+	r.content(n, n.ShortTag())
+}
+
+// content streams the given node using the given standard tag. An empty tag resolves the tag from
+// the value.
+func (r *yamlReader) content(n *yaml3.Node, tag string) {
+	switch n.Kind {
+	case yaml3.ScalarNode:
+		r.add(r.scalar(n, tag))
+	case yaml3.SequenceNode:
+		if tag != `` && tag != `!!seq` {
+			fail(n, fmt.Sprintf(`unsupported tag '%s' for a sequence`, tag))
+		}
+		r.index++
+		r.consumer.AddArray(len(n.Content), func() { r.streamAll(n.Content) })
+	case yaml3.MappingNode:
+		if tag != `` && tag != `!!map` {
+			fail(n, fmt.Sprintf(`unsupported tag '%s' for a mapping`, tag))
+		}
+		entries := r.entries(n)
+		r.index++
+		r.consumer.AddHash(len(entries)/2, func() { r.streamAll(entries) })
+	}
+}
+
+func (r *yamlReader) streamAll(nodes []*yaml3.Node) {
+	for _, c := range nodes {
+		r.stream(c)
+	}
+}
+
+func (r *yamlReader) add(v eval.Value) {
+	r.index++
+	r.consumer.Add(v)
+}
+
+// entries returns the keys and values of the given mapping with merge keys resolved. Entries
+// from merged mappings never override the explicit entries of the mapping or entries from
+// mappings that were merged before them.
+func (r *yamlReader) entries(n *yaml3.Node) []*yaml3.Node {
+	merge := false
+	for i := 0; i < len(n.Content); i += 2 {
+		if isMergeKey(n.Content[i]) {
+			merge = true
+			break
+		}
+	}
+	if !merge {
+		return n.Content
+	}
+
+	explicit := make(map[string]bool)
+	for i := 0; i < len(n.Content); i += 2 {
+		if k := n.Content[i]; !isMergeKey(k) {
+			explicit[keyString(k)] = true
+		}
+	}
+	seen := make(map[string]bool)
+	result := make([]*yaml3.Node, 0, len(n.Content))
+	for i := 0; i < len(n.Content); i += 2 {
+		k := n.Content[i]
+		v := n.Content[i+1]
+		if !isMergeKey(k) {
+			seen[keyString(k)] = true
+			result = append(result, k, v)
+			continue
+		}
+
+		var sources []*yaml3.Node
+		if deref(v).Kind == yaml3.SequenceNode {
+			sources = deref(v).Content
+		} else {
+			sources = []*yaml3.Node{v}
+		}
+		for _, s := range sources {
+			if s = deref(s); s.Kind != yaml3.MappingNode {
+				fail(s, `a merge key must refer to a mapping or a sequence of mappings`)
+			}
+			me := r.entries(s)
+			for j := 0; j < len(me); j += 2 {
+				ks := keyString(me[j])
+				if !(explicit[ks] || seen[ks]) {
+					seen[ks] = true
+					result = append(result, me[j], me[j+1])
+				}
+			}
+		}
+	}
+	return result
+}
+
+func deref(n *yaml3.Node) *yaml3.Node {
+	if n.Kind == yaml3.AliasNode {
+		return n.Alias
+	}
+	return n
+}
+
+func isMergeKey(n *yaml3.Node) bool {
+	return n.Kind == yaml3.ScalarNode && n.ShortTag() == `!!merge`
+}
+
+// isPlain returns true if the given scalar is written without quotes and is not a block scalar
+func isPlain(n *yaml3.Node) bool {
+	return n.Style&(yaml3.DoubleQuotedStyle|yaml3.SingleQuotedStyle|yaml3.LiteralStyle|yaml3.FoldedStyle) == 0
+}
+
+// keyString returns a string that identifies the given key when merging mappings
+func keyString(n *yaml3.Node) string {
+	n = deref(n)
+	if n.Kind == yaml3.ScalarNode {
+		return n.ShortTag() + "\x00" + n.Value
+	}
+	return fmt.Sprintf("\x01%p", n)
+}
+
+// localTag returns the type name of a local tag such as !Timestamp
+func localTag(tag string) (string, bool) {
+	if len(tag) > 1 && tag[0] == '!' && strings.IndexByte(tag[1:], '!') < 0 {
+		return tag[1:], true
+	}
+	return ``, false
+}
+
+func (r *yamlReader) scalar(n *yaml3.Node, tag string) eval.Value {
+	implicit := tag == `` || n.Style&yaml3.TaggedStyle == 0
+	if tag == `` {
+		if !isPlain(n) {
+			return types.WrapString(n.Value)
+		}
+		tag = (&yaml3.Node{Kind: yaml3.ScalarNode, Value: n.Value}).ShortTag()
+	}
+	if implicit && tag == `!!str` && isPlain(n) {
+		// Plain scalars resolve the same way as with Unmarshal, i.e. YAML 1.1 booleans are recognized
+		if b, ok := yaml11Booleans[n.Value]; ok {
+			return b
+		}
+	}
+	switch tag {
+	case `!`, `!!str`, `!!timestamp`:
+		return types.WrapString(n.Value)
+	case `!!null`:
+		return eval.UNDEF
+	case `!!binary`:
+		bs, err := base64.StdEncoding.DecodeString(strings.Map(func(c rune) rune {
+			if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+				return -1
+			}
+			return c
+		}, n.Value))
+		if err != nil {
+			fail(n, err.Error())
+		}
+		return types.WrapBinary(bs)
+	case `!!bool`, `!!int`, `!!float`:
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			fail(n, fmt.Sprintf(`cannot resolve '%s' as %s`, n.Value, tag))
+		}
+		switch v.(type) {
+		case bool:
+			return types.WrapBoolean(v.(bool))
+		case int:
+			return types.WrapInteger(int64(v.(int)))
+		case int64:
+			return types.WrapInteger(v.(int64))
+		case float64:
+			return types.WrapFloat(v.(float64))
+		}
+		fail(n, fmt.Sprintf(`cannot resolve '%s' as %s`, n.Value, tag))
+	}
+	fail(n, fmt.Sprintf(`unsupported tag '%s'`, tag))
+	return nil
+}
+
+// yaml11Booleans are the plain scalars that YAML 1.1 resolves as booleans in addition to true and
+// false
+var yaml11Booleans = map[string]eval.Value{}
+
+func init() {
+	for _, s := range []string{`y`, `Y`, `yes`, `Yes`, `YES`, `on`, `On`, `ON`} {
+		yaml11Booleans[s] = types.BooleanTrue
+	}
+	for _, s := range []string{`n`, `N`, `no`, `No`, `NO`, `off`, `Off`, `OFF`} {
+		yaml11Booleans[s] = types.BooleanFalse
+	}
+}
@@ -0,0 +1,146 @@
+package yaml_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-evaluator/yaml"
+
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func evaluate(ctx eval.Context, source string) eval.Value {
+	expr := ctx.ParseAndValidate(`site.pp`, source, false)
+	ctx.AddDefinitions(expr)
+	v, err := eval.TopEvaluate(ctx, expr)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+const richSource = `
+	type Pcore::Point = Object[attributes => { x => Integer, y => Integer }]
+	$shared = ['a', 'b']
+	$h = {
+		'when' => Timestamp('2019-01-02T03:04:05 UTC'),
+		'secret' => Sensitive('xyzzy'),
+		'origin' => Pcore::Point(0, 0),
+		'lists' => [$shared, $shared],
+		'text' => "line 1\nline 2\n",
+	}
+	$h`
+
+func ExampleMarshal() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		fmt.Print(string(yaml.Marshal(ctx, evaluate(ctx, richSource), eval.EMPTY_MAP)))
+	})
+	// Output:
+	// when:
+	//   __ptype: Timestamp
+	//   __pvalue: 2019-01-02T03:04:05.000000000 UTC
+	// secret:
+	//   __ptype: Sensitive
+	//   __pvalue: xyzzy
+	// origin:
+	//   __ptype: Pcore::Point
+	//   x: 0
+	//   "y": 0
+	// lists:
+	//   - &ref1
+	//     - a
+	//     - b
+	//   - *ref1
+	// text: |
+	//   line 1
+	//   line 2
+}
+
+func ExampleMarshal_tags() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		fmt.Print(string(yaml.Marshal(ctx, evaluate(ctx, richSource), types.SingletonHash2(`tags`, types.BooleanTrue))))
+	})
+	// Output:
+	// when: !Timestamp 2019-01-02T03:04:05.000000000 UTC
+	// secret: !Sensitive xyzzy
+	// origin: !Pcore::Point
+	//   x: 0
+	//   "y": 0
+	// lists:
+	//   - &ref1
+	//     - a
+	//     - b
+	//   - *ref1
+	// text: |
+	//   line 1
+	//   line 2
+}
+
+func ExampleUnmarshalRich() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		evaluate(ctx, `type Pcore::Point = Object[attributes => { x => Integer, y => Integer }]`)
+		v := yaml.UnmarshalRich(ctx, []byte(`
+defaults: &defaults
+  timeout: !Timespan '00:00:30'
+  version: !SemVer 1.2.3
+server:
+  <<: *defaults
+  timeout: !Timespan '00:01:00'
+  location: !Pcore::Point { x: 1, "y": 2 }
+  password: !Sensitive s3cret
+  data: !!binary aGVsbG8=
+`), eval.EMPTY_MAP)
+		v.(eval.OrderedMap).Get5(`server`, eval.UNDEF).(eval.OrderedMap).EachPair(func(k, e eval.Value) {
+			fmt.Printf("%s: %s %s\n", k, e.PType().Name(), e)
+		})
+	})
+	// Output:
+	// version: SemVer 1.2.3
+	// timeout: Timespan 60
+	// location: Pcore::Point Pcore::Point('x' => 1, 'y' => 2)
+	// password: Sensitive Sensitive [value redacted]
+	// data: Binary aGVsbG8=
+}
+
+func ExampleUnmarshalRich_roundtrip() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		v := evaluate(ctx, richSource)
+		for _, tags := range []bool{false, true} {
+			data := yaml.Marshal(ctx, v, types.SingletonHash2(`tags`, types.WrapBoolean(tags)))
+			fmt.Println(fmt.Sprint(yaml.UnmarshalRich(ctx, data, eval.EMPTY_MAP)) == fmt.Sprint(v))
+		}
+	})
+	// Output:
+	// true
+	// true
+}
+
+func ExampleYamlToData() {
+	eval.Puppet.Do(func(ctx eval.Context) {
+		for _, source := range []string{
+			"[yes, 'no', !!str on, 0x10, !!float 1, ~, 2001-12-14, !!binary aGk=]",
+			"? [a, b]\n: complex key",
+			"%YAML 1.1\n---\na: !!int x",
+			"a: !!set {}",
+		} {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						fmt.Println(strings.TrimSpace(fmt.Sprint(r)))
+					}
+				}()
+				c := serialization.NewCollector()
+				yaml.YamlToData([]byte(source), c)
+				fmt.Println(c.Value())
+			}()
+		}
+	})
+	// Output:
+	// [true, 'no', 'on', 16, 1.00000, undef, '2001-12-14', Binary('aGk=')]
+	// {['a', 'b'] => 'complex key'}
+	// Unable to parse YAML. Detail: cannot resolve 'x' as !!int at line 3
+	// Unable to parse YAML. Detail: unsupported tag '!!set' for a mapping at line 1
+}